@@ -22,13 +22,16 @@ const (
 	AuthProvCleanupAnnotationValUnlocked = "unlocked"
 	OpenLdapAuthInput                    = "openLdapAuthInput"
 	ActiveDirectoryAuthInput             = "activeDirectoryAuthInput"
+	FreeIpaAuthInput                     = "freeIpaAuthInput"
 	AccessModeUnrestricted               = "unrestricted"
 	AccessModeRestricted                 = "restricted"
 	AccessModeRequired                   = "required"
 	OpenLdap                             = "openldap"
 	ActiveDirectory                      = "activedirectory"
+	FreeIpa                              = "freeipa"
 	OpenLdapPasswordSecretID             = "openldapconfig-serviceaccountpassword"
 	ActiveDirectoryPasswordSecretID      = "activedirectoryconfig-serviceaccountpassword"
+	FreeIpaPasswordSecretID              = "freeipaconfig-serviceaccountpassword"
 )
 
 type User struct {
@@ -103,7 +106,7 @@ func LoginAsAuthUser(client *rancher.Client, user *v3.User, providerName string)
 	return client.AsAuthUser(user, auth.Provider(providerName))
 }
 
-// NewPrincipalID constructs a principal ID string in the format required by AD authentication
+// NewPrincipalID constructs a principal ID string in the format required by the LDAP-based auth providers
 func NewPrincipalID(authConfigID, principalType, name, userSearchBase, groupSearchBase string) string {
 	baseDN := userSearchBase
 
@@ -111,11 +114,7 @@ func NewPrincipalID(authConfigID, principalType, name, userSearchBase, groupSear
 		baseDN = groupSearchBase
 	}
 
-	cnAttribute := "cn"
-	if authConfigID == ActiveDirectory {
-		cnAttribute = "CN"
-	}
-	return fmt.Sprintf("%s_%s://%s=%s,%s", authConfigID, principalType, cnAttribute, name, baseDN)
+	return fmt.Sprintf("%s_%s://%s=%s,%s", authConfigID, principalType, principalRDNAttribute(authConfigID, principalType), name, baseDN)
 }
 
 // NewAuthConfigWithAccessMode retrieves the current auth config and returns both the existing config and an updated version with the specified access mode
@@ -173,15 +172,12 @@ func EnsureAuthProviderEnabled(client *rancher.Client, providerName string) erro
 		return nil
 	}
 
-	switch providerName {
-	case OpenLdap:
-		err = client.Auth.OLDAP.Enable()
-	case ActiveDirectory:
-		err = client.Auth.ActiveDirectory.Enable()
-	default:
-		return fmt.Errorf("unsupported auth provider: %s", providerName)
+	providerClient, err := ldapProviderClient(client, providerName)
+	if err != nil {
+		return err
 	}
 
+	err = providerClient.Enable()
 	if err != nil {
 		return fmt.Errorf("failed to enable auth provider %s: %w", providerName, err)
 	}
@@ -220,15 +216,12 @@ func UpdateAccessMode(client *rancher.Client, providerName, accessMode string, a
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare auth config with access mode %s: %w", accessMode, err)
 	}
-	var updatedConfig *v3.AuthConfig
-	switch providerName {
-	case OpenLdap:
-		updatedConfig, err = client.Auth.OLDAP.Update(existing, updates)
-	case ActiveDirectory:
-		updatedConfig, err = client.Auth.ActiveDirectory.Update(existing, updates)
-	default:
-		return nil, fmt.Errorf("unsupported auth provider for update: %s", providerName)
+	providerClient, err := ldapProviderClient(client, providerName)
+	if err != nil {
+		return nil, err
 	}
+
+	updatedConfig, err := providerClient.Update(existing, updates)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update auth config to access mode %s: %w", accessMode, err)
 	}
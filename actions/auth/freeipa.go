@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/auth/openldap"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+)
+
+const (
+	FreeIpaConfigurationFileKey = "freeIPA"
+	freeIpaSchemaType           = "freeIpaConfigs"
+)
+
+// FreeIPAClient enables, updates and disables the FreeIPA auth provider. FreeIPA is configured with the same fields
+// as OpenLDAP, read from the freeIPA key of the config file.
+type FreeIPAClient struct {
+	client  *management.Client
+	session *session.Session
+
+	Config *openldap.Config
+}
+
+// loadFreeIPAConfig reads FreeIPA from the configuration file, once
+var loadFreeIPAConfig = sync.OnceValue(func() *openldap.Config {
+	freeIpaConfig := new(openldap.Config)
+	config.LoadConfig(FreeIpaConfigurationFileKey, freeIpaConfig)
+
+	return freeIpaConfig
+})
+
+// NewFreeIPAClient constructs a FreeIPAClient with FreeIPA as read from the configuration file
+func NewFreeIPAClient(client *rancher.Client) *FreeIPAClient {
+	return &FreeIPAClient{
+		client:  client.Management,
+		session: client.Session,
+		Config:  loadFreeIPAConfig(),
+	}
+}
+
+// Enable makes a request to the testAndApply action with the configured values
+func (f *FreeIPAClient) Enable() error {
+	var jsonResp map[string]any
+
+	enableActionInput, err := f.newEnableInputFromConfig()
+	if err != nil {
+		return err
+	}
+
+	err = f.client.Ops.DoModify("POST", f.newActionURL("testAndApply"), enableActionInput, &jsonResp)
+	if err != nil {
+		return err
+	}
+
+	f.session.RegisterCleanupFunc(func() error {
+		return f.Disable()
+	})
+
+	return nil
+}
+
+// Update makes an update with the given configuration values
+func (f *FreeIPAClient) Update(existing, updates *management.AuthConfig) (*management.AuthConfig, error) {
+	return f.client.AuthConfig.Update(existing, updates)
+}
+
+// Disable makes a request to disable FreeIPA
+func (f *FreeIPAClient) Disable() error {
+	var jsonResp map[string]any
+
+	disableActionInput := []byte(`{"action": "disable"}`)
+
+	return f.client.Ops.DoModify("POST", f.newActionURL("disable"), &disableActionInput, &jsonResp)
+}
+
+func (f *FreeIPAClient) newActionURL(action string) string {
+	return fmt.Sprintf("%v/%v/%v?action=%v", f.client.Opts.URL, freeIpaSchemaType, FreeIpa, action)
+}
+
+func (f *FreeIPAClient) newEnableInputFromConfig() (*management.FreeIpaTestAndApplyInput, error) {
+	if f.Config.Hostname == "" && f.Config.IP == "" {
+		return nil, fmt.Errorf("FreeIPA Hostname and IP are empty, please provide one of them")
+	}
+
+	server := f.Config.Hostname
+	if server == "" {
+		server = f.Config.IP
+	}
+
+	if f.Config.ServiceAccount == nil || f.Config.Users == nil || f.Config.Groups == nil {
+		return nil, fmt.Errorf("FreeIPA serviceAccount, users and groups are required")
+	}
+
+	if f.Config.Users.Admin == nil || f.Config.Users.Admin.Username == "" || f.Config.Users.Admin.Password == "" {
+		return nil, fmt.Errorf("admin username or password are empty, please provide them")
+	}
+
+	ldapConfig := &management.LdapConfig{
+		Enabled:                         true,
+		AccessMode:                      f.Config.AccessMode,
+		Servers:                         []string{server},
+		ServiceAccountDistinguishedName: f.Config.ServiceAccount.DistinguishedName,
+		ServiceAccountPassword:          f.Config.ServiceAccount.Password,
+		UserSearchBase:                  f.Config.Users.SearchBase,
+		UserObjectClass:                 "inetOrgPerson",
+		UserLoginAttribute:              "uid",
+		UserNameAttribute:               "givenName",
+		UserMemberAttribute:             "memberOf",
+		GroupSearchBase:                 f.Config.Groups.SearchBase,
+		GroupObjectClass:                f.Config.Groups.ObjectClass,
+		GroupMemberMappingAttribute:     f.Config.Groups.MemberMappingAttribute,
+		GroupMemberUserAttribute:        "entryDN",
+		GroupDNAttribute:                "entryDN",
+		NestedGroupMembershipEnabled:    f.Config.Groups.NestedGroupMembershipEnabled,
+	}
+
+	return &management.FreeIpaTestAndApplyInput{
+		LdapConfig: ldapConfig,
+		Username:   f.Config.Users.Admin.Username,
+		Password:   f.Config.Users.Admin.Password,
+	}, nil
+}
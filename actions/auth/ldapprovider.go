@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/auth/activedirectory"
+	"github.com/rancher/shepherd/clients/rancher/auth/openldap"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
+)
+
+// LDAPProviderClient is the set of operations shared by the clients of the LDAP-based auth providers
+type LDAPProviderClient interface {
+	Enable() error
+	Disable() error
+	Update(existing, updates *management.AuthConfig) (*management.AuthConfig, error)
+}
+
+// LDAPProvider describes an LDAP-based auth provider, so the same tests can run against OpenLDAP, FreeIPA and
+// Active Directory
+type LDAPProvider struct {
+	// Name is the ID of the auth config of the provider, also used as the scheme of its principal IDs
+	Name        string
+	DisplayName string
+	// AuthInputKey is the config file key of the AuthConfig with the users and groups used by the tests
+	AuthInputKey     string
+	PasswordSecretID string
+	// UserRDNAttribute and GroupRDNAttribute are the attributes of the first component of user and group DNs
+	UserRDNAttribute       string
+	GroupRDNAttribute      string
+	UserSearchBase         string
+	GroupSearchBase        string
+	AdminUser              User
+	ServiceAccountPassword string
+
+	descriptor ldapProviderDescriptor
+}
+
+// ldapProviderDescriptor holds everything that differs between the LDAP-based auth providers
+type ldapProviderDescriptor struct {
+	displayName       string
+	authInputKey      string
+	passwordSecretID  string
+	userRDNAttribute  string
+	groupRDNAttribute string
+	// newClient returns the client enabling, updating and disabling the provider
+	newClient func(client *rancher.Client) LDAPProviderClient
+	// loadConfig fills the search bases and credentials of the provider from the configuration of the client
+	loadConfig func(client *rancher.Client, provider *LDAPProvider)
+}
+
+var ldapProviders = map[string]ldapProviderDescriptor{
+	OpenLdap: {
+		displayName:       "OpenLDAP",
+		authInputKey:      OpenLdapAuthInput,
+		passwordSecretID:  OpenLdapPasswordSecretID,
+		userRDNAttribute:  "cn",
+		groupRDNAttribute: "cn",
+		newClient: func(client *rancher.Client) LDAPProviderClient {
+			return client.Auth.OLDAP
+		},
+		loadConfig: func(client *rancher.Client, provider *LDAPProvider) {
+			fillFromOpenLDAPConfig(provider, client.Auth.OLDAP.Config)
+		},
+	},
+	FreeIpa: {
+		displayName:       "FreeIPA",
+		authInputKey:      FreeIpaAuthInput,
+		passwordSecretID:  FreeIpaPasswordSecretID,
+		userRDNAttribute:  "uid",
+		groupRDNAttribute: "cn",
+		newClient: func(client *rancher.Client) LDAPProviderClient {
+			return NewFreeIPAClient(client)
+		},
+		loadConfig: func(_ *rancher.Client, provider *LDAPProvider) {
+			fillFromOpenLDAPConfig(provider, loadFreeIPAConfig())
+		},
+	},
+	ActiveDirectory: {
+		displayName:       "Active Directory",
+		authInputKey:      ActiveDirectoryAuthInput,
+		passwordSecretID:  ActiveDirectoryPasswordSecretID,
+		userRDNAttribute:  "CN",
+		groupRDNAttribute: "CN",
+		newClient: func(client *rancher.Client) LDAPProviderClient {
+			return client.Auth.ActiveDirectory
+		},
+		loadConfig: func(client *rancher.Client, provider *LDAPProvider) {
+			fillFromActiveDirectoryConfig(provider, client.Auth.ActiveDirectory.Config)
+		},
+	},
+}
+
+// NewLDAPProvider returns the descriptor of the LDAP-based auth provider, filled from the provider configuration of
+// the client
+func NewLDAPProvider(client *rancher.Client, providerName string) (*LDAPProvider, error) {
+	descriptor, ok := ldapProviders[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported LDAP auth provider: %s", providerName)
+	}
+
+	provider := &LDAPProvider{
+		Name:              providerName,
+		DisplayName:       descriptor.displayName,
+		AuthInputKey:      descriptor.authInputKey,
+		PasswordSecretID:  descriptor.passwordSecretID,
+		UserRDNAttribute:  descriptor.userRDNAttribute,
+		GroupRDNAttribute: descriptor.groupRDNAttribute,
+		descriptor:        descriptor,
+	}
+
+	descriptor.loadConfig(client, provider)
+
+	return provider, nil
+}
+
+// NewClient returns the client enabling, updating and disabling the provider
+func (p *LDAPProvider) NewClient(client *rancher.Client) LDAPProviderClient {
+	return p.descriptor.newClient(client)
+}
+
+// LoadLDAPAuthInput returns the users and groups used by the tests of the provider from the config file. For
+// OpenLDAP, when a fixture seed file is configured, the fixture is deployed and the AuthConfig of its seed is
+// returned instead.
+func LoadLDAPAuthInput(client *rancher.Client, providerName string) (*AuthConfig, error) {
+	if providerName == OpenLdap {
+		fixtureConfig := new(OpenLDAPFixtureConfig)
+		config.LoadConfig(OpenLdapFixtureInput, fixtureConfig)
+
+		if fixtureConfig.SeedFile != "" {
+			seed, err := LoadOpenLDAPSeed(fixtureConfig.SeedFile)
+			if err != nil {
+				return nil, err
+			}
+
+			return DeployOpenLDAPFixture(client, seed)
+		}
+	}
+
+	provider, err := NewLDAPProvider(client, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig := new(AuthConfig)
+	config.LoadConfig(provider.AuthInputKey, authConfig)
+
+	return authConfig, nil
+}
+
+// UserPrincipalID returns the principal ID of the user in the provider
+func (p *LDAPProvider) UserPrincipalID(username string) string {
+	return GetUserPrincipalID(p.Name, username, p.UserSearchBase, p.GroupSearchBase)
+}
+
+// GroupPrincipalID returns the principal ID of the group in the provider
+func (p *LDAPProvider) GroupPrincipalID(groupName string) string {
+	return GetGroupPrincipalID(p.Name, groupName, p.UserSearchBase, p.GroupSearchBase)
+}
+
+// ldapProviderClient returns the client of the LDAP-based auth provider
+func ldapProviderClient(client *rancher.Client, providerName string) (LDAPProviderClient, error) {
+	descriptor, ok := ldapProviders[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported auth provider: %s", providerName)
+	}
+
+	return descriptor.newClient(client), nil
+}
+
+// principalRDNAttribute returns the attribute of the first component of the DN of a principal of the provider
+func principalRDNAttribute(providerName, principalType string) string {
+	descriptor, ok := ldapProviders[providerName]
+	if !ok {
+		return "cn"
+	}
+
+	if principalType == "group" {
+		return descriptor.groupRDNAttribute
+	}
+
+	return descriptor.userRDNAttribute
+}
+
+func fillFromOpenLDAPConfig(provider *LDAPProvider, ldapConfig *openldap.Config) {
+	if ldapConfig.Users != nil {
+		provider.UserSearchBase = ldapConfig.Users.SearchBase
+		if ldapConfig.Users.Admin != nil {
+			provider.AdminUser = User{Username: ldapConfig.Users.Admin.Username, Password: ldapConfig.Users.Admin.Password}
+		}
+	}
+	if ldapConfig.Groups != nil {
+		provider.GroupSearchBase = ldapConfig.Groups.SearchBase
+	}
+	if ldapConfig.ServiceAccount != nil {
+		provider.ServiceAccountPassword = ldapConfig.ServiceAccount.Password
+	}
+}
+
+func fillFromActiveDirectoryConfig(provider *LDAPProvider, adConfig *activedirectory.Config) {
+	if adConfig.Users != nil {
+		provider.UserSearchBase = adConfig.Users.SearchBase
+		if adConfig.Users.Admin != nil {
+			provider.AdminUser = User{Username: adConfig.Users.Admin.Username, Password: adConfig.Users.Admin.Password}
+		}
+	}
+	if adConfig.Groups != nil {
+		provider.GroupSearchBase = adConfig.Groups.SearchBase
+	}
+	if adConfig.ServiceAccount != nil {
+		provider.ServiceAccountPassword = adConfig.ServiceAccount.Password
+	}
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	apisv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	GenericOIDC                   = "genericoidc"
+	GenericOIDCClientSecretID     = "genericoidcconfig-clientsecret"
+	genericOIDCSchemaType         = "genericOIDCConfigs"
+	genericOIDCPublicProviderType = "genericOIDCProviders"
+)
+
+// NewGenericOIDCConfig returns the generic OIDC configuration of Rancher for the fixture
+func NewGenericOIDCConfig(fixture *OIDCFixture) apisv3.OIDCConfig {
+	groupSearchEnabled := false
+
+	return apisv3.OIDCConfig{
+		AuthConfig: apisv3.AuthConfig{
+			AccessMode: AccessModeUnrestricted,
+		},
+		ClientID:           fixture.ClientID,
+		ClientSecret:       fixture.ClientSecret,
+		RancherURL:         fixture.RancherURL,
+		Issuer:             fixture.Issuer,
+		Scopes:             oidcScopes,
+		GroupsClaim:        oidcGroupsClaim,
+		GroupSearchEnabled: &groupSearchEnabled,
+	}
+}
+
+// EnableGenericOIDC configures the generic OIDC provider against the fixture and enables it. The admin user of the
+// seed logs in the identity provider to test the configuration, and its principal is bound to the current user.
+// The provider is disabled when the client session is cleaned up.
+func EnableGenericOIDC(client *rancher.Client, fixture *OIDCFixture) error {
+	code, err := fixture.AuthorizationCode(fixture.Seed.Admin())
+	if err != nil {
+		return fmt.Errorf("failed to log admin in the identity provider: %w", err)
+	}
+
+	applyInput := apisv3.OIDCApplyInput{
+		OIDCConfig: NewGenericOIDCConfig(fixture),
+		Code:       code,
+		Enabled:    true,
+	}
+
+	var jsonResp map[string]any
+	url := fmt.Sprintf("%s/%s/%s?action=testAndApply", client.Management.Opts.URL, genericOIDCSchemaType, GenericOIDC)
+	err = client.Management.Ops.DoModify("POST", url, applyInput, &jsonResp)
+	if err != nil {
+		return fmt.Errorf("failed to enable generic OIDC: %w", err)
+	}
+
+	client.Session.RegisterCleanupFunc(func() error {
+		return DisableGenericOIDC(client)
+	})
+
+	_, err = WaitForAuthProviderAnnotationUpdate(client, GenericOIDC, AuthProvCleanupAnnotationValUnlocked)
+	return err
+}
+
+// DisableGenericOIDC disables the generic OIDC provider
+func DisableGenericOIDC(client *rancher.Client) error {
+	var jsonResp map[string]any
+	url := fmt.Sprintf("%s/%s/%s?action=disable", client.Management.Opts.URL, genericOIDCSchemaType, GenericOIDC)
+
+	return client.Management.Ops.DoModify("POST", url, nil, &jsonResp)
+}
+
+// LoginAsOIDCUser logs the user in the identity provider and in Rancher with the authorization code, and returns a
+// client with the Rancher token of the user and the token itself
+func LoginAsOIDCUser(client *rancher.Client, fixture *OIDCFixture, user User) (*rancher.Client, *management.Token, error) {
+	code, err := fixture.AuthorizationCode(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := &management.Token{}
+	url := fmt.Sprintf("https://%s/v3-public/%s/%s?action=login", client.RancherConfig.Host, genericOIDCPublicProviderType, GenericOIDC)
+	err = client.Management.Ops.DoModify("POST", url, apisv3.OIDCLogin{Code: code}, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user [%v] failed to login to Rancher: %w", user.Username, err)
+	}
+
+	userClient, err := rancher.NewClientForConfig(token.Token, client.RancherConfig, client.Session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userClient, token, nil
+}
+
+// VerifyOIDCUserLogins attempts to log every user in through the identity provider, and verifies that the login
+// succeeds or fails as expected
+func VerifyOIDCUserLogins(client *rancher.Client, fixture *OIDCFixture, users []User, description string, shouldSucceed bool) error {
+	for _, user := range users {
+		_, _, err := LoginAsOIDCUser(client, fixture, user)
+
+		if shouldSucceed && err != nil {
+			return fmt.Errorf("user [%v] should be able to login (%s): %w", user.Username, description, err)
+		}
+
+		if !shouldSucceed && err == nil {
+			return fmt.Errorf("user [%v] should NOT be able to login (%s)", user.Username, description)
+		}
+	}
+
+	return nil
+}
+
+// LogoutAuthUser logs the user of the client out, which revokes the token of the client
+func LogoutAuthUser(userClient *rancher.Client) error {
+	var jsonResp map[string]any
+	url := fmt.Sprintf("%s/tokens?action=logout", userClient.Management.Opts.URL)
+
+	return userClient.Management.Ops.DoModify("POST", url, nil, &jsonResp)
+}
+
+// SearchPrincipals searches the principals of the enabled auth providers matching the name and type
+func SearchPrincipals(client *rancher.Client, name, principalType string) ([]management.Principal, error) {
+	searchInput := map[string]string{
+		"name":          name,
+		"principalType": principalType,
+	}
+
+	var collection management.PrincipalCollection
+	url := fmt.Sprintf("%s/principals?action=search", client.Management.Opts.URL)
+	err := client.Management.Ops.DoModify("POST", url, searchInput, &collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search principals matching %s: %w", name, err)
+	}
+
+	return collection.Data, nil
+}
+
+// GetPrincipal returns the principal with the ID, as the user of the client sees it
+func GetPrincipal(client *rancher.Client, principalID string) (*management.Principal, error) {
+	principal, err := client.Management.Principal.ByID(url.PathEscape(principalID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get principal %s: %w", principalID, err)
+	}
+
+	return principal, nil
+}
+
+// ListPrincipals returns the principals of the user of the client: its user principal and its group principals
+func ListPrincipals(client *rancher.Client) ([]management.Principal, error) {
+	collection, err := client.Management.Principal.List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list principals: %w", err)
+	}
+
+	return collection.Data, nil
+}
+
+// GetOIDCGroupPrincipalID returns the principal ID of a group of the groups claim
+func GetOIDCGroupPrincipalID(providerName, groupName string) string {
+	return fmt.Sprintf("%s_group://%s", providerName, groupName)
+}
+
+// VerifyUserGroupPrincipals waits for the group principals Rancher stored for the user at login to be exactly the
+// expected groups of the provider
+func VerifyUserGroupPrincipals(client *rancher.Client, userID, providerName string, expectedGroups []string) error {
+	var expectedPrincipalIDs []string
+	for _, group := range expectedGroups {
+		expectedPrincipalIDs = append(expectedPrincipalIDs, GetOIDCGroupPrincipalID(providerName, group))
+	}
+	slices.Sort(expectedPrincipalIDs)
+
+	var principalIDs []string
+	err := kwait.PollUntilContextTimeout(context.Background(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(context.Context) (bool, error) {
+		userAttribute, err := client.WranglerContext.Mgmt.UserAttribute().Get(userID, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+
+		principalIDs = nil
+		for _, principal := range userAttribute.GroupPrincipals[providerName].Items {
+			principalIDs = append(principalIDs, principal.Name)
+		}
+		slices.Sort(principalIDs)
+
+		return slices.Equal(principalIDs, expectedPrincipalIDs), nil
+	})
+	if err != nil {
+		return fmt.Errorf("group principals of user %s are %v, expected %v: %w", userID, principalIDs, expectedPrincipalIDs, err)
+	}
+
+	return nil
+}
+
+// UpdateOIDCAccessMode updates the access mode of the OIDC auth provider with optional allowed principal IDs
+func UpdateOIDCAccessMode(client *rancher.Client, providerName, accessMode string, allowedPrincipalIDs []string) (*management.AuthConfig, error) {
+	existing, updates, err := NewAuthConfigWithAccessMode(client, providerName, accessMode, allowedPrincipalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare auth config with access mode %s: %w", accessMode, err)
+	}
+
+	updatedConfig, err := client.Management.AuthConfig.Update(existing, updates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update auth config to access mode %s: %w", accessMode, err)
+	}
+
+	return updatedConfig, nil
+}
+
+// SeedGroupsOfUser returns the groups of the seed the user is a direct member of, the groups the identity provider
+// sends in the groups claim
+func SeedGroupsOfUser(seed *OpenLDAPSeed, username string) []string {
+	var groups []string
+	for _, group := range seed.Groups {
+		if slices.Contains(group.Users, username) {
+			groups = append(groups, group.Name)
+		}
+	}
+
+	return groups
+}
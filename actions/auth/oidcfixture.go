@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	OIDCFixtureInput    = "oidcFixtureInput"
+	DefaultDexImage     = "ghcr.io/dexidp/dex:v2.41.1"
+	dexFixtureName      = "dex"
+	dexPort             = 5556
+	dexConfigFileName   = "config.yaml"
+	dexConfigDir        = "/etc/dex"
+	dexConnectorID      = "ldap"
+	dexClientID         = "rancher"
+	dexEmailSuffix      = "example.com"
+	oidcGroupsClaim     = "groups"
+	oidcScopes          = "openid profile email groups"
+	maxAuthorizationHop = 10
+)
+
+// OIDCFixtureConfig is the configuration of the OIDC fixture, read from the oidcFixtureInput key of the config file
+type OIDCFixtureConfig struct {
+	// SeedFile is the OpenLDAP seed with the users and groups the identity provider authenticates
+	SeedFile string `json:"seedFile" yaml:"seedFile"`
+	Image    string `json:"image" yaml:"image"`
+}
+
+// OIDCFixture is a Dex identity provider deployed in the local cluster, backed by an OpenLDAP server seeded with
+// the users and groups of an OpenLDAP seed. The groups of a user are sent in the groups claim.
+type OIDCFixture struct {
+	Namespace    string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RancherURL is the redirect URI registered for Rancher in the identity provider
+	RancherURL string
+	Seed       *OpenLDAPSeed
+
+	proxyURL    string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// DeployOIDCFixture deploys an OpenLDAP server seeded from the seed and a Dex identity provider authenticating its
+// users in a new namespace of the local cluster. Rancher reaches the identity provider through its service, and the
+// tests through the Kubernetes service proxy of the local cluster. The namespace is removed when the client session
+// is cleaned up.
+func DeployOIDCFixture(client *rancher.Client, seed *OpenLDAPSeed, image string) (*OIDCFixture, error) {
+	err := seed.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenLDAP seed: %w", err)
+	}
+
+	if image == "" {
+		image = DefaultDexImage
+	}
+
+	namespaceName, err := createFixtureNamespace(client, "oidc-fixture")
+	if err != nil {
+		return nil, err
+	}
+
+	ldapHostname, err := deployOpenLDAPServer(client, namespaceName, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &OIDCFixture{
+		Namespace:    namespaceName,
+		Issuer:       fmt.Sprintf("http://%s:%d/dex", fixtureServiceHostname(dexFixtureName, namespaceName), dexPort),
+		ClientID:     dexClientID,
+		ClientSecret: namegen.RandStringLower(32),
+		RancherURL:   fmt.Sprintf("https://%s/verify-auth", client.RancherConfig.Host),
+		Seed:         seed,
+		proxyURL:     fixtureProxyURL(client, dexFixtureName, namespaceName, dexPort),
+		bearerToken:  client.RancherConfig.AdminToken,
+		httpClient:   newFixtureHTTPClient(client, nil),
+	}
+
+	dexConfig, err := fixture.dexConfig(ldapHostname)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = client.WranglerContext.Core.Secret().Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dexFixtureName,
+			Namespace: namespaceName,
+		},
+		StringData: map[string]string{
+			dexConfigFileName: dexConfig,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Dex config secret: %w", err)
+	}
+
+	labels := map[string]string{"app": dexFixtureName}
+	_, err = client.WranglerContext.Apps.Deployment().Create(newDexDeployment(namespaceName, image, labels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Dex deployment: %w", err)
+	}
+
+	_, err = client.WranglerContext.Core.Service().Create(newFixtureService(dexFixtureName, namespaceName, labels, dexPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Dex service: %w", err)
+	}
+
+	err = deployments.WaitForDeploymentActive(client, extclusterapi.LocalCluster, namespaceName, dexFixtureName)
+	if err != nil {
+		return nil, fmt.Errorf("Dex fixture did not become ready: %w", err)
+	}
+
+	return fixture, nil
+}
+
+// AuthorizationCode logs the user in the identity provider without a browser, following the authorization code
+// flow up to the redirection to Rancher, and returns the authorization code Rancher exchanges for the user tokens
+func (f *OIDCFixture) AuthorizationCode(user User) (string, error) {
+	query := url.Values{}
+	query.Set("client_id", f.ClientID)
+	query.Set("redirect_uri", f.RancherURL)
+	query.Set("response_type", "code")
+	query.Set("scope", oidcScopes)
+	query.Set("state", namegen.RandStringLower(16))
+	query.Set("connector_id", dexConnectorID)
+
+	requestURL := f.proxyURL + "/dex/auth?" + query.Encode()
+	method := http.MethodGet
+	var form url.Values
+
+	for range maxAuthorizationHop {
+		resp, err := f.do(method, requestURL, form)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 300 && resp.StatusCode < 400:
+			location := resp.Header.Get("Location")
+			if strings.HasPrefix(location, f.RancherURL) {
+				return authorizationCodeFromRedirect(location)
+			}
+
+			requestURL, err = fixtureProxiedURL(f.proxyURL, dexFixtureName, dexPort, location)
+			if err != nil {
+				return "", err
+			}
+			method, form = http.MethodGet, nil
+		case resp.StatusCode == http.StatusOK && method == http.MethodGet:
+			// the login form is posted back to the URL it is served from
+			method = http.MethodPost
+			form = url.Values{"login": {user.Username}, "password": {user.Password}}
+		case resp.StatusCode == http.StatusOK:
+			return "", fmt.Errorf("identity provider rejected the credentials of user %s", user.Username)
+		default:
+			return "", fmt.Errorf("unexpected status %d from the identity provider at %s", resp.StatusCode, requestURL)
+		}
+	}
+
+	return "", fmt.Errorf("no redirection to Rancher after %d requests to the identity provider", maxAuthorizationHop)
+}
+
+func (f *OIDCFixture) do(method, requestURL string, form url.Values) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+f.bearerToken)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	return f.httpClient.Do(req)
+}
+
+func (f *OIDCFixture) dexConfig(ldapHostname string) (string, error) {
+	config := map[string]any{
+		"issuer": f.Issuer,
+		"storage": map[string]any{
+			"type": "memory",
+		},
+		"web": map[string]any{
+			"http": fmt.Sprintf("0.0.0.0:%d", dexPort),
+		},
+		"oauth2": map[string]any{
+			"skipApprovalScreen": true,
+		},
+		"staticClients": []map[string]any{
+			{
+				"id":           f.ClientID,
+				"name":         "Rancher",
+				"secret":       f.ClientSecret,
+				"redirectURIs": []string{f.RancherURL},
+			},
+		},
+		"connectors": []map[string]any{
+			{
+				"type": "ldap",
+				"id":   dexConnectorID,
+				"name": "OpenLDAP",
+				"config": map[string]any{
+					"host":          fmt.Sprintf("%s:%d", ldapHostname, openLDAPPort),
+					"insecureNoSSL": true,
+					"bindDN":        f.Seed.AdminDN(),
+					"bindPW":        f.Seed.AdminPassword,
+					"userSearch": map[string]any{
+						"baseDN":      f.Seed.UserSearchBase(),
+						"filter":      "(objectClass=inetOrgPerson)",
+						"username":    "uid",
+						"idAttr":      "uid",
+						"nameAttr":    "cn",
+						"emailSuffix": dexEmailSuffix,
+					},
+					"groupSearch": map[string]any{
+						"baseDN": f.Seed.GroupSearchBase(),
+						"filter": fmt.Sprintf("(objectClass=%s)", openLDAPGroupObjectClass),
+						"userMatchers": []map[string]any{
+							{"userAttr": "DN", "groupAttr": openLDAPMemberAttribute},
+						},
+						"nameAttr": "cn",
+					},
+				},
+			},
+		},
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to render Dex config: %w", err)
+	}
+
+	return string(content), nil
+}
+
+func newDexDeployment(namespace, image string, labels map[string]string) *appsv1.Deployment {
+	var replicas int32 = 1
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dexFixtureName,
+			Namespace: namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            dexFixtureName,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"dex", "serve", dexConfigDir + "/" + dexConfigFileName},
+							Ports: []corev1.ContainerPort{
+								{Name: "http", ContainerPort: dexPort},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "config", MountPath: dexConfigDir},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/dex/.well-known/openid-configuration",
+										Port: intstr.FromInt32(dexPort),
+									},
+								},
+								PeriodSeconds: 5,
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: dexFixtureName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func authorizationCodeFromRedirect(location string) (string, error) {
+	redirect, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirection to Rancher %q: %w", location, err)
+	}
+
+	query := redirect.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		return "", fmt.Errorf("identity provider returned %s: %s", errorCode, query.Get("error_description"))
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("no authorization code in the redirection to Rancher %q", location)
+	}
+
+	return code, nil
+}
@@ -0,0 +1,603 @@
+package auth
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/auth/openldap"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	OpenLdapFixtureInput       = "openLdapFixtureInput"
+	DefaultOpenLDAPImage       = "osixia/openldap:1.5.0"
+	openLDAPFixtureName        = "openldap"
+	openLDAPPort               = 389
+	openLDAPSeedFileName       = "50-seed.ldif"
+	openLDAPCustomLDIFDir      = "/container/service/slapd/assets/config/bootstrap/ldif/custom"
+	openLDAPGroupObjectClass   = "groupOfNames"
+	openLDAPMemberAttribute    = "member"
+	openLDAPUsersOrganization  = "users"
+	openLDAPGroupsOrganization = "groups"
+)
+
+// OpenLDAPFixtureConfig is the configuration of the OpenLDAP fixture, read from the openLdapFixtureInput key of the
+// config file. When SeedFile is empty, the tests use the external LDAP server of the openLDAP config instead.
+type OpenLDAPFixtureConfig struct {
+	SeedFile string `json:"seedFile" yaml:"seedFile"`
+}
+
+// OpenLDAPSeed is the declarative description of the users and groups the OpenLDAP fixture is seeded with
+type OpenLDAPSeed struct {
+	// Domain is the LDAP domain, such as qa.rancher.space, that the base DN dc=qa,dc=rancher,dc=space is built from
+	Domain        string `yaml:"domain"`
+	AdminPassword string `yaml:"adminPassword"`
+	// AdminUser is the seeded user that enables the auth provider and becomes the Rancher admin
+	AdminUser string              `yaml:"adminUser"`
+	Image     string              `yaml:"image"`
+	Users     []User              `yaml:"users"`
+	Groups    []OpenLDAPSeedGroup `yaml:"groups"`
+	AuthInput OpenLDAPSeedInput   `yaml:"authInput"`
+}
+
+// OpenLDAPSeedGroup is a seeded group, with its member users and the groups nested in it
+type OpenLDAPSeedGroup struct {
+	Name   string   `yaml:"name"`
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
+// OpenLDAPSeedInput maps the groups of the AuthConfig used by the tests to seeded groups
+type OpenLDAPSeedInput struct {
+	Group             string `yaml:"group"`
+	NestedGroup       string `yaml:"nestedGroup"`
+	DoubleNestedGroup string `yaml:"doubleNestedGroup"`
+	TripleNestedGroup string `yaml:"tripleNestedGroup"`
+}
+
+// LoadOpenLDAPSeed reads an OpenLDAP seed from a YAML file and validates it
+func LoadOpenLDAPSeed(path string) (*OpenLDAPSeed, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenLDAP seed %s: %w", path, err)
+	}
+
+	seed := new(OpenLDAPSeed)
+	err = yaml.UnmarshalStrict(content, seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenLDAP seed %s: %w", path, err)
+	}
+
+	err = seed.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenLDAP seed %s: %w", path, err)
+	}
+
+	return seed, nil
+}
+
+// BaseDN returns the base DN of the seed domain
+func (s *OpenLDAPSeed) BaseDN() string {
+	var components []string
+	for _, component := range strings.Split(s.Domain, ".") {
+		components = append(components, "dc="+component)
+	}
+
+	return strings.Join(components, ",")
+}
+
+// AdminDN returns the DN of the LDAP admin, used as the service account of the providers
+func (s *OpenLDAPSeed) AdminDN() string {
+	return "cn=admin," + s.BaseDN()
+}
+
+// UserSearchBase returns the DN of the organizational unit the users are seeded in
+func (s *OpenLDAPSeed) UserSearchBase() string {
+	return fmt.Sprintf("ou=%s,%s", openLDAPUsersOrganization, s.BaseDN())
+}
+
+// GroupSearchBase returns the DN of the organizational unit the groups are seeded in
+func (s *OpenLDAPSeed) GroupSearchBase() string {
+	return fmt.Sprintf("ou=%s,%s", openLDAPGroupsOrganization, s.BaseDN())
+}
+
+// Validate checks that every name in the seed is usable in a DN, that every member and mapped group is seeded, and
+// that the nested groups don't form a cycle
+func (s *OpenLDAPSeed) Validate() error {
+	if s.Domain == "" || s.AdminPassword == "" {
+		return fmt.Errorf("domain and adminPassword are required")
+	}
+
+	users := map[string]bool{}
+	for _, user := range s.Users {
+		if err := validateDNValue(user.Username); err != nil {
+			return fmt.Errorf("user %q: %w", user.Username, err)
+		}
+		if user.Password == "" {
+			return fmt.Errorf("user %q has no password", user.Username)
+		}
+		users[user.Username] = true
+	}
+
+	if !users[s.AdminUser] {
+		return fmt.Errorf("admin user %q is not a seeded user", s.AdminUser)
+	}
+
+	groups := map[string]bool{}
+	for _, group := range s.Groups {
+		if err := validateDNValue(group.Name); err != nil {
+			return fmt.Errorf("group %q: %w", group.Name, err)
+		}
+		groups[group.Name] = true
+	}
+
+	for _, group := range s.Groups {
+		if len(group.Users) == 0 && len(group.Groups) == 0 {
+			return fmt.Errorf("group %q has no members", group.Name)
+		}
+		for _, user := range group.Users {
+			if !users[user] {
+				return fmt.Errorf("group %q: member user %q is not seeded", group.Name, user)
+			}
+		}
+		for _, nested := range group.Groups {
+			if !groups[nested] {
+				return fmt.Errorf("group %q: member group %q is not seeded", group.Name, nested)
+			}
+		}
+	}
+
+	for _, mapped := range []string{s.AuthInput.Group, s.AuthInput.NestedGroup, s.AuthInput.DoubleNestedGroup, s.AuthInput.TripleNestedGroup} {
+		if mapped != "" && !groups[mapped] {
+			return fmt.Errorf("auth input group %q is not seeded", mapped)
+		}
+	}
+
+	_, err := s.orderedGroups()
+	return err
+}
+
+// LDIF renders the seed as the LDIF loaded by the OpenLDAP fixture. Groups are ordered after the groups nested in them.
+func (s *OpenLDAPSeed) LDIF() (string, error) {
+	groups, err := s.orderedGroups()
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for _, organization := range []string{openLDAPUsersOrganization, openLDAPGroupsOrganization} {
+		builder.WriteString(ldifAttribute("dn", fmt.Sprintf("ou=%s,%s", organization, s.BaseDN())))
+		builder.WriteString(ldifAttribute("objectClass", "organizationalUnit"))
+		builder.WriteString(ldifAttribute("ou", organization))
+		builder.WriteString("\n")
+	}
+
+	for _, user := range s.Users {
+		builder.WriteString(ldifAttribute("dn", s.userDN(user.Username)))
+		builder.WriteString(ldifAttribute("objectClass", "inetOrgPerson"))
+		builder.WriteString(ldifAttribute("cn", user.Username))
+		builder.WriteString(ldifAttribute("sn", user.Username))
+		builder.WriteString(ldifAttribute("uid", user.Username))
+		builder.WriteString(ldifAttribute("userPassword", user.Password))
+		builder.WriteString("\n")
+	}
+
+	for _, group := range groups {
+		builder.WriteString(ldifAttribute("dn", s.groupDN(group.Name)))
+		builder.WriteString(ldifAttribute("objectClass", openLDAPGroupObjectClass))
+		builder.WriteString(ldifAttribute("cn", group.Name))
+		for _, user := range group.Users {
+			builder.WriteString(ldifAttribute(openLDAPMemberAttribute, s.userDN(user)))
+		}
+		for _, nested := range group.Groups {
+			builder.WriteString(ldifAttribute(openLDAPMemberAttribute, s.groupDN(nested)))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String(), nil
+}
+
+// AuthConfig returns the AuthConfig of the seed, with the direct member users of every mapped group
+func (s *OpenLDAPSeed) AuthConfig() *AuthConfig {
+	return &AuthConfig{
+		Group:             s.AuthInput.Group,
+		Users:             s.groupUsers(s.AuthInput.Group),
+		NestedGroup:       s.AuthInput.NestedGroup,
+		NestedUsers:       s.groupUsers(s.AuthInput.NestedGroup),
+		DoubleNestedGroup: s.AuthInput.DoubleNestedGroup,
+		DoubleNestedUsers: s.groupUsers(s.AuthInput.DoubleNestedGroup),
+		TripleNestedGroup: s.AuthInput.TripleNestedGroup,
+		TripleNestedUsers: s.groupUsers(s.AuthInput.TripleNestedGroup),
+	}
+}
+
+// Admin returns the seeded user that enables the auth provider
+func (s *OpenLDAPSeed) Admin() User {
+	return s.user(s.AdminUser)
+}
+
+// DeployOpenLDAPFixture deploys an OpenLDAP server seeded from the seed in a new namespace of the local cluster, and
+// waits for the seeded entries to be served. The OpenLDAP configuration of the client is pointed to the fixture, so
+// EnsureAuthProviderEnabled enables the provider against it, and the AuthConfig of the seed is returned. The
+// namespace is removed when the client session is cleaned up.
+func DeployOpenLDAPFixture(client *rancher.Client, seed *OpenLDAPSeed) (*AuthConfig, error) {
+	err := seed.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenLDAP seed: %w", err)
+	}
+
+	namespaceName, err := createFixtureNamespace(client, "openldap-fixture")
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := deployOpenLDAPServer(client, namespaceName, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	adminUser := seed.Admin()
+	client.Auth.OLDAP.Config = &openldap.Config{
+		Hostname:   hostname,
+		AccessMode: AccessModeUnrestricted,
+		ServiceAccount: &openldap.ServiceAccount{
+			DistinguishedName: seed.AdminDN(),
+			Password:          seed.AdminPassword,
+		},
+		Users: &openldap.Users{
+			Admin: &openldap.User{
+				Username: adminUser.Username,
+				Password: adminUser.Password,
+			},
+			SearchBase: seed.UserSearchBase(),
+		},
+		Groups: &openldap.Groups{
+			ObjectClass:                  openLDAPGroupObjectClass,
+			MemberMappingAttribute:       openLDAPMemberAttribute,
+			NestedGroupMembershipEnabled: true,
+			SearchDirectGroupMemberships: true,
+			SearchBase:                   seed.GroupSearchBase(),
+		},
+	}
+
+	return seed.AuthConfig(), nil
+}
+
+// createFixtureNamespace creates a namespace with a generated name in the local cluster, removed when the client
+// session is cleaned up
+func createFixtureNamespace(client *rancher.Client, prefix string) (string, error) {
+	namespaceName := namegen.AppendRandomString(prefix)
+	logrus.Infof("Creating fixture namespace %s in the local cluster", namespaceName)
+
+	_, err := client.WranglerContext.Core.Namespace().Create(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespaceName,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create namespace %s: %w", namespaceName, err)
+	}
+
+	client.Session.RegisterCleanupFunc(func() error {
+		return client.WranglerContext.Core.Namespace().Delete(namespaceName, &metav1.DeleteOptions{})
+	})
+
+	return namespaceName, nil
+}
+
+// deployOpenLDAPServer deploys the OpenLDAP server of the seed in the namespace of the local cluster, waits for the
+// seeded entries to be served and returns the hostname of its service
+func deployOpenLDAPServer(client *rancher.Client, namespaceName string, seed *OpenLDAPSeed) (string, error) {
+	ldif, err := seed.LDIF()
+	if err != nil {
+		return "", err
+	}
+
+	image := seed.Image
+	if image == "" {
+		image = DefaultOpenLDAPImage
+	}
+
+	_, err = client.WranglerContext.Core.Secret().Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      openLDAPFixtureName,
+			Namespace: namespaceName,
+		},
+		StringData: map[string]string{
+			openLDAPSeedFileName:  ldif,
+			"LDAP_ADMIN_PASSWORD": seed.AdminPassword,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create OpenLDAP seed secret: %w", err)
+	}
+
+	labels := map[string]string{"app": openLDAPFixtureName}
+	_, err = client.WranglerContext.Apps.Deployment().Create(newOpenLDAPDeployment(namespaceName, image, seed, labels))
+	if err != nil {
+		return "", fmt.Errorf("failed to create OpenLDAP deployment: %w", err)
+	}
+
+	_, err = client.WranglerContext.Core.Service().Create(newFixtureService(openLDAPFixtureName, namespaceName, labels, openLDAPPort))
+	if err != nil {
+		return "", fmt.Errorf("failed to create OpenLDAP service: %w", err)
+	}
+
+	err = deployments.WaitForDeploymentActive(client, extclusterapi.LocalCluster, namespaceName, openLDAPFixtureName)
+	if err != nil {
+		return "", fmt.Errorf("OpenLDAP fixture did not become ready: %w", err)
+	}
+
+	return fixtureServiceHostname(openLDAPFixtureName, namespaceName), nil
+}
+
+// newFixtureService returns a service exposing the port of the pods with the labels
+func newFixtureService(name, namespace string, labels map[string]string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       name,
+					Port:       port,
+					TargetPort: intstr.FromInt32(port),
+				},
+			},
+		},
+	}
+}
+
+// fixtureServiceHostname returns the hostname the service is reached with from the pods of the local cluster,
+// including Rancher
+func fixtureServiceHostname(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc", name, namespace)
+}
+
+// fixtureProxyURL returns the URL of the service through the Kubernetes service proxy of the local cluster, which is
+// how the tests reach the fixtures
+func fixtureProxyURL(client *rancher.Client, name, namespace string, port int32) string {
+	return fmt.Sprintf("https://%s/k8s/clusters/%s/api/v1/namespaces/%s/services/http:%s:%d/proxy",
+		client.RancherConfig.Host, extclusterapi.LocalCluster, namespace, name, port)
+}
+
+// fixtureProxiedURL returns the URL of a location of the service through the service proxy. Locations are either
+// relative to the service, absolute with its service hostname, or already rewritten by the proxy with its own path
+// prefix.
+func fixtureProxiedURL(proxyURL, name string, port int32, location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid location %q from %s: %w", location, name, err)
+	}
+
+	path := parsed.Path
+	if _, proxied, found := strings.Cut(path, fmt.Sprintf("/services/http:%s:%d/proxy", name, port)); found {
+		path = proxied
+	}
+
+	proxied := proxyURL + path
+	if parsed.RawQuery != "" {
+		proxied += "?" + parsed.RawQuery
+	}
+
+	return proxied, nil
+}
+
+// newFixtureHTTPClient returns an HTTP client for the Rancher server of the client, which returns redirections
+// instead of following them
+func newFixtureHTTPClient(client *rancher.Client, jar http.CookieJar) *http.Client {
+	insecure := client.RancherConfig.Insecure != nil && *client.RancherConfig.Insecure
+
+	return &http.Client{
+		Jar: jar,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func newOpenLDAPDeployment(namespace, image string, seed *OpenLDAPSeed, labels map[string]string) *appsv1.Deployment {
+	var replicas int32 = 1
+
+	readinessCommand := fmt.Sprintf(`ldapsearch -x -H ldap://localhost -D "%s" -w "$LDAP_ADMIN_PASSWORD" -b "%s" -s base`, seed.AdminDN(), seed.GroupSearchBase())
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      openLDAPFixtureName,
+			Namespace: namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            openLDAPFixtureName,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							// the image rewrites its bootstrap files, which fails on the read-only secret volume
+							// unless they are copied first
+							Args: []string{"--copy-service"},
+							Env: []corev1.EnvVar{
+								{Name: "LDAP_DOMAIN", Value: seed.Domain},
+								{Name: "LDAP_BASE_DN", Value: seed.BaseDN()},
+								{Name: "LDAP_TLS", Value: "false"},
+								{
+									Name: "LDAP_ADMIN_PASSWORD",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: corev1.LocalObjectReference{Name: openLDAPFixtureName},
+											Key:                  "LDAP_ADMIN_PASSWORD",
+										},
+									},
+								},
+							},
+							Ports: []corev1.ContainerPort{
+								{Name: "ldap", ContainerPort: openLDAPPort},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "seed", MountPath: openLDAPCustomLDIFDir},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{
+										Command: []string{"sh", "-c", readinessCommand},
+									},
+								},
+								PeriodSeconds: 5,
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "seed",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: openLDAPFixtureName,
+									Items: []corev1.KeyToPath{
+										{Key: openLDAPSeedFileName, Path: openLDAPSeedFileName},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// orderedGroups returns the groups of the seed ordered after the groups nested in them, so that every member of a
+// group already exists when the group is added
+func (s *OpenLDAPSeed) orderedGroups() ([]OpenLDAPSeedGroup, error) {
+	byName := map[string]OpenLDAPSeedGroup{}
+	for _, group := range s.Groups {
+		byName[group.Name] = group
+	}
+
+	var ordered []OpenLDAPSeedGroup
+	visited := map[string]bool{}
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if slices.Contains(path, name) {
+			return fmt.Errorf("group nesting cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+
+		path = append(path, name)
+		for _, nested := range byName[name].Groups {
+			if err := visit(nested); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+
+		visited[name] = true
+		ordered = append(ordered, byName[name])
+
+		return nil
+	}
+
+	for _, group := range s.Groups {
+		if err := visit(group.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+func (s *OpenLDAPSeed) groupUsers(groupName string) []User {
+	var users []User
+	for _, group := range s.Groups {
+		if group.Name != groupName {
+			continue
+		}
+
+		for _, user := range s.Users {
+			if slices.Contains(group.Users, user.Username) {
+				users = append(users, user)
+			}
+		}
+	}
+
+	return users
+}
+
+func (s *OpenLDAPSeed) user(username string) User {
+	for _, user := range s.Users {
+		if user.Username == username {
+			return user
+		}
+	}
+
+	return User{}
+}
+
+func (s *OpenLDAPSeed) userDN(username string) string {
+	return fmt.Sprintf("cn=%s,%s", username, s.UserSearchBase())
+}
+
+func (s *OpenLDAPSeed) groupDN(groupName string) string {
+	return fmt.Sprintf("cn=%s,%s", groupName, s.GroupSearchBase())
+}
+
+// validateDNValue rejects empty names and names that would need escaping in a DN
+func validateDNValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if strings.ContainsAny(value, `,+"\<>;=#`) || strings.TrimSpace(value) != value {
+		return fmt.Errorf("name contains characters that need escaping in a DN")
+	}
+
+	return nil
+}
+
+// ldifAttribute renders an LDIF attribute line, base64 encoding values that are not safe strings in LDIF
+func ldifAttribute(name, value string) string {
+	safe := !strings.HasPrefix(value, " ") && !strings.HasPrefix(value, ":") && !strings.HasPrefix(value, "<") && !strings.HasSuffix(value, " ")
+	for _, char := range value {
+		if char > 127 || char == '\n' || char == '\r' || char == 0 {
+			safe = false
+		}
+	}
+
+	if !safe {
+		return fmt.Sprintf("%s:: %s\n", name, base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+
+	return fmt.Sprintf("%s: %s\n", name, value)
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+)
+
+const (
+	KeyCloakSAML                   = "keycloak"
+	KeyCloakSAMLSPKeySecretID      = "keycloakconfig-spkey"
+	keyCloakSAMLSchemaType         = "keyCloakConfigs"
+	keyCloakSAMLPublicProviderType = "keyCloakProviders"
+	samlSessionCookie              = "R_SESS"
+	samlErrorCodeParam             = "errorCode"
+)
+
+// NewKeyCloakSAMLConfig returns the Keycloak SAML configuration of Rancher for the fixture
+func NewKeyCloakSAMLConfig(client *rancher.Client, fixture *SAMLFixture) *management.KeyCloakConfig {
+	return &management.KeyCloakConfig{
+		Name:               KeyCloakSAML,
+		Type:               management.KeyCloakConfigType,
+		AccessMode:         AccessModeUnrestricted,
+		EntityID:           fixture.EntityID,
+		IDPMetadataContent: fixture.IDPMetadata,
+		SpCert:             fixture.SPCert,
+		SpKey:              fixture.SPKey,
+		RancherAPIHost:     fmt.Sprintf("https://%s", client.RancherConfig.Host),
+		UIDField:           samlUIDAttribute,
+		UserNameField:      samlUserNameAttribute,
+		DisplayNameField:   samlDisplayAttribute,
+		GroupsField:        samlGroupsAttribute,
+	}
+}
+
+// EnableKeyCloakSAML configures the Keycloak SAML provider against the fixture and enables it. The admin user of the
+// seed logs in the identity provider to test the configuration, and its principal is bound to the current user.
+// The provider is disabled when the client session is cleaned up.
+func EnableKeyCloakSAML(client *rancher.Client, fixture *SAMLFixture) error {
+	var jsonResp map[string]any
+	configURL := fmt.Sprintf("%s/%s/%s", client.Management.Opts.URL, keyCloakSAMLSchemaType, KeyCloakSAML)
+	err := client.Management.Ops.DoModify("PUT", configURL, NewKeyCloakSAMLConfig(client, fixture), &jsonResp)
+	if err != nil {
+		return fmt.Errorf("failed to configure Keycloak SAML: %w", err)
+	}
+
+	client.Session.RegisterCleanupFunc(func() error {
+		return DisableKeyCloakSAML(client)
+	})
+
+	httpClient, err := newSAMLHTTPClient(client)
+	if err != nil {
+		return err
+	}
+
+	testInput := map[string]string{
+		"finalRedirectUrl": fmt.Sprintf("https://%s/dashboard/auth/verify?config=%s", client.RancherConfig.Host, KeyCloakSAML),
+	}
+	idpRedirectURL, err := samlRedirect(httpClient, configURL+"?action=testAndEnable", client.RancherConfig.AdminToken, testInput)
+	if err != nil {
+		return fmt.Errorf("failed to test Keycloak SAML: %w", err)
+	}
+
+	_, err = postSAMLAssertion(httpClient, fixture, idpRedirectURL, fixture.Seed.Admin())
+	if err != nil {
+		return fmt.Errorf("failed to enable Keycloak SAML: %w", err)
+	}
+
+	_, err = WaitForAuthProviderAnnotationUpdate(client, KeyCloakSAML, AuthProvCleanupAnnotationValUnlocked)
+	return err
+}
+
+// DisableKeyCloakSAML disables the Keycloak SAML provider
+func DisableKeyCloakSAML(client *rancher.Client) error {
+	var jsonResp map[string]any
+	url := fmt.Sprintf("%s/%s/%s?action=disable", client.Management.Opts.URL, keyCloakSAMLSchemaType, KeyCloakSAML)
+
+	return client.Management.Ops.DoModify("POST", url, nil, &jsonResp)
+}
+
+// LoginAsSAMLUser starts a login in Rancher, logs the user in the identity provider and posts the assertion back to
+// Rancher, the way a browser does, and returns a client with the Rancher token of the user and the token itself
+func LoginAsSAMLUser(client *rancher.Client, fixture *SAMLFixture, user User) (*rancher.Client, *management.Token, error) {
+	httpClient, err := newSAMLHTTPClient(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loginInput := map[string]string{
+		"finalRedirectUrl": fmt.Sprintf("https://%s/dashboard/auth/verify", client.RancherConfig.Host),
+	}
+	loginURL := fmt.Sprintf("https://%s/v3-public/%s/%s?action=login", client.RancherConfig.Host, keyCloakSAMLPublicProviderType, KeyCloakSAML)
+	idpRedirectURL, err := samlRedirect(httpClient, loginURL, "", loginInput)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user [%v] failed to start a login to Rancher: %w", user.Username, err)
+	}
+
+	tokenValue, err := postSAMLAssertion(httpClient, fixture, idpRedirectURL, user)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user [%v] failed to login to Rancher: %w", user.Username, err)
+	}
+
+	userClient, err := rancher.NewClientForConfig(tokenValue, client.RancherConfig, client.Session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenName, _, _ := strings.Cut(tokenValue, ":")
+	token, err := userClient.Management.Token.ByID(tokenName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the token of user [%v]: %w", user.Username, err)
+	}
+
+	return userClient, token, nil
+}
+
+// VerifySAMLUserLogins attempts to log every user in through the identity provider, and verifies that the login
+// succeeds or fails as expected
+func VerifySAMLUserLogins(client *rancher.Client, fixture *SAMLFixture, users []User, description string, shouldSucceed bool) error {
+	for _, user := range users {
+		_, _, err := LoginAsSAMLUser(client, fixture, user)
+
+		if shouldSucceed && err != nil {
+			return fmt.Errorf("user [%v] should be able to login (%s): %w", user.Username, description, err)
+		}
+
+		if !shouldSucceed && err == nil {
+			return fmt.Errorf("user [%v] should NOT be able to login (%s)", user.Username, description)
+		}
+	}
+
+	return nil
+}
+
+// GetSAMLGroupPrincipalID returns the principal ID of a group of the groups attribute of the assertions
+func GetSAMLGroupPrincipalID(providerName, groupName string) string {
+	return fmt.Sprintf("%s_group://%s", providerName, groupName)
+}
+
+// UpdateSAMLAccessMode updates the access mode of the SAML auth provider with optional allowed principal IDs. SAML
+// providers are updated through the generic auth config, the same way as the OIDC providers.
+func UpdateSAMLAccessMode(client *rancher.Client, providerName, accessMode string, allowedPrincipalIDs []string) (*management.AuthConfig, error) {
+	return UpdateOIDCAccessMode(client, providerName, accessMode, allowedPrincipalIDs)
+}
+
+// newSAMLHTTPClient returns an HTTP client for Rancher that keeps the cookies Rancher stores the state of a SAML
+// login in, since they are read back when the assertion is posted
+func newSAMLHTTPClient(client *rancher.Client) (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFixtureHTTPClient(client, jar), nil
+}
+
+// samlRedirect posts the input to the SAML action of Rancher and returns the redirection to the identity provider
+func samlRedirect(httpClient *http.Client, actionURL, bearerToken string, input map[string]string) (string, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, actionURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, actionURL)
+	}
+
+	var output struct {
+		IDPRedirectURL string `json:"idpRedirectUrl"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&output)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the response of %s: %w", actionURL, err)
+	}
+
+	return output.IDPRedirectURL, nil
+}
+
+// postSAMLAssertion logs the user in the identity provider and posts the assertion to the assertion consumer service
+// of Rancher, and returns the Rancher token of the session cookie Rancher sets
+func postSAMLAssertion(httpClient *http.Client, fixture *SAMLFixture, idpRedirectURL string, user User) (string, error) {
+	samlResponse, relayState, err := fixture.Assertion(idpRedirectURL, user)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{samlResponseFormField: {samlResponse}, samlRelayStateFormField: {relayState}}
+	resp, err := httpClient.PostForm(fixture.ACSURL, form)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("invalid redirection from the assertion consumer service: %w", err)
+	}
+
+	if errorCode := location.Query().Get(samlErrorCodeParam); errorCode != "" {
+		return "", fmt.Errorf("Rancher rejected the assertion of user [%v] with error code %s", user.Username, errorCode)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == samlSessionCookie {
+			return cookie.Value, nil
+		}
+	}
+
+	return "", fmt.Errorf("no session cookie in the response of the assertion consumer service, status %d", resp.StatusCode)
+}
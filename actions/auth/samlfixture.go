@@ -0,0 +1,460 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"html"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	SAMLFixtureInput        = "samlFixtureInput"
+	DefaultKeycloakImage    = "quay.io/keycloak/keycloak:26.0.7"
+	keycloakFixtureName     = "keycloak"
+	keycloakPort            = 8080
+	keycloakRealm           = "rancher"
+	keycloakRealmFileName   = "rancher-realm.json"
+	keycloakImportDir       = "/opt/keycloak/data/import"
+	keycloakEmailSuffix     = "example.com"
+	samlUIDAttribute        = "uid"
+	samlUserNameAttribute   = "userName"
+	samlDisplayAttribute    = "displayName"
+	samlGroupsAttribute     = "member"
+	samlSPCertValidity      = 365 * 24 * time.Hour
+	maxSAMLLoginHop         = 10
+	samlLoginFormID         = "kc-form-login"
+	samlResponseFormField   = "SAMLResponse"
+	samlRelayStateFormField = "RelayState"
+)
+
+var (
+	samlLoginFormPattern = regexp.MustCompile(`(?is)<form[^>]*id="` + samlLoginFormID + `"[^>]*>`)
+	formActionPattern    = regexp.MustCompile(`(?i)\saction="([^"]*)"`)
+)
+
+// SAMLFixtureConfig is the configuration of the SAML fixture, read from the samlFixtureInput key of the config file
+type SAMLFixtureConfig struct {
+	// SeedFile is the OpenLDAP seed with the users and groups the identity provider authenticates
+	SeedFile string `json:"seedFile" yaml:"seedFile"`
+	Image    string `json:"image" yaml:"image"`
+}
+
+// SAMLFixture is a Keycloak identity provider deployed in the local cluster, with a realm holding the users and
+// groups of an OpenLDAP seed and a SAML client for Rancher. The direct groups of a user are sent in the member
+// attribute of the assertion.
+type SAMLFixture struct {
+	Namespace string
+	// EntityID is the entity ID of Rancher, which is the client ID of the SAML client in the identity provider
+	EntityID string
+	// ACSURL is the assertion consumer service of Rancher the identity provider posts the assertions to
+	ACSURL      string
+	IDPMetadata string
+	SPCert      string
+	SPKey       string
+	Seed        *OpenLDAPSeed
+
+	proxyURL    string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// DeploySAMLFixture deploys a Keycloak identity provider with a realm of the users and groups of the seed in a new
+// namespace of the local cluster, and returns it with the identity provider metadata and a service provider
+// certificate for Rancher. The tests reach the identity provider through the Kubernetes service proxy of the local
+// cluster; Rancher never calls it, since the assertions are posted back by the tests. The namespace is removed when
+// the client session is cleaned up.
+func DeploySAMLFixture(client *rancher.Client, seed *OpenLDAPSeed, image string) (*SAMLFixture, error) {
+	err := seed.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenLDAP seed: %w", err)
+	}
+
+	if image == "" {
+		image = DefaultKeycloakImage
+	}
+
+	spCert, spKey, err := newSAMLServiceProviderCert(client.RancherConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceName, err := createFixtureNamespace(client, "saml-fixture")
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &SAMLFixture{
+		Namespace:   namespaceName,
+		EntityID:    fmt.Sprintf("https://%s/v1-saml/%s/saml/metadata", client.RancherConfig.Host, KeyCloakSAML),
+		ACSURL:      fmt.Sprintf("https://%s/v1-saml/%s/saml/acs", client.RancherConfig.Host, KeyCloakSAML),
+		SPCert:      spCert,
+		SPKey:       spKey,
+		Seed:        seed,
+		proxyURL:    fixtureProxyURL(client, keycloakFixtureName, namespaceName, keycloakPort),
+		bearerToken: client.RancherConfig.AdminToken,
+		httpClient:  newFixtureHTTPClient(client, nil),
+	}
+
+	realm, err := fixture.keycloakRealm()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = client.WranglerContext.Core.Secret().Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      keycloakFixtureName,
+			Namespace: namespaceName,
+		},
+		StringData: map[string]string{
+			keycloakRealmFileName: realm,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Keycloak realm secret: %w", err)
+	}
+
+	labels := map[string]string{"app": keycloakFixtureName}
+	hostname := fmt.Sprintf("http://%s:%d", fixtureServiceHostname(keycloakFixtureName, namespaceName), keycloakPort)
+	_, err = client.WranglerContext.Apps.Deployment().Create(newKeycloakDeployment(namespaceName, image, hostname, labels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Keycloak deployment: %w", err)
+	}
+
+	_, err = client.WranglerContext.Core.Service().Create(newFixtureService(keycloakFixtureName, namespaceName, labels, keycloakPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Keycloak service: %w", err)
+	}
+
+	err = deployments.WaitForDeploymentActive(client, extclusterapi.LocalCluster, namespaceName, keycloakFixtureName)
+	if err != nil {
+		return nil, fmt.Errorf("Keycloak fixture did not become ready: %w", err)
+	}
+
+	fixture.IDPMetadata, err = fixture.idpMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// Assertion logs the user in the identity provider without a browser, starting from the redirection to the
+// identity provider Rancher returned, and returns the SAML response and relay state the identity provider posts
+// back to the assertion consumer service of Rancher
+func (f *SAMLFixture) Assertion(idpRedirectURL string, user User) (samlResponse, relayState string, err error) {
+	requestURL, err := fixtureProxiedURL(f.proxyURL, keycloakFixtureName, keycloakPort, idpRedirectURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	method := http.MethodGet
+	var form url.Values
+	// the session cookies of the identity provider are scoped to its own paths, not to the paths of the proxy
+	cookies := map[string]*http.Cookie{}
+
+	for range maxSAMLLoginHop {
+		resp, err := f.do(method, requestURL, form, cookies)
+		if err != nil {
+			return "", "", err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", "", err
+		}
+
+		for _, cookie := range resp.Cookies() {
+			cookies[cookie.Name] = cookie
+		}
+
+		switch {
+		case resp.StatusCode >= 300 && resp.StatusCode < 400:
+			requestURL, err = fixtureProxiedURL(f.proxyURL, keycloakFixtureName, keycloakPort, resp.Header.Get("Location"))
+			if err != nil {
+				return "", "", err
+			}
+			method, form = http.MethodGet, nil
+		case resp.StatusCode != http.StatusOK:
+			return "", "", fmt.Errorf("unexpected status %d from the identity provider at %s", resp.StatusCode, requestURL)
+		case hiddenFormValue(body, samlResponseFormField) != "":
+			return hiddenFormValue(body, samlResponseFormField), hiddenFormValue(body, samlRelayStateFormField), nil
+		case form != nil:
+			return "", "", fmt.Errorf("identity provider rejected the credentials of user %s", user.Username)
+		default:
+			loginForm := samlLoginFormPattern.Find(body)
+			action := formActionPattern.FindSubmatch(loginForm)
+			if action == nil {
+				return "", "", fmt.Errorf("no login form in the page of the identity provider at %s", requestURL)
+			}
+
+			requestURL, err = fixtureProxiedURL(f.proxyURL, keycloakFixtureName, keycloakPort, html.UnescapeString(string(action[1])))
+			if err != nil {
+				return "", "", err
+			}
+			method = http.MethodPost
+			form = url.Values{"username": {user.Username}, "password": {user.Password}}
+		}
+	}
+
+	return "", "", fmt.Errorf("no assertion for Rancher after %d requests to the identity provider", maxSAMLLoginHop)
+}
+
+func (f *SAMLFixture) do(method, requestURL string, form url.Values, cookies map[string]*http.Cookie) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+f.bearerToken)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	for _, cookie := range cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	return f.httpClient.Do(req)
+}
+
+// idpMetadata returns the SAML metadata of the realm, which Rancher validates the assertions with
+func (f *SAMLFixture) idpMetadata() (string, error) {
+	resp, err := f.do(http.MethodGet, fmt.Sprintf("%s/realms/%s/protocol/saml/descriptor", f.proxyURL, keycloakRealm), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the identity provider metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the identity provider metadata: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d for the identity provider metadata", resp.StatusCode)
+	}
+
+	return string(body), nil
+}
+
+// keycloakRealm returns the realm the identity provider imports at startup. Every group of the seed is a top level
+// group, and the users are members of the groups they are direct members of in the seed.
+func (f *SAMLFixture) keycloakRealm() (string, error) {
+	var groups []map[string]any
+	for _, group := range f.Seed.Groups {
+		groups = append(groups, map[string]any{"name": group.Name})
+	}
+
+	var users []map[string]any
+	for _, user := range f.Seed.Users {
+		var userGroups []string
+		for _, group := range SeedGroupsOfUser(f.Seed, user.Username) {
+			userGroups = append(userGroups, "/"+group)
+		}
+
+		users = append(users, map[string]any{
+			"username":      user.Username,
+			"enabled":       true,
+			"firstName":     user.Username,
+			"lastName":      user.Username,
+			"email":         fmt.Sprintf("%s@%s", user.Username, keycloakEmailSuffix),
+			"emailVerified": true,
+			"credentials": []map[string]any{
+				{"type": "password", "value": user.Password, "temporary": false},
+			},
+			"groups": userGroups,
+		})
+	}
+
+	realm := map[string]any{
+		"realm":       keycloakRealm,
+		"enabled":     true,
+		"sslRequired": "none",
+		"groups":      groups,
+		"users":       users,
+		"clients": []map[string]any{
+			{
+				"clientId":     f.EntityID,
+				"name":         "Rancher",
+				"protocol":     "saml",
+				"enabled":      true,
+				"redirectUris": []string{f.ACSURL},
+				"attributes": map[string]string{
+					"saml.server.signature":            "true",
+					"saml.client.signature":            "false",
+					"saml.force.post.binding":          "true",
+					"saml.authnstatement":              "true",
+					"saml_assertion_consumer_url_post": f.ACSURL,
+				},
+				"protocolMappers": []map[string]any{
+					newSAMLUserPropertyMapper(samlUIDAttribute, "username"),
+					newSAMLUserPropertyMapper(samlUserNameAttribute, "username"),
+					newSAMLUserPropertyMapper(samlDisplayAttribute, "firstName"),
+					{
+						"name":           samlGroupsAttribute,
+						"protocol":       "saml",
+						"protocolMapper": "saml-group-membership-mapper",
+						"config": map[string]string{
+							"attribute.name":       samlGroupsAttribute,
+							"attribute.nameformat": "Basic",
+							"full.path":            "false",
+							"single":               "true",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	content, err := json.Marshal(realm)
+	if err != nil {
+		return "", fmt.Errorf("failed to render Keycloak realm: %w", err)
+	}
+
+	return string(content), nil
+}
+
+func newSAMLUserPropertyMapper(attribute, property string) map[string]any {
+	return map[string]any{
+		"name":           attribute,
+		"protocol":       "saml",
+		"protocolMapper": "saml-user-property-mapper",
+		"config": map[string]string{
+			"user.attribute":       property,
+			"attribute.name":       attribute,
+			"attribute.nameformat": "Basic",
+		},
+	}
+}
+
+func newKeycloakDeployment(namespace, image, hostname string, labels map[string]string) *appsv1.Deployment {
+	var replicas int32 = 1
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      keycloakFixtureName,
+			Namespace: namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            keycloakFixtureName,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"start-dev", "--import-realm", fmt.Sprintf("--http-port=%d", keycloakPort)},
+							// the URLs of the identity provider use its service hostname, whichever way it is reached
+							Env: []corev1.EnvVar{
+								{Name: "KC_HOSTNAME", Value: hostname},
+							},
+							Ports: []corev1.ContainerPort{
+								{Name: "http", ContainerPort: keycloakPort},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "realm", MountPath: keycloakImportDir, ReadOnly: true},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: fmt.Sprintf("/realms/%s", keycloakRealm),
+										Port: intstr.FromInt32(keycloakPort),
+									},
+								},
+								InitialDelaySeconds: 20,
+								PeriodSeconds:       5,
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "realm",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: keycloakFixtureName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newSAMLServiceProviderCert returns a self-signed certificate and its PKCS1 RSA key in PEM, which Rancher signs its
+// authentication requests and relay states with
+func newSAMLServiceProviderCert(host string) (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate the service provider key: %w", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate the service provider certificate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(samlSPCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create the service provider certificate: %w", err)
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return string(cert), string(keyPEM), nil
+}
+
+// hiddenFormValue returns the unescaped value of the named hidden input of the page, or an empty string when the
+// page has no such input
+func hiddenFormValue(page []byte, name string) string {
+	pattern := regexp.MustCompile(`(?i)<input[^>]*name="` + regexp.QuoteMeta(name) + `"[^>]*value="([^"]*)"`)
+
+	match := pattern.FindSubmatch(page)
+	if match == nil {
+		return ""
+	}
+
+	return html.UnescapeString(string(match[1]))
+}
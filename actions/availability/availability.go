@@ -0,0 +1,178 @@
+package availability
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultProbeInterval = time.Second
+	defaultProbeTimeout  = 3 * time.Second
+)
+
+// Target is an endpoint continuously checked by the prober. Check returns an error when the endpoint is unavailable.
+type Target struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Failure records a single failed request against a target
+type Failure struct {
+	Target    string
+	Timestamp time.Time
+	Error     string
+}
+
+// TargetReport holds the results of probing a single target
+type TargetReport struct {
+	Name     string
+	Requests int
+	Failures []Failure
+	Downtime time.Duration
+}
+
+// Report holds the results of a probing session for all of its targets
+type Report struct {
+	Start   time.Time
+	End     time.Time
+	Targets map[string]*TargetReport
+}
+
+// Prober continuously checks a set of targets in the background and records every failed request
+type Prober struct {
+	interval time.Duration
+	timeout  time.Duration
+	targets  []Target
+
+	mu      sync.Mutex
+	report  *Report
+	lastRun map[string]time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewProber is a constructor that returns a prober for the given targets, using the intervals of the config
+func NewProber(config *Config, targets ...Target) (*Prober, error) {
+	interval, err := parseDuration(config.ProbeInterval, defaultProbeInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := parseDuration(config.ProbeTimeout, defaultProbeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Prober{
+		interval: interval,
+		timeout:  timeout,
+		targets:  targets,
+	}, nil
+}
+
+// Start begins probing every target in its own goroutine until Stop is called
+func (p *Prober) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.report = &Report{
+		Start:   time.Now(),
+		Targets: map[string]*TargetReport{},
+	}
+	p.lastRun = map[string]time.Time{}
+
+	for _, target := range p.targets {
+		p.report.Targets[target.Name] = &TargetReport{Name: target.Name}
+
+		p.wg.Add(1)
+		go p.probe(ctx, target)
+	}
+
+	logrus.Infof("Started availability probing of %d targets every %s", len(p.targets), p.interval)
+}
+
+// Stop stops probing and returns the report of the session
+func (p *Prober) Stop() *Report {
+	p.cancel()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.report.End = time.Now()
+	for _, target := range p.report.Targets {
+		logrus.Infof("Availability of %s: %d requests, %d failures, %s downtime", target.Name, target.Requests, len(target.Failures), target.Downtime)
+	}
+
+	return p.report
+}
+
+// Run probes the targets while the operation runs and returns the report together with the operation's error
+func (p *Prober) Run(operation func() error) (*Report, error) {
+	p.Start()
+	err := operation()
+
+	return p.Stop(), err
+}
+
+func (p *Prober) probe(ctx context.Context, target Target) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, p.timeout)
+			err := target.Check(checkCtx)
+			cancel()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.record(target.Name, time.Now(), err)
+		}
+	}
+}
+
+// record stores the result of a single request. A failed request accounts for the time elapsed since the previous request.
+func (p *Prober) record(name string, timestamp time.Time, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	targetReport := p.report.Targets[name]
+	targetReport.Requests++
+
+	previous, ok := p.lastRun[name]
+	if !ok {
+		previous = p.report.Start
+	}
+	p.lastRun[name] = timestamp
+
+	if err == nil {
+		return
+	}
+
+	logrus.Debugf("Availability probe of %s failed: %v", name, err)
+	targetReport.Failures = append(targetReport.Failures, Failure{
+		Target:    name,
+		Timestamp: timestamp,
+		Error:     err.Error(),
+	})
+	targetReport.Downtime += timestamp.Sub(previous)
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,13 @@
+package availability
+
+const (
+	ConfigurationFileKey = "availabilityInput"
+)
+
+// Config is used to configure the availability prober and the downtime allowed while an operation runs
+type Config struct {
+	ProbeInterval  string `json:"probeInterval" yaml:"probeInterval" default:"1s"`
+	ProbeTimeout   string `json:"probeTimeout" yaml:"probeTimeout" default:"3s"`
+	DowntimeBudget string `json:"downtimeBudget" yaml:"downtimeBudget" default:"0s"`
+	BatchBudget    string `json:"batchBudget" yaml:"batchBudget" default:"30s"`
+}
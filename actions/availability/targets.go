@@ -0,0 +1,97 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/ingresses"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewHTTPTarget returns a target that sends a GET request to each url until one of them returns a 2xx or 3xx
+// response, so a workload exposed on several nodes is only counted as down when none of them serves it
+func NewHTTPTarget(name string, urls ...string) Target {
+	return Target{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			// urls are checked concurrently, so an unreachable node can't use up the probe timeout of the others
+			results := make(chan error, len(urls))
+			for _, url := range urls {
+				go func() {
+					results <- checkURL(ctx, url)
+				}()
+			}
+
+			var errs []error
+			for range urls {
+				err := <-results
+				if err == nil {
+					return nil
+				}
+
+				errs = append(errs, err)
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+func checkURL(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return nil
+}
+
+// NewIngressTarget returns a target that checks an ingress hostname is externally accessible
+func NewIngressTarget(client *rancher.Client, hostname string) Target {
+	return Target{
+		Name: "ingress/" + hostname,
+		Check: func(ctx context.Context) error {
+			_, err := ingresses.GetExternalIngressResponse(client, hostname, "", false)
+			return err
+		},
+	}
+}
+
+// NewAPIServerTarget returns a target that checks the readyz endpoint of the kube-apiserver of a downstream cluster
+func NewAPIServerTarget(client *rancher.Client, clusterID string) (Target, error) {
+	clientset, err := newClientset(client, clusterID)
+	if err != nil {
+		return Target{}, err
+	}
+
+	return Target{
+		Name: "kube-apiserver/" + clusterID,
+		Check: func(ctx context.Context) error {
+			_, err := clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+			return err
+		},
+	}, nil
+}
+
+func newClientset(client *rancher.Client, clusterID string) (*kubernetes.Clientset, error) {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(clusterContext.RESTConfig)
+}
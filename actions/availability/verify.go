@@ -0,0 +1,133 @@
+package availability
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	provv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	defaultConcurrency = "1"
+	defaultBatchBudget = 30 * time.Second
+)
+
+// DowntimeBudget returns the downtime allowed for a target while nodes of a single role are upgraded or replaced.
+// Drained nodes are expected to move workloads without any downtime, so only the base budget of the config is allowed.
+// Otherwise, every batch of nodes upgraded at the same time, as set by the concurrency, is allowed the batch budget.
+func DowntimeBudget(config *Config, concurrency string, drainOptions rkev1.DrainOptions, nodeCount int) (time.Duration, error) {
+	budget, err := parseDuration(config.DowntimeBudget, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if drainOptions.Enabled || nodeCount == 0 {
+		return budget, nil
+	}
+
+	batchBudget, err := parseDuration(config.BatchBudget, defaultBatchBudget)
+	if err != nil {
+		return 0, err
+	}
+
+	if concurrency == "" {
+		concurrency = defaultConcurrency
+	}
+
+	concurrencyValue := intstr.Parse(concurrency)
+	batchSize, err := intstr.GetScaledValueFromIntOrPercent(&concurrencyValue, nodeCount, true)
+	if err != nil {
+		return 0, err
+	}
+
+	// a concurrency of 0 upgrades every node at once
+	if batchSize <= 0 || batchSize > nodeCount {
+		batchSize = nodeCount
+	}
+
+	batches := (nodeCount + batchSize - 1) / batchSize
+
+	return budget + time.Duration(batches)*batchBudget, nil
+}
+
+// WorkerDowntimeBudget returns the downtime allowed for workloads, given the worker settings of the upgrade strategy
+func WorkerDowntimeBudget(config *Config, strategy rkev1.ClusterUpgradeStrategy, workerCount int) (time.Duration, error) {
+	return DowntimeBudget(config, strategy.WorkerConcurrency, strategy.WorkerDrainOptions, workerCount)
+}
+
+// ControlPlaneDowntimeBudget returns the downtime allowed for the kube-apiserver, given the control plane settings of the upgrade strategy
+func ControlPlaneDowntimeBudget(config *Config, strategy rkev1.ClusterUpgradeStrategy, controlPlaneCount int) (time.Duration, error) {
+	return DowntimeBudget(config, strategy.ControlPlaneConcurrency, strategy.ControlPlaneDrainOptions, controlPlaneCount)
+}
+
+// ClusterDowntimeBudgets returns the worker and control plane downtime budgets of a RKE2/K3S cluster,
+// based on its upgrade strategy and the quantity of its machine pools
+func ClusterDowntimeBudgets(config *Config, cluster *v1.SteveAPIObject) (workerBudget, controlPlaneBudget time.Duration, err error) {
+	clusterSpec := &provv1.ClusterSpec{}
+	err = v1.ConvertToK8sType(cluster.Spec, clusterSpec)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if clusterSpec.RKEConfig == nil {
+		return 0, 0, fmt.Errorf("cluster %s has no RKE config", cluster.Name)
+	}
+
+	var workerCount, controlPlaneCount int
+	for _, pool := range clusterSpec.RKEConfig.MachinePools {
+		quantity := 1
+		if pool.Quantity != nil {
+			quantity = int(*pool.Quantity)
+		}
+
+		if pool.WorkerRole {
+			workerCount += quantity
+		}
+
+		if pool.ControlPlaneRole {
+			controlPlaneCount += quantity
+		}
+	}
+
+	strategy := clusterSpec.RKEConfig.UpgradeStrategy
+
+	workerBudget, err = WorkerDowntimeBudget(config, strategy, workerCount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	controlPlaneBudget, err = ControlPlaneDowntimeBudget(config, strategy, controlPlaneCount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return workerBudget, controlPlaneBudget, nil
+}
+
+// VerifyDowntime returns an error listing every failed request of the targets whose downtime exceeded their budget.
+// Targets without a budget are not allowed any downtime.
+func VerifyDowntime(report *Report, budgets map[string]time.Duration) error {
+	var errs []string
+
+	for name, target := range report.Targets {
+		budget := budgets[name]
+		if target.Downtime <= budget {
+			continue
+		}
+
+		errs = append(errs, fmt.Sprintf("%s was down for %s, exceeding the budget of %s:", name, target.Downtime, budget))
+		for _, failure := range target.Failures {
+			errs = append(errs, fmt.Sprintf("  %s %s", failure.Timestamp.Format(time.RFC3339), failure.Error))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("downtime budget exceeded:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	kubeapinodes "github.com/rancher/shepherd/extensions/kubeapi/nodes"
+	extensionsworkloads "github.com/rancher/shepherd/extensions/workloads"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeapi/namespaces"
+	"github.com/rancher/tests/actions/kubeapi/services"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	"github.com/rancher/tests/actions/kubeapi/workloads/pods"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	probeWorkloadPort     = 80
+	probeWorkloadReplicas = 2
+)
+
+// DeployProbeWorkload creates a namespace with a replicated nginx deployment and a NodePort service in front of it,
+// and returns a target probing that node port on every node of the cluster. The workload is reached directly, so the
+// downstream kube-apiserver and the Rancher agent tunnel don't count against its downtime.
+func DeployProbeWorkload(client *rancher.Client, clusterID string) (Target, error) {
+	namespaceName := namegen.AppendRandomString("availability")
+	workloadName := namegen.AppendRandomString("probe")
+
+	logrus.Infof("Creating availability probe workload %s/%s in cluster %s", namespaceName, workloadName, clusterID)
+	_, err := namespaces.CreateNamespace(client, clusterID, "", namespaceName, "", nil, nil)
+	if err != nil {
+		return Target{}, err
+	}
+
+	container := extensionsworkloads.NewContainer(workloadName, pods.DefaultImageName, corev1.PullIfNotPresent, nil, nil, nil, nil, nil)
+	container.Ports = []corev1.ContainerPort{{ContainerPort: probeWorkloadPort}}
+	podTemplate := extensionsworkloads.NewPodTemplate([]corev1.Container{container}, nil, nil, nil, nil)
+
+	deployment, err := deployments.CreateDeployment(client, clusterID, workloadName, namespaceName, podTemplate, probeWorkloadReplicas)
+	if err != nil {
+		return Target{}, err
+	}
+
+	err = deployments.WaitForDeploymentActive(client, clusterID, namespaceName, deployment.Name)
+	if err != nil {
+		return Target{}, err
+	}
+
+	service, err := services.CreateService(client, clusterID, workloadName, namespaceName, corev1.ServiceSpec{
+		Type:     corev1.ServiceTypeNodePort,
+		Selector: deployment.Spec.Selector.MatchLabels,
+		Ports: []corev1.ServicePort{
+			{
+				Port:       probeWorkloadPort,
+				TargetPort: intstr.FromInt(probeWorkloadPort),
+			},
+		},
+	})
+	if err != nil {
+		return Target{}, err
+	}
+
+	urls, err := nodePortURLs(client, clusterID, service.Spec.Ports[0].NodePort)
+	if err != nil {
+		return Target{}, err
+	}
+
+	return NewHTTPTarget("service/"+namespaceName+"/"+workloadName, urls...), nil
+}
+
+// nodePortURLs returns the URLs of the node port on every node, using the external IP of the nodes that have one
+// and the internal IP otherwise
+func nodePortURLs(client *rancher.Client, clusterID string, nodePort int32) ([]string, error) {
+	clientset, err := newClientset(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, node := range nodeList.Items {
+		nodeIP := kubeapinodes.GetNodeIP(&node, corev1.NodeExternalIP)
+		if nodeIP == "" {
+			nodeIP = kubeapinodes.GetNodeIP(&node, corev1.NodeInternalIP)
+		}
+		if nodeIP == "" {
+			continue
+		}
+
+		urls = append(urls, fmt.Sprintf("http://%s/", net.JoinHostPort(nodeIP, strconv.Itoa(int(nodePort)))))
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no node of cluster %s has an IP to reach node port %d", clusterID, nodePort)
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,149 @@
+package certificates
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/extensions/sshkeys"
+	"github.com/rancher/shepherd/pkg/nodes"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// rotateCAScriptURL is the script generating cross-signed CA certificates, pinned to a K3S release so upstream
+	// changes don't alter the rotation. It supports both K3S and RKE2 with the PRODUCT variable.
+	rotateCAScriptURL = "https://raw.githubusercontent.com/k3s-io/k3s/v1.32.5%2Bk3s1/contrib/util/rotate-default-ca-certs.sh"
+
+	controlPlaneRoleLabel = "node-role.kubernetes.io/control-plane"
+	etcdRoleLabel         = "node-role.kubernetes.io/etcd"
+	k3sClusterType        = "k3s"
+)
+
+// RotateCA rotates the self-signed CA certificates of a RKE2/K3S downstream cluster. The new CA certificates are
+// generated cross-signed by the current ones on the first server node and applied with `certificate rotate-ca`, then
+// every server node, followed by every agent node, is restarted to re-issue its certificates from the new CA. It waits
+// for the cluster to be active again.
+func RotateCA(client *rancher.Client, clusterName string) error {
+	clusterNodes, err := getClusterNodes(client, clusterName)
+	if err != nil {
+		return err
+	}
+
+	rotateCAPath := "/var/lib/rancher/" + clusterNodes.clusterType + "/server/rotate-ca"
+	commands := []string{
+		fmt.Sprintf("curl -sfL %s | sudo PRODUCT=%s bash -", rotateCAScriptURL, clusterNodes.clusterType),
+		fmt.Sprintf("sudo %s certificate rotate-ca --path=%s", clusterNodes.clusterType, rotateCAPath),
+	}
+
+	logrus.Infof("Generating and applying cross-signed CA certificates on cluster %s", clusterName)
+	for _, command := range commands {
+		output, err := clusterNodes.servers[0].ExecuteCommand(command)
+		if err != nil {
+			return fmt.Errorf("%s: %w: %s", command, err, output)
+		}
+	}
+
+	return restartClusterServices(client, clusterNodes)
+}
+
+// clusterNodes are the SSH nodes of a RKE2/K3S downstream cluster, split between server and agent nodes
+type clusterNodes struct {
+	clusterType    string
+	provisioningID string
+	servers        []*nodes.Node
+	agents         []*nodes.Node
+}
+
+// serverService returns the systemd service of the server nodes
+func (c *clusterNodes) serverService() string {
+	if c.clusterType == k3sClusterType {
+		return k3sClusterType
+	}
+
+	return c.clusterType + "-server"
+}
+
+// agentService returns the systemd service of the agent nodes
+func (c *clusterNodes) agentService() string {
+	return c.clusterType + "-agent"
+}
+
+func getClusterNodes(client *rancher.Client, clusterName string) (*clusterNodes, error) {
+	clusterID, err := clusters.GetClusterIDByName(client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioningID, err := clusters.GetV1ProvisioningClusterByName(client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := steveclient.SteveType(stevetypes.Node).List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &clusterNodes{provisioningID: provisioningID}
+	for _, node := range nodeList.Data {
+		sshNode, err := sshkeys.GetSSHNodeFromMachine(client, &node)
+		if err != nil {
+			return nil, err
+		}
+
+		result.clusterType = node.Labels["node.kubernetes.io/instance-type"]
+		if node.Labels[controlPlaneRoleLabel] == "true" || node.Labels[etcdRoleLabel] == "true" {
+			result.servers = append(result.servers, sshNode)
+		} else {
+			result.agents = append(result.agents, sshNode)
+		}
+	}
+
+	if len(result.servers) == 0 {
+		return nil, errors.New("no server node found in cluster " + clusterName)
+	}
+
+	return result, nil
+}
+
+// executeOnNodes runs the command built for the service of each node, servers first, then agents
+func (c *clusterNodes) executeOnNodes(command func(service string) string) error {
+	for _, group := range []struct {
+		nodes   []*nodes.Node
+		service string
+	}{
+		{c.servers, c.serverService()},
+		{c.agents, c.agentService()},
+	} {
+		for _, node := range group.nodes {
+			cmd := command(group.service)
+			output, err := node.ExecuteCommand(cmd)
+			if err != nil {
+				return fmt.Errorf("%s on %s: %w: %s", cmd, node.PublicIPAddress, err, output)
+			}
+		}
+	}
+
+	return nil
+}
+
+// restartClusterServices restarts the service of every server node, followed by every agent node, and waits for the
+// cluster to be active again
+func restartClusterServices(client *rancher.Client, clusterNodes *clusterNodes) error {
+	err := clusterNodes.executeOnNodes(func(service string) string {
+		return "sudo systemctl restart " + service
+	})
+	if err != nil {
+		return err
+	}
+
+	return waitForClusterActive(client, clusterNodes.provisioningID)
+}
@@ -0,0 +1,164 @@
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/extensions/sshkeys"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AdminService             = "admin"
+	APIServerService         = "api-server"
+	AuthProxyService         = "auth-proxy"
+	CloudControllerService   = "cloud-controller"
+	ControllerManagerService = "controller-manager"
+	EtcdService              = "etcd"
+	KubeletService           = "kubelet"
+	KubeProxyService         = "kube-proxy"
+	SchedulerService         = "scheduler"
+
+	controllerServiceSuffix = "-controller"
+	serverServiceSuffix     = "-server"
+)
+
+// CertificateInfo holds the parsed x509 data of a certificate
+type CertificateInfo struct {
+	Name           string
+	Service        string
+	IsCA           bool
+	Subject        string
+	Issuer         string
+	SerialNumber   string
+	AuthorityKeyID string
+	SANs           []string
+	NotBefore      time.Time
+	NotAfter       time.Time
+}
+
+// ParseCertificate parses the first PEM encoded certificate of the data
+func ParseCertificate(name string, pemData string) (*CertificateInfo, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("no PEM data found for certificate " + name)
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CertificateInfo{
+		Name:           name,
+		IsCA:           certificate.IsCA,
+		Subject:        certificate.Subject.String(),
+		Issuer:         certificate.Issuer.String(),
+		SerialNumber:   certificate.SerialNumber.String(),
+		AuthorityKeyID: hex.EncodeToString(certificate.AuthorityKeyId),
+		SANs:           certificate.DNSNames,
+		NotBefore:      certificate.NotBefore,
+		NotAfter:       certificate.NotAfter,
+	}
+
+	for _, ip := range certificate.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	return info, nil
+}
+
+// GetClusterCertificateInfo returns the parsed component and CA certificates of every node of a downstream cluster, by node ID and certificate name.
+func GetClusterCertificateInfo(client *rancher.Client, clusterName string) (map[string]map[string]*CertificateInfo, error) {
+	clusterID, err := clusters.GetClusterIDByName(client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := steveclient.SteveType(stevetypes.Node).List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCertificates := map[string]map[string]*CertificateInfo{}
+
+	for _, node := range nodeList.Data {
+		certificates, err := getCertificateInfoFromMachine(client, &node)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeCertificates[node.ID] = certificates
+	}
+
+	return nodeCertificates, nil
+}
+
+// CertificateService returns the name of the service, as used in a per-service rotation, that owns the certificate.
+// It returns an empty string for CA certificates and certificates that don't belong to a rotatable service.
+func CertificateService(certificateName, clusterType string) string {
+	switch certificateName {
+	case "client-admin":
+		return AdminService
+	case "client-auth-proxy":
+		return AuthProxyService
+	case "client-controller", "kube-controller-manager/kube-controller-manager":
+		return ControllerManagerService
+	case "client-kube-apiserver", "serving-kube-apiserver":
+		return APIServerService
+	case "client-kubelet":
+		return KubeletService
+	case "client-kube-proxy":
+		return KubeProxyService
+	case "client-" + clusterType + "-cloud-controller":
+		return CloudControllerService
+	case "client-" + clusterType + "-controller":
+		return clusterType + controllerServiceSuffix
+	case "client-supervisor":
+		return clusterType + serverServiceSuffix
+	case "client-scheduler", "kube-scheduler/kube-scheduler":
+		return SchedulerService
+	case "etcd/client", "etcd/peer-server-client":
+		return EtcdService
+	}
+
+	return ""
+}
+
+// getCertificateInfoFromMachine reads and parses the component and CA certificates from a given machine node.
+func getCertificateInfoFromMachine(client *rancher.Client, machineNode *v1.SteveAPIObject) (map[string]*CertificateInfo, error) {
+	sshNode, err := sshkeys.GetSSHNodeFromMachine(client, machineNode)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Getting certificate data from machine: %s", machineNode.Name)
+
+	clusterType := machineNode.Labels["node.kubernetes.io/instance-type"]
+	certsList := append(componentCertificates(clusterType), caCertificates()...)
+
+	certificates := map[string]*CertificateInfo{}
+	for name, pemData := range readCertificates(sshNode, clusterType, certsList) {
+		info, err := ParseCertificate(name, pemData)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Service = CertificateService(name, clusterType)
+		certificates[name] = info
+	}
+
+	return certificates, nil
+}
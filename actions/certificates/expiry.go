@@ -0,0 +1,58 @@
+package certificates
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// signedCertExpirationDaysEnv is the validity, in days, of the certificates RKE2 and K3S sign
+	signedCertExpirationDaysEnv = "CATTLE_NEW_SIGNED_CERT_EXPIRATION_DAYS"
+)
+
+// IssueShortLivedCertificates re-issues the certificates of every service of a RKE2/K3S downstream cluster with a
+// validity of the given number of days, so they are within the renewal window of RKE2 and K3S. The validity is set in
+// the environment file of the service of every node for the rotation only, the next restart of the services uses the
+// default validity again.
+func IssueShortLivedCertificates(client *rancher.Client, clusterName string, days int) (err error) {
+	clusterNodes, err := getClusterNodes(client, clusterName)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Setting %s=%d on the nodes of cluster %s", signedCertExpirationDaysEnv, days, clusterName)
+	err = clusterNodes.executeOnNodes(func(service string) string {
+		return fmt.Sprintf("%s && echo %s=%d | sudo tee -a /etc/default/%s", removeEnvCommand(service), signedCertExpirationDaysEnv, days, service)
+	})
+	defer func() {
+		removeErr := clusterNodes.executeOnNodes(removeEnvCommand)
+		if err == nil {
+			err = removeErr
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	return RotateCerts(client, clusterName)
+}
+
+// RestartClusterServices restarts the RKE2/K3S service of every server node, followed by every agent node, of a
+// downstream cluster, which renews the certificates that are expired or about to expire without a rotation. It waits
+// for the cluster to be active again.
+func RestartClusterServices(client *rancher.Client, clusterName string) error {
+	clusterNodes, err := getClusterNodes(client, clusterName)
+	if err != nil {
+		return err
+	}
+
+	return restartClusterServices(client, clusterNodes)
+}
+
+// removeEnvCommand returns the command removing the certificate validity from the environment file of the service
+func removeEnvCommand(service string) string {
+	envFile := "/etc/default/" + service
+	return fmt.Sprintf("sudo touch %s && sudo sed -i '/^%s=/d' %s", envFile, signedCertExpirationDaysEnv, envFile)
+}
@@ -1,6 +1,13 @@
 package certificates
 
-import "github.com/sirupsen/logrus"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
 
 // VerifyCertificateRotation verifies if the certificates have been rotated by comparing the certificates before and after rotation.
 func VerifyCertificateRotation(oldCertificates, newCertificates map[string]map[string]string) bool {
@@ -20,3 +27,146 @@ func VerifyCertificateRotation(oldCertificates, newCertificates map[string]map[s
 
 	return isRotated
 }
+
+// VerifyRotatedCertificates compares the parsed certificates before and after a rotation. Certificates of the rotated
+// services must be re-issued with a later NotAfter, the same SANs and the same issuing CA. Certificates of any other
+// service, and the CA certificates, must be left untouched. An empty list of services means every service was rotated.
+func VerifyRotatedCertificates(oldCertificates, newCertificates map[string]map[string]*CertificateInfo, services []string) error {
+	var errs []error
+
+	for nodeID, oldNodeCertificates := range oldCertificates {
+		newNodeCertificates, ok := newCertificates[nodeID]
+		if !ok {
+			errs = append(errs, fmt.Errorf("node %s: no certificates found after rotation", nodeID))
+			continue
+		}
+
+		for name, oldCert := range oldNodeCertificates {
+			newCert, ok := newNodeCertificates[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("node %s: certificate %s not found after rotation", nodeID, name))
+				continue
+			}
+
+			logrus.Debugf("%s %s: subject %s, issuer %s, SANs %v, NotAfter %s -> %s", nodeID, name, newCert.Subject, newCert.Issuer, newCert.SANs, oldCert.NotAfter, newCert.NotAfter)
+
+			if oldCert.IsCA || oldCert.Service == "" || (len(services) > 0 && !slices.Contains(services, oldCert.Service)) {
+				if oldCert.SerialNumber != newCert.SerialNumber {
+					errs = append(errs, fmt.Errorf("node %s: certificate %s was re-issued but was not requested for rotation", nodeID, name))
+				}
+
+				continue
+			}
+
+			for _, err := range verifyReissuedCertificate(oldCert, newCert) {
+				errs = append(errs, fmt.Errorf("node %s: %w", nodeID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// VerifyRotatedCA compares the parsed certificates before and after a CA rotation. Every CA certificate must be
+// re-issued, and every component certificate must be re-issued by a new CA with the same SANs.
+func VerifyRotatedCA(oldCertificates, newCertificates map[string]map[string]*CertificateInfo) error {
+	var errs []error
+
+	for nodeID, oldNodeCertificates := range oldCertificates {
+		for name, oldCert := range oldNodeCertificates {
+			newCert, ok := newCertificates[nodeID][name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("node %s: certificate %s not found after CA rotation", nodeID, name))
+				continue
+			}
+
+			if oldCert.SerialNumber == newCert.SerialNumber {
+				errs = append(errs, fmt.Errorf("node %s: certificate %s was not re-issued", nodeID, name))
+				continue
+			}
+
+			if oldCert.IsCA {
+				continue
+			}
+
+			if oldCert.AuthorityKeyID == newCert.AuthorityKeyID {
+				errs = append(errs, fmt.Errorf("node %s: certificate %s is still issued by the old CA %s", nodeID, name, oldCert.Issuer))
+			}
+
+			for _, san := range oldCert.SANs {
+				if !slices.Contains(newCert.SANs, san) {
+					errs = append(errs, fmt.Errorf("node %s: certificate %s lost SAN %s", nodeID, name, san))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetExpiringCertificates returns the names of the certificates, by node ID, that expire before the given time
+func GetExpiringCertificates(nodeCertificates map[string]map[string]*CertificateInfo, before time.Time) map[string][]string {
+	expiring := map[string][]string{}
+	for nodeID, certificates := range nodeCertificates {
+		for name, cert := range certificates {
+			if cert.NotAfter.Before(before) {
+				expiring[nodeID] = append(expiring[nodeID], name)
+			}
+		}
+	}
+
+	return expiring
+}
+
+// VerifyRenewedCertificates verifies that the expiring certificates, by node ID, were re-issued by the same CA with the
+// same SANs, and are no longer expiring before the given time
+func VerifyRenewedCertificates(oldCertificates, newCertificates map[string]map[string]*CertificateInfo, expiring map[string][]string, before time.Time) error {
+	var errs []error
+
+	for nodeID, names := range expiring {
+		for _, name := range names {
+			oldCert, newCert := oldCertificates[nodeID][name], newCertificates[nodeID][name]
+			if oldCert == nil || newCert == nil {
+				errs = append(errs, fmt.Errorf("node %s: certificate %s not found", nodeID, name))
+				continue
+			}
+
+			certErrs := verifyReissuedCertificate(oldCert, newCert)
+			if len(certErrs) == 0 && newCert.NotAfter.Before(before) {
+				certErrs = append(certErrs, fmt.Errorf("certificate %s was renewed but still expires at %s", name, newCert.NotAfter))
+			}
+
+			for _, err := range certErrs {
+				errs = append(errs, fmt.Errorf("node %s: %w", nodeID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// verifyReissuedCertificate verifies a certificate was re-issued by the same CA with a longer validity and the same SANs
+func verifyReissuedCertificate(oldCert, newCert *CertificateInfo) []error {
+	var errs []error
+
+	if oldCert.SerialNumber == newCert.SerialNumber {
+		errs = append(errs, fmt.Errorf("certificate %s was not re-issued", oldCert.Name))
+		return errs
+	}
+
+	if !newCert.NotAfter.After(oldCert.NotAfter) {
+		errs = append(errs, fmt.Errorf("certificate %s validity was not extended: NotAfter %s -> %s", oldCert.Name, oldCert.NotAfter, newCert.NotAfter))
+	}
+
+	for _, san := range oldCert.SANs {
+		if !slices.Contains(newCert.SANs, san) {
+			errs = append(errs, fmt.Errorf("certificate %s lost SAN %s", oldCert.Name, san))
+		}
+	}
+
+	if oldCert.Issuer != newCert.Issuer || oldCert.AuthorityKeyID != newCert.AuthorityKeyID {
+		errs = append(errs, fmt.Errorf("certificate %s is not issued by the same CA: %s -> %s", oldCert.Name, oldCert.Issuer, newCert.Issuer))
+	}
+
+	return errs
+}
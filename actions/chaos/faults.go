@@ -0,0 +1,246 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	powerOffCommand = "sudo shutdown -h now"
+	rebootCommand   = "sudo reboot"
+
+	partitionChain   = "RANCHER-CHAOS"
+	sshPort          = "22"
+	diskFillFilename = "chaos-disk-fill"
+
+	clusterAgentNamespace  = "cattle-system"
+	clusterAgentDeployment = "cattle-cluster-agent"
+
+	clusterAgentReplicasFile = "/var/tmp/rancher-chaos-cluster-agent-replicas"
+
+	// safetyRevertMargin is added to the duration of a fault before the node reverts it by itself
+	safetyRevertMargin = 5 * time.Minute
+)
+
+// Fault is a failure that can be injected into a node of a cluster and reverted afterwards
+type Fault interface {
+	// Name returns a human readable description of the fault and its target
+	Name() string
+	// Inject injects the fault. The duration is the time the fault is expected to last, it is used by the faults
+	// that can schedule their own revert on the node in case Revert is never called.
+	Inject(duration time.Duration) error
+	// Revert reverts the fault. It is safe to call Revert more than once, or on a fault that was never injected.
+	Revert() error
+}
+
+// sshFault is a fault that is injected and reverted by running commands on the node through ssh
+type sshFault struct {
+	name          string
+	target        *Target
+	injectCommand string
+	revertCommand string
+}
+
+// Name returns a human readable description of the fault and its target
+func (f *sshFault) Name() string {
+	return fmt.Sprintf("%s on %s", f.name, f.target.Name)
+}
+
+// Inject runs the inject command on the node. When the fault has a revert command, a transient systemd timer is
+// created first so the node reverts the fault by itself if the test never does.
+func (f *sshFault) Inject(duration time.Duration) error {
+	if f.revertCommand != "" {
+		seconds := int((duration + safetyRevertMargin).Seconds())
+		timerCommand := fmt.Sprintf("sudo systemd-run --unit=%s --on-active=%ds /bin/sh -c %q", f.unitName(), seconds, f.revertCommand)
+
+		output, err := f.target.SSHNode.ExecuteCommand(timerCommand)
+		if err != nil {
+			return fmt.Errorf("failed to schedule the revert of %s: %w: %s", f.Name(), err, output)
+		}
+	}
+
+	logrus.Infof("Injecting %s", f.Name())
+	output, err := f.target.SSHNode.ExecuteCommand(f.injectCommand)
+	if err != nil && !errors.Is(err, &ssh.ExitMissingError{}) {
+		return fmt.Errorf("failed to inject %s: %w: %s", f.Name(), err, output)
+	}
+
+	return nil
+}
+
+// Revert runs the revert command on the node and removes the scheduled revert
+func (f *sshFault) Revert() error {
+	if f.revertCommand == "" {
+		return nil
+	}
+
+	logrus.Infof("Reverting %s", f.Name())
+	output, err := f.target.SSHNode.ExecuteCommand("sudo /bin/sh -c " + fmt.Sprintf("%q", f.revertCommand))
+	if err != nil {
+		return fmt.Errorf("failed to revert %s: %w: %s", f.Name(), err, output)
+	}
+
+	_, _ = f.target.SSHNode.ExecuteCommand(fmt.Sprintf("sudo systemctl stop %s.timer", f.unitName()))
+
+	return nil
+}
+
+func (f *sshFault) unitName() string {
+	return "rancher-chaos-" + strings.ReplaceAll(f.name, " ", "-")
+}
+
+// NewReboot returns a fault rebooting the node. The node comes back by itself, so reverting it does nothing.
+func NewReboot(target *Target) Fault {
+	return &sshFault{
+		name:          "reboot",
+		target:        target,
+		injectCommand: rebootCommand,
+	}
+}
+
+// NewServiceStop returns a fault stopping a systemd service of the node, such as rke2-server, rke2-agent, k3s or
+// k3s-agent. Stopping the service also stops the kubelet it runs. Reverting the fault starts the service again.
+func NewServiceStop(target *Target, service string) Fault {
+	return &sshFault{
+		name:          service + " stop",
+		target:        target,
+		injectCommand: "sudo systemctl stop " + service,
+		revertCommand: "systemctl start " + service,
+	}
+}
+
+// NewNetworkPartition returns a fault dropping every packet to and from the node, except for ssh so the fault
+// can be reverted. Reverting the fault removes the iptables rules.
+func NewNetworkPartition(target *Target) Fault {
+	rules := []string{
+		"iptables -N " + partitionChain,
+		"iptables -A " + partitionChain + " -p tcp --dport " + sshPort + " -j ACCEPT",
+		"iptables -A " + partitionChain + " -p tcp --sport " + sshPort + " -j ACCEPT",
+		"iptables -A " + partitionChain + " -i lo -j ACCEPT",
+		"iptables -A " + partitionChain + " -o lo -j ACCEPT",
+		"iptables -A " + partitionChain + " -j DROP",
+		"iptables -I INPUT -j " + partitionChain,
+		"iptables -I OUTPUT -j " + partitionChain,
+	}
+
+	revertRules := []string{
+		"iptables -D INPUT -j " + partitionChain,
+		"iptables -D OUTPUT -j " + partitionChain,
+		"iptables -F " + partitionChain,
+		"iptables -X " + partitionChain,
+	}
+
+	return &sshFault{
+		name:          "network partition",
+		target:        target,
+		injectCommand: "sudo /bin/sh -c " + fmt.Sprintf("%q", strings.Join(rules, " && ")),
+		revertCommand: strings.Join(revertRules, "; ") + "; true",
+	}
+}
+
+// NewEtcdMemberKill returns a fault killing the etcd member of the node. On RKE2 the etcd static pod manifest is
+// moved away so the kubelet does not restart it, on K3S the embedded etcd is stopped with the k3s service.
+// Reverting the fault restores the manifest or starts the service again.
+func NewEtcdMemberKill(target *Target, clusterType string) Fault {
+	if clusterType == k3s {
+		fault := NewServiceStop(target, k3s).(*sshFault)
+		fault.name = "etcd member kill"
+
+		return fault
+	}
+
+	manifest := "/var/lib/rancher/" + clusterType + "/agent/pod-manifests/etcd.yaml"
+	backup := "/var/lib/rancher/" + clusterType + "/etcd.yaml.chaos"
+
+	return &sshFault{
+		name:          "etcd member kill",
+		target:        target,
+		injectCommand: fmt.Sprintf("sudo mv %s %s && sudo pkill -9 -x etcd", manifest, backup),
+		revertCommand: fmt.Sprintf("if [ -f %s ]; then mv %s %s; fi", backup, backup, manifest),
+	}
+}
+
+// NewClusterAgentScaleDown returns a fault disconnecting the downstream cluster from Rancher by scaling the
+// cattle-cluster-agent deployment to 0 with the kubectl of a control plane node. Reverting the fault scales the
+// deployment back to the replicas it had.
+func NewClusterAgentScaleDown(target *Target, clusterType string) Fault {
+	kubectl := "/var/lib/rancher/" + clusterType + "/bin/kubectl --kubeconfig /etc/rancher/" + clusterType + "/" + clusterType + ".yaml"
+	if clusterType == k3s {
+		kubectl = "k3s kubectl"
+	}
+
+	scale := fmt.Sprintf("%s -n %s scale deployment %s", kubectl, clusterAgentNamespace, clusterAgentDeployment)
+	saveReplicas := fmt.Sprintf("%s -n %s get deployment %s -o jsonpath='{.spec.replicas}' > %s", kubectl, clusterAgentNamespace, clusterAgentDeployment, clusterAgentReplicasFile)
+
+	return &sshFault{
+		name:          "cluster agent scale down",
+		target:        target,
+		injectCommand: fmt.Sprintf("sudo /bin/sh -c %q", saveReplicas+" && "+scale+" --replicas=0"),
+		revertCommand: fmt.Sprintf("if [ -f %s ]; then xargs -I{} %s --replicas={} < %s && rm -f %s; fi", clusterAgentReplicasFile, scale, clusterAgentReplicasFile, clusterAgentReplicasFile),
+	}
+}
+
+// NewDiskFill returns a fault filling the disk of the data directory of the node with a file of the given size,
+// such as 10G. Reverting the fault deletes the file.
+func NewDiskFill(target *Target, clusterType, size string) Fault {
+	filename := "/var/lib/rancher/" + clusterType + "/" + diskFillFilename
+
+	return &sshFault{
+		name:          "disk fill",
+		target:        target,
+		injectCommand: fmt.Sprintf("sudo fallocate -l %s %s", size, filename),
+		revertCommand: "rm -f " + filename,
+	}
+}
+
+// powerOffFault powers off a node. A powered off node can't be started through ssh, so it is reverted by deleting
+// its machine and letting Rancher replace it.
+type powerOffFault struct {
+	client *rancher.Client
+	target *Target
+}
+
+// NewPowerOff returns a fault powering off the node. Reverting the fault deletes the machine of the node, if it
+// still exists, so Rancher replaces it.
+func NewPowerOff(client *rancher.Client, target *Target) Fault {
+	return &powerOffFault{
+		client: client,
+		target: target,
+	}
+}
+
+// Name returns a human readable description of the fault and its target
+func (f *powerOffFault) Name() string {
+	return "power off on " + f.target.Name
+}
+
+// Inject shuts the node down
+func (f *powerOffFault) Inject(_ time.Duration) error {
+	logrus.Infof("Injecting %s", f.Name())
+	output, err := f.target.SSHNode.ExecuteCommand(powerOffCommand)
+	if err != nil && !errors.Is(err, &ssh.ExitMissingError{}) {
+		return fmt.Errorf("failed to inject %s: %w: %s", f.Name(), err, output)
+	}
+
+	return nil
+}
+
+// Revert deletes the machine of the node if it has not been replaced yet
+func (f *powerOffFault) Revert() error {
+	machine, err := f.client.Steve.SteveType(stevetypes.Machine).ByID(f.target.MachineID)
+	if err != nil {
+		logrus.Debugf("Machine %s was already removed: %v", f.target.Name, err)
+		return nil
+	}
+
+	logrus.Infof("Reverting %s by deleting the machine", f.Name())
+
+	return f.client.Steve.SteveType(stevetypes.Machine).Delete(machine)
+}
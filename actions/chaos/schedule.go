@@ -0,0 +1,90 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledFault is a fault injected in the background after a delay, and reverted once its duration has passed
+type ScheduledFault struct {
+	fault    Fault
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	injected bool
+	err      error
+}
+
+// Schedule injects the fault after the delay and reverts it once the duration has passed. The fault is always
+// reverted, even when the injection fails or the schedule is stopped early.
+func Schedule(fault Fault, delay, duration time.Duration) *ScheduledFault {
+	scheduled := &ScheduledFault{
+		fault: fault,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go scheduled.run(delay, duration)
+
+	return scheduled
+}
+
+// Stop reverts the fault now, or cancels it if it has not been injected yet, and waits for the revert
+func (s *ScheduledFault) Stop() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	return s.Wait()
+}
+
+// Wait waits for the fault to be reverted and returns any error met while injecting or reverting it
+func (s *ScheduledFault) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Injected reports whether the fault was injected. It is only accurate once Wait or Stop returned.
+func (s *ScheduledFault) Injected() bool {
+	<-s.done
+	return s.injected
+}
+
+func (s *ScheduledFault) run(delay, duration time.Duration) {
+	defer close(s.done)
+
+	select {
+	case <-time.After(delay):
+	case <-s.stop:
+		logrus.Infof("Cancelled %s before it was injected", s.fault.Name())
+		return
+	}
+
+	injectErr := s.fault.Inject(duration)
+	s.injected = injectErr == nil
+
+	if injectErr == nil {
+		select {
+		case <-time.After(duration):
+		case <-s.stop:
+		}
+	}
+
+	revertErr := s.fault.Revert()
+	s.err = errors.Join(injectErr, revertErr)
+}
+
+// RunDuring runs the operation while the fault is injected after the delay and reverted once the duration has
+// passed. It waits for both the operation and the revert, and returns their errors.
+func RunDuring(fault Fault, delay, duration time.Duration, operation func() error) error {
+	scheduled := Schedule(fault, delay, duration)
+
+	operationErr := operation()
+	if operationErr != nil {
+		operationErr = fmt.Errorf("operation failed during %s: %w", fault.Name(), operationErr)
+	}
+
+	return errors.Join(operationErr, scheduled.Wait())
+}
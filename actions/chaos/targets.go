@@ -0,0 +1,71 @@
+package chaos
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/extensions/sshkeys"
+	"github.com/rancher/shepherd/pkg/nodes"
+)
+
+const (
+	ControlPlaneRole = "control-plane"
+	EtcdRole         = "etcd"
+	WorkerRole       = "worker"
+
+	k3s = "k3s"
+
+	nodeRoleLabelPrefix    = "node-role.kubernetes.io/"
+	machineSteveAnnotation = "cluster.x-k8s.io/machine"
+)
+
+// Target is a node of a downstream cluster that faults can be injected into
+type Target struct {
+	// Name is the name of the node
+	Name string
+	// MachineID is the steve ID of the machine of the node in the local cluster
+	MachineID string
+	// SSHNode is used to run the commands injecting and reverting faults on the node
+	SSHNode *nodes.Node
+}
+
+// GetTargetsWithRole returns a target for every node of the downstream cluster with the given role
+func GetTargetsWithRole(client *rancher.Client, clusterID, nodeRole string) ([]*Target, error) {
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := url.ParseQuery("labelSelector=" + nodeRoleLabelPrefix + nodeRole + "=true")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := steveclient.SteveType(stevetypes.Node).List(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodeList.Data) == 0 {
+		return nil, errors.New("no node found with role " + nodeRole)
+	}
+
+	var targets []*Target
+	for _, node := range nodeList.Data {
+		sshNode, err := sshkeys.GetSSHNodeFromMachine(client, &node)
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, &Target{
+			Name:      node.Name,
+			MachineID: namespaces.FleetDefault + "/" + node.Annotations[machineSteveAnnotation],
+			SSHNode:   sshNode,
+		})
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,32 @@
+package chaos
+
+import (
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
+	nodestat "github.com/rancher/shepherd/extensions/nodes"
+	"github.com/sirupsen/logrus"
+)
+
+// VerifyRecovery waits for the cluster to be done updating and for every machine of the cluster to be ready again
+func VerifyRecovery(client *rancher.Client, clusterID string, timeout time.Duration) error {
+	logrus.Infof("Waiting for cluster %s to recover", clusterID)
+	err := clusters.WaitClusterToBeUpgraded(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return nodestat.AllMachineReady(client, clusterID, timeout)
+}
+
+// InjectAndRecover injects the fault for the duration, reverts it and verifies the cluster recovers
+func InjectAndRecover(client *rancher.Client, clusterID string, fault Fault, duration time.Duration) error {
+	err := Schedule(fault, 0, duration).Wait()
+	if err != nil {
+		return err
+	}
+
+	return VerifyRecovery(client, clusterID, defaults.ThirtyMinuteTimeout)
+}
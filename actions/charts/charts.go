@@ -50,6 +50,8 @@ type RancherMonitoringOpts struct {
 	Etcd              bool `json:"etcd" yaml:"etcd"`
 	Proxy             bool `json:"proxy" yaml:"proxy"`
 	Scheduler         bool `json:"scheduler" yaml:"scheduler"`
+	// Server enables the single target of the K3s server, which embeds the control plane components
+	Server bool `json:"server" yaml:"server"`
 }
 
 // RancherLoggingOpts is a struct of the required options to install Rancher Logging with desired chart values.
@@ -1,9 +1,19 @@
 package charts
 
 const (
-	ConfigurationFileKey = "chartUpgrade"
+	ConfigurationFileKey             = "chartUpgrade"
+	ValuesMatrixConfigurationFileKey = "chartValuesMatrix"
 )
 
 type Config struct {
 	IsUpgradable bool `json:"isUpgradable" yaml:"isUpgradable"`
 }
+
+// ValuesMatrixConfig bounds the installs of the chart values matrix
+type ValuesMatrixConfig struct {
+	// MaxCases is the maximum number of installs per chart, including the defaults and hardened cases
+	MaxCases int `json:"maxCases" yaml:"maxCases"`
+	// Seed rotates the values tested when they don't fit in MaxCases. A random seed is used when it is not set, and
+	// logged so a run can be repeated with the same values.
+	Seed *int64 `json:"seed" yaml:"seed"`
+}
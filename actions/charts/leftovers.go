@@ -0,0 +1,255 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/tests/actions/namespaces"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	helmChartLabel            = "helm.sh/chart"
+	instanceLabel             = "app.kubernetes.io/instance"
+	releaseLabel              = "release"
+	helmReleaseSecretPrefix   = "sh.helm.release.v1."
+	podSecurityLabelPrefix    = "pod-security.kubernetes.io/"
+)
+
+// auditedClusterResources are the cluster scoped resources a chart uninstall is audited for
+var auditedClusterResources = []schema.GroupVersionResource{
+	{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+	{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "validatingwebhookconfigurations"},
+	{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "mutatingwebhookconfigurations"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"},
+	{Group: "policy", Version: "v1beta1", Resource: "podsecuritypolicies"},
+	namespaces.NamespaceGroupVersionResource,
+}
+
+// auditedNamespacedResources are the namespaced resources a chart uninstall is audited for, in all namespaces
+var auditedNamespacedResources = []schema.GroupVersionResource{
+	{Version: "v1", Resource: "serviceaccounts"},
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "secrets"},
+	{Version: "v1", Resource: "services"},
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+}
+
+// namespaceDefaults are the objects Kubernetes creates in every namespace, they are reported with the namespace
+var namespaceDefaults = map[string]string{
+	"configmaps":      "kube-root-ca.crt",
+	"serviceaccounts": "default",
+}
+
+// auditedObject is the metadata of an object in a cluster snapshot
+type auditedObject struct {
+	resource    string
+	namespace   string
+	name        string
+	uid         string
+	labels      map[string]string
+	annotations map[string]string
+	finalizers  []string
+	terminating bool
+}
+
+// ClusterSnapshot is the metadata of the audited objects of a cluster at a point in time
+type ClusterSnapshot struct {
+	objects map[string]auditedObject
+}
+
+// Leftover is an object left behind by a chart uninstall
+type Leftover struct {
+	Resource  string
+	Namespace string
+	Name      string
+	Reason    string
+}
+
+func (l Leftover) String() string {
+	if l.Namespace == "" {
+		return fmt.Sprintf("%s %s: %s", l.Resource, l.Name, l.Reason)
+	}
+
+	return fmt.Sprintf("%s %s/%s: %s", l.Resource, l.Namespace, l.Name, l.Reason)
+}
+
+// TakeClusterSnapshot records the metadata of the audited cluster scoped and namespaced objects of the cluster.
+// Resources the cluster doesn't serve, like pod security policies on recent Kubernetes versions, are skipped.
+func TakeClusterSnapshot(client *rancher.Client, clusterID string) (*ClusterSnapshot, error) {
+	adminClient, err := rancher.NewClient(client.RancherConfig.AdminToken, client.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := adminClient.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &ClusterSnapshot{objects: map[string]auditedObject{}}
+	for _, gvr := range slices.Concat(auditedClusterResources, auditedNamespacedResources) {
+		list, err := dynamicClient.Resource(gvr).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+
+		for _, item := range list.Items {
+			object := auditedObject{
+				resource:    gvr.Resource,
+				namespace:   item.GetNamespace(),
+				name:        item.GetName(),
+				uid:         string(item.GetUID()),
+				labels:      item.GetLabels(),
+				annotations: item.GetAnnotations(),
+				finalizers:  item.GetFinalizers(),
+				terminating: item.GetDeletionTimestamp() != nil,
+			}
+			snapshot.objects[object.key()] = object
+		}
+	}
+
+	return snapshot, nil
+}
+
+func (o auditedObject) key() string {
+	return o.resource + "/" + o.namespace + "/" + o.name
+}
+
+// ChartLeftovers compares snapshots taken before the chart was installed and after it was uninstalled, and returns
+// the objects created in between that are attributed to the chart by their helm release annotations, release labels,
+// names or namespace. Namespaces stuck terminating and pod security admission labels added to existing namespaces are
+// reported too, as no other change is expected between the snapshots.
+func ChartLeftovers(descriptor ChartDescriptor, before, after *ClusterSnapshot) []Leftover {
+	var leftovers []Leftover
+	for key, object := range after.objects {
+		previous, existed := before.objects[key]
+		if existed && previous.uid == object.uid {
+			if object.resource == namespaces.NamespaceGroupVersionResource.Resource {
+				leftovers = append(leftovers, addedPodSecurityLabels(previous, object)...)
+			}
+
+			continue
+		}
+
+		reason := descriptor.attribution(object)
+		if reason == "" {
+			continue
+		}
+
+		if object.terminating {
+			reason += fmt.Sprintf(", stuck terminating with finalizers %v", object.finalizers)
+		}
+
+		leftovers = append(leftovers, Leftover{
+			Resource:  object.resource,
+			Namespace: object.namespace,
+			Name:      object.name,
+			Reason:    reason,
+		})
+	}
+
+	sort.Slice(leftovers, func(i, j int) bool {
+		return leftovers[i].String() < leftovers[j].String()
+	})
+
+	return leftovers
+}
+
+// LeftoversError returns an error listing the leftovers, nil if there are none
+func LeftoversError(leftovers []Leftover) error {
+	if len(leftovers) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(leftovers))
+	for _, leftover := range leftovers {
+		lines = append(lines, leftover.String())
+	}
+
+	return errors.New("chart uninstall left objects behind:\n" + strings.Join(lines, "\n"))
+}
+
+// releases returns the release names of the chart and its CRD chart
+func (d ChartDescriptor) releases() []string {
+	releases := []string{d.Name}
+	if d.CRDChart != "" {
+		releases = append(releases, d.CRDChart)
+	}
+
+	return releases
+}
+
+// attribution returns why the object is attributed to the chart, empty if it isn't
+func (d ChartDescriptor) attribution(object auditedObject) string {
+	isNamespace := object.resource == namespaces.NamespaceGroupVersionResource.Resource
+	if isNamespace && object.name == d.Namespace {
+		if d.DeleteNamespace || object.terminating {
+			return "chart namespace"
+		}
+
+		// the namespace is expected to be kept, its content is audited instead
+		return ""
+	}
+
+	if object.namespace == d.Namespace && namespaceDefaults[object.resource] == object.name {
+		return ""
+	}
+
+	for _, release := range d.releases() {
+		switch {
+		case object.annotations[helmReleaseNameAnnotation] == release:
+			return fmt.Sprintf("annotated with helm release %s", release)
+		case object.labels[instanceLabel] == release, object.labels[releaseLabel] == release:
+			return fmt.Sprintf("labeled with release %s", release)
+		case strings.HasPrefix(object.labels[helmChartLabel], release+"-"):
+			return fmt.Sprintf("labeled with chart %s", object.labels[helmChartLabel])
+		case strings.HasPrefix(object.name, helmReleaseSecretPrefix+release+"."):
+			return fmt.Sprintf("helm release secret of %s", release)
+		case strings.HasPrefix(object.name, release):
+			return fmt.Sprintf("named after release %s", release)
+		}
+	}
+
+	if object.namespace != "" && object.namespace == d.Namespace {
+		return "in chart namespace"
+	}
+
+	return ""
+}
+
+// addedPodSecurityLabels returns the pod security admission labels added to or changed on an existing namespace
+func addedPodSecurityLabels(before, after auditedObject) []Leftover {
+	var leftovers []Leftover
+	for key, value := range after.labels {
+		if !strings.HasPrefix(key, podSecurityLabelPrefix) || before.labels[key] == value {
+			continue
+		}
+
+		leftovers = append(leftovers, Leftover{
+			Resource: after.resource,
+			Name:     after.name,
+			Reason:   fmt.Sprintf("pod security admission label %s=%s added", key, value),
+		})
+	}
+
+	return leftovers
+}
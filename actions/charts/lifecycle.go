@@ -0,0 +1,535 @@
+package charts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/catalog"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/pkg/api/steve/catalog/types"
+	"github.com/rancher/shepherd/pkg/wait"
+	"github.com/rancher/tests/actions/namespaces"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ChartDescriptor describes a chart installed from a cluster repo. It is all the chart lifecycle needs to install,
+// upgrade, roll back, uninstall and verify the chart.
+type ChartDescriptor struct {
+	// Name of the chart, also used as its release name
+	Name string
+	// Repo is the cluster repo of the chart, the rancher charts repo when empty
+	Repo string
+	// Namespace the chart is installed in
+	Namespace string
+	// CRDChart is the chart installing the CRDs of the chart, empty if it has none. It is installed and upgraded with
+	// the chart and uninstalled after it.
+	CRDChart string
+	// DeleteNamespace deletes the namespace of the chart once the chart is uninstalled
+	DeleteNamespace bool
+	// Values returns the values of the chart for the payload options, nil to use the defaults of the chart
+	Values func(p *PayloadOpts) (map[string]any, error)
+}
+
+// chartRevision is a version of the chart applied with its values
+type chartRevision struct {
+	version string
+	values  map[string]any
+}
+
+// ChartLifecycle installs, upgrades, rolls back, uninstalls and verifies a chart from its descriptor
+type ChartLifecycle struct {
+	Descriptor ChartDescriptor
+
+	client    *rancher.Client
+	payload   PayloadOpts
+	revisions []chartRevision
+	installed bool
+}
+
+// NewChartLifecycle returns the lifecycle of the chart described by the descriptor on the cluster of the install
+// options. An empty version in the install options is resolved to the latest version of the chart on install.
+func NewChartLifecycle(client *rancher.Client, descriptor ChartDescriptor, installOptions *InstallOptions) (*ChartLifecycle, error) {
+	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
+	if err != nil {
+		return nil, err
+	}
+
+	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := PayloadOpts{
+		InstallOptions:  *installOptions,
+		Name:            descriptor.Name,
+		Namespace:       descriptor.Namespace,
+		Host:            serverSetting.Value,
+		DefaultRegistry: registrySetting.Value,
+	}
+
+	return newChartLifecycle(client, descriptor, payload), nil
+}
+
+func newChartLifecycle(client *rancher.Client, descriptor ChartDescriptor, payload PayloadOpts) *ChartLifecycle {
+	if descriptor.Repo == "" {
+		descriptor.Repo = catalog.RancherChartRepo
+	}
+
+	return &ChartLifecycle{
+		Descriptor: descriptor,
+		client:     client,
+		payload:    payload,
+	}
+}
+
+// Version returns the version of the chart applied last, empty if the chart was not installed by the lifecycle
+func (l *ChartLifecycle) Version() string {
+	if len(l.revisions) == 0 {
+		return ""
+	}
+
+	return l.revisions[len(l.revisions)-1].version
+}
+
+// Install installs the chart and its CRD chart, registers their uninstallation as a cleanup function, and waits for
+// the chart to be deployed
+func (l *ChartLifecycle) Install() error {
+	catalogClient, err := l.client.GetClusterCatalogClient(l.payload.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	if l.payload.Version == "" {
+		l.payload.Version, err = catalogClient.GetLatestChartVersion(l.Descriptor.Name, l.Descriptor.Repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	values, err := l.values()
+	if err != nil {
+		return err
+	}
+
+	var chartInstalls []types.ChartInstall
+	if l.Descriptor.CRDChart != "" {
+		chartInstallCRD := NewChartInstall(l.Descriptor.CRDChart, l.payload.Version, l.payload.Cluster.ID, l.payload.Cluster.Name, l.payload.Host, l.Descriptor.Repo, l.payload.ProjectID, l.payload.DefaultRegistry, nil)
+		chartInstalls = append(chartInstalls, *chartInstallCRD)
+	}
+	chartInstall := NewChartInstall(l.Descriptor.Name, l.payload.Version, l.payload.Cluster.ID, l.payload.Cluster.Name, l.payload.Host, l.Descriptor.Repo, l.payload.ProjectID, l.payload.DefaultRegistry, values)
+	chartInstalls = append(chartInstalls, *chartInstall)
+
+	chartInstallAction := NewChartInstallAction(l.Descriptor.Namespace, l.payload.ProjectID, chartInstalls)
+
+	if !l.installed {
+		l.client.Session.RegisterCleanupFunc(func() error {
+			if !l.installed {
+				return nil
+			}
+
+			return l.Uninstall()
+		})
+	}
+
+	logrus.Infof("Installing %s chart version %s", l.Descriptor.Name, l.payload.Version)
+	err = catalogClient.InstallChart(chartInstallAction, l.Descriptor.Repo)
+	if err != nil {
+		return err
+	}
+	l.installed = true
+
+	err = l.waitForRevision(0, l.payload.Version)
+	if err != nil {
+		return err
+	}
+
+	l.revisions = append(l.revisions, chartRevision{version: l.payload.Version, values: values})
+
+	return nil
+}
+
+// Upgrade upgrades the chart and its CRD chart to the version, with the values the descriptor builds for it, and
+// waits for the upgraded chart to be deployed
+func (l *ChartLifecycle) Upgrade(version string) error {
+	l.payload.Version = version
+
+	values, err := l.values()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Upgrading %s chart to version %s", l.Descriptor.Name, version)
+	err = l.apply(chartRevision{version: version, values: values})
+	if err != nil {
+		return err
+	}
+
+	l.installed = true
+
+	return nil
+}
+
+// Rollback restores the version and values of the chart applied before the last install, upgrade or rollback. The
+// apps API of Rancher has no rollback action, so the previous revision is applied again as an upgrade, which, like a
+// helm rollback, creates a new release revision.
+func (l *ChartLifecycle) Rollback() error {
+	if len(l.revisions) < 2 {
+		return fmt.Errorf("chart %s has no previous revision to roll back to", l.Descriptor.Name)
+	}
+
+	previous := l.revisions[len(l.revisions)-2]
+	l.payload.Version = previous.version
+
+	logrus.Infof("Rolling back %s chart to version %s", l.Descriptor.Name, previous.version)
+
+	return l.apply(previous)
+}
+
+// apply upgrades the chart to the revision and records it once the chart is deployed
+func (l *ChartLifecycle) apply(revision chartRevision) error {
+	catalogClient, err := l.client.GetClusterCatalogClient(l.payload.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	app, err := catalogClient.Apps(l.Descriptor.Namespace).Get(context.TODO(), l.Descriptor.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	var chartUpgrades []types.ChartUpgrade
+	if l.Descriptor.CRDChart != "" {
+		chartUpgradeCRD := NewChartUpgrade(l.Descriptor.CRDChart, l.Descriptor.CRDChart, revision.version, l.payload.Cluster.ID, l.payload.Cluster.Name, l.payload.Host, l.payload.DefaultRegistry, nil)
+		chartUpgrades = append(chartUpgrades, *chartUpgradeCRD)
+	}
+	chartUpgrade := NewChartUpgrade(l.Descriptor.Name, l.Descriptor.Name, revision.version, l.payload.Cluster.ID, l.payload.Cluster.Name, l.payload.Host, l.payload.DefaultRegistry, revision.values)
+	chartUpgrades = append(chartUpgrades, *chartUpgrade)
+
+	chartUpgradeAction := NewChartUpgradeAction(l.Descriptor.Namespace, chartUpgrades)
+
+	err = catalogClient.UpgradeChart(chartUpgradeAction, l.Descriptor.Repo)
+	if err != nil {
+		return err
+	}
+
+	err = l.waitForRevision(app.Spec.Version, revision.version)
+	if err != nil {
+		return err
+	}
+
+	l.revisions = append(l.revisions, revision)
+
+	return nil
+}
+
+// Uninstall uninstalls the chart, then its CRD chart, waiting for each app to be removed, and deletes the namespace of
+// the chart if the descriptor requires it. Charts that are not installed are skipped.
+func (l *ChartLifecycle) Uninstall() error {
+	catalogClient, err := l.client.GetClusterCatalogClient(l.payload.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	adminCatalogClient, err := l.adminCatalogClient()
+	if err != nil {
+		return err
+	}
+
+	for _, release := range l.Descriptor.releases() {
+		_, err := adminCatalogClient.Apps(l.Descriptor.Namespace).Get(context.TODO(), release, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		logrus.Infof("Uninstalling %s chart", release)
+		err = catalogClient.UninstallChart(release, l.Descriptor.Namespace, NewChartUninstallAction())
+		if err != nil {
+			return err
+		}
+
+		err = kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+			_, err := adminCatalogClient.Apps(l.Descriptor.Namespace).Get(ctx, release, metav1.GetOptions{})
+			if k8serrors.IsNotFound(err) {
+				return true, nil
+			}
+
+			return false, err
+		})
+		if err != nil {
+			return fmt.Errorf("chart %s was not uninstalled: %w", release, err)
+		}
+	}
+
+	l.installed = false
+
+	if !l.Descriptor.DeleteNamespace {
+		return nil
+	}
+
+	return l.deleteNamespace()
+}
+
+// deleteNamespace deletes the namespace of the chart and waits for it to be removed
+func (l *ChartLifecycle) deleteNamespace() error {
+	steveclient, err := l.client.Steve.ProxyDownstream(l.payload.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	namespaceClient := steveclient.SteveType(namespaces.NamespaceSteveType)
+
+	namespace, err := namespaceClient.ByID(l.Descriptor.Namespace)
+	if err != nil {
+		return err
+	}
+
+	err = namespaceClient.Delete(namespace)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := rancher.NewClient(l.client.RancherConfig.AdminToken, l.client.Session)
+	if err != nil {
+		return err
+	}
+
+	adminDynamicClient, err := adminClient.GetDownStreamClusterClient(l.payload.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	adminNamespaceResource := adminDynamicClient.Resource(namespaces.NamespaceGroupVersionResource).Namespace("")
+
+	return kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := adminNamespaceResource.Get(ctx, l.Descriptor.Namespace, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+}
+
+// Verify checks that the chart and its CRD chart are deployed at the version applied last, and that the chart was
+// deployed with the values applied last
+func (l *ChartLifecycle) Verify() error {
+	if len(l.revisions) == 0 {
+		return fmt.Errorf("chart %s was not installed by the lifecycle", l.Descriptor.Name)
+	}
+	revision := l.revisions[len(l.revisions)-1]
+
+	adminCatalogClient, err := l.adminCatalogClient()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, release := range l.Descriptor.releases() {
+		app, err := adminCatalogClient.Apps(l.Descriptor.Namespace).Get(context.TODO(), release, metav1.GetOptions{})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if state := app.Status.Summary.State; state != string(catalogv1.StatusDeployed) {
+			errs = append(errs, fmt.Errorf("chart %s: expected state %s, got %s", release, catalogv1.StatusDeployed, state))
+		}
+
+		if version := appChartVersion(app); version != revision.version {
+			errs = append(errs, fmt.Errorf("chart %s: expected version %s, got %s", release, revision.version, version))
+		}
+
+		if release != l.Descriptor.Name {
+			continue
+		}
+
+		err = verifyValues(app, revision.values)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chart %s: %w", release, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForWorkloads waits for the deployments, daemonsets and statefulsets in the namespace of the chart to have all
+// their replicas ready
+func (l *ChartLifecycle) WaitForWorkloads() error {
+	wranglerContext := l.client.WranglerContext
+	if l.payload.Cluster.ID != localCluster {
+		var err error
+		wranglerContext, err = l.client.WranglerContext.DownStreamClusterWranglerContext(l.payload.Cluster.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	namespace := l.Descriptor.Namespace
+
+	var notReady []string
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(context.Context) (bool, error) {
+		notReady = nil
+
+		deployments, err := wranglerContext.Apps.Deployment().List(namespace, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, deployment := range deployments.Items {
+			replicas := desiredReplicas(deployment.Spec.Replicas)
+			if deployment.Status.ObservedGeneration < deployment.Generation || deployment.Status.ReadyReplicas < replicas || deployment.Status.UpdatedReplicas < replicas {
+				notReady = append(notReady, "deployment "+deployment.Name)
+			}
+		}
+
+		daemonSets, err := wranglerContext.Apps.DaemonSet().List(namespace, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, daemonSet := range daemonSets.Items {
+			status := daemonSet.Status
+			if status.ObservedGeneration < daemonSet.Generation || status.NumberReady < status.DesiredNumberScheduled || status.UpdatedNumberScheduled < status.DesiredNumberScheduled {
+				notReady = append(notReady, "daemonset "+daemonSet.Name)
+			}
+		}
+
+		statefulSets, err := wranglerContext.Apps.StatefulSet().List(namespace, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, statefulSet := range statefulSets.Items {
+			replicas := desiredReplicas(statefulSet.Spec.Replicas)
+			if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.ReadyReplicas < replicas || statefulSet.Status.UpdatedReplicas < replicas {
+				notReady = append(notReady, "statefulset "+statefulSet.Name)
+			}
+		}
+
+		return len(notReady) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("workloads of chart %s in namespace %s are not ready %v: %w", l.Descriptor.Name, namespace, notReady, err)
+	}
+
+	return nil
+}
+
+// desiredReplicas returns the replicas of a workload spec, which default to one
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+
+	return *replicas
+}
+
+// values returns the values the descriptor builds for the current payload options
+func (l *ChartLifecycle) values() (map[string]any, error) {
+	if l.Descriptor.Values == nil {
+		return nil, nil
+	}
+
+	values, err := l.Descriptor.Values(&l.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build values of chart %s: %w", l.Descriptor.Name, err)
+	}
+
+	return values, nil
+}
+
+func (l *ChartLifecycle) adminCatalogClient() (*catalog.Client, error) {
+	adminClient, err := rancher.NewClient(l.client.RancherConfig.AdminToken, l.client.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	return adminClient.GetClusterCatalogClient(l.payload.Cluster.ID)
+}
+
+// waitForRevision waits for the chart to be deployed at the version, in a release revision newer than the given one
+func (l *ChartLifecycle) waitForRevision(previousRevision int, version string) error {
+	adminCatalogClient, err := l.adminCatalogClient()
+	if err != nil {
+		return err
+	}
+
+	watchAppInterface, err := adminCatalogClient.Apps(l.Descriptor.Namespace).Watch(context.TODO(), metav1.ListOptions{
+		FieldSelector:  "metadata.name=" + l.Descriptor.Name,
+		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
+	})
+	if err != nil {
+		return err
+	}
+
+	return wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
+		if event.Type == watch.Error {
+			return false, fmt.Errorf("there was an error deploying %s chart", l.Descriptor.Name)
+		}
+
+		app, ok := event.Object.(*catalogv1.App)
+		if !ok || app.Spec.Version <= previousRevision {
+			return false, nil
+		}
+
+		switch app.Status.Summary.State {
+		case string(catalogv1.StatusFailed):
+			return false, fmt.Errorf("%s chart version %s failed to deploy", l.Descriptor.Name, version)
+		case string(catalogv1.StatusDeployed):
+			return appChartVersion(app) == version, nil
+		}
+
+		return false, nil
+	})
+}
+
+func appChartVersion(app *catalogv1.App) string {
+	if app.Spec.Chart == nil || app.Spec.Chart.Metadata == nil {
+		return ""
+	}
+
+	return app.Spec.Chart.Metadata.Version
+}
+
+// verifyValues checks that each of the expected values is set on the app. The values are compared in their JSON form,
+// as the app returns numbers as floats.
+func verifyValues(app *catalogv1.App, expected map[string]any) error {
+	for key, value := range expected {
+		expectedValue, err := normalizeValue(value)
+		if err != nil {
+			return err
+		}
+
+		actualValue, err := normalizeValue(app.Spec.Values[key])
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(expectedValue, actualValue) {
+			return fmt.Errorf("expected value %s to be %v, got %v", key, expectedValue, actualValue)
+		}
+	}
+
+	return nil
+}
+
+func normalizeValue(value any) (any, error) {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized any
+	err = json.Unmarshal(valueBytes, &normalized)
+
+	return normalized, err
+}
@@ -0,0 +1,395 @@
+package charts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/extensions/kubeconfig"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	"github.com/rancher/tests/actions/services"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubewait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// LoggingSinkPort is the port the logging sink receives records on
+	LoggingSinkPort = 9880
+	// Image of the logging sink, fluent-bit with an HTTP input and a stdout output
+	loggingSinkImage = "fluent/fluent-bit:3.2.10"
+	// Image of the log emitter workloads
+	logEmitterImage = "busybox:1.36.1"
+	// Buffer size used to read the logs of the logging sink
+	loggingSinkLogBufferSize = "8MB"
+	// JournaldAggregatorName is the name suffix of the workloads rancher-logging deploys to collect the RKE2 and K3s
+	// node logs when additional logging sources are enabled, like rancher-logging-rke2-journald-aggregator
+	JournaldAggregatorName = "journald-aggregator"
+	loggingGroup           = "logging.banzaicloud.io"
+	loggingVersion         = "v1beta1"
+)
+
+// FlowGroupVersionResource is the required Group Version Resource for accessing logging operator flows in a cluster, using the dynamic client.
+var FlowGroupVersionResource = schema.GroupVersionResource{
+	Group:    loggingGroup,
+	Version:  loggingVersion,
+	Resource: "flows",
+}
+
+// ClusterFlowGroupVersionResource is the required Group Version Resource for accessing logging operator clusterflows in a cluster, using the dynamic client.
+var ClusterFlowGroupVersionResource = schema.GroupVersionResource{
+	Group:    loggingGroup,
+	Version:  loggingVersion,
+	Resource: "clusterflows",
+}
+
+// OutputGroupVersionResource is the required Group Version Resource for accessing logging operator outputs in a cluster, using the dynamic client.
+var OutputGroupVersionResource = schema.GroupVersionResource{
+	Group:    loggingGroup,
+	Version:  loggingVersion,
+	Resource: "outputs",
+}
+
+// ClusterOutputGroupVersionResource is the required Group Version Resource for accessing logging operator clusteroutputs in a cluster, using the dynamic client.
+var ClusterOutputGroupVersionResource = schema.GroupVersionResource{
+	Group:    loggingGroup,
+	Version:  loggingVersion,
+	Resource: "clusteroutputs",
+}
+
+// LoggingSink is an HTTP sink in the cluster that prints the records it receives to its logs, one JSON object per line
+type LoggingSink struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// Endpoint returns the in-cluster URL outputs ship the records to
+func (s *LoggingSink) Endpoint() string {
+	return fmt.Sprintf("http://%s.%s.svc:%d/", s.Name, s.Namespace, LoggingSinkPort)
+}
+
+// CreateLoggingSink is a helper function that creates the deployment and the cluster IP service of a logging sink, and
+// waits for the deployment to be available. The deployment is removed with the namespace.
+func CreateLoggingSink(client *rancher.Client, clusterID, namespace, name string) (*LoggingSink, error) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "sink",
+					Image: loggingSinkImage,
+					Args: []string{
+						"-f", "1",
+						"-i", "http", "-p", fmt.Sprintf("port=%d", LoggingSinkPort),
+						"-o", "stdout", "-p", "format=json_lines", "-m", "*",
+					},
+					Ports: []corev1.ContainerPort{
+						{
+							ContainerPort: LoggingSinkPort,
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	deployment, err := deployments.CreateDeployment(client, clusterID, name, namespace, template, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []corev1.ServicePort{
+		{
+			Name: "http",
+			Port: LoggingSinkPort,
+		},
+	}
+	serviceTemplate := services.NewServiceTemplate(name, namespace, corev1.ServiceTypeClusterIP, ports, deployment.Spec.Template.Labels)
+	_, err = services.CreateService(steveclient, serviceTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deployments.WatchAndWaitDeployments(client, clusterID, namespace, metav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoggingSink{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    deployment.Spec.Template.Labels,
+	}, nil
+}
+
+// CreateLogEmitter is a helper function that creates a deployment printing the lines every five seconds, and returns
+// the labels of its pods to select them in flows. The deployment is removed with the namespace.
+func CreateLogEmitter(client *rancher.Client, clusterID, namespace, name string, lines []string) (map[string]string, error) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:    "emitter",
+					Image:   logEmitterImage,
+					Command: []string{"/bin/sh", "-c"},
+					Args:    []string{`while true; do echo "$LOG_LINES"; sleep 5; done`},
+					Env: []corev1.EnvVar{
+						{
+							Name:  "LOG_LINES",
+							Value: strings.Join(lines, "\n"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	deployment, err := deployments.CreateDeployment(client, clusterID, name, namespace, template, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment.Spec.Template.Labels, nil
+}
+
+// ParserFilter returns a flow filter that parses the log of the records with the parser type, like json, and keeps
+// the other fields of the records
+func ParserFilter(parserType string) map[string]any {
+	return map[string]any{
+		"parser": map[string]any{
+			"remove_key_name_field": true,
+			"reserve_data":          true,
+			"parse": map[string]any{
+				"type": parserType,
+			},
+		},
+	}
+}
+
+// GrepExcludeFilter returns a flow filter that drops the records whose key matches the pattern, like /^debug$/
+func GrepExcludeFilter(key, pattern string) map[string]any {
+	return map[string]any{
+		"grep": map[string]any{
+			"exclude": []any{
+				map[string]any{"key": key, "pattern": pattern},
+			},
+		},
+	}
+}
+
+// GrepRegexpFilter returns a flow filter that keeps only the records whose key matches the pattern. Nested keys use
+// the record accessor syntax, like $.kubernetes.pod_name.
+func GrepRegexpFilter(key, pattern string) map[string]any {
+	return map[string]any{
+		"grep": map[string]any{
+			"regexp": []any{
+				map[string]any{"key": key, "pattern": pattern},
+			},
+		},
+	}
+}
+
+// RecordModifierFilter returns a flow filter that adds the fields to the records
+func RecordModifierFilter(fields map[string]string) map[string]any {
+	records := []any{}
+	for key, value := range fields {
+		records = append(records, map[string]any{key: value})
+	}
+
+	return map[string]any{
+		"record_modifier": map[string]any{
+			"records": records,
+		},
+	}
+}
+
+// sinkOutputSpec returns the spec of an output shipping the records to the sink as JSON, flushed every few seconds
+func sinkOutputSpec(sink *LoggingSink) map[string]any {
+	return map[string]any{
+		"http": map[string]any{
+			"endpoint":     sink.Endpoint(),
+			"content_type": "application/json",
+			"json_array":   true,
+			"format": map[string]any{
+				"type": "json",
+			},
+			"buffer": map[string]any{
+				"flush_mode":      "interval",
+				"flush_interval":  "5s",
+				"timekey":         "10s",
+				"timekey_wait":    "0s",
+				"timekey_use_utc": true,
+			},
+		},
+	}
+}
+
+// CreateLoggingOutput is a helper function that creates an Output in the namespace shipping the records to the sink
+func CreateLoggingOutput(client *rancher.Client, clusterID, namespace, name string, sink *LoggingSink) error {
+	return createLoggingResource(client, clusterID, OutputGroupVersionResource, "Output", namespace, name, sinkOutputSpec(sink))
+}
+
+// CreateLoggingClusterOutput is a helper function that creates a ClusterOutput in the rancher-logging namespace
+// shipping the records to the sink
+func CreateLoggingClusterOutput(client *rancher.Client, clusterID, name string, sink *LoggingSink) error {
+	return createLoggingResource(client, clusterID, ClusterOutputGroupVersionResource, "ClusterOutput", RancherLoggingNamespace, name, sinkOutputSpec(sink))
+}
+
+// CreateLoggingFlow is a helper function that creates a Flow in the namespace sending the logs of the pods with the
+// labels through the filters to the output of the namespace
+func CreateLoggingFlow(client *rancher.Client, clusterID, namespace, name string, labels map[string]string, filters []map[string]any, outputName string) error {
+	spec := map[string]any{
+		"match": []any{
+			map[string]any{
+				"select": map[string]any{
+					"labels": labels,
+				},
+			},
+		},
+		"filters":         filters,
+		"localOutputRefs": []any{outputName},
+	}
+
+	return createLoggingResource(client, clusterID, FlowGroupVersionResource, "Flow", namespace, name, spec)
+}
+
+// CreateLoggingClusterFlow is a helper function that creates a ClusterFlow in the rancher-logging namespace sending
+// the logs of the pods of the namespaces through the filters to the cluster output
+func CreateLoggingClusterFlow(client *rancher.Client, clusterID, name string, namespaces []string, filters []map[string]any, clusterOutputName string) error {
+	spec := map[string]any{
+		"match": []any{
+			map[string]any{
+				"select": map[string]any{
+					"namespaces": namespaces,
+				},
+			},
+		},
+		"filters":          filters,
+		"globalOutputRefs": []any{clusterOutputName},
+	}
+
+	return createLoggingResource(client, clusterID, ClusterFlowGroupVersionResource, "ClusterFlow", RancherLoggingNamespace, name, spec)
+}
+
+// createLoggingResource creates a logging operator resource with the dynamic client, and registers its deletion
+func createLoggingResource(client *rancher.Client, clusterID string, gvr schema.GroupVersionResource, kind, namespace, name string, spec map[string]any) error {
+	dynamicClient, err := client.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return err
+	}
+
+	// the spec is built from typed maps and slices, unstructured objects only hold JSON types
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	specMap := map[string]any{}
+	err = json.Unmarshal(specBytes, &specMap)
+	if err != nil {
+		return err
+	}
+
+	object := &unstructuredv1.Unstructured{Object: map[string]any{
+		"apiVersion": loggingGroup + "/" + loggingVersion,
+		"kind":       kind,
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": specMap,
+	}}
+
+	resource := dynamicClient.Resource(gvr).Namespace(namespace)
+	_, err = resource.Create(context.TODO(), object, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create %s %s/%s: %w", kind, namespace, name, err)
+	}
+
+	client.Session.RegisterCleanupFunc(func() error {
+		err := resource.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	})
+
+	return nil
+}
+
+// GetLoggingSinkRecords is a helper function that returns the records the sink received, one JSON object per line
+func GetLoggingSinkRecords(client *rancher.Client, clusterID string, sink *LoggingSink) (string, error) {
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	selector := make([]string, 0, len(sink.Labels))
+	for key, value := range sink.Labels {
+		selector = append(selector, key+"="+value)
+	}
+
+	pods, err := steveclient.SteveType(stevetypes.Pod).NamespacedSteveClient(sink.Namespace).List(url.Values{
+		"labelSelector": []string{strings.Join(selector, ",")},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var records strings.Builder
+	for _, pod := range pods.Data {
+		logs, err := kubeconfig.GetPodLogs(client, clusterID, pod.Name, sink.Namespace, loggingSinkLogBufferSize)
+		if err != nil {
+			return "", err
+		}
+
+		records.WriteString(logs)
+	}
+
+	return records.String(), nil
+}
+
+// WaitForLoggingSinkRecords is a helper function that waits until the sink received records containing each of the
+// substrings, and returns all the records it received
+func WaitForLoggingSinkRecords(client *rancher.Client, clusterID string, sink *LoggingSink, substrings []string) (string, error) {
+	var records string
+	var missing []string
+	err := kubewait.PollUntilContextTimeout(context.TODO(), 10*time.Second, 10*time.Minute, true, func(context.Context) (bool, error) {
+		var err error
+		records, err = GetLoggingSinkRecords(client, clusterID, sink)
+		if err != nil {
+			return false, err
+		}
+
+		missing = nil
+		for _, substring := range substrings {
+			if !strings.Contains(records, substring) {
+				missing = append(missing, substring)
+			}
+		}
+
+		return len(missing) == 0, nil
+	})
+	if err != nil && len(missing) > 0 {
+		return records, fmt.Errorf("logging sink %s/%s did not receive records with %v: %w", sink.Namespace, sink.Name, missing, err)
+	}
+
+	return records, err
+}
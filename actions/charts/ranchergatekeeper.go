@@ -1,18 +1,7 @@
 package charts
 
 import (
-	"context"
-	"fmt"
-
-	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/shepherd/clients/rancher"
-	"github.com/rancher/shepherd/clients/rancher/catalog"
-	"github.com/rancher/shepherd/extensions/defaults"
-	"github.com/rancher/shepherd/pkg/api/steve/catalog/types"
-	"github.com/rancher/shepherd/pkg/wait"
-	"github.com/rancher/tests/actions/namespaces"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
 )
 
 const (
@@ -24,275 +13,32 @@ const (
 	RancherGatekeeperCRDName = "rancher-gatekeeper-crd"
 )
 
-// InstallRancherGatekeeperChart installs the OPA gatekeeper chart
-func InstallRancherGatekeeperChart(client *rancher.Client, installOptions *InstallOptions) error {
-	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
-	if err != nil {
-		return err
-	}
-
-	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
-	if err != nil {
-		return err
-	}
-
-	gatekeeperChartInstallActionPayload := &PayloadOpts{
-		InstallOptions:  *installOptions,
+// RancherGatekeeperChart returns the descriptor of the OPA gatekeeper chart
+func RancherGatekeeperChart() ChartDescriptor {
+	return ChartDescriptor{
 		Name:            RancherGatekeeperName,
 		Namespace:       RancherGatekeeperNamespace,
-		Host:            serverSetting.Value,
-		DefaultRegistry: registrySetting.Value,
-	}
-
-	chartInstallAction := newGatekeeperChartInstallAction(gatekeeperChartInstallActionPayload)
-
-	catalogClient, err := client.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
+		CRDChart:        RancherGatekeeperCRDName,
+		DeleteNamespace: true,
 	}
+}
 
-	// Cleanup registration
-
-	// register uninstall rancher-gatekeeper as a cleanup function
-	client.Session.RegisterCleanupFunc(func() error {
-		// UninstallAction for when uninstalling the rancher-gatekeeper chart
-		defaultChartUninstallAction := NewChartUninstallAction()
-
-		err := catalogClient.UninstallChart(RancherGatekeeperName, RancherGatekeeperNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err := catalogClient.Apps(RancherGatekeeperNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherGatekeeperName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			chart := event.Object.(*catalogv1.App)
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher gatekeeper chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
-			} else if chart == nil {
-				return true, nil
-			}
-			return false, nil
-		})
-		if err != nil {
-			return err
-		}
-
-		err = catalogClient.UninstallChart(RancherGatekeeperCRDName, RancherGatekeeperNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err = catalogClient.Apps(RancherGatekeeperNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherGatekeeperCRDName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			chart := event.Object.(*catalogv1.App)
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher gatekeeper chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
-			} else if chart == nil {
-				return true, nil
-			}
-			return false, nil
-		})
-		if err != nil {
-			return err
-		}
-
-		steveclient, err := client.Steve.ProxyDownstream(installOptions.Cluster.ID)
-		if err != nil {
-			return err
-		}
-
-		namespaceClient := steveclient.SteveType(namespaces.NamespaceSteveType)
-
-		namespace, err := namespaceClient.ByID(RancherGatekeeperNamespace)
-		if err != nil {
-			return err
-		}
-
-		err = namespaceClient.Delete(namespace)
-		if err != nil {
-			return err
-		}
-
-		adminClient, err := rancher.NewClient(client.RancherConfig.AdminToken, client.Session)
-		if err != nil {
-			return err
-		}
-		adminDynamicClient, err := adminClient.GetDownStreamClusterClient(installOptions.Cluster.ID)
-		if err != nil {
-			return err
-		}
-		adminNamespaceResource := adminDynamicClient.Resource(namespaces.NamespaceGroupVersionResource).Namespace("")
-
-		watchNamespaceInterface, err := adminNamespaceResource.Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherGatekeeperNamespace,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		return wait.WatchWait(watchNamespaceInterface, func(event watch.Event) (ready bool, err error) {
-			if event.Type == watch.Deleted {
-				return true, nil
-			}
-			return false, nil
-		})
-	})
-
-	err = catalogClient.InstallChart(chartInstallAction, catalog.RancherChartRepo)
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be fully deployed
-	watchAppInterface, err := catalogClient.Apps(RancherGatekeeperNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherGatekeeperName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusDeployed) {
-			return true, nil
-		}
-		return false, nil
-	})
+// InstallRancherGatekeeperChart installs the OPA gatekeeper chart
+func InstallRancherGatekeeperChart(client *rancher.Client, installOptions *InstallOptions) error {
+	lifecycle, err := NewChartLifecycle(client, RancherGatekeeperChart(), installOptions)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// newGatekeeperChartInstallAction is a helper function that returns an array of NewChartInstallActions for installing the gatekeeper and gatekeepr-crd charts
-func newGatekeeperChartInstallAction(p *PayloadOpts) *types.ChartInstallAction {
-	chartInstall := NewChartInstall(p.Name, p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, nil)
-	chartInstallCRD := NewChartInstall(p.Name+"-crd", p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, nil)
-
-	chartInstalls := []types.ChartInstall{*chartInstallCRD, *chartInstall}
-
-	chartInstallAction := NewChartInstallAction(p.Namespace, p.ProjectID, chartInstalls)
 
-	return chartInstallAction
+	return lifecycle.Install()
 }
 
 // UpgradeRanchergatekeeperChart is a helper function that upgrades the rancher-gatekeeper chart.
 func UpgradeRancherGatekeeperChart(client *rancher.Client, installOptions *InstallOptions) error {
-	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
+	lifecycle, err := NewChartLifecycle(client, RancherGatekeeperChart(), installOptions)
 	if err != nil {
 		return err
 	}
 
-	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
-	if err != nil {
-		return err
-	}
-
-	gatekeeperChartUpgradeActionPayload := &PayloadOpts{
-		InstallOptions:  *installOptions,
-		Name:            RancherGatekeeperName,
-		Namespace:       RancherGatekeeperNamespace,
-		Host:            serverSetting.Value,
-		DefaultRegistry: registrySetting.Value,
-	}
-
-	chartUpgradeAction := newGatekeeperChartUpgradeAction(gatekeeperChartUpgradeActionPayload)
-
-	catalogClient, err := client.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	err = catalogClient.UpgradeChart(chartUpgradeAction, catalog.RancherChartRepo)
-	if err != nil {
-		return err
-	}
-
-	adminClient, err := rancher.NewClient(client.RancherConfig.AdminToken, client.Session)
-	if err != nil {
-		return err
-	}
-	adminCatalogClient, err := adminClient.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be in status pending upgrade
-	watchAppInterface, err := adminCatalogClient.Apps(RancherGatekeeperNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherGatekeeperName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusPendingUpgrade) {
-			return true, nil
-		}
-		return false, nil
-	})
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be full deployed
-	watchAppInterface, err = adminCatalogClient.Apps(RancherGatekeeperNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherGatekeeperName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusDeployed) {
-			return true, nil
-		}
-		return false, nil
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// newGatekeeperChartUpgradeAction is a private helper function that returns chart upgrade action.
-func newGatekeeperChartUpgradeAction(p *PayloadOpts) *types.ChartUpgradeAction {
-	chartUpgrade := NewChartUpgrade(p.Name, p.Name, p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, p.DefaultRegistry, nil)
-	chartUpgradeCRD := NewChartUpgrade(p.Name+"-crd", p.Name+"-crd", p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, p.DefaultRegistry, nil)
-	chartUpgrades := []types.ChartUpgrade{*chartUpgradeCRD, *chartUpgrade}
-
-	chartUpgradeAction := NewChartUpgradeAction(p.Namespace, chartUpgrades)
-
-	return chartUpgradeAction
+	return lifecycle.Upgrade(installOptions.Version)
 }
@@ -1,18 +1,7 @@
 package charts
 
 import (
-	"context"
-	"fmt"
-
-	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/shepherd/clients/rancher"
-	"github.com/rancher/shepherd/clients/rancher/catalog"
-	"github.com/rancher/shepherd/extensions/defaults"
-	"github.com/rancher/shepherd/pkg/api/steve/catalog/types"
-	"github.com/rancher/shepherd/pkg/wait"
-	"github.com/rancher/tests/actions/namespaces"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
 )
 
 const (
@@ -24,184 +13,38 @@ const (
 	RancherLoggingCRDName = "rancher-logging-crd"
 )
 
-// InstallRancherLoggingChart is a helper function that installs the rancher-logging chart.
-func InstallRancherLoggingChart(client *rancher.Client, installOptions *InstallOptions, rancherLoggingOpts *RancherLoggingOpts) error {
-	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
-	if err != nil {
-		return err
-	}
-
-	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
-	if err != nil {
-		return err
-	}
-
-	loggingChartInstallActionPayload := &PayloadOpts{
-		InstallOptions:  *installOptions,
+// RancherLoggingChart returns the descriptor of the rancher-logging chart installed with the logging options
+func RancherLoggingChart(rancherLoggingOpts *RancherLoggingOpts) ChartDescriptor {
+	return ChartDescriptor{
 		Name:            RancherLoggingName,
 		Namespace:       RancherLoggingNamespace,
-		Host:            serverSetting.Value,
-		DefaultRegistry: registrySetting.Value,
-	}
-
-	chartInstallAction := newLoggingChartInstallAction(loggingChartInstallActionPayload, rancherLoggingOpts)
-
-	catalogClient, err := client.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	// Cleanup registration
-	client.Session.RegisterCleanupFunc(func() error {
-		// UninstallAction for when uninstalling the rancher-logging chart
-		defaultChartUninstallAction := NewChartUninstallAction()
-
-		err = catalogClient.UninstallChart(RancherLoggingName, RancherLoggingNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err := catalogClient.Apps(RancherLoggingNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherLoggingName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher logging chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
-			}
-			return false, nil
-		})
-		if err != nil {
-			return err
-		}
-
-		err = catalogClient.UninstallChart(RancherLoggingCRDName, RancherLoggingNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err = catalogClient.Apps(RancherLoggingNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherLoggingCRDName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			chart := event.Object.(*catalogv1.App)
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher logging chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
-			} else if chart == nil {
-				return true, nil
-			}
-			return false, nil
-		})
-		if err != nil {
-			return err
-		}
-
-		steveclient, err := client.Steve.ProxyDownstream(installOptions.Cluster.ID)
-		if err != nil {
-			return err
-		}
-
-		namespaceClient := steveclient.SteveType(namespaces.NamespaceSteveType)
-
-		namespace, err := namespaceClient.ByID(RancherLoggingNamespace)
-		if err != nil {
-			return err
-		}
-
-		err = namespaceClient.Delete(namespace)
-		if err != nil {
-			return err
-		}
-
-		adminClient, err := rancher.NewClient(client.RancherConfig.AdminToken, client.Session)
-		if err != nil {
-			return err
-		}
-		adminDynamicClient, err := adminClient.GetDownStreamClusterClient(installOptions.Cluster.ID)
-		if err != nil {
-			return err
-		}
-		adminNamespaceResource := adminDynamicClient.Resource(namespaces.NamespaceGroupVersionResource).Namespace("")
-
-		watchNamespaceInterface, err := adminNamespaceResource.Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherLoggingNamespace,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		return wait.WatchWait(watchNamespaceInterface, func(event watch.Event) (ready bool, err error) {
-			if event.Type == watch.Deleted {
-				return true, nil
-			}
-			return false, nil
-		})
-	})
-
-	err = catalogClient.InstallChart(chartInstallAction, catalog.RancherChartRepo)
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be full deployed
-	watchAppInterface, err := catalogClient.Apps(RancherLoggingNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherLoggingName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
+		CRDChart:        RancherLoggingCRDName,
+		DeleteNamespace: true,
+		Values: func(p *PayloadOpts) (map[string]any, error) {
+			return map[string]any{
+				// the chart reads the node log sources of each provider from additionalLoggingSources.<provider>
+				"additionalLoggingSources": map[string]any{
+					string(p.Cluster.Provider): map[string]any{
+						"enabled": rancherLoggingOpts.AdditionalLoggingSources,
+					},
+				},
+				"logging": map[string]any{
+					"enabled": rancherLoggingOpts.LoggingEnabledSources,
+				},
+				"testReceiver": map[string]any{
+					"enabled": rancherLoggingOpts.LoggingEnabledSources,
+				},
+			}, nil
+		},
 	}
+}
 
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusDeployed) {
-			return true, nil
-		}
-		return false, nil
-	})
+// InstallRancherLoggingChart is a helper function that installs the rancher-logging chart.
+func InstallRancherLoggingChart(client *rancher.Client, installOptions *InstallOptions, rancherLoggingOpts *RancherLoggingOpts) error {
+	lifecycle, err := NewChartLifecycle(client, RancherLoggingChart(rancherLoggingOpts), installOptions)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// newLoggingChartInstallAction is a private helper function that returns chart install action with logging and payload options.
-func newLoggingChartInstallAction(p *PayloadOpts, rancherLoggingOpts *RancherLoggingOpts) *types.ChartInstallAction {
-	loggingValues := map[string]any{
-		string(p.Cluster.Provider): map[string]any{
-			"additionalLoggingSources": map[string]any{
-				"enabled": rancherLoggingOpts.AdditionalLoggingSources,
-			},
-		},
-		"logging": map[string]any{
-			"enabled": rancherLoggingOpts.LoggingEnabledSources,
-		},
-		"testReceiver": map[string]any{
-			"enabled": rancherLoggingOpts.LoggingEnabledSources,
-		},
-	}
-
-	chartInstall := NewChartInstall(p.Name, p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, loggingValues)
-	chartInstallCRD := NewChartInstall(p.Name+"-crd", p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, nil)
-	chartInstalls := []types.ChartInstall{*chartInstallCRD, *chartInstall}
-
-	chartInstallAction := NewChartInstallAction(p.Namespace, p.ProjectID, chartInstalls)
 
-	return chartInstallAction
+	return lifecycle.Install()
 }
@@ -1,22 +1,14 @@
 package charts
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
-	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/shepherd/clients/rancher"
-	"github.com/rancher/shepherd/clients/rancher/catalog"
 	"github.com/rancher/shepherd/extensions/clusters"
-	"github.com/rancher/shepherd/extensions/defaults"
 	"github.com/rancher/shepherd/extensions/kubectl"
-	"github.com/rancher/shepherd/pkg/api/steve/catalog/types"
-	"github.com/rancher/shepherd/pkg/wait"
 	"github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
 )
 
 const (
@@ -30,271 +22,55 @@ const (
 	RancherMonitoringCRDName = "rancher-monitoring-crd"
 )
 
-// InstallRancherMonitoringChart is a helper function that installs the rancher-monitoring chart.
-func InstallRancherMonitoringChart(client *rancher.Client, installOptions *InstallOptions, rancherMonitoringOpts *RancherMonitoringOpts) error {
-	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
-	if err != nil {
-		return err
-	}
-
-	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
-	if err != nil {
-		return err
-	}
-
-	monitoringChartInstallActionPayload := &PayloadOpts{
-		InstallOptions:  *installOptions,
-		Name:            RancherMonitoringName,
-		Namespace:       RancherMonitoringNamespace,
-		Host:            serverSetting.Value,
-		DefaultRegistry: registrySetting.Value,
-	}
-
-	chartInstallAction, err := newMonitoringChartInstallAction(monitoringChartInstallActionPayload, rancherMonitoringOpts)
-	if err != nil {
-		return err
-	}
-
-	catalogClient, err := client.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	// Cleanup registration
-	client.Session.RegisterCleanupFunc(func() error {
-		// UninstallAction for when uninstalling the rancher-monitoring chart
-		defaultChartUninstallAction := NewChartUninstallAction()
-
-		err = catalogClient.UninstallChart(RancherMonitoringName, RancherMonitoringNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err := catalogClient.Apps(RancherMonitoringNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherMonitoringName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher monitoring chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
+// RancherMonitoringChart returns the descriptor of the rancher-monitoring chart installed with the monitoring options
+func RancherMonitoringChart(rancherMonitoringOpts *RancherMonitoringOpts) ChartDescriptor {
+	return ChartDescriptor{
+		Name:      RancherMonitoringName,
+		Namespace: RancherMonitoringNamespace,
+		CRDChart:  RancherMonitoringCRDName,
+		Values: func(p *PayloadOpts) (map[string]any, error) {
+			monitoringValues := map[string]any{
+				"prometheus": map[string]any{
+					"prometheusSpec": map[string]any{
+						"evaluationInterval": "1m",
+						"retentionSize":      "50GiB",
+						"scrapeInterval":     "1m",
+					},
+				},
 			}
-			return false, nil
-		})
-		if err != nil {
-			return err
-		}
 
-		err = catalogClient.UninstallChart(RancherMonitoringCRDName, RancherMonitoringNamespace, defaultChartUninstallAction)
-		if err != nil {
-			return err
-		}
-
-		watchAppInterface, err = catalogClient.Apps(RancherMonitoringNamespace).Watch(context.TODO(), metav1.ListOptions{
-			FieldSelector:  "metadata.name=" + RancherMonitoringCRDName,
-			TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-		})
-		if err != nil {
-			return err
-		}
-
-		err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-			chart := event.Object.(*catalogv1.App)
-			if event.Type == watch.Error {
-				return false, fmt.Errorf("there was an error uninstalling rancher monitoring chart")
-			} else if event.Type == watch.Deleted {
-				return true, nil
-			} else if chart == nil {
-				return true, nil
+			opts, err := addMonitoringProviderPrefix(p.Cluster.Provider, rancherMonitoringOpts)
+			if err != nil {
+				return nil, err
 			}
-			return false, nil
-		})
-		return err
-	})
-
-	err = catalogClient.InstallChart(chartInstallAction, catalog.RancherChartRepo)
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be full deployed
-	watchAppInterface, err := catalogClient.Apps(RancherMonitoringNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherMonitoringName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
 
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusDeployed) {
-			return true, nil
-		}
-		return false, nil
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
+			for k, v := range opts {
+				monitoringValues[k] = v
+			}
 
-// newMonitoringChartInstallAction is a private helper function that returns chart install action with monitoring and payload options.
-func newMonitoringChartInstallAction(p *PayloadOpts, rancherMonitoringOpts *RancherMonitoringOpts) (*types.ChartInstallAction, error) {
-	monitoringValues := map[string]interface{}{
-		"prometheus": map[string]interface{}{
-			"prometheusSpec": map[string]interface{}{
-				"evaluationInterval": "1m",
-				"retentionSize":      "50GiB",
-				"scrapeInterval":     "1m",
-			},
+			return monitoringValues, nil
 		},
 	}
+}
 
-	opts, err := addMonitoringProviderPrefix(p.Cluster.Provider, rancherMonitoringOpts)
+// InstallRancherMonitoringChart is a helper function that installs the rancher-monitoring chart.
+func InstallRancherMonitoringChart(client *rancher.Client, installOptions *InstallOptions, rancherMonitoringOpts *RancherMonitoringOpts) error {
+	lifecycle, err := NewChartLifecycle(client, RancherMonitoringChart(rancherMonitoringOpts), installOptions)
 	if err != nil {
-		return nil, err
-	}
-
-	for k, v := range opts {
-		monitoringValues[k] = v
+		return err
 	}
 
-	chartInstall := NewChartInstall(p.Name, p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, monitoringValues)
-	chartInstallCRD := NewChartInstall(p.Name+"-crd", p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, rancherChartsName, p.ProjectID, p.DefaultRegistry, nil)
-	chartInstalls := []types.ChartInstall{*chartInstallCRD, *chartInstall}
-
-	chartInstallAction := NewChartInstallAction(p.Namespace, p.ProjectID, chartInstalls)
-
-	return chartInstallAction, nil
+	return lifecycle.Install()
 }
 
 // UpgradeMonitoringChart is a helper function that upgrades the rancher-monitoring chart.
 func UpgradeRancherMonitoringChart(client *rancher.Client, installOptions *InstallOptions, rancherMonitoringOpts *RancherMonitoringOpts) error {
-	serverSetting, err := client.Management.Setting.ByID(serverURLSettingID)
+	lifecycle, err := NewChartLifecycle(client, RancherMonitoringChart(rancherMonitoringOpts), installOptions)
 	if err != nil {
 		return err
 	}
 
-	registrySetting, err := client.Management.Setting.ByID(defaultRegistrySettingID)
-	if err != nil {
-		return err
-	}
-
-	monitoringChartUpgradeActionPayload := &PayloadOpts{
-		InstallOptions:  *installOptions,
-		Name:            RancherMonitoringName,
-		Namespace:       RancherMonitoringNamespace,
-		Host:            serverSetting.Value,
-		DefaultRegistry: registrySetting.Value,
-	}
-
-	chartUpgradeAction, err := newMonitoringChartUpgradeAction(monitoringChartUpgradeActionPayload, rancherMonitoringOpts)
-	if err != nil {
-		return err
-	}
-
-	catalogClient, err := client.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	err = catalogClient.UpgradeChart(chartUpgradeAction, catalog.RancherChartRepo)
-	if err != nil {
-		return err
-	}
-
-	adminClient, err := rancher.NewClient(client.RancherConfig.AdminToken, client.Session)
-	if err != nil {
-		return err
-	}
-	adminCatalogClient, err := adminClient.GetClusterCatalogClient(installOptions.Cluster.ID)
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be in status pending upgrade
-	watchAppInterface, err := adminCatalogClient.Apps(RancherMonitoringNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherMonitoringName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusPendingUpgrade) {
-			return true, nil
-		}
-		return false, nil
-	})
-	if err != nil {
-		return err
-	}
-
-	// wait for chart to be full deployed
-	watchAppInterface, err = adminCatalogClient.Apps(RancherMonitoringNamespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + RancherMonitoringName,
-		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
-	})
-	if err != nil {
-		return err
-	}
-
-	err = wait.WatchWait(watchAppInterface, func(event watch.Event) (ready bool, err error) {
-		app := event.Object.(*catalogv1.App)
-
-		state := app.Status.Summary.State
-		if state == string(catalogv1.StatusDeployed) {
-			return true, nil
-		}
-		return false, nil
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// newMonitoringChartUpgradeAction is a private helper function that returns chart upgrade action with monitoring and payload options.
-func newMonitoringChartUpgradeAction(p *PayloadOpts, rancherMonitoringOpts *RancherMonitoringOpts) (*types.ChartUpgradeAction, error) {
-	monitoringValues := map[string]interface{}{
-		"prometheus": map[string]interface{}{
-			"prometheusSpec": map[string]interface{}{
-				"evaluationInterval": "1m",
-				"retentionSize":      "50GiB",
-				"scrapeInterval":     "1m",
-			},
-		},
-	}
-
-	opts, err := addMonitoringProviderPrefix(p.Cluster.Provider, rancherMonitoringOpts)
-	if err != nil {
-		return nil, err
-	}
-
-	for k, v := range opts {
-		monitoringValues[k] = v
-	}
-
-	chartUpgrade := NewChartUpgrade(p.Name, p.Name, p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, p.DefaultRegistry, monitoringValues)
-	chartUpgradeCRD := NewChartUpgrade(p.Name+"-crd", p.Name+"-crd", p.Version, p.Cluster.ID, p.Cluster.Name, p.Host, p.DefaultRegistry, monitoringValues)
-	chartUpgrades := []types.ChartUpgrade{*chartUpgradeCRD, *chartUpgrade}
-
-	chartUpgradeAction := NewChartUpgradeAction(p.Namespace, chartUpgrades)
-
-	return chartUpgradeAction, nil
+	return lifecycle.Upgrade(installOptions.Version)
 }
 
 // addProvider prefix is a private helper function that adds kubernetes provider to the monitoring opts payload keys. ex) maps "scheduler" to "rke2Scheduler"
@@ -0,0 +1,365 @@
+package charts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rancher/shepherd/clients/rancher/catalog"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	clusterReposURL  = "v1/catalog.cattle.io.clusterrepos/"
+	valuesSchemaFile = "values.schema.json"
+	hardenedKeyword  = "hardened"
+)
+
+// providerPrefixes are the prefixes of the provider specific values of the charts, like rke2Etcd in rancher-monitoring
+var providerPrefixes = []string{"rke2", "rke", "k3s", "kubeAdm", "aks", "eks", "gke"}
+
+// ValueOption is a value of a chart that can take a bounded set of values, like a boolean toggle or an enum
+type ValueOption struct {
+	// Path of the value, dotted like the variables of questions.yaml
+	Path    string
+	Default any
+	Values  []any
+}
+
+// ValuesCase is a combination of chart values to install the chart with
+type ValuesCase struct {
+	Name   string
+	Values map[string]any
+}
+
+// chartQuestion is a question of the questions.yaml file of a chart
+type chartQuestion struct {
+	Variable     string          `yaml:"variable"`
+	Type         string          `yaml:"type"`
+	Default      any             `yaml:"default"`
+	Options      []any           `yaml:"options"`
+	Subquestions []chartQuestion `yaml:"subquestions"`
+}
+
+type chartQuestions struct {
+	Questions []chartQuestion `yaml:"questions"`
+}
+
+// GetChartValueOptions returns the boolean and enum values of a chart version, read from the values.schema.json and
+// questions.yaml files of the chart in the catalog. Options are sorted by path.
+func GetChartValueOptions(catalogClient *catalog.Client, repoName, chartName, chartVersion string) ([]ValueOption, error) {
+	tarball, err := catalogClient.RESTClient().Get().
+		AbsPath(clusterReposURL+repoName).Param("link", "chart").Param("chartName", chartName).Param("version", chartVersion).
+		Do(context.TODO()).Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := chartFiles(tarball, valuesSchemaFile, "questions.yaml", "questions.yml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart %s version %s: %w", chartName, chartVersion, err)
+	}
+
+	options := map[string]ValueOption{}
+
+	if schema, ok := files[valuesSchemaFile]; ok {
+		var schemaMap map[string]any
+		err = json.Unmarshal(schema, &schemaMap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of chart %s: %w", valuesSchemaFile, chartName, err)
+		}
+
+		schemaOptions("", schemaMap, options)
+	}
+
+	for _, name := range []string{"questions.yaml", "questions.yml"} {
+		questionsFile, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		var questions chartQuestions
+		err = yaml.Unmarshal(questionsFile, &questions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of chart %s: %w", name, chartName, err)
+		}
+
+		questionOptions(questions.Questions, options)
+	}
+
+	sorted := make([]ValueOption, 0, len(options))
+	for _, path := range slices.Sorted(maps.Keys(options)) {
+		sorted = append(sorted, options[path])
+	}
+
+	return sorted, nil
+}
+
+// chartFiles returns the content of the files at the root of a chart tarball
+func chartFiles(tarball []byte, names ...string) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.SplitN(header.Name, "/", 2)
+		if len(parts) != 2 || !slices.Contains(names, strings.ToLower(parts[1])) {
+			continue
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.ToLower(parts[1])] = content
+	}
+}
+
+// schemaOptions adds the boolean and enum properties of a JSON schema to the options
+func schemaOptions(path string, schema map[string]any, options map[string]ValueOption) {
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			propertySchema, ok := property.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			schemaOptions(joinValuePath(path, name), propertySchema, options)
+		}
+
+		return
+	}
+
+	if path == "" {
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 1 {
+		options[path] = ValueOption{Path: path, Default: schema["default"], Values: enum}
+		return
+	}
+
+	if schemaHasType(schema, "boolean") {
+		defaultValue, _ := schema["default"].(bool)
+		options[path] = ValueOption{Path: path, Default: defaultValue, Values: []any{true, false}}
+	}
+}
+
+func schemaHasType(schema map[string]any, schemaType string) bool {
+	switch types := schema["type"].(type) {
+	case string:
+		return types == schemaType
+	case []any:
+		return slices.Contains(types, any(schemaType))
+	}
+
+	return false
+}
+
+// questionOptions adds the boolean and enum questions, and their subquestions, to the options. Questions take
+// precedence over the schema, as they are what users are offered.
+func questionOptions(questions []chartQuestion, options map[string]ValueOption) {
+	for _, question := range questions {
+		switch {
+		case question.Variable == "":
+		case question.Type == "boolean":
+			defaultValue, _ := strconv.ParseBool(fmt.Sprint(question.Default))
+			options[question.Variable] = ValueOption{Path: question.Variable, Default: defaultValue, Values: []any{true, false}}
+		case question.Type == "enum" && len(question.Options) > 1:
+			options[question.Variable] = ValueOption{Path: question.Variable, Default: question.Default, Values: question.Options}
+		}
+
+		questionOptions(question.Subquestions, options)
+	}
+}
+
+// ProviderValueOptions drops the options specific to providers other than the one of the cluster, like the rke2
+// values of rancher-monitoring on a K3s cluster
+func ProviderValueOptions(options []ValueOption, provider clusters.KubernetesProvider) []ValueOption {
+	var filtered []ValueOption
+	for _, option := range options {
+		optionProvider, ok := valueProvider(option.Path)
+		if ok && optionProvider != string(provider) {
+			continue
+		}
+
+		filtered = append(filtered, option)
+	}
+
+	return filtered
+}
+
+// valueProvider returns the provider a value is specific to, based on the prefix of its top level key
+func valueProvider(path string) (string, bool) {
+	key, _, _ := strings.Cut(path, ".")
+	for _, prefix := range providerPrefixes {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+
+		if rest == "" || unicode.IsUpper(rune(rest[0])) {
+			return strings.ToLower(prefix), true
+		}
+	}
+
+	return "", false
+}
+
+// ValuesMatrix returns a bounded set of value cases, each changing a single value of an option from its default, so a
+// failing case points at the value that broke the chart. It starts with the chart defaults and a hardened case when
+// the chart has hardening toggles. The non-default values are ordered with the first non-default value of every option
+// before the other values of the enums. When they don't fit in maxCases, the cases are a window of that order, rotated
+// by the seed and wrapping around, so consecutive seeds test consecutive windows and every value is eventually tested.
+func ValuesMatrix(options []ValueOption, maxCases int, seed int64) []ValuesCase {
+	matrix := []ValuesCase{{Name: "defaults", Values: map[string]any{}}}
+
+	hardened := map[string]any{}
+	for _, option := range options {
+		if strings.Contains(strings.ToLower(option.Path), hardenedKeyword) && slices.Contains(option.Values, any(true)) {
+			setValuePath(hardened, option.Path, true)
+		}
+	}
+	if len(hardened) > 0 {
+		matrix = append(matrix, ValuesCase{Name: hardenedKeyword, Values: hardened})
+	}
+
+	type assignment struct {
+		path  string
+		value any
+	}
+
+	// the non-default values of the options, in rounds of one value per option
+	var rounds [][]assignment
+	for _, option := range options {
+		round := 0
+		for _, value := range option.Values {
+			if reflect.DeepEqual(value, option.Default) {
+				continue
+			}
+
+			if round == len(rounds) {
+				rounds = append(rounds, nil)
+			}
+			rounds[round] = append(rounds[round], assignment{option.Path, value})
+			round++
+		}
+	}
+
+	assignments := slices.Concat(rounds...)
+	window := min(maxCases-len(matrix), len(assignments))
+	if window <= 0 {
+		return matrix
+	}
+
+	offset := 0
+	if window < len(assignments) {
+		count := int64(len(assignments))
+		offset = int((seed % count) * int64(window) % count)
+		if offset < 0 {
+			offset += len(assignments)
+		}
+	}
+
+	for i := range window {
+		a := assignments[(offset+i)%len(assignments)]
+
+		values := map[string]any{}
+		setValuePath(values, a.path, a.value)
+		matrix = append(matrix, ValuesCase{Name: fmt.Sprintf("%s=%v", a.path, a.value), Values: values})
+	}
+
+	return matrix
+}
+
+// WithValues returns the descriptor with the values merged over the values it builds
+func WithValues(descriptor ChartDescriptor, values map[string]any) ChartDescriptor {
+	build := descriptor.Values
+	descriptor.Values = func(p *PayloadOpts) (map[string]any, error) {
+		merged := map[string]any{}
+		if build != nil {
+			base, err := build(p)
+			if err != nil {
+				return nil, err
+			}
+
+			mergeValues(merged, base)
+		}
+
+		mergeValues(merged, values)
+
+		return merged, nil
+	}
+
+	return descriptor
+}
+
+// mergeValues deep merges the source values into the destination values
+func mergeValues(destination, source map[string]any) {
+	for key, value := range source {
+		sourceMap, sourceIsMap := value.(map[string]any)
+		destinationMap, destinationIsMap := destination[key].(map[string]any)
+		if sourceIsMap && destinationIsMap {
+			merged := map[string]any{}
+			mergeValues(merged, destinationMap)
+			mergeValues(merged, sourceMap)
+			destination[key] = merged
+			continue
+		}
+
+		if sourceIsMap {
+			copied := map[string]any{}
+			mergeValues(copied, sourceMap)
+			value = copied
+		}
+
+		destination[key] = value
+	}
+}
+
+// setValuePath sets the value at the dotted path, creating the intermediate maps
+func setValuePath(values map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := values[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			values[key] = next
+		}
+		values = next
+	}
+
+	values[keys[len(keys)-1]] = value
+}
+
+func joinValuePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,107 @@
+package hosted
+
+import (
+	"errors"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/cloudcredentials"
+	"github.com/rancher/shepherd/extensions/cloudcredentials/azure"
+	"github.com/rancher/shepherd/extensions/clusters/aks"
+	"github.com/rancher/tests/actions/provisioning"
+)
+
+const (
+	azureCredentialProvider = "azure"
+)
+
+// aksAdapter reads and writes the AKS config of a hosted cluster
+type aksAdapter struct {
+	clusterConfig aks.ClusterConfig
+}
+
+func newAKSProvider(client *rancher.Client, clusterConfig aks.ClusterConfig) HostedProvider {
+	return &rancherProvider{
+		client:       client,
+		providerType: AKS,
+		adapter:      &aksAdapter{clusterConfig: clusterConfig},
+	}
+}
+
+func (a *aksAdapter) getConfig(cluster *management.Cluster, upstream bool) ([]NodePool, string, error) {
+	spec := cluster.AKSConfig
+	if upstream {
+		if cluster.AKSStatus == nil {
+			return nil, "", errors.New("cluster " + cluster.Name + " has no AKS status")
+		}
+
+		spec = cluster.AKSStatus.UpstreamSpec
+	}
+
+	if spec == nil || spec.NodePools == nil {
+		return nil, "", errors.New("cluster " + cluster.Name + " has no AKS node pools")
+	}
+
+	var nodePools []NodePool
+	for _, aksNodePool := range *spec.NodePools {
+		nodePools = append(nodePools, NodePool{
+			Name:              stringValue(aksNodePool.Name),
+			Count:             int64Value(aksNodePool.Count),
+			KubernetesVersion: stringValue(aksNodePool.OrchestratorVersion),
+		})
+	}
+
+	return nodePools, stringValue(spec.KubernetesVersion), nil
+}
+
+func (a *aksAdapter) setConfig(cluster *management.Cluster, nodePools []NodePool, version string) error {
+	if cluster.AKSConfig == nil || cluster.AKSConfig.NodePools == nil || len(*cluster.AKSConfig.NodePools) == 0 {
+		return errors.New("cluster " + cluster.Name + " has no AKS node pools")
+	}
+
+	existingNodePools := *cluster.AKSConfig.NodePools
+	var aksNodePools []management.AKSNodePool
+	for _, nodePool := range nodePools {
+		// new node pools copy the settings of the first node pool
+		aksNodePool := existingNodePools[0]
+		for _, existingNodePool := range existingNodePools {
+			if stringValue(existingNodePool.Name) == nodePool.Name {
+				aksNodePool = existingNodePool
+			}
+		}
+
+		aksNodePool.Name = &nodePool.Name
+		aksNodePool.Count = &nodePool.Count
+		aksNodePool.OrchestratorVersion = stringPointer(nodePool.KubernetesVersion)
+		aksNodePools = append(aksNodePools, aksNodePool)
+	}
+
+	cluster.AKSConfig.NodePools = &aksNodePools
+	cluster.AKSConfig.KubernetesVersion = stringPointer(version)
+
+	return nil
+}
+
+func (a *aksAdapter) provision(client *rancher.Client) (*management.Cluster, error) {
+	return provisioning.CreateProvisioningAKSHostedCluster(client, a.clusterConfig)
+}
+
+func (a *aksAdapter) importCluster(client *rancher.Client, displayName, clusterName string) (*management.Cluster, error) {
+	cloudCredentialConfig := cloudcredentials.LoadCloudCredential(azureCredentialProvider)
+	cloudCredential, err := azure.CreateAzureCloudCredentials(client, cloudCredentialConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &management.Cluster{
+		AKSConfig: &management.AKSClusterConfigSpec{
+			AzureCredentialSecret: cloudCredential.Namespace + ":" + cloudCredential.Name,
+			ClusterName:           clusterName,
+			Imported:              true,
+			ResourceGroup:         a.clusterConfig.ResourceGroup,
+			ResourceLocation:      a.clusterConfig.ResourceLocation,
+		},
+		DockerRootDir: dockerRootDir,
+		Name:          displayName,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package hosted
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/extensions/clusters/aks"
+	"github.com/rancher/shepherd/extensions/clusters/eks"
+	"github.com/rancher/shepherd/extensions/clusters/gke"
+)
+
+// CloudState is the state of a hosted cluster as reported by the cloud provider itself
+type CloudState struct {
+	KubernetesVersion string
+	NodePools         []NodePool
+}
+
+// CloudClient reads and changes hosted clusters directly on the cloud provider, outside of Rancher
+type CloudClient interface {
+	// GetClusterState returns the state of the cluster with the given cloud provider name
+	GetClusterState(clusterName string) (*CloudState, error)
+	// ScaleNodePool sets the node count of a node pool of the cluster with the given cloud provider name
+	ScaleNodePool(clusterName, nodePoolName string, count int64) error
+}
+
+// UpstreamReader reads the upstream spec of a hosted cluster, which is the state of the cluster on the cloud
+// provider as last synced by Rancher
+type UpstreamReader interface {
+	// GetUpstreamSpec returns the node pools and the control plane version of the upstream spec of the cluster
+	GetUpstreamSpec(clusterID string) ([]NodePool, string, error)
+}
+
+// NewCLICloudClient returns a cloud client of the given provider type running the az, aws or gcloud CLI, which must
+// be installed and logged in. The config must be an aks.ClusterConfig, eks.ClusterConfig or gke.ClusterConfig, and
+// gives the resource group, region, zone or project of the clusters.
+func NewCLICloudClient(providerType string, clusterConfig any) (CloudClient, error) {
+	switch providerType {
+	case AKS:
+		aksClusterConfig, ok := clusterConfig.(aks.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected an AKS cluster config, got %T", clusterConfig)
+		}
+
+		return &aksCLI{resourceGroup: aksClusterConfig.ResourceGroup}, nil
+	case EKS:
+		eksClusterConfig, ok := clusterConfig.(eks.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected an EKS cluster config, got %T", clusterConfig)
+		}
+
+		return &eksCLI{region: eksClusterConfig.Region}, nil
+	case GKE:
+		gkeClusterConfig, ok := clusterConfig.(gke.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected a GKE cluster config, got %T", clusterConfig)
+		}
+
+		location := gkeClusterConfig.Zone
+		locationFlag := "--zone"
+		if location == "" {
+			location = gkeClusterConfig.Region
+			locationFlag = "--region"
+		}
+
+		return &gkeCLI{projectID: gkeClusterConfig.ProjectID, locationFlag: locationFlag, location: location}, nil
+	}
+
+	return nil, fmt.Errorf("unknown hosted provider %s", providerType)
+}
@@ -0,0 +1,211 @@
+package hosted
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	azCmd     = "az"
+	awsCmd    = "aws"
+	gcloudCmd = "gcloud"
+)
+
+// aksCLI is a CloudClient running the az CLI
+type aksCLI struct {
+	resourceGroup string
+}
+
+type aksShowOutput struct {
+	CurrentKubernetesVersion string `json:"currentKubernetesVersion"`
+	AgentPoolProfiles        []struct {
+		Name                       string `json:"name"`
+		Count                      int64  `json:"count"`
+		CurrentOrchestratorVersion string `json:"currentOrchestratorVersion"`
+	} `json:"agentPoolProfiles"`
+}
+
+func (a *aksCLI) GetClusterState(clusterName string) (*CloudState, error) {
+	output := &aksShowOutput{}
+	err := runCLI(output, azCmd, "aks", "show", "--resource-group", a.resourceGroup, "--name", clusterName, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CloudState{KubernetesVersion: output.CurrentKubernetesVersion}
+	for _, profile := range output.AgentPoolProfiles {
+		state.NodePools = append(state.NodePools, NodePool{
+			Name:              profile.Name,
+			Count:             profile.Count,
+			KubernetesVersion: profile.CurrentOrchestratorVersion,
+		})
+	}
+
+	return state, nil
+}
+
+func (a *aksCLI) ScaleNodePool(clusterName, nodePoolName string, count int64) error {
+	return runCLI(nil, azCmd, "aks", "nodepool", "scale", "--resource-group", a.resourceGroup, "--cluster-name", clusterName,
+		"--name", nodePoolName, "--node-count", strconv.FormatInt(count, 10), "--output", "json")
+}
+
+// eksCLI is a CloudClient running the aws CLI
+type eksCLI struct {
+	region string
+}
+
+type eksClusterOutput struct {
+	Cluster struct {
+		Version string `json:"version"`
+	} `json:"cluster"`
+}
+
+type eksNodeGroupsOutput struct {
+	NodeGroups []string `json:"nodegroups"`
+}
+
+type eksNodeGroupOutput struct {
+	NodeGroup struct {
+		NodegroupName string `json:"nodegroupName"`
+		Version       string `json:"version"`
+		ScalingConfig struct {
+			DesiredSize int64 `json:"desiredSize"`
+		} `json:"scalingConfig"`
+	} `json:"nodegroup"`
+}
+
+func (e *eksCLI) GetClusterState(clusterName string) (*CloudState, error) {
+	clusterOutput := &eksClusterOutput{}
+	err := runCLI(clusterOutput, awsCmd, "eks", "describe-cluster", "--region", e.region, "--name", clusterName, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeGroupsOutput := &eksNodeGroupsOutput{}
+	err = runCLI(nodeGroupsOutput, awsCmd, "eks", "list-nodegroups", "--region", e.region, "--cluster-name", clusterName, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CloudState{KubernetesVersion: clusterOutput.Cluster.Version}
+	for _, nodeGroupName := range nodeGroupsOutput.NodeGroups {
+		nodeGroupOutput := &eksNodeGroupOutput{}
+		err = runCLI(nodeGroupOutput, awsCmd, "eks", "describe-nodegroup", "--region", e.region, "--cluster-name", clusterName,
+			"--nodegroup-name", nodeGroupName, "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+
+		state.NodePools = append(state.NodePools, NodePool{
+			Name:              nodeGroupOutput.NodeGroup.NodegroupName,
+			Count:             nodeGroupOutput.NodeGroup.ScalingConfig.DesiredSize,
+			KubernetesVersion: nodeGroupOutput.NodeGroup.Version,
+		})
+	}
+
+	return state, nil
+}
+
+// ScaleNodePool sets the desired size of the node group, which must be within its min and max sizes
+func (e *eksCLI) ScaleNodePool(clusterName, nodePoolName string, count int64) error {
+	return runCLI(nil, awsCmd, "eks", "update-nodegroup-config", "--region", e.region, "--cluster-name", clusterName,
+		"--nodegroup-name", nodePoolName, "--scaling-config", "desiredSize="+strconv.FormatInt(count, 10), "--output", "json")
+}
+
+// gkeCLI is a CloudClient running the gcloud CLI
+type gkeCLI struct {
+	projectID    string
+	locationFlag string
+	location     string
+}
+
+type gkeClusterOutput struct {
+	CurrentMasterVersion string `json:"currentMasterVersion"`
+	NodePools            []struct {
+		Name              string   `json:"name"`
+		InstanceGroupURLs []string `json:"instanceGroupUrls"`
+		Version           string   `json:"version"`
+	} `json:"nodePools"`
+}
+
+type gkeInstanceGroupOutput struct {
+	TargetSize int64 `json:"targetSize"`
+}
+
+// GetClusterState returns the state of the cluster. The node count of a node pool is the live target size of its
+// instance groups, which is per zone like the node count given to a resize.
+func (g *gkeCLI) GetClusterState(clusterName string) (*CloudState, error) {
+	output := &gkeClusterOutput{}
+	err := runCLI(output, gcloudCmd, "container", "clusters", "describe", clusterName, "--project", g.projectID, g.locationFlag, g.location, "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CloudState{KubernetesVersion: output.CurrentMasterVersion}
+	for _, nodePool := range output.NodePools {
+		count, err := g.getNodePoolSize(nodePool.Name, nodePool.InstanceGroupURLs)
+		if err != nil {
+			return nil, err
+		}
+
+		state.NodePools = append(state.NodePools, NodePool{
+			Name:              nodePool.Name,
+			Count:             count,
+			KubernetesVersion: nodePool.Version,
+		})
+	}
+
+	return state, nil
+}
+
+// getNodePoolSize returns the target size of the instance groups of a node pool, which must be the same in every zone
+func (g *gkeCLI) getNodePoolSize(nodePoolName string, instanceGroupURLs []string) (int64, error) {
+	if len(instanceGroupURLs) == 0 {
+		return 0, fmt.Errorf("node pool %s has no instance group", nodePoolName)
+	}
+
+	var count int64
+	for i, instanceGroupURL := range instanceGroupURLs {
+		output := &gkeInstanceGroupOutput{}
+		err := runCLI(output, gcloudCmd, "compute", "instance-groups", "managed", "describe", instanceGroupURL, "--project", g.projectID, "--format", "json")
+		if err != nil {
+			return 0, err
+		}
+
+		if i > 0 && output.TargetSize != count {
+			return 0, fmt.Errorf("instance groups of node pool %s have different sizes: %d and %d", nodePoolName, count, output.TargetSize)
+		}
+
+		count = output.TargetSize
+	}
+
+	return count, nil
+}
+
+func (g *gkeCLI) ScaleNodePool(clusterName, nodePoolName string, count int64) error {
+	return runCLI(nil, gcloudCmd, "container", "clusters", "resize", clusterName, "--project", g.projectID, g.locationFlag, g.location,
+		"--node-pool", nodePoolName, "--num-nodes", strconv.FormatInt(count, 10), "--quiet", "--format", "json")
+}
+
+// runCLI runs the command and decodes its JSON output into the output, if given
+func runCLI(output any, command string, args ...string) error {
+	logrus.Debugf("Running %s %v", command, args)
+	result, err := exec.Command(command, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("%s failed: %w: %s", command, err, string(exitErr.Stderr))
+		}
+
+		return err
+	}
+
+	if output == nil {
+		return nil
+	}
+
+	return json.Unmarshal(result, output)
+}
@@ -0,0 +1,12 @@
+package hosted
+
+const (
+	ConfigurationFileKey = "hostedInput"
+)
+
+// Config selects the hosted provider to test and the optional lifecycle operations to run
+type Config struct {
+	Provider          string `json:"provider" yaml:"provider"`
+	UpgradeVersion    string `json:"upgradeVersion,omitempty" yaml:"upgradeVersion,omitempty"`
+	ImportClusterName string `json:"importClusterName,omitempty" yaml:"importClusterName,omitempty"`
+}
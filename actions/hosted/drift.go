@@ -0,0 +1,135 @@
+package hosted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/sirupsen/logrus"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// VerifyUpstreamSpec verifies the upstream spec of the cluster matches the state of the cluster on the cloud provider,
+// reporting any difference in node pools, node counts or versions. Versions are compared on the numeric parts both
+// sides report, as cloud providers add suffixes such as -eks-1 or -gke.100 and EKS only reports the minor version.
+func VerifyUpstreamSpec(reader UpstreamReader, clusterID string, expected *CloudState) error {
+	nodePools, version, err := reader.GetUpstreamSpec(clusterID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if compareReportedVersions(version, expected.KubernetesVersion) != 0 {
+		errs = append(errs, fmt.Errorf("upstream spec is on version %s, cloud provider reports %s", version, expected.KubernetesVersion))
+	}
+
+	var expectedNodePools []NodePool
+	for _, nodePool := range expected.NodePools {
+		// node pool versions are compared on their reported parts below
+		expectedNodePools = append(expectedNodePools, NodePool{Name: nodePool.Name, Count: nodePool.Count})
+	}
+
+	err = compareNodePools(expectedNodePools, nodePools)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, expectedNodePool := range expected.NodePools {
+		index, err := findNodePool(nodePools, expectedNodePool.Name)
+		if err != nil {
+			continue
+		}
+
+		if expectedNodePool.KubernetesVersion != "" && compareReportedVersions(nodePools[index].KubernetesVersion, expectedNodePool.KubernetesVersion) != 0 {
+			errs = append(errs, fmt.Errorf("node pool %s is on version %s in the upstream spec, cloud provider reports %s", expectedNodePool.Name, nodePools[index].KubernetesVersion, expectedNodePool.KubernetesVersion))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForUpstreamSpec waits for Rancher to sync the state of the cluster on the cloud provider into its upstream spec
+func WaitForUpstreamSpec(reader UpstreamReader, cloud CloudClient, clusterID, cloudClusterName string, timeout time.Duration) error {
+	return waitForUpstreamSpec(reader, cloud, clusterID, cloudClusterName, nil, timeout)
+}
+
+// ScaleNodePoolOutOfBand scales a node pool directly on the cloud provider, and waits for both the cloud provider and
+// the upstream spec of the cluster to report the requested node count
+func ScaleNodePoolOutOfBand(reader UpstreamReader, cloud CloudClient, clusterID, cloudClusterName, nodePoolName string, count int64, timeout time.Duration) error {
+	logrus.Infof("Scaling node pool %s of %s to %d nodes outside of Rancher", nodePoolName, cloudClusterName, count)
+	err := cloud.ScaleNodePool(cloudClusterName, nodePoolName, count)
+	if err != nil {
+		return err
+	}
+
+	return waitForUpstreamSpec(reader, cloud, clusterID, cloudClusterName, func(state *CloudState) error {
+		index, err := findNodePool(state.NodePools, nodePoolName)
+		if err != nil {
+			return err
+		}
+
+		if state.NodePools[index].Count != count {
+			return fmt.Errorf("cloud provider reports %d nodes in node pool %s, expected %d", state.NodePools[index].Count, nodePoolName, count)
+		}
+
+		return nil
+	}, timeout)
+}
+
+// waitForUpstreamSpec waits for the state of the cluster on the cloud provider to pass verifyState, if given, and
+// to be synced into the upstream spec of the cluster
+func waitForUpstreamSpec(reader UpstreamReader, cloud CloudClient, clusterID, cloudClusterName string, verifyState func(*CloudState) error, timeout time.Duration) error {
+	var lastErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.TenSecondTimeout, timeout, true, func(ctx context.Context) (done bool, err error) {
+		state, err := cloud.GetClusterState(cloudClusterName)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		if verifyState != nil {
+			lastErr = verifyState(state)
+			if lastErr != nil {
+				return false, nil
+			}
+		}
+
+		lastErr = VerifyUpstreamSpec(reader, clusterID, state)
+
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("upstream spec of cluster %s did not match the cloud provider: %w", clusterID, errors.Join(err, lastErr))
+	}
+
+	return nil
+}
+
+// compareReportedVersions compares two Kubernetes versions on the numeric parts both of them have: 1.33 matches
+// 1.33.2, but 1.33.1 doesn't. A missing version only matches another missing version.
+func compareReportedVersions(a, b string) int {
+	aParts := versionParts(a)
+	bParts := versionParts(b)
+
+	count := min(len(aParts), len(bParts))
+	if count == 0 {
+		return compareVersions(a, b)
+	}
+
+	return compareVersions(joinVersionParts(aParts[:count]), joinVersionParts(bParts[:count]))
+}
+
+func joinVersionParts(parts []int) string {
+	version := ""
+	for i, part := range parts {
+		if i > 0 {
+			version += "."
+		}
+
+		version += fmt.Sprint(part)
+	}
+
+	return version
+}
@@ -0,0 +1,130 @@
+package hosted
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUpstreamReader is an UpstreamReader returning a fixed upstream spec
+type stubUpstreamReader struct {
+	nodePools []NodePool
+	version   string
+}
+
+func (r *stubUpstreamReader) GetUpstreamSpec(clusterID string) ([]NodePool, string, error) {
+	return r.nodePools, r.version, nil
+}
+
+// stubCloudClient is a CloudClient returning a fixed cloud state, which scaling a node pool changes
+type stubCloudClient struct {
+	state CloudState
+}
+
+func (c *stubCloudClient) GetClusterState(clusterName string) (*CloudState, error) {
+	return &c.state, nil
+}
+
+func (c *stubCloudClient) ScaleNodePool(clusterName, nodePoolName string, count int64) error {
+	index, err := findNodePool(c.state.NodePools, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	c.state.NodePools[index].Count = count
+
+	return nil
+}
+
+func TestVerifyUpstreamSpec(t *testing.T) {
+	cloudState := &CloudState{
+		KubernetesVersion: "1.33.2-eks-1",
+		NodePools:         []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2-eks-1"}},
+	}
+
+	tests := []struct {
+		name        string
+		nodePools   []NodePool
+		version     string
+		expectedErr string
+	}{
+		{"In_Sync", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, "1.33.2", ""},
+		{"Minor_Version_Only", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33"}}, "1.33", ""},
+		{"Control_Plane_Patch_Drift", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, "1.33.1", "upstream spec is on version 1.33.1"},
+		{"Control_Plane_Minor_Drift", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, "1.32", "upstream spec is on version 1.32"},
+		{"Missing_Version", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, "", "upstream spec is on version "},
+		{"Node_Pool_Patch_Drift", []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.1"}}, "1.33.2", "node pool pool-a is on version 1.33.1 in the upstream spec"},
+		{"Node_Count_Drift", []NodePool{{Name: "pool-a", Count: 3, KubernetesVersion: "1.33.2"}}, "1.33.2", "node pool pool-a has 3 nodes, expected 2"},
+		{"Missing_Node_Pool", []NodePool{{Name: "pool-b", Count: 2, KubernetesVersion: "1.33.2"}}, "1.33.2", "node pool pool-a not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &stubUpstreamReader{nodePools: tt.nodePools, version: tt.version}
+
+			err := VerifyUpstreamSpec(reader, "c-stub", cloudState)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestWaitForUpstreamSpecDrift(t *testing.T) {
+	cloud := &stubCloudClient{state: CloudState{
+		KubernetesVersion: "1.33.2",
+		NodePools:         []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}},
+	}}
+
+	tests := []struct {
+		name        string
+		reader      *stubUpstreamReader
+		expectedErr string
+	}{
+		{"In_Sync", &stubUpstreamReader{nodePools: []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, version: "1.33.2"}, ""},
+		{"Version_Drift", &stubUpstreamReader{nodePools: []NodePool{{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.2"}}, version: "1.33.1"}, "upstream spec is on version 1.33.1, cloud provider reports 1.33.2"},
+		{"Node_Count_Drift", &stubUpstreamReader{nodePools: []NodePool{{Name: "pool-a", Count: 1, KubernetesVersion: "1.33.2"}}, version: "1.33.2"}, "node pool pool-a has 1 nodes, expected 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WaitForUpstreamSpec(tt.reader, cloud, "c-stub", "stub", time.Millisecond)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorContains(t, err, "upstream spec of cluster c-stub did not match the cloud provider")
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestScaleNodePoolOutOfBand(t *testing.T) {
+	t.Run("Synced", func(t *testing.T) {
+		provider, clusterID := newTestProvider(t, testVersion)
+		cloud := provider.Cloud()
+
+		err := ScaleNodePoolOutOfBand(provider, cloud, clusterID, "provisioned", testNodePoolName, 4, time.Millisecond)
+		require.NoError(t, err)
+
+		state, err := cloud.GetClusterState("provisioned")
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), state.NodePools[0].Count)
+	})
+
+	t.Run("Not_Synced", func(t *testing.T) {
+		nodePools := []NodePool{{Name: testNodePoolName, Count: 2, KubernetesVersion: testVersion}}
+		reader := &stubUpstreamReader{nodePools: nodePools, version: testVersion}
+		cloud := &stubCloudClient{state: CloudState{KubernetesVersion: testVersion, NodePools: []NodePool{nodePools[0]}}}
+
+		err := ScaleNodePoolOutOfBand(reader, cloud, "c-stub", "stub", testNodePoolName, 4, time.Millisecond)
+		require.ErrorContains(t, err, fmt.Sprintf("node pool %s has 2 nodes, expected 4", testNodePoolName))
+	})
+}
@@ -0,0 +1,116 @@
+package hosted
+
+import (
+	"errors"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/cloudcredentials"
+	"github.com/rancher/shepherd/extensions/cloudcredentials/aws"
+	"github.com/rancher/shepherd/extensions/clusters/eks"
+	"github.com/rancher/tests/actions/provisioning"
+)
+
+const (
+	awsCredentialProvider = "aws"
+)
+
+// eksAdapter reads and writes the EKS config of a hosted cluster
+type eksAdapter struct {
+	clusterConfig eks.ClusterConfig
+}
+
+func newEKSProvider(client *rancher.Client, clusterConfig eks.ClusterConfig) HostedProvider {
+	return &rancherProvider{
+		client:       client,
+		providerType: EKS,
+		adapter:      &eksAdapter{clusterConfig: clusterConfig},
+	}
+}
+
+func (e *eksAdapter) getConfig(cluster *management.Cluster, upstream bool) ([]NodePool, string, error) {
+	spec := cluster.EKSConfig
+	if upstream {
+		if cluster.EKSStatus == nil {
+			return nil, "", errors.New("cluster " + cluster.Name + " has no EKS status")
+		}
+
+		spec = cluster.EKSStatus.UpstreamSpec
+	}
+
+	if spec == nil || spec.NodeGroups == nil {
+		return nil, "", errors.New("cluster " + cluster.Name + " has no EKS node groups")
+	}
+
+	var nodePools []NodePool
+	for _, nodeGroup := range *spec.NodeGroups {
+		nodePools = append(nodePools, NodePool{
+			Name:              stringValue(nodeGroup.NodegroupName),
+			Count:             int64Value(nodeGroup.DesiredSize),
+			KubernetesVersion: stringValue(nodeGroup.Version),
+		})
+	}
+
+	return nodePools, stringValue(spec.KubernetesVersion), nil
+}
+
+func (e *eksAdapter) setConfig(cluster *management.Cluster, nodePools []NodePool, version string) error {
+	if cluster.EKSConfig == nil || cluster.EKSConfig.NodeGroups == nil || len(*cluster.EKSConfig.NodeGroups) == 0 {
+		return errors.New("cluster " + cluster.Name + " has no EKS node groups")
+	}
+
+	existingNodeGroups := *cluster.EKSConfig.NodeGroups
+	var nodeGroups []management.NodeGroup
+	for _, nodePool := range nodePools {
+		// new node groups copy the settings of the first node group
+		nodeGroup := existingNodeGroups[0]
+		for _, existingNodeGroup := range existingNodeGroups {
+			if stringValue(existingNodeGroup.NodegroupName) == nodePool.Name {
+				nodeGroup = existingNodeGroup
+			}
+		}
+
+		nodeGroup.NodegroupName = &nodePool.Name
+		nodeGroup.DesiredSize = &nodePool.Count
+		nodeGroup.Version = stringPointer(nodePool.KubernetesVersion)
+
+		// the node group bounds must contain the desired size
+		if nodeGroup.MinSize != nil && *nodeGroup.MinSize > nodePool.Count {
+			nodeGroup.MinSize = &nodePool.Count
+		}
+
+		if nodeGroup.MaxSize != nil && *nodeGroup.MaxSize < nodePool.Count {
+			nodeGroup.MaxSize = &nodePool.Count
+		}
+
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+
+	cluster.EKSConfig.NodeGroups = &nodeGroups
+	cluster.EKSConfig.KubernetesVersion = stringPointer(version)
+
+	return nil
+}
+
+func (e *eksAdapter) provision(client *rancher.Client) (*management.Cluster, error) {
+	return provisioning.CreateProvisioningEKSHostedCluster(client, e.clusterConfig)
+}
+
+func (e *eksAdapter) importCluster(client *rancher.Client, displayName, clusterName string) (*management.Cluster, error) {
+	cloudCredentialConfig := cloudcredentials.LoadCloudCredential(awsCredentialProvider)
+	cloudCredential, err := aws.CreateAWSCloudCredentials(client, cloudCredentialConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &management.Cluster{
+		EKSConfig: &management.EKSClusterConfigSpec{
+			AmazonCredentialSecret: cloudCredential.Namespace + ":" + cloudCredential.Name,
+			DisplayName:            clusterName,
+			Imported:               true,
+			Region:                 e.clusterConfig.Region,
+		},
+		DockerRootDir: dockerRootDir,
+		Name:          displayName,
+	}, nil
+}
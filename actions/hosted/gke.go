@@ -0,0 +1,105 @@
+package hosted
+
+import (
+	"errors"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/cloudcredentials"
+	"github.com/rancher/shepherd/extensions/cloudcredentials/google"
+	"github.com/rancher/shepherd/extensions/clusters/gke"
+	"github.com/rancher/tests/actions/provisioning"
+	"github.com/rancher/tests/actions/provisioninginput"
+)
+
+// gkeAdapter reads and writes the GKE config of a hosted cluster
+type gkeAdapter struct {
+	clusterConfig gke.ClusterConfig
+}
+
+func newGKEProvider(client *rancher.Client, clusterConfig gke.ClusterConfig) HostedProvider {
+	return &rancherProvider{
+		client:       client,
+		providerType: GKE,
+		adapter:      &gkeAdapter{clusterConfig: clusterConfig},
+	}
+}
+
+func (g *gkeAdapter) getConfig(cluster *management.Cluster, upstream bool) ([]NodePool, string, error) {
+	spec := cluster.GKEConfig
+	if upstream {
+		if cluster.GKEStatus == nil {
+			return nil, "", errors.New("cluster " + cluster.Name + " has no GKE status")
+		}
+
+		spec = cluster.GKEStatus.UpstreamSpec
+	}
+
+	if spec == nil || spec.NodePools == nil {
+		return nil, "", errors.New("cluster " + cluster.Name + " has no GKE node pools")
+	}
+
+	var nodePools []NodePool
+	for _, gkeNodePool := range *spec.NodePools {
+		nodePools = append(nodePools, NodePool{
+			Name:              stringValue(gkeNodePool.Name),
+			Count:             int64Value(gkeNodePool.InitialNodeCount),
+			KubernetesVersion: stringValue(gkeNodePool.Version),
+		})
+	}
+
+	return nodePools, stringValue(spec.KubernetesVersion), nil
+}
+
+func (g *gkeAdapter) setConfig(cluster *management.Cluster, nodePools []NodePool, version string) error {
+	if cluster.GKEConfig == nil || cluster.GKEConfig.NodePools == nil || len(*cluster.GKEConfig.NodePools) == 0 {
+		return errors.New("cluster " + cluster.Name + " has no GKE node pools")
+	}
+
+	existingNodePools := *cluster.GKEConfig.NodePools
+	var gkeNodePools []management.GKENodePoolConfig
+	for _, nodePool := range nodePools {
+		// new node pools copy the settings of the first node pool
+		gkeNodePool := existingNodePools[0]
+		for _, existingNodePool := range existingNodePools {
+			if stringValue(existingNodePool.Name) == nodePool.Name {
+				gkeNodePool = existingNodePool
+			}
+		}
+
+		gkeNodePool.Name = &nodePool.Name
+		gkeNodePool.InitialNodeCount = &nodePool.Count
+		gkeNodePool.Version = stringPointer(nodePool.KubernetesVersion)
+		gkeNodePools = append(gkeNodePools, gkeNodePool)
+	}
+
+	cluster.GKEConfig.NodePools = &gkeNodePools
+	cluster.GKEConfig.KubernetesVersion = stringPointer(version)
+
+	return nil
+}
+
+func (g *gkeAdapter) provision(client *rancher.Client) (*management.Cluster, error) {
+	return provisioning.CreateProvisioningGKEHostedCluster(client, g.clusterConfig)
+}
+
+func (g *gkeAdapter) importCluster(client *rancher.Client, displayName, clusterName string) (*management.Cluster, error) {
+	cloudCredentialConfig := cloudcredentials.LoadCloudCredential(provisioninginput.GoogleProviderName.String())
+	cloudCredential, err := google.CreateGoogleCloudCredentials(client, cloudCredentialConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &management.Cluster{
+		GKEConfig: &management.GKEClusterConfigSpec{
+			ClusterName:            clusterName,
+			GoogleCredentialSecret: cloudCredential.Namespace + ":" + cloudCredential.Name,
+			Imported:               true,
+			ProjectID:              g.clusterConfig.ProjectID,
+			Region:                 g.clusterConfig.Region,
+			Zone:                   g.clusterConfig.Zone,
+		},
+		DockerRootDir: dockerRootDir,
+		Name:          displayName,
+	}, nil
+}
@@ -0,0 +1,169 @@
+package hosted
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ScaleNodePoolBy scales a node pool of the cluster by the given number of nodes, which can be negative,
+// and verifies the node pools of the cluster afterwards
+func ScaleNodePoolBy(provider HostedProvider, clusterID, nodePoolName string, nodes int64) error {
+	nodePools, err := provider.GetNodePools(clusterID)
+	if err != nil {
+		return err
+	}
+
+	index, err := findNodePool(nodePools, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	count := nodePools[index].Count + nodes
+	if count < 0 {
+		return fmt.Errorf("node pool %s can't be scaled by %d from %d nodes", nodePoolName, nodes, nodePools[index].Count)
+	}
+
+	err = provider.ScaleNodePool(clusterID, nodePoolName, count)
+	if err != nil {
+		return err
+	}
+
+	nodePools[index].Count = count
+
+	return VerifyNodePools(provider, clusterID, nodePools)
+}
+
+// AddAndRemoveNodePool adds a node pool to the cluster, verifies it was added, then removes it and verifies the
+// cluster is back to its original node pools
+func AddAndRemoveNodePool(provider HostedProvider, clusterID string, nodePool NodePool) error {
+	nodePools, err := provider.GetNodePools(clusterID)
+	if err != nil {
+		return err
+	}
+
+	err = provider.AddNodePool(clusterID, nodePool)
+	if err != nil {
+		return err
+	}
+
+	err = VerifyNodePools(provider, clusterID, append(nodePools, nodePool))
+	if err != nil {
+		return err
+	}
+
+	err = provider.RemoveNodePool(clusterID, nodePool.Name)
+	if err != nil {
+		return err
+	}
+
+	return VerifyNodePools(provider, clusterID, nodePools)
+}
+
+// UpgradeCluster upgrades the control plane of the cluster to the Kubernetes version, then every node pool, and
+// verifies the cluster ends up on that version
+func UpgradeCluster(provider HostedProvider, clusterID, version string) error {
+	currentVersion, err := provider.GetKubernetesVersion(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if compareVersions(version, currentVersion) < 0 {
+		return fmt.Errorf("cluster %s can't be upgraded from %s to an older version %s", clusterID, currentVersion, version)
+	}
+
+	if currentVersion != version {
+		err = provider.UpgradeControlPlane(clusterID, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	nodePools, err := provider.GetNodePools(clusterID)
+	if err != nil {
+		return err
+	}
+
+	for i, nodePool := range nodePools {
+		if nodePool.KubernetesVersion == version {
+			continue
+		}
+
+		logrus.Infof("Upgrading node pool %s of cluster %s to %s", nodePool.Name, clusterID, version)
+		err = provider.UpgradeNodePool(clusterID, nodePool.Name, version)
+		if err != nil {
+			return err
+		}
+
+		nodePools[i].KubernetesVersion = version
+	}
+
+	upgradedVersion, err := provider.GetKubernetesVersion(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if upgradedVersion != version {
+		return fmt.Errorf("control plane of cluster %s is on version %s, expected %s", clusterID, upgradedVersion, version)
+	}
+
+	return VerifyNodePools(provider, clusterID, nodePools)
+}
+
+// VerifyNodePools verifies the cluster has exactly the expected node pools, with the expected counts and versions
+func VerifyNodePools(provider HostedProvider, clusterID string, expected []NodePool) error {
+	actual, err := provider.GetNodePools(clusterID)
+	if err != nil {
+		return err
+	}
+
+	return compareNodePools(expected, actual)
+}
+
+// compareVersions compares two Kubernetes versions, such as 1.33 or v1.33.2, by their numeric parts.
+// Suffixes after a dash or a plus, such as -eks-1 or -gke.100, are ignored.
+func compareVersions(a, b string) int {
+	aParts := versionParts(a)
+	bParts := versionParts(b)
+
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+	version, _, _ = strings.Cut(version, "+")
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+
+		parts = append(parts, number)
+	}
+
+	return parts
+}
@@ -0,0 +1,193 @@
+package hosted
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testVersion      = "1.32.3"
+	testNextVersion  = "1.33.1"
+	testNodePoolName = "pool-a"
+)
+
+// newTestProvider returns an in-memory provider with a provisioned cluster of a single node pool of two nodes
+func newTestProvider(t *testing.T, version string, nodePools ...NodePool) (*InMemoryProvider, string) {
+	if len(nodePools) == 0 {
+		nodePools = []NodePool{{Name: testNodePoolName, Count: 2}}
+	}
+
+	provider := NewInMemoryProvider(version, nodePools...)
+
+	clusterID, err := provider.Provision()
+	require.NoError(t, err)
+
+	return provider, clusterID
+}
+
+func TestScaleNodePoolBy(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodePoolName  string
+		nodes         int64
+		expectedCount int64
+		expectedErr   string
+	}{
+		{"Scale_Up", testNodePoolName, 2, 4, ""},
+		{"Scale_Down", testNodePoolName, -1, 1, ""},
+		{"Scale_To_Zero", testNodePoolName, -2, 0, ""},
+		{"Scale_Below_Zero", testNodePoolName, -3, 2, "can't be scaled by -3 from 2 nodes"},
+		{"Unknown_Node_Pool", "missing", 1, 2, "node pool missing not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, clusterID := newTestProvider(t, testVersion)
+
+			err := ScaleNodePoolBy(provider, clusterID, tt.nodePoolName, tt.nodes)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				assert.Len(t, provider.Operations, 1)
+			} else {
+				require.NoError(t, err)
+			}
+
+			nodePools, err := provider.GetNodePools(clusterID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCount, nodePools[0].Count)
+		})
+	}
+}
+
+func TestAddAndRemoveNodePool(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodePool    NodePool
+		expectedOps []string
+		expectedErr string
+	}{
+		{"Default_Version", NodePool{Name: "pool-b", Count: 1}, []string{"add c-memory1/pool-b", "remove c-memory1/pool-b"}, ""},
+		{"Control_Plane_Version", NodePool{Name: "pool-b", Count: 3, KubernetesVersion: testVersion}, []string{"add c-memory1/pool-b", "remove c-memory1/pool-b"}, ""},
+		{"Existing_Name", NodePool{Name: testNodePoolName, Count: 1}, []string{}, "node pool pool-a already exists"},
+		{"Negative_Count", NodePool{Name: "pool-b", Count: -1}, []string{}, "can't have -1 nodes"},
+		{"Newer_Than_Control_Plane", NodePool{Name: "pool-b", Count: 1, KubernetesVersion: testNextVersion}, []string{}, "newer than the control plane version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, clusterID := newTestProvider(t, testVersion)
+
+			err := AddAndRemoveNodePool(provider, clusterID, tt.nodePool)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedOps, provider.Operations[1:])
+
+			err = VerifyNodePools(provider, clusterID, []NodePool{{Name: testNodePoolName, Count: 2, KubernetesVersion: testVersion}})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRemoveLastNodePool(t *testing.T) {
+	provider, clusterID := newTestProvider(t, testVersion)
+
+	err := provider.RemoveNodePool(clusterID, testNodePoolName)
+	require.ErrorContains(t, err, "last node pool of the cluster")
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		nodePools   []NodePool
+		target      string
+		expectedOps []string
+		expectedErr string
+	}{
+		{
+			name:        "Upgrade_Control_Plane_And_Node_Pools",
+			version:     testVersion,
+			nodePools:   []NodePool{{Name: testNodePoolName, Count: 2}, {Name: "pool-b", Count: 1}},
+			target:      testNextVersion,
+			expectedOps: []string{"upgrade c-memory1 1.33.1", "upgrade c-memory1/pool-a 1.33.1", "upgrade c-memory1/pool-b 1.33.1"},
+		},
+		{
+			name:        "Upgrade_Lagging_Node_Pool",
+			version:     testNextVersion,
+			nodePools:   []NodePool{{Name: testNodePoolName, Count: 2, KubernetesVersion: testVersion}, {Name: "pool-b", Count: 1}},
+			target:      testNextVersion,
+			expectedOps: []string{"upgrade c-memory1/pool-a 1.33.1"},
+		},
+		{
+			name:        "Already_Upgraded",
+			version:     testVersion,
+			target:      testVersion,
+			expectedOps: []string{},
+		},
+		{
+			name:        "Downgrade",
+			version:     testNextVersion,
+			target:      testVersion,
+			expectedErr: "can't be upgraded from 1.33.1 to an older version 1.32.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, clusterID := newTestProvider(t, tt.version, tt.nodePools...)
+
+			err := UpgradeCluster(provider, clusterID, tt.target)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				assert.Len(t, provider.Operations, 1)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOps, provider.Operations[1:])
+
+			version, err := provider.GetKubernetesVersion(clusterID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.target, version)
+
+			nodePools, err := provider.GetNodePools(clusterID)
+			require.NoError(t, err)
+
+			for _, nodePool := range nodePools {
+				assert.Equal(t, tt.target, nodePool.KubernetesVersion, nodePool.Name)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected int
+	}{
+		{"Equal", "1.33.2", "1.33.2", 0},
+		{"Prefix", "v1.33.2", "1.33.2", 0},
+		{"Missing_Patch", "1.33", "1.33.0", 0},
+		{"Older_Patch", "1.33.2", "1.33.10", -1},
+		{"Newer_Minor", "1.34.0", "1.33.10", 1},
+		{"EKS_Suffix", "1.33.2-eks-1", "v1.33.2", 0},
+		{"GKE_Suffix", "1.33.5-gke.100", "1.33.4", 1},
+		{"Build_Metadata", "v1.33.2+rke2r1", "1.33.2", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, compareVersions(tt.a, tt.b))
+			assert.Equal(t, -tt.expected, compareVersions(tt.b, tt.a))
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package hosted
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// InMemoryProvider is a HostedProvider keeping its clusters in memory. It applies the same rules as the cloud
+// providers, so the orchestration of node pool and upgrade operations can be exercised without a cloud account.
+type InMemoryProvider struct {
+	// Operations lists every successful operation, in order
+	Operations []string
+
+	mu       sync.Mutex
+	clusters map[string]*inMemoryCluster
+	template []NodePool
+	version  string
+	nextID   int
+}
+
+type inMemoryCluster struct {
+	name      string
+	version   string
+	nodePools []NodePool
+}
+
+// NewInMemoryProvider returns an in-memory provider creating clusters on the given version with the given node pools
+func NewInMemoryProvider(version string, nodePools ...NodePool) *InMemoryProvider {
+	return &InMemoryProvider{
+		clusters: map[string]*inMemoryCluster{},
+		template: nodePools,
+		version:  version,
+	}
+}
+
+// Type returns the type of the provider
+func (m *InMemoryProvider) Type() string {
+	return "memory"
+}
+
+// Provision creates a new cluster and returns its ID
+func (m *InMemoryProvider) Provision() (string, error) {
+	return m.create("provisioned")
+}
+
+// Import creates a cluster with the given name and returns its ID
+func (m *InMemoryProvider) Import(clusterName string) (string, error) {
+	return m.create(clusterName)
+}
+
+// GetNodePools returns the node pools of the cluster
+func (m *InMemoryProvider) GetNodePools(clusterID string) ([]NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(cluster.nodePools), nil
+}
+
+// AddNodePool adds a node pool to the cluster. A node pool without a version gets the version of the control plane.
+func (m *InMemoryProvider) AddNodePool(clusterID string, nodePool NodePool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := findNodePool(cluster.nodePools, nodePool.Name); err == nil {
+		return fmt.Errorf("node pool %s already exists", nodePool.Name)
+	}
+
+	if nodePool.Count < 0 {
+		return fmt.Errorf("node pool %s can't have %d nodes", nodePool.Name, nodePool.Count)
+	}
+
+	if nodePool.KubernetesVersion == "" {
+		nodePool.KubernetesVersion = cluster.version
+	}
+
+	err = verifyNodePoolVersion(cluster, nodePool)
+	if err != nil {
+		return err
+	}
+
+	cluster.nodePools = append(cluster.nodePools, nodePool)
+
+	m.record("add %s/%s", clusterID, nodePool.Name)
+
+	return nil
+}
+
+// RemoveNodePool removes a node pool from the cluster
+func (m *InMemoryProvider) RemoveNodePool(clusterID, nodePoolName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return err
+	}
+
+	cluster.nodePools, err = removeNodePool(cluster.nodePools, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	m.record("remove %s/%s", clusterID, nodePoolName)
+
+	return nil
+}
+
+// ScaleNodePool sets the node count of a node pool
+func (m *InMemoryProvider) ScaleNodePool(clusterID, nodePoolName string, count int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return err
+	}
+
+	index, err := findNodePool(cluster.nodePools, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return fmt.Errorf("node pool %s can't have %d nodes", nodePoolName, count)
+	}
+
+	cluster.nodePools[index].Count = count
+
+	m.record("scale %s/%s %d", clusterID, nodePoolName, count)
+
+	return nil
+}
+
+// GetKubernetesVersion returns the Kubernetes version of the control plane
+func (m *InMemoryProvider) GetKubernetesVersion(clusterID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	return cluster.version, nil
+}
+
+// UpgradeControlPlane upgrades the control plane. Like the cloud providers, it rejects downgrades.
+func (m *InMemoryProvider) UpgradeControlPlane(clusterID, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if compareVersions(version, cluster.version) < 0 {
+		return fmt.Errorf("control plane can't be downgraded from %s to %s", cluster.version, version)
+	}
+
+	cluster.version = version
+
+	m.record("upgrade %s %s", clusterID, version)
+
+	return nil
+}
+
+// UpgradeNodePool upgrades a node pool. Like the cloud providers, it rejects versions newer than the control plane.
+func (m *InMemoryProvider) UpgradeNodePool(clusterID, nodePoolName, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return err
+	}
+
+	index, err := findNodePool(cluster.nodePools, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	nodePool := cluster.nodePools[index]
+	nodePool.KubernetesVersion = version
+
+	err = verifyNodePoolVersion(cluster, nodePool)
+	if err != nil {
+		return err
+	}
+
+	cluster.nodePools[index] = nodePool
+
+	m.record("upgrade %s/%s %s", clusterID, nodePoolName, version)
+
+	return nil
+}
+
+func (m *InMemoryProvider) create(clusterName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.template) == 0 {
+		return "", fmt.Errorf("cluster %s needs at least one node pool", clusterName)
+	}
+
+	m.nextID++
+	clusterID := fmt.Sprintf("c-memory%d", m.nextID)
+
+	nodePools := slices.Clone(m.template)
+	for i := range nodePools {
+		if nodePools[i].KubernetesVersion == "" {
+			nodePools[i].KubernetesVersion = m.version
+		}
+	}
+
+	m.clusters[clusterID] = &inMemoryCluster{
+		name:      clusterName,
+		version:   m.version,
+		nodePools: nodePools,
+	}
+
+	m.record("create %s %s", clusterID, clusterName)
+
+	return clusterID, nil
+}
+
+func (m *InMemoryProvider) get(clusterID string) (*inMemoryCluster, error) {
+	cluster, ok := m.clusters[clusterID]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+
+	return cluster, nil
+}
+
+func (m *InMemoryProvider) record(format string, args ...any) {
+	m.Operations = append(m.Operations, fmt.Sprintf(format, args...))
+}
+
+// verifyNodePoolVersion verifies the node pool is not on a version newer than the control plane
+func verifyNodePoolVersion(cluster *inMemoryCluster, nodePool NodePool) error {
+	if compareVersions(nodePool.KubernetesVersion, cluster.version) > 0 {
+		return fmt.Errorf("node pool %s can't be on version %s, newer than the control plane version %s", nodePool.Name, nodePool.KubernetesVersion, cluster.version)
+	}
+
+	return nil
+}
+
+// GetUpstreamSpec returns the node pools and the control plane version of the cluster. The clusters of the provider
+// are also its cloud state, so the upstream spec is always in sync.
+func (m *InMemoryProvider) GetUpstreamSpec(clusterID string) ([]NodePool, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cluster, err := m.get(clusterID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return slices.Clone(cluster.nodePools), cluster.version, nil
+}
+
+// Cloud returns a cloud client changing the clusters of the provider by name, as done outside of Rancher
+func (m *InMemoryProvider) Cloud() CloudClient {
+	return &inMemoryCloud{provider: m}
+}
+
+// inMemoryCloud is a CloudClient over the clusters of an InMemoryProvider
+type inMemoryCloud struct {
+	provider *InMemoryProvider
+}
+
+func (c *inMemoryCloud) GetClusterState(clusterName string) (*CloudState, error) {
+	clusterID, err := c.clusterID(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	nodePools, version, err := c.provider.GetUpstreamSpec(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudState{KubernetesVersion: version, NodePools: nodePools}, nil
+}
+
+func (c *inMemoryCloud) ScaleNodePool(clusterName, nodePoolName string, count int64) error {
+	clusterID, err := c.clusterID(clusterName)
+	if err != nil {
+		return err
+	}
+
+	return c.provider.ScaleNodePool(clusterID, nodePoolName, count)
+}
+
+func (c *inMemoryCloud) clusterID(clusterName string) (string, error) {
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+
+	for clusterID, cluster := range c.provider.clusters {
+		if cluster.name == clusterName {
+			return clusterID, nil
+		}
+	}
+
+	return "", fmt.Errorf("cluster %s not found", clusterName)
+}
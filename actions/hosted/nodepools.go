@@ -0,0 +1,87 @@
+package hosted
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// findNodePool returns the index of the node pool with the given name
+func findNodePool(nodePools []NodePool, nodePoolName string) (int, error) {
+	index := slices.IndexFunc(nodePools, func(nodePool NodePool) bool {
+		return nodePool.Name == nodePoolName
+	})
+	if index < 0 {
+		return -1, fmt.Errorf("node pool %s not found", nodePoolName)
+	}
+
+	return index, nil
+}
+
+// removeNodePool returns the node pools without the one with the given name. The last node pool can't be removed.
+func removeNodePool(nodePools []NodePool, nodePoolName string) ([]NodePool, error) {
+	index, err := findNodePool(nodePools, nodePoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodePools) == 1 {
+		return nil, fmt.Errorf("node pool %s is the last node pool of the cluster", nodePoolName)
+	}
+
+	return slices.Delete(slices.Clone(nodePools), index, index+1), nil
+}
+
+// compareNodePools returns an error listing every expected node pool missing from the actual node pools or whose
+// count or version differ. Node pools without an expected version only have their count compared.
+func compareNodePools(expected, actual []NodePool) error {
+	var errs []error
+
+	if len(expected) != len(actual) {
+		errs = append(errs, fmt.Errorf("expected %d node pools, found %d", len(expected), len(actual)))
+	}
+
+	for _, expectedNodePool := range expected {
+		index, err := findNodePool(actual, expectedNodePool.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		actualNodePool := actual[index]
+		if actualNodePool.Count != expectedNodePool.Count {
+			errs = append(errs, fmt.Errorf("node pool %s has %d nodes, expected %d", expectedNodePool.Name, actualNodePool.Count, expectedNodePool.Count))
+		}
+
+		if expectedNodePool.KubernetesVersion != "" && actualNodePool.KubernetesVersion != expectedNodePool.KubernetesVersion {
+			errs = append(errs, fmt.Errorf("node pool %s is on version %s, expected %s", expectedNodePool.Name, actualNodePool.KubernetesVersion, expectedNodePool.KubernetesVersion))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func int64Value(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+
+	return *value
+}
+
+// stringPointer returns nil for an empty string, so unset versions are left to the cloud provider
+func stringPointer(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
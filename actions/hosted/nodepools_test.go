@@ -0,0 +1,68 @@
+package hosted
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareNodePools(t *testing.T) {
+	actual := []NodePool{
+		{Name: "pool-a", Count: 2, KubernetesVersion: "1.33.1"},
+		{Name: "pool-b", Count: 1, KubernetesVersion: "1.32.3"},
+	}
+
+	tests := []struct {
+		name         string
+		expected     []NodePool
+		expectedErrs []string
+	}{
+		{
+			name:     "Equal",
+			expected: actual,
+		},
+		{
+			name:     "Count_Only",
+			expected: []NodePool{{Name: "pool-a", Count: 2}, {Name: "pool-b", Count: 1}},
+		},
+		{
+			name:         "Different_Count",
+			expected:     []NodePool{{Name: "pool-a", Count: 3}, {Name: "pool-b", Count: 1}},
+			expectedErrs: []string{"node pool pool-a has 2 nodes, expected 3"},
+		},
+		{
+			name:         "Different_Version",
+			expected:     []NodePool{{Name: "pool-a", Count: 2}, {Name: "pool-b", Count: 1, KubernetesVersion: "1.33.1"}},
+			expectedErrs: []string{"node pool pool-b is on version 1.32.3, expected 1.33.1"},
+		},
+		{
+			name:         "Missing_Node_Pool",
+			expected:     []NodePool{{Name: "pool-a", Count: 2}, {Name: "pool-c", Count: 1}},
+			expectedErrs: []string{"node pool pool-c not found"},
+		},
+		{
+			name:         "Extra_Node_Pool",
+			expected:     []NodePool{{Name: "pool-a", Count: 2}},
+			expectedErrs: []string{"expected 1 node pools, found 2"},
+		},
+		{
+			name:         "Every_Difference",
+			expected:     []NodePool{{Name: "pool-a", Count: 1, KubernetesVersion: "1.32.3"}},
+			expectedErrs: []string{"expected 1 node pools, found 2", "pool-a has 2 nodes, expected 1", "pool-a is on version 1.33.1, expected 1.32.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareNodePools(tt.expected, actual)
+			if len(tt.expectedErrs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			for _, expectedErr := range tt.expectedErrs {
+				require.ErrorContains(t, err, expectedErr)
+			}
+		})
+	}
+}
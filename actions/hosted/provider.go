@@ -0,0 +1,80 @@
+package hosted
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/clusters/aks"
+	"github.com/rancher/shepherd/extensions/clusters/eks"
+	"github.com/rancher/shepherd/extensions/clusters/gke"
+)
+
+const (
+	AKS = "aks"
+	EKS = "eks"
+	GKE = "gke"
+)
+
+// NodePool is the provider agnostic view of an AKS node pool, an EKS node group or a GKE node pool
+type NodePool struct {
+	Name              string `json:"name" yaml:"name"`
+	Count             int64  `json:"count" yaml:"count"`
+	KubernetesVersion string `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
+}
+
+// HostedProvider manages the lifecycle of a hosted cluster and its node pools, regardless of the cloud provider.
+// Every operation returns once the change is applied and the cluster is active again.
+type HostedProvider interface {
+	// Type returns the type of the provider: aks, eks or gke
+	Type() string
+	// Provision creates a new hosted cluster through Rancher and returns its ID
+	Provision() (string, error)
+	// Import imports an existing hosted cluster of the cloud provider into Rancher and returns its ID
+	Import(clusterName string) (string, error)
+	// GetNodePools returns the node pools of the cluster
+	GetNodePools(clusterID string) ([]NodePool, error)
+	// AddNodePool adds a node pool to the cluster, using the first node pool as a template for the provider specific settings
+	AddNodePool(clusterID string, nodePool NodePool) error
+	// RemoveNodePool removes a node pool from the cluster
+	RemoveNodePool(clusterID, nodePoolName string) error
+	// ScaleNodePool sets the node count of a node pool
+	ScaleNodePool(clusterID, nodePoolName string, count int64) error
+	// GetKubernetesVersion returns the Kubernetes version of the control plane
+	GetKubernetesVersion(clusterID string) (string, error)
+	// UpgradeControlPlane upgrades the control plane to the Kubernetes version
+	UpgradeControlPlane(clusterID, version string) error
+	// UpgradeNodePool upgrades a node pool to the Kubernetes version
+	UpgradeNodePool(clusterID, nodePoolName, version string) error
+
+	UpstreamReader
+}
+
+// NewHostedProvider returns the provider of the given type, managing hosted clusters through Rancher with the
+// cluster config of the provider. The config must be an aks.ClusterConfig, eks.ClusterConfig or gke.ClusterConfig.
+func NewHostedProvider(client *rancher.Client, providerType string, clusterConfig any) (HostedProvider, error) {
+	switch providerType {
+	case AKS:
+		aksClusterConfig, ok := clusterConfig.(aks.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected an AKS cluster config, got %T", clusterConfig)
+		}
+
+		return newAKSProvider(client, aksClusterConfig), nil
+	case EKS:
+		eksClusterConfig, ok := clusterConfig.(eks.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected an EKS cluster config, got %T", clusterConfig)
+		}
+
+		return newEKSProvider(client, eksClusterConfig), nil
+	case GKE:
+		gkeClusterConfig, ok := clusterConfig.(gke.ClusterConfig)
+		if !ok {
+			return nil, fmt.Errorf("expected a GKE cluster config, got %T", clusterConfig)
+		}
+
+		return newGKEProvider(client, gkeClusterConfig), nil
+	}
+
+	return nil, fmt.Errorf("unknown hosted provider %s", providerType)
+}
@@ -0,0 +1,240 @@
+package hosted
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/sirupsen/logrus"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	activeState   = "active"
+	dockerRootDir = "/var/lib/docker"
+)
+
+// configAdapter reads and writes the node pools and the version of the provider specific config of a hosted cluster
+type configAdapter interface {
+	// getConfig returns the node pools and the control plane version of the config of the cluster. When upstream is
+	// true, they are read from the spec reported by the cloud provider instead of the spec requested in Rancher.
+	getConfig(cluster *management.Cluster, upstream bool) ([]NodePool, string, error)
+	// setConfig sets the node pools and the control plane version in the config of the cluster
+	setConfig(cluster *management.Cluster, nodePools []NodePool, version string) error
+	// provision creates a new hosted cluster
+	provision(client *rancher.Client) (*management.Cluster, error)
+	// importCluster returns the cluster importing the existing hosted cluster of the cloud provider
+	importCluster(client *rancher.Client, displayName, clusterName string) (*management.Cluster, error)
+}
+
+// rancherProvider is a HostedProvider managing hosted clusters through the Rancher management API
+type rancherProvider struct {
+	client       *rancher.Client
+	providerType string
+	adapter      configAdapter
+}
+
+// Type returns the type of the provider
+func (p *rancherProvider) Type() string {
+	return p.providerType
+}
+
+// Provision creates a new hosted cluster through Rancher and returns its ID
+func (p *rancherProvider) Provision() (string, error) {
+	cluster, err := p.adapter.provision(p.client)
+	if err != nil {
+		return "", err
+	}
+
+	return cluster.ID, p.waitForCluster(cluster.ID, nil, nil)
+}
+
+// Import imports an existing hosted cluster of the cloud provider into Rancher and returns its ID
+func (p *rancherProvider) Import(clusterName string) (string, error) {
+	displayName := namegen.AppendRandomString(p.providerType + "import")
+
+	cluster, err := p.adapter.importCluster(p.client, displayName, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Infof("Importing %s cluster %s as %s", p.providerType, clusterName, displayName)
+	createdCluster, err := p.client.Management.Cluster.Create(cluster)
+	if err != nil {
+		return "", err
+	}
+
+	return createdCluster.ID, p.waitForCluster(createdCluster.ID, nil, nil)
+}
+
+// GetNodePools returns the node pools of the cluster
+func (p *rancherProvider) GetNodePools(clusterID string) ([]NodePool, error) {
+	cluster, err := p.client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodePools, _, err := p.adapter.getConfig(cluster, false)
+
+	return nodePools, err
+}
+
+// GetUpstreamSpec returns the node pools and the control plane version of the cluster as last synced from the cloud provider
+func (p *rancherProvider) GetUpstreamSpec(clusterID string) ([]NodePool, string, error) {
+	cluster, err := p.client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return p.adapter.getConfig(cluster, true)
+}
+
+// AddNodePool adds a node pool to the cluster
+func (p *rancherProvider) AddNodePool(clusterID string, nodePool NodePool) error {
+	return p.updateNodePools(clusterID, func(nodePools []NodePool, version string) ([]NodePool, string, error) {
+		_, err := findNodePool(nodePools, nodePool.Name)
+		if err == nil {
+			return nil, "", fmt.Errorf("node pool %s already exists", nodePool.Name)
+		}
+
+		logrus.Infof("Adding node pool %s with %d nodes", nodePool.Name, nodePool.Count)
+
+		return append(nodePools, nodePool), version, nil
+	})
+}
+
+// RemoveNodePool removes a node pool from the cluster
+func (p *rancherProvider) RemoveNodePool(clusterID, nodePoolName string) error {
+	return p.updateNodePools(clusterID, func(nodePools []NodePool, version string) ([]NodePool, string, error) {
+		updatedNodePools, err := removeNodePool(nodePools, nodePoolName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		logrus.Infof("Removing node pool %s", nodePoolName)
+
+		return updatedNodePools, version, nil
+	})
+}
+
+// ScaleNodePool sets the node count of a node pool
+func (p *rancherProvider) ScaleNodePool(clusterID, nodePoolName string, count int64) error {
+	return p.updateNodePools(clusterID, func(nodePools []NodePool, version string) ([]NodePool, string, error) {
+		index, err := findNodePool(nodePools, nodePoolName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		logrus.Infof("Scaling node pool %s from %d to %d nodes", nodePoolName, nodePools[index].Count, count)
+		nodePools[index].Count = count
+
+		return nodePools, version, nil
+	})
+}
+
+// GetKubernetesVersion returns the Kubernetes version of the control plane
+func (p *rancherProvider) GetKubernetesVersion(clusterID string) (string, error) {
+	cluster, err := p.client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	_, version, err := p.adapter.getConfig(cluster, false)
+
+	return version, err
+}
+
+// UpgradeControlPlane upgrades the control plane to the Kubernetes version
+func (p *rancherProvider) UpgradeControlPlane(clusterID, version string) error {
+	return p.updateNodePools(clusterID, func(nodePools []NodePool, currentVersion string) ([]NodePool, string, error) {
+		logrus.Infof("Upgrading the control plane from %s to %s", currentVersion, version)
+		return nodePools, version, nil
+	})
+}
+
+// UpgradeNodePool upgrades a node pool to the Kubernetes version
+func (p *rancherProvider) UpgradeNodePool(clusterID, nodePoolName, version string) error {
+	return p.updateNodePools(clusterID, func(nodePools []NodePool, currentVersion string) ([]NodePool, string, error) {
+		index, err := findNodePool(nodePools, nodePoolName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		logrus.Infof("Upgrading node pool %s from %s to %s", nodePoolName, nodePools[index].KubernetesVersion, version)
+		nodePools[index].KubernetesVersion = version
+
+		return nodePools, currentVersion, nil
+	})
+}
+
+// updateNodePools applies the update to the node pools and the version of the cluster, and waits for the cloud
+// provider to report the updated config
+func (p *rancherProvider) updateNodePools(clusterID string, update func(nodePools []NodePool, version string) ([]NodePool, string, error)) error {
+	cluster, err := p.client.Management.Cluster.ByID(clusterID)
+	if err != nil {
+		return err
+	}
+
+	nodePools, version, err := p.adapter.getConfig(cluster, false)
+	if err != nil {
+		return err
+	}
+
+	nodePools, version, err = update(nodePools, version)
+	if err != nil {
+		return err
+	}
+
+	err = p.adapter.setConfig(cluster, nodePools, version)
+	if err != nil {
+		return err
+	}
+
+	updates := &management.Cluster{
+		AKSConfig:              cluster.AKSConfig,
+		EKSConfig:              cluster.EKSConfig,
+		GKEConfig:              cluster.GKEConfig,
+		DockerRootDir:          dockerRootDir,
+		EnableNetworkPolicy:    cluster.EnableNetworkPolicy,
+		Labels:                 cluster.Labels,
+		Name:                   cluster.Name,
+		WindowsPreferedCluster: cluster.WindowsPreferedCluster,
+	}
+
+	_, err = p.client.Management.Cluster.Update(cluster, updates)
+	if err != nil {
+		return err
+	}
+
+	return p.waitForCluster(clusterID, nodePools, &version)
+}
+
+// waitForCluster waits for the cluster to be active and, if given, for the cloud provider to report the node pools
+// and the version
+func (p *rancherProvider) waitForCluster(clusterID string, nodePools []NodePool, version *string) error {
+	return kwait.PollUntilContextTimeout(context.TODO(), 10*time.Second, defaults.ThirtyMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+		cluster, err := p.client.Management.Cluster.ByID(clusterID)
+		if err != nil {
+			return false, nil
+		}
+
+		if cluster.State != activeState {
+			return false, nil
+		}
+
+		upstreamNodePools, upstreamVersion, err := p.adapter.getConfig(cluster, true)
+		if err != nil {
+			return false, nil
+		}
+
+		if version != nil && *version != "" && upstreamVersion != *version {
+			return false, nil
+		}
+
+		return nodePools == nil || compareNodePools(nodePools, upstreamNodePools) == nil, nil
+	})
+}
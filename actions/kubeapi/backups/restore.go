@@ -0,0 +1,84 @@
+package backups
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// RestoreGroupVersionResource is the required Group Version Resource for accessing rancher-backup restores in a cluster,
+// using the dynamic client.
+var RestoreGroupVersionResource = schema.GroupVersionResource{
+	Group:    "resources.cattle.io",
+	Version:  "v1",
+	Resource: "restores",
+}
+
+// CreateRestore creates a rancher-backup restore of the given backup file directly against the cluster behind restConfig,
+// without going through the Rancher API, and waits for it to complete. This allows restoring while Rancher is scaled down.
+// It returns the name of the restore.
+func CreateRestore(restConfig *rest.Config, backupFilename string, prune bool) (string, error) {
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return "", err
+	}
+
+	restore := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": RestoreGroupVersionResource.GroupVersion().String(),
+		"kind":       "Restore",
+		"metadata": map[string]any{
+			"generateName": "restore-",
+		},
+		"spec": map[string]any{
+			"backupFilename": backupFilename,
+			"prune":          prune,
+		},
+	}}
+
+	restoreResource := dynamicClient.Resource(RestoreGroupVersionResource)
+
+	logrus.Infof("Restoring backup file %s", backupFilename)
+	createdRestore, err := restoreResource.Create(context.TODO(), restore, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	restoreName := createdRestore.GetName()
+	err = kwait.PollUntilContextTimeout(context.TODO(), defaults.TenSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		restore, err := restoreResource.Get(ctx, restoreName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+
+		conditions, _, err := unstructured.NestedSlice(restore.Object, "status", "conditions")
+		if err != nil {
+			return false, err
+		}
+
+		for _, condition := range conditions {
+			conditionMap, ok := condition.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if conditionMap["type"] == "Ready" && conditionMap["status"] == "True" {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("restore %s of %s did not complete: %w", restoreName, backupFilename, err)
+	}
+
+	return restoreName, nil
+}
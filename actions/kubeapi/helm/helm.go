@@ -2,6 +2,10 @@ package helm
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -12,6 +16,12 @@ import (
 	"github.com/rancher/shepherd/pkg/session"
 )
 
+const (
+	RancherReleaseName = "rancher"
+	RancherNamespace   = "cattle-system"
+	helmCmd            = "helm_v3"
+)
+
 // InstallRancher installs latest version of rancher including cert-manager
 // using helm CLI with some predefined values set such as
 // - BootstrapPassword : admin
@@ -94,3 +104,77 @@ func InstallCertManager(ts *session.Session, restConfig *rest.Config) error {
 
 	return nil
 }
+
+// UpgradeRancher upgrades the Rancher release to the given chart version using helm CLI, reusing the values of the
+// current release. Send the helm set command strings such as "--set", "rancherImageTag=v2.x.y" in the args argument.
+func UpgradeRancher(chart, version string, args ...string) error {
+	commandArgs := []string{
+		"upgrade",
+		RancherReleaseName,
+		chart,
+		"--namespace",
+		RancherNamespace,
+		"--reuse-values",
+		"--wait",
+	}
+
+	commandArgs = append(commandArgs, args...)
+
+	if version != "" {
+		commandArgs = append(commandArgs, "--version", version)
+	}
+
+	msg, err := exec.Command(helmCmd, commandArgs...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("UpgradeRancher: %w: %s", err, string(msg))
+	}
+
+	return nil
+}
+
+// RollbackRancher rolls the Rancher release back to the given revision using helm CLI.
+func RollbackRancher(revision int) error {
+	commandArgs := []string{
+		"rollback",
+		RancherReleaseName,
+		strconv.Itoa(revision),
+		"--namespace",
+		RancherNamespace,
+		"--wait",
+	}
+
+	msg, err := exec.Command(helmCmd, commandArgs...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("RollbackRancher: %w: %s", err, string(msg))
+	}
+
+	return nil
+}
+
+// GetRancherRevision returns the current revision of the Rancher release using helm CLI.
+func GetRancherRevision() (int, error) {
+	commandArgs := []string{
+		"status",
+		RancherReleaseName,
+		"--namespace",
+		RancherNamespace,
+		"--output",
+		"json",
+	}
+
+	msg, err := exec.Command(helmCmd, commandArgs...).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("GetRancherRevision: %w: %s", err, string(msg))
+	}
+
+	var release struct {
+		Version int `json:"version"`
+	}
+
+	err = json.Unmarshal(msg, &release)
+	if err != nil {
+		return 0, fmt.Errorf("GetRancherRevision: failed to unmarshal json: %w", err)
+	}
+
+	return release.Version, nil
+}
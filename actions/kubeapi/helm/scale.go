@@ -0,0 +1,73 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/shepherd/extensions/defaults"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RancherRESTConfig returns the rest config of the local cluster Rancher is installed on, loaded from the same
+// kubeconfig the helm CLI uses. It stays usable while the Rancher deployment is scaled down.
+func RancherRESTConfig() (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+
+	return clientConfig.ClientConfig()
+}
+
+// ScaleRancher scales the Rancher deployment to the given number of replicas and waits for the rollout to settle.
+// It returns the number of replicas the deployment had before scaling.
+func ScaleRancher(restConfig *rest.Config, replicas int32) (int32, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	deployments := clientset.AppsV1().Deployments(RancherNamespace)
+
+	scale, err := deployments.GetScale(context.TODO(), RancherReleaseName, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	previousReplicas := scale.Spec.Replicas
+	scale.Spec.Replicas = replicas
+
+	_, err = deployments.UpdateScale(context.TODO(), RancherReleaseName, scale, metav1.UpdateOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	err = kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := deployments.Get(ctx, RancherReleaseName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+
+		return isScaled(deployment, replicas), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ScaleRancher: deployment %s/%s did not reach %d replicas: %w", RancherNamespace, RancherReleaseName, replicas, err)
+	}
+
+	return previousReplicas, nil
+}
+
+func isScaled(deployment *appsv1.Deployment, replicas int32) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+
+	if replicas == 0 {
+		return deployment.Status.Replicas == 0
+	}
+
+	return deployment.Status.UpdatedReplicas == replicas && deployment.Status.ReadyReplicas == replicas
+}
@@ -0,0 +1,370 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	roleTemplateKind = "RoleTemplate"
+	globalRoleKind   = "GlobalRole"
+)
+
+// RoleGraph holds RoleTemplates and GlobalRoles by name, to resolve their inheritance offline
+type RoleGraph struct {
+	RoleTemplates map[string]*v3.RoleTemplate
+	GlobalRoles   map[string]*v3.GlobalRole
+}
+
+// EffectivePermissions are the rules a RoleTemplate grants once its inheritance is resolved
+type EffectivePermissions struct {
+	// Context is the context of the role template: cluster or project
+	Context string
+	// RoleTemplates lists the role template and every role template it inherits, in resolution order
+	RoleTemplates []string
+	// Depth is the length of the longest inheritance chain, 0 for a role template that inherits nothing
+	Depth int
+	// Rules are the rules of the aggregated cluster role, in the local and downstream clusters
+	Rules []rbacv1.PolicyRule
+	// ClusterManagementRules are the rules of the cluster management aggregated cluster role, in the local cluster.
+	// Only role templates of the cluster context have one.
+	ClusterManagementRules []rbacv1.PolicyRule
+	// ProjectManagementRules are the rules of the project management aggregated cluster role, in the local cluster
+	ProjectManagementRules []rbacv1.PolicyRule
+}
+
+// GlobalRolePermissions are the rules a GlobalRole grants once the role templates it applies to downstream clusters are resolved
+type GlobalRolePermissions struct {
+	// Rules are the rules granted in the local cluster
+	Rules []rbacv1.PolicyRule
+	// NamespacedRules are the rules granted in namespaces of the local cluster, by namespace
+	NamespacedRules map[string][]rbacv1.PolicyRule
+	// DownstreamRules are the rules granted in every downstream cluster
+	DownstreamRules []rbacv1.PolicyRule
+	// ClusterManagementRules are the cluster management rules granted for every downstream cluster
+	ClusterManagementRules []rbacv1.PolicyRule
+}
+
+// NewRoleGraph returns a role graph of the given role templates and global roles
+func NewRoleGraph(roleTemplates []*v3.RoleTemplate, globalRoles []*v3.GlobalRole) *RoleGraph {
+	graph := &RoleGraph{
+		RoleTemplates: map[string]*v3.RoleTemplate{},
+		GlobalRoles:   map[string]*v3.GlobalRole{},
+	}
+
+	for _, roleTemplate := range roleTemplates {
+		graph.RoleTemplates[roleTemplate.Name] = roleTemplate
+	}
+
+	for _, globalRole := range globalRoles {
+		graph.GlobalRoles[globalRole.Name] = globalRole
+	}
+
+	return graph
+}
+
+// GetRoleGraph returns a role graph of every RoleTemplate and GlobalRole of the Rancher server
+func GetRoleGraph(client *rancher.Client) (*RoleGraph, error) {
+	roleTemplateList, err := client.WranglerContext.Mgmt.RoleTemplate().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RoleTemplates: %w", err)
+	}
+
+	globalRoleList, err := client.WranglerContext.Mgmt.GlobalRole().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GlobalRoles: %w", err)
+	}
+
+	var roleTemplates []*v3.RoleTemplate
+	for i := range roleTemplateList.Items {
+		roleTemplates = append(roleTemplates, &roleTemplateList.Items[i])
+	}
+
+	var globalRoles []*v3.GlobalRole
+	for i := range globalRoleList.Items {
+		globalRoles = append(globalRoles, &globalRoleList.Items[i])
+	}
+
+	return NewRoleGraph(roleTemplates, globalRoles), nil
+}
+
+// LoadRoleGraph returns a role graph of the RoleTemplates and GlobalRoles of YAML or JSON fixture files.
+// A file may hold several documents, and documents of any other kind are ignored.
+func LoadRoleGraph(paths ...string) (*RoleGraph, error) {
+	graph := NewRoleGraph(nil, nil)
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = graph.decode(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load role fixtures %s: %w", path, err)
+		}
+	}
+
+	return graph, nil
+}
+
+func (g *RoleGraph) decode(reader io.Reader) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+
+	for {
+		object := map[string]any{}
+		err := decoder.Decode(&object)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch object["kind"] {
+		case roleTemplateKind:
+			roleTemplate := &v3.RoleTemplate{}
+			err = runtime.DefaultUnstructuredConverter.FromUnstructured(object, roleTemplate)
+			if err != nil {
+				return err
+			}
+
+			g.RoleTemplates[roleTemplate.Name] = roleTemplate
+		case globalRoleKind:
+			globalRole := &v3.GlobalRole{}
+			err = runtime.DefaultUnstructuredConverter.FromUnstructured(object, globalRole)
+			if err != nil {
+				return err
+			}
+
+			g.GlobalRoles[globalRole.Name] = globalRole
+		}
+	}
+}
+
+// ResolveInheritance returns the role template and every role template it inherits through roleTemplateNames, without
+// duplicates, and the length of the longest inheritance chain. It returns an error for cycles, missing role templates,
+// and role templates inheriting a role template of another context.
+func (g *RoleGraph) ResolveInheritance(roleTemplateName string) ([]string, int, error) {
+	var resolved []string
+	depths := map[string]int{}
+
+	var visit func(name string, path []string) (int, error)
+	visit = func(name string, path []string) (int, error) {
+		if slices.Contains(path, name) {
+			return 0, fmt.Errorf("inheritance cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+
+		if depth, ok := depths[name]; ok {
+			return depth, nil
+		}
+
+		roleTemplate, ok := g.RoleTemplates[name]
+		if !ok {
+			if len(path) == 0 {
+				return 0, fmt.Errorf("RoleTemplate %s not found", name)
+			}
+
+			return 0, fmt.Errorf("RoleTemplate %s inherited by %s not found", name, path[len(path)-1])
+		}
+
+		resolved = append(resolved, name)
+
+		depth := 0
+		for _, inheritedName := range roleTemplate.RoleTemplateNames {
+			inherited, ok := g.RoleTemplates[inheritedName]
+			if ok && inherited.Context != roleTemplate.Context {
+				return 0, fmt.Errorf("RoleTemplate %s of context %s inherits %s of context %s", name, roleTemplate.Context, inheritedName, inherited.Context)
+			}
+
+			inheritedDepth, err := visit(inheritedName, append(slices.Clone(path), name))
+			if err != nil {
+				return 0, err
+			}
+
+			depth = max(depth, inheritedDepth+1)
+		}
+
+		depths[name] = depth
+
+		return depth, nil
+	}
+
+	depth, err := visit(roleTemplateName, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resolved, depth, nil
+}
+
+// EffectiveRoleTemplatePermissions resolves the inheritance of the role template and returns the rules it grants.
+// The rules of external role templates are their external rules, when set. Management rules are split from the
+// other rules the same way the management aggregated cluster roles are verified.
+func (g *RoleGraph) EffectiveRoleTemplatePermissions(roleTemplateName string) (*EffectivePermissions, error) {
+	roleTemplateNames, depth, err := g.ResolveInheritance(roleTemplateName)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := &EffectivePermissions{
+		Context:       g.RoleTemplates[roleTemplateName].Context,
+		RoleTemplates: roleTemplateNames,
+		Depth:         depth,
+	}
+
+	for _, name := range roleTemplateNames {
+		permissions.Rules = appendUniqueRules(permissions.Rules, roleTemplateRules(g.RoleTemplates[name])...)
+	}
+
+	if permissions.Context == ClusterContext {
+		permissions.ClusterManagementRules = filterMgmtRules(permissions.Rules, ClusterContext)
+	}
+
+	permissions.ProjectManagementRules = filterMgmtRules(permissions.Rules, ProjectContext)
+
+	return permissions, nil
+}
+
+// EffectiveGlobalRolePermissions returns the rules the global role grants, resolving the inheritance of the role
+// templates it applies to every downstream cluster
+func (g *RoleGraph) EffectiveGlobalRolePermissions(globalRoleName string) (*GlobalRolePermissions, error) {
+	globalRole, ok := g.GlobalRoles[globalRoleName]
+	if !ok {
+		return nil, fmt.Errorf("GlobalRole %s not found", globalRoleName)
+	}
+
+	permissions := &GlobalRolePermissions{
+		Rules:           appendUniqueRules(nil, globalRole.Rules...),
+		NamespacedRules: map[string][]rbacv1.PolicyRule{},
+	}
+
+	for namespace, rules := range globalRole.NamespacedRules {
+		permissions.NamespacedRules[namespace] = appendUniqueRules(nil, rules...)
+	}
+
+	for _, roleTemplateName := range globalRole.InheritedClusterRoles {
+		roleTemplate, ok := g.RoleTemplates[roleTemplateName]
+		if ok && roleTemplate.Context != ClusterContext {
+			return nil, fmt.Errorf("GlobalRole %s inherits RoleTemplate %s of context %s", globalRoleName, roleTemplateName, roleTemplate.Context)
+		}
+
+		inherited, err := g.EffectiveRoleTemplatePermissions(roleTemplateName)
+		if err != nil {
+			return nil, fmt.Errorf("GlobalRole %s: %w", globalRoleName, err)
+		}
+
+		permissions.DownstreamRules = appendUniqueRules(permissions.DownstreamRules, inherited.Rules...)
+		permissions.ClusterManagementRules = appendUniqueRules(permissions.ClusterManagementRules, inherited.ClusterManagementRules...)
+	}
+
+	return permissions, nil
+}
+
+// VerifyEffectiveRoleTemplatePermissions verifies the aggregated cluster roles Rancher generated for the role template
+// in the local and the downstream cluster, and its management aggregated cluster roles in the local cluster, match
+// the permissions computed from the role graph
+func VerifyEffectiveRoleTemplatePermissions(client *rancher.Client, clusterID string, graph *RoleGraph, roleTemplateName string) error {
+	permissions, err := graph.EffectiveRoleTemplatePermissions(roleTemplateName)
+	if err != nil {
+		return err
+	}
+
+	type expectedACR struct {
+		clusterID string
+		name      string
+		rules     []rbacv1.PolicyRule
+	}
+
+	expected := []expectedACR{
+		{extclusterapi.LocalCluster, roleTemplateName + ResourceAggregator, permissions.Rules},
+		{clusterID, roleTemplateName + ResourceAggregator, permissions.Rules},
+		{extclusterapi.LocalCluster, roleTemplateName + ProjectMgmtResourceAggregator, permissions.ProjectManagementRules},
+	}
+
+	if permissions.Context == ClusterContext {
+		expected = append(expected, expectedACR{extclusterapi.LocalCluster, roleTemplateName + ClusterMgmtResourceAggregator, permissions.ClusterManagementRules})
+	}
+
+	var errs []error
+	for _, acr := range expected {
+		actualRules, err := GetClusterRoleRules(client, acr.clusterID, acr.name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", acr.clusterID, err))
+			continue
+		}
+
+		if !effectiveRulesMatch(actualRules, acr.rules) {
+			errs = append(errs, fmt.Errorf("ACR %s in cluster %s rules do not match the effective rules of %s.\nExpected: %+v\nActual: %+v", acr.name, acr.clusterID, strings.Join(permissions.RoleTemplates, ", "), acr.rules, actualRules))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// VerifyEffectiveGlobalRolePermissions verifies the aggregated cluster roles Rancher generated in the downstream cluster
+// for the role templates the global role inherits together grant the downstream permissions computed from the role graph
+func VerifyEffectiveGlobalRolePermissions(client *rancher.Client, clusterID string, graph *RoleGraph, globalRoleName string) error {
+	permissions, err := graph.EffectiveGlobalRolePermissions(globalRoleName)
+	if err != nil {
+		return err
+	}
+
+	var actualRules []rbacv1.PolicyRule
+	for _, roleTemplateName := range graph.GlobalRoles[globalRoleName].InheritedClusterRoles {
+		rules, err := GetClusterRoleRules(client, clusterID, roleTemplateName+ResourceAggregator)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", clusterID, err)
+		}
+
+		actualRules = appendUniqueRules(actualRules, rules...)
+	}
+
+	if !effectiveRulesMatch(actualRules, permissions.DownstreamRules) {
+		return fmt.Errorf("ACRs in cluster %s of the role templates inherited by GlobalRole %s do not match its effective downstream rules.\nExpected: %+v\nActual: %+v", clusterID, globalRoleName, permissions.DownstreamRules, actualRules)
+	}
+
+	return nil
+}
+
+func roleTemplateRules(roleTemplate *v3.RoleTemplate) []rbacv1.PolicyRule {
+	if roleTemplate.External && len(roleTemplate.ExternalRules) > 0 {
+		return roleTemplate.ExternalRules
+	}
+
+	return roleTemplate.Rules
+}
+
+// appendUniqueRules appends the rules that are not already in the slice, as the cluster role aggregation does
+func appendUniqueRules(rules []rbacv1.PolicyRule, newRules ...rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	for _, rule := range newRules {
+		if !slices.ContainsFunc(rules, func(existing rbacv1.PolicyRule) bool { return reflect.DeepEqual(existing, rule) }) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+func effectiveRulesMatch(actual, expected []rbacv1.PolicyRule) bool {
+	if len(actual) == 0 && len(expected) == 0 {
+		return true
+	}
+
+	return ruleSlicesMatch(actual, expected)
+}
@@ -0,0 +1,137 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var (
+	podsRule        = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}
+	crtbRule        = rbacv1.PolicyRule{APIGroups: []string{ManagementAPIGroup}, Resources: []string{"clusterroletemplatebindings"}, Verbs: []string{"get"}}
+	deploymentsRule = rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get"}}
+	secretsRule     = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}
+	configMapsRule  = rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}
+)
+
+// loadTestRoleGraph returns the role graph of every fixture of the testdata directory
+func loadTestRoleGraph(t *testing.T) *RoleGraph {
+	graph, err := LoadRoleGraph("testdata/roletemplates.yaml", "testdata/invalid_roletemplates.yaml", "testdata/globalroles.yaml")
+	require.NoError(t, err)
+
+	return graph
+}
+
+func TestLoadRoleGraph(t *testing.T) {
+	graph := loadTestRoleGraph(t)
+
+	assert.Len(t, graph.RoleTemplates, 9)
+	assert.Len(t, graph.GlobalRoles, 3)
+	assert.NotContains(t, graph.RoleTemplates, "ignored")
+	assert.Equal(t, []string{"cluster-mid", "cluster-base"}, graph.RoleTemplates["cluster-top"].RoleTemplateNames)
+
+	_, err := LoadRoleGraph("testdata/missing.yaml")
+	require.Error(t, err)
+}
+
+func TestResolveInheritance(t *testing.T) {
+	graph := loadTestRoleGraph(t)
+
+	tests := []struct {
+		name          string
+		roleTemplate  string
+		expected      []string
+		expectedDepth int
+		expectedErr   string
+	}{
+		{"No_Inheritance", "cluster-base", []string{"cluster-base"}, 0, ""},
+		{"Cluster_Chain", "cluster-top", []string{"cluster-top", "cluster-mid", "cluster-base"}, 2, ""},
+		{"Project_Chain", "project-external", []string{"project-external", "project-base"}, 1, ""},
+		{"Cycle", "cycle-a", nil, 0, "inheritance cycle: cycle-a -> cycle-b -> cycle-a"},
+		{"Missing_Role_Template", "absent", nil, 0, "RoleTemplate absent not found"},
+		{"Missing_Inherited_Role_Template", "orphan", nil, 0, "RoleTemplate missing-template inherited by orphan not found"},
+		{"Cross_Context", "project-cross", nil, 0, "RoleTemplate project-cross of context project inherits cluster-base of context cluster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, depth, err := graph.ResolveInheritance(tt.roleTemplate)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resolved)
+			assert.Equal(t, tt.expectedDepth, depth)
+		})
+	}
+}
+
+func TestEffectiveRoleTemplatePermissions(t *testing.T) {
+	graph := loadTestRoleGraph(t)
+
+	tests := []struct {
+		name                     string
+		roleTemplate             string
+		expectedRules            []rbacv1.PolicyRule
+		expectedClusterMgmtRules []rbacv1.PolicyRule
+		expectedProjectMgmtRules []rbacv1.PolicyRule
+		expectedErr              string
+	}{
+		{
+			name:                     "Cluster_Context",
+			roleTemplate:             "cluster-top",
+			expectedRules:            []rbacv1.PolicyRule{podsRule, deploymentsRule, crtbRule},
+			expectedClusterMgmtRules: []rbacv1.PolicyRule{crtbRule},
+		},
+		{
+			name:                     "External_Rules",
+			roleTemplate:             "project-external",
+			expectedRules:            []rbacv1.PolicyRule{configMapsRule, secretsRule},
+			expectedProjectMgmtRules: []rbacv1.PolicyRule{secretsRule},
+		},
+		{
+			name:         "Cycle",
+			roleTemplate: "cycle-b",
+			expectedErr:  "inheritance cycle: cycle-b -> cycle-a -> cycle-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissions, err := graph.EffectiveRoleTemplatePermissions(tt.roleTemplate)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRules, permissions.Rules)
+			assert.Equal(t, tt.expectedClusterMgmtRules, permissions.ClusterManagementRules)
+			assert.Equal(t, tt.expectedProjectMgmtRules, permissions.ProjectManagementRules)
+		})
+	}
+}
+
+func TestEffectiveGlobalRolePermissions(t *testing.T) {
+	graph := loadTestRoleGraph(t)
+
+	permissions, err := graph.EffectiveGlobalRolePermissions("cluster-viewer")
+	require.NoError(t, err)
+	assert.Len(t, permissions.Rules, 1)
+	assert.Len(t, permissions.NamespacedRules["fleet-default"], 1)
+	assert.Equal(t, []rbacv1.PolicyRule{podsRule, deploymentsRule, crtbRule}, permissions.DownstreamRules)
+	assert.Equal(t, []rbacv1.PolicyRule{crtbRule}, permissions.ClusterManagementRules)
+
+	_, err = graph.EffectiveGlobalRolePermissions("project-viewer")
+	require.EqualError(t, err, "GlobalRole project-viewer inherits RoleTemplate project-base of context project")
+
+	_, err = graph.EffectiveGlobalRolePermissions("cycle-viewer")
+	require.EqualError(t, err, "GlobalRole cycle-viewer: inheritance cycle: cycle-a -> cycle-b -> cycle-a")
+
+	_, err = graph.EffectiveGlobalRolePermissions("absent")
+	require.EqualError(t, err, "GlobalRole absent not found")
+}
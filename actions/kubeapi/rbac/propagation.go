@@ -0,0 +1,184 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// BindingChange describes the bindings Rancher generates in a downstream cluster for a CRTB or a PRTB. A CRTB
+// generates a ClusterRoleBinding, and a PRTB a RoleBinding in every namespace of the project.
+type BindingChange struct {
+	UserName         string
+	RoleTemplateName string
+	BindingName      string
+	// Namespaces are the namespaces of the project of a PRTB, and are empty for a CRTB
+	Namespaces []string
+}
+
+// PropagationResult is the outcome of a binding change in a single downstream cluster
+type PropagationResult struct {
+	ClusterID string
+	// Latency is the time between the change and the moment the downstream cluster matched it
+	Latency time.Duration
+	// Unreachable is true if the downstream cluster couldn't be reached at least once while waiting
+	Unreachable bool
+	Err         error
+}
+
+// MeasureBindingPropagation waits for the bindings of the change to exist in every downstream cluster, with the role
+// refs returned by expectedRoleNames, and returns the latency of every cluster measured from the time of the change
+func MeasureBindingPropagation(client *rancher.Client, change BindingChange, clusterIDs []string, changedAt time.Time, timeout time.Duration) []PropagationResult {
+	return measureClusters(clusterIDs, changedAt, timeout, func(clusterID string) (bool, error) {
+		count, err := countGeneratedBindings(client, clusterID, change, true)
+		if err != nil {
+			return false, err
+		}
+
+		expectedCount := len(change.Namespaces)
+		if expectedCount == 0 {
+			expectedCount = 1
+		}
+
+		return count == expectedCount, nil
+	})
+}
+
+// MeasureBindingRemoval waits for every binding of the role template and user to be removed from every downstream
+// cluster, and returns the latency of every cluster measured from the time of the change. Unreachable clusters,
+// such as clusters disconnected during the change, are polled until they are back or the timeout expires.
+func MeasureBindingRemoval(client *rancher.Client, change BindingChange, clusterIDs []string, changedAt time.Time, timeout time.Duration) []PropagationResult {
+	return measureClusters(clusterIDs, changedAt, timeout, func(clusterID string) (bool, error) {
+		count, err := countGeneratedBindings(client, clusterID, change, false)
+		if err != nil {
+			return false, err
+		}
+
+		return count == 0, nil
+	})
+}
+
+// VerifyPropagationResults logs the latency of every downstream cluster and returns the errors of the clusters
+// that didn't match the change, or matched it after the maximum latency when it is not zero
+func VerifyPropagationResults(results []PropagationResult, maxLatency time.Duration) error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", result.ClusterID, result.Err))
+			continue
+		}
+
+		logrus.Infof("Binding change reached cluster %s after %s (unreachable while waiting: %t)", result.ClusterID, result.Latency.Round(time.Millisecond), result.Unreachable)
+		if maxLatency > 0 && result.Latency > maxLatency {
+			errs = append(errs, fmt.Errorf("cluster %s: binding change took %s, more than %s", result.ClusterID, result.Latency, maxLatency))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForClusterUnreachable waits for the downstream cluster to stop answering through Rancher, such as after its
+// cluster agent was scaled down
+func WaitForClusterUnreachable(client *rancher.Client, clusterID string, timeout time.Duration) error {
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, timeout, true, func(ctx context.Context) (done bool, err error) {
+		_, err = ListClusterRoleBindings(client, clusterID, metav1.ListOptions{Limit: 1})
+
+		return err != nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("cluster %s is still reachable after %s: %w", clusterID, timeout, err)
+	}
+
+	return nil
+}
+
+func measureClusters(clusterIDs []string, changedAt time.Time, timeout time.Duration, matches func(clusterID string) (bool, error)) []PropagationResult {
+	results := make([]PropagationResult, len(clusterIDs))
+
+	var wg sync.WaitGroup
+	for i, clusterID := range clusterIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := PropagationResult{ClusterID: clusterID}
+			var lastErr error
+
+			err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveHundredMillisecondTimeout, timeout, true, func(ctx context.Context) (done bool, err error) {
+				matched, err := matches(clusterID)
+				if err != nil {
+					result.Unreachable = true
+					lastErr = err
+					return false, nil
+				}
+
+				return matched, nil
+			})
+			result.Latency = time.Since(changedAt)
+			if err != nil {
+				result.Err = fmt.Errorf("binding change not matched after %s: %w", timeout, errors.Join(err, lastErr))
+			}
+
+			results[i] = result
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// countGeneratedBindings returns the number of bindings of the user in the downstream cluster for the role template
+// of the change. When exactRoleRef is true, only bindings to the role names returned by expectedRoleNames are
+// counted, otherwise any binding to a role starting with the role template name is.
+func countGeneratedBindings(client *rancher.Client, clusterID string, change BindingChange, exactRoleRef bool) (int, error) {
+	expectedNames := expectedRoleNames(clusterID, change.BindingName, change.RoleTemplateName, 1)
+	matchesRoleRef := func(roleRefName string) bool {
+		if exactRoleRef {
+			return slices.Contains(expectedNames, roleRefName)
+		}
+
+		return strings.HasPrefix(roleRefName, change.RoleTemplateName)
+	}
+
+	count := 0
+	if len(change.Namespaces) == 0 {
+		clusterRoleBindings, err := ListClusterRoleBindings(client, clusterID, metav1.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, clusterRoleBinding := range filterClusterRoleBindings(clusterRoleBindings, change.UserName, change.RoleTemplateName) {
+			if matchesRoleRef(clusterRoleBinding.RoleRef.Name) {
+				count++
+			}
+		}
+
+		return count, nil
+	}
+
+	for _, namespace := range change.Namespaces {
+		roleBindings, err := ListRoleBindings(client, clusterID, namespace, metav1.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, roleBinding := range filterRoleBindings(roleBindings, change.UserName, change.RoleTemplateName) {
+			if matchesRoleRef(roleBinding.RoleRef.Name) {
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
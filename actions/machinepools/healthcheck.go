@@ -0,0 +1,157 @@
+package machinepools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	wranglername "github.com/rancher/wrangler/pkg/name"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+)
+
+const (
+	machineHealthCheckSteveType = "cluster.x-k8s.io.machinehealthcheck"
+)
+
+// UpdateMachinePoolHealthCheck sets the unhealthy node timeout and the max unhealthy machines of the machine pool of
+// the machine, and waits for the machine health check of the pool to carry the new settings. The pool is resolved
+// from the machine deployment label of the machine. An empty maxUnhealthy keeps the default of the pool. The previous
+// settings of the pool are restored when the client session is cleaned up.
+func UpdateMachinePoolHealthCheck(client *rancher.Client, cluster *v1.SteveAPIObject, machine *v1.SteveAPIObject, unhealthyNodeTimeout time.Duration, maxUnhealthy string) (*v1.SteveAPIObject, error) {
+	updateCluster, err := client.Steve.SteveType(stevetypes.Provisioning).ByID(cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedCluster := new(apisV1.Cluster)
+	err = v1.ConvertToK8sType(updateCluster, &updatedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := machineMachinePool(updatedCluster, machine)
+	if err != nil {
+		return nil, err
+	}
+
+	previousTimeout, previousMaxUnhealthy := pool.UnhealthyNodeTimeout, pool.MaxUnhealthy
+	pool.UnhealthyNodeTimeout = &metav1.Duration{Duration: unhealthyNodeTimeout}
+	if maxUnhealthy != "" {
+		pool.MaxUnhealthy = &maxUnhealthy
+	}
+
+	logrus.Infof("Setting the unhealthy node timeout of machine pool %s to %s", pool.Name, unhealthyNodeTimeout)
+	cluster, err = client.Steve.SteveType(stevetypes.Provisioning).Update(updateCluster, updatedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	poolName := pool.Name
+	client.Session.RegisterCleanupFunc(func() error {
+		return restoreMachinePoolHealthCheck(client, cluster.ID, poolName, previousTimeout, previousMaxUnhealthy)
+	})
+
+	healthCheckID := fleetNamespace + "/" + wranglername.SafeConcatName(updatedCluster.Name, poolName)
+
+	var healthCheckErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), 5*time.Second, defaults.FiveMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+		healthCheck, err := client.Steve.SteveType(machineHealthCheckSteveType).ByID(healthCheckID)
+		if err != nil {
+			healthCheckErr = err
+			return false, nil
+		}
+
+		healthCheckErr = verifyMachineHealthCheck(healthCheck, unhealthyNodeTimeout, maxUnhealthy)
+
+		return healthCheckErr == nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("machine health check %s was not updated: %w", healthCheckID, errors.Join(err, healthCheckErr))
+	}
+
+	return cluster, nil
+}
+
+// machineMachinePool returns the machine pool of the cluster the machine belongs to, by its machine deployment label
+func machineMachinePool(cluster *apisV1.Cluster, machine *v1.SteveAPIObject) (*apisV1.RKEMachinePool, error) {
+	deploymentName := machine.Labels[capi.MachineDeploymentNameLabel]
+	if deploymentName == "" {
+		return nil, fmt.Errorf("machine %s has no %s label", machine.Name, capi.MachineDeploymentNameLabel)
+	}
+
+	for i := range cluster.Spec.RKEConfig.MachinePools {
+		pool := &cluster.Spec.RKEConfig.MachinePools[i]
+		if wranglername.SafeConcatName(cluster.Name, pool.Name) == deploymentName {
+			return pool, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no machine pool of cluster %s matches machine deployment %s", cluster.Name, deploymentName)
+}
+
+// restoreMachinePoolHealthCheck sets the unhealthy node timeout and the max unhealthy machines of the machine pool
+// back to the given values
+func restoreMachinePoolHealthCheck(client *rancher.Client, clusterID, poolName string, unhealthyNodeTimeout *metav1.Duration, maxUnhealthy *string) error {
+	updateCluster, err := client.Steve.SteveType(stevetypes.Provisioning).ByID(clusterID)
+	if err != nil {
+		return err
+	}
+
+	updatedCluster := new(apisV1.Cluster)
+	err = v1.ConvertToK8sType(updateCluster, &updatedCluster)
+	if err != nil {
+		return err
+	}
+
+	for i := range updatedCluster.Spec.RKEConfig.MachinePools {
+		pool := &updatedCluster.Spec.RKEConfig.MachinePools[i]
+		if pool.Name != poolName {
+			continue
+		}
+
+		logrus.Infof("Restoring the health check settings of machine pool %s", poolName)
+		pool.UnhealthyNodeTimeout = unhealthyNodeTimeout
+		pool.MaxUnhealthy = maxUnhealthy
+
+		_, err = client.Steve.SteveType(stevetypes.Provisioning).Update(updateCluster, updatedCluster)
+		return err
+	}
+
+	return fmt.Errorf("machine pool %s not found in cluster %s", poolName, clusterID)
+}
+
+// verifyMachineHealthCheck verifies the machine health check carries the unhealthy node timeout on every unhealthy
+// node condition, and the max unhealthy machines when one is given.
+func verifyMachineHealthCheck(healthCheck *v1.SteveAPIObject, unhealthyNodeTimeout time.Duration, maxUnhealthy string) error {
+	spec := new(capi.MachineHealthCheckSpec)
+	err := v1.ConvertToK8sType(healthCheck.Spec, spec)
+	if err != nil {
+		return err
+	}
+
+	if len(spec.Checks.UnhealthyNodeConditions) == 0 {
+		return errors.New("no unhealthy node conditions are set")
+	}
+
+	for _, condition := range spec.Checks.UnhealthyNodeConditions {
+		if condition.TimeoutSeconds == nil || time.Duration(*condition.TimeoutSeconds)*time.Second != unhealthyNodeTimeout {
+			return fmt.Errorf("unhealthy node condition %s=%s does not have a timeout of %s", condition.Type, condition.Status, unhealthyNodeTimeout)
+		}
+	}
+
+	unhealthyLimit := spec.Remediation.TriggerIf.UnhealthyLessThanOrEqualTo
+	if maxUnhealthy != "" && (unhealthyLimit == nil || unhealthyLimit.String() != maxUnhealthy) {
+		return fmt.Errorf("max unhealthy is %v, expected %s", unhealthyLimit, maxUnhealthy)
+	}
+
+	return nil
+}
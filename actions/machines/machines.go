@@ -53,3 +53,12 @@ func GetMachinesByRole(client *rancher.Client, cluster *v1.SteveAPIObject, role
 
 	return matchingMachines, nil
 }
+
+// GetMachineNodeRoles returns the roles of a machine, as set by the role labels of its machine pool.
+func GetMachineNodeRoles(machine *v1.SteveAPIObject) machinepools.NodeRoles {
+	return machinepools.NodeRoles{
+		Etcd:         machine.Labels[etcdLabel] == "true",
+		ControlPlane: machine.Labels[controlLabel] == "true",
+		Worker:       machine.Labels[workerLabel] == "true",
+	}
+}
@@ -13,6 +13,10 @@ import (
 	kwait "k8s.io/apimachinery/pkg/util/wait"
 )
 
+const (
+	machineDeploymentLabel = "cluster.x-k8s.io/deployment-name"
+)
+
 // VerifyMachineReplacement verifies that a machine has been successfully replaced by checking for its deletion.
 func VerifyMachineReplacement(client *rancher.Client, replacedMachine *v1.SteveAPIObject) error {
 	err := kwait.PollUntilContextTimeout(context.TODO(), 5*time.Second, defaults.TenMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
@@ -31,3 +35,45 @@ func VerifyMachineReplacement(client *rancher.Client, replacedMachine *v1.SteveA
 
 	return nil
 }
+
+// VerifyUnhealthyMachineReplaced verifies that a replacement machine is created in the machine pool of an unhealthy
+// machine within the timeout, and that the unhealthy machine is then removed. The machine must not have been deleted.
+func VerifyUnhealthyMachineReplaced(client *rancher.Client, unhealthyMachine *v1.SteveAPIObject, timeout time.Duration) (*v1.SteveAPIObject, error) {
+	poolLabel := unhealthyMachine.Labels[machineDeploymentLabel]
+	if poolLabel == "" {
+		return nil, fmt.Errorf("machine %s does not belong to a machine pool", unhealthyMachine.Name)
+	}
+
+	var replacement *v1.SteveAPIObject
+	err := kwait.PollUntilContextTimeout(context.TODO(), 5*time.Second, timeout, true, func(ctx context.Context) (done bool, err error) {
+		machineList, err := client.Steve.SteveType(stevetypes.Machine).NamespacedSteveClient(unhealthyMachine.Namespace).List(nil)
+		if err != nil {
+			return false, nil
+		}
+
+		for _, machine := range machineList.Data {
+			if machine.Labels[machineDeploymentLabel] != poolLabel || machine.Name == unhealthyMachine.Name {
+				continue
+			}
+
+			if machine.CreationTimestamp.After(unhealthyMachine.CreationTimestamp.Time) {
+				logrus.Infof("Machine %s was created to replace unhealthy machine %s", machine.Name, unhealthyMachine.Name)
+				replacement = &machine
+
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no replacement for unhealthy machine %s was created within %s: %w", unhealthyMachine.Name, timeout, err)
+	}
+
+	err = VerifyMachineReplacement(client, unhealthyMachine)
+	if err != nil {
+		return nil, err
+	}
+
+	return replacement, nil
+}
@@ -0,0 +1,439 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	GlobalScope    = "global"
+	ClusterScope   = "cluster"
+	ProjectScope   = "project"
+	NamespaceScope = "namespace"
+
+	authenticatedGroup       = "system:authenticated"
+	cattleAuthenticatedGroup = "system:cattle:authenticated"
+)
+
+// SubjectAccessReviewGroupVersionResource is the required Group Version Resource for creating subject access reviews in a cluster, using the dynamic client.
+var SubjectAccessReviewGroupVersionResource = schema.GroupVersionResource{
+	Group:    authorizationv1.SchemeGroupVersion.Group,
+	Version:  authorizationv1.SchemeGroupVersion.Version,
+	Resource: "subjectaccessreviews",
+}
+
+// PermissionMatrix is a table of the verbs each role is expected to be allowed or denied on a resource, at a scope
+type PermissionMatrix struct {
+	Rules []PermissionRule `json:"rules" yaml:"rules"`
+}
+
+// PermissionRule is a row of a permission matrix. The scope sets both how the role is granted and where the verbs are evaluated:
+//   - global: the role is a global role, evaluated cluster wide in the local cluster
+//   - cluster: the role is a role template bound to the cluster, evaluated cluster wide in the cluster, or in the
+//     namespace of the cluster in the local cluster when localCluster is set
+//   - project: the role is a role template bound to the project, evaluated in the namespace of the project in the local cluster
+//   - namespace: the role is a role template bound to the project, evaluated in a namespace of the project in the cluster
+type PermissionRule struct {
+	Role         Role     `json:"role" yaml:"role"`
+	Scope        string   `json:"scope" yaml:"scope"`
+	Group        string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Version      string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Resource     string   `json:"resource" yaml:"resource"`
+	LocalCluster bool     `json:"localCluster,omitempty" yaml:"localCluster,omitempty"`
+	Allowed      []string `json:"allowed,omitempty" yaml:"allowed,omitempty"`
+	Denied       []string `json:"denied,omitempty" yaml:"denied,omitempty"`
+}
+
+// MatrixTarget is the cluster, project and namespace the role templates of a permission matrix are bound to and evaluated in
+type MatrixTarget struct {
+	Cluster   *management.Cluster
+	Project   *v3.Project
+	Namespace string
+}
+
+// MatrixResult is the outcome of a verb of a permission rule. APIAllowed is nil when the verb was only evaluated
+// through a SubjectAccessReview, because it has no API call or the API call was inconclusive.
+type MatrixResult struct {
+	Role       Role
+	Scope      string
+	Resource   string
+	Verb       string
+	Expected   bool
+	SARAllowed bool
+	APIAllowed *bool
+	Err        error
+}
+
+// Mismatch returns true if the SubjectAccessReview or the API call didn't match the expected result, or failed
+func (r MatrixResult) Mismatch() bool {
+	if r.Err != nil || r.SARAllowed != r.Expected {
+		return true
+	}
+
+	return r.APIAllowed != nil && *r.APIAllowed != r.Expected
+}
+
+// LoadPermissionMatrix reads a permission matrix from a YAML file
+func LoadPermissionMatrix(path string) (*PermissionMatrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &PermissionMatrix{}
+	err = yaml.UnmarshalStrict(data, matrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse permission matrix %s: %w", path, err)
+	}
+
+	for i, rule := range matrix.Rules {
+		if !slices.Contains([]string{GlobalScope, ClusterScope, ProjectScope, NamespaceScope}, rule.Scope) {
+			return nil, fmt.Errorf("rule %d of %s has unknown scope %q", i, path, rule.Scope)
+		}
+
+		if rule.Role == "" || rule.Resource == "" {
+			return nil, fmt.Errorf("rule %d of %s needs a role and a resource", i, path)
+		}
+	}
+
+	return matrix, nil
+}
+
+// VerifyPermissionMatrix evaluates the permission matrix and returns an error with the grid of mismatches, if any
+func VerifyPermissionMatrix(client *rancher.Client, matrix *PermissionMatrix, target *MatrixTarget) error {
+	results, err := EvaluatePermissionMatrix(client, matrix, target)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(results, MatrixResult.Mismatch) {
+		return nil
+	}
+
+	return fmt.Errorf("permission matrix mismatches:\n%s", FormatMismatches(results))
+}
+
+// EvaluatePermissionMatrix creates a user for every role and scope of the matrix, and evaluates every verb of its
+// rules through a SubjectAccessReview impersonating the user and through an API call made as the user. Verbs
+// expected to be allowed are retried for a minute, as bindings take time to reach the downstream cluster.
+func EvaluatePermissionMatrix(client *rancher.Client, matrix *PermissionMatrix, target *MatrixTarget) ([]MatrixResult, error) {
+	users := map[string]*management.User{}
+	userClients := map[string]*rancher.Client{}
+
+	var results []MatrixResult
+	for _, rule := range matrix.Rules {
+		userKey := rule.Role.String()
+		if rule.Scope == GlobalScope {
+			userKey = GlobalScope + "/" + userKey
+		}
+
+		if _, ok := users[userKey]; !ok {
+			user, userClient, err := setupMatrixUser(client, rule, target)
+			if err != nil {
+				return nil, err
+			}
+
+			users[userKey] = user
+			userClients[userKey] = userClient
+		}
+
+		ruleResults, err := evaluatePermissionRule(client, userClients[userKey], users[userKey], rule, target)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, ruleResults...)
+	}
+
+	return results, nil
+}
+
+// FormatMismatches returns a grid of the rules with a mismatch, with a column per verb. Cells show "ok" when the
+// verb matched, "ALLOWED" or "DENIED" with the check that got it wrong when it didn't, and "ERROR" when it failed.
+func FormatMismatches(results []MatrixResult) string {
+	var verbs []string
+	var rows []string
+	cells := map[string]map[string]string{}
+
+	for _, result := range results {
+		row := fmt.Sprintf("%s\t%s\t%s", result.Role, result.Scope, result.Resource)
+		if !slices.Contains(verbs, result.Verb) {
+			verbs = append(verbs, result.Verb)
+		}
+
+		if _, ok := cells[row]; !ok {
+			cells[row] = map[string]string{}
+		}
+
+		cells[row][result.Verb] = formatCell(result)
+
+		if result.Mismatch() && !slices.Contains(rows, row) {
+			rows = append(rows, row)
+		}
+	}
+
+	builder := &strings.Builder{}
+	writer := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "ROLE\tSCOPE\tRESOURCE\t%s\n", strings.ToUpper(strings.Join(verbs, "\t")))
+
+	for _, row := range rows {
+		var rowCells []string
+		for _, verb := range verbs {
+			cell, ok := cells[row][verb]
+			if !ok {
+				cell = "-"
+			}
+
+			rowCells = append(rowCells, cell)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\n", row, strings.Join(rowCells, "\t"))
+	}
+
+	writer.Flush()
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(builder, "%s %s %s %s: %v\n", result.Role, result.Scope, result.Resource, result.Verb, result.Err)
+		}
+	}
+
+	return builder.String()
+}
+
+func formatCell(result MatrixResult) string {
+	if result.Err != nil {
+		return "ERROR"
+	}
+
+	if !result.Mismatch() {
+		return "ok"
+	}
+
+	outcome := "DENIED"
+	if !result.Expected {
+		outcome = "ALLOWED"
+	}
+
+	var checks []string
+	if result.SARAllowed != result.Expected {
+		checks = append(checks, "sar")
+	}
+
+	if result.APIAllowed != nil && *result.APIAllowed != result.Expected {
+		checks = append(checks, "api")
+	}
+
+	return fmt.Sprintf("%s(%s)", outcome, strings.Join(checks, ","))
+}
+
+// setupMatrixUser creates a user with the global role of a global rule, or a standard user bound to the role template of the rule
+func setupMatrixUser(client *rancher.Client, rule PermissionRule, target *MatrixTarget) (*management.User, *rancher.Client, error) {
+	if rule.Scope == GlobalScope {
+		logrus.Infof("Creating a user with global role %s", rule.Role)
+		return SetupUser(client, rule.Role.String())
+	}
+
+	logrus.Infof("Creating a user with role template %s", rule.Role)
+	return AddUserWithRoleToCluster(client, StandardUser.String(), rule.Role.String(), target.Cluster, target.Project)
+}
+
+func evaluatePermissionRule(client, userClient *rancher.Client, user *management.User, rule PermissionRule, target *MatrixTarget) ([]MatrixResult, error) {
+	clusterID, namespace := ruleLocation(rule, target)
+
+	adminDynamicClient, err := client.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	userDynamicClient, err := userClient.GetDownStreamClusterClient(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	gvr := schema.GroupVersionResource{Group: rule.Group, Version: rule.Version, Resource: rule.Resource}
+	if gvr.Version == "" {
+		gvr.Version = "v1"
+		if gvr.Group == rbacapi.ManagementAPIGroup {
+			gvr.Version = rbacapi.Version
+		}
+	}
+
+	var results []MatrixResult
+	for _, verb := range append(slices.Clone(rule.Allowed), rule.Denied...) {
+		result := MatrixResult{
+			Role:     rule.Role,
+			Scope:    rule.Scope,
+			Resource: rule.Resource,
+			Verb:     verb,
+			Expected: slices.Contains(rule.Allowed, verb),
+		}
+
+		evaluate := func() {
+			result.Err = nil
+			result.SARAllowed, result.Err = subjectAccessReview(adminDynamicClient, user.ID, verb, gvr, namespace)
+			if result.Err != nil {
+				return
+			}
+
+			result.APIAllowed, result.Err = apiAccess(userDynamicClient, verb, gvr, namespace)
+		}
+
+		evaluate()
+		if result.Expected && result.Mismatch() {
+			_ = kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+				evaluate()
+				return !result.Mismatch(), nil
+			})
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ruleLocation returns the cluster and the namespace the verbs of the rule are evaluated in
+func ruleLocation(rule PermissionRule, target *MatrixTarget) (string, string) {
+	switch rule.Scope {
+	case GlobalScope:
+		return extclusterapi.LocalCluster, ""
+	case ClusterScope:
+		if rule.LocalCluster {
+			return extclusterapi.LocalCluster, target.Cluster.ID
+		}
+
+		return target.Cluster.ID, ""
+	case ProjectScope:
+		if target.Project.Status.BackingNamespace != "" {
+			return extclusterapi.LocalCluster, fmt.Sprintf("%s-%s", target.Project.Spec.ClusterName, target.Project.Name)
+		}
+
+		return extclusterapi.LocalCluster, target.Project.Name
+	}
+
+	return target.Cluster.ID, target.Namespace
+}
+
+// subjectAccessReview returns whether the user is allowed the verb on the resource, as reviewed by the cluster with the groups Rancher impersonates
+func subjectAccessReview(dynamicClient dynamic.Interface, userID, verb string, gvr schema.GroupVersionResource, namespace string) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: authorizationv1.SchemeGroupVersion.String(),
+			Kind:       "SubjectAccessReview",
+		},
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userID,
+			Groups: []string{authenticatedGroup, cattleAuthenticatedGroup},
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Resource:  gvr.Resource,
+			},
+		},
+	}
+
+	unstructuredReview, err := runtime.DefaultUnstructuredConverter.ToUnstructured(review)
+	if err != nil {
+		return false, err
+	}
+
+	createdReview, err := dynamicClient.Resource(SubjectAccessReviewGroupVersionResource).Create(context.TODO(), &unstructured.Unstructured{Object: unstructuredReview}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to create subject access review: %w", err)
+	}
+
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(createdReview.Object, review)
+	if err != nil {
+		return false, err
+	}
+
+	return review.Status.Allowed, nil
+}
+
+// apiAccess makes a dry run request for the verb on an object that doesn't exist, and returns whether the request got
+// past authorization. It returns nil for verbs without an API call, and for responses that don't tell either way.
+func apiAccess(dynamicClient dynamic.Interface, verb string, gvr schema.GroupVersionResource, namespace string) (*bool, error) {
+	resource := dynamicClient.Resource(gvr).Namespace(namespace)
+	name := namegen.AppendRandomString("rbac-matrix")
+	dryRun := []string{metav1.DryRunAll}
+
+	var err error
+	switch verb {
+	case "get":
+		_, err = resource.Get(context.TODO(), name, metav1.GetOptions{})
+	case "list":
+		_, err = resource.List(context.TODO(), metav1.ListOptions{Limit: 1})
+	case "watch":
+		var watchInterface watch.Interface
+		watchInterface, err = resource.Watch(context.TODO(), metav1.ListOptions{TimeoutSeconds: &defaults.WatchTimeoutSeconds})
+		if err == nil {
+			watchInterface.Stop()
+		}
+	case "create":
+		object := &unstructured.Unstructured{}
+		object.SetName(name)
+		object.SetNamespace(namespace)
+		_, err = resource.Create(context.TODO(), object, metav1.CreateOptions{DryRun: dryRun})
+	case "update":
+		object := &unstructured.Unstructured{}
+		object.SetName(name)
+		object.SetNamespace(namespace)
+		_, err = resource.Update(context.TODO(), object, metav1.UpdateOptions{DryRun: dryRun})
+	case "patch":
+		_, err = resource.Patch(context.TODO(), name, types.MergePatchType, []byte("{}"), metav1.PatchOptions{DryRun: dryRun})
+	case "delete":
+		err = resource.Delete(context.TODO(), name, metav1.DeleteOptions{DryRun: dryRun})
+	default:
+		return nil, nil
+	}
+
+	allowed := true
+	denied := false
+
+	switch {
+	case err == nil:
+		return &allowed, nil
+	case apierrors.IsForbidden(err):
+		return &denied, nil
+	case apierrors.IsNotFound(err):
+		// only a missing object proves the request was authorized, an unknown resource path is also not found
+		statusErr := &apierrors.StatusError{}
+		if errors.As(err, &statusErr) && statusErr.ErrStatus.Details != nil && statusErr.ErrStatus.Details.Name == name {
+			return &allowed, nil
+		}
+
+		return nil, nil
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err), apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		return &allowed, nil
+	}
+
+	return nil, fmt.Errorf("%s %s: %w", verb, gvr.Resource, err)
+}
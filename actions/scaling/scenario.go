@@ -0,0 +1,503 @@
+package scaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	apisV1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	extensionsworkloads "github.com/rancher/shepherd/extensions/workloads"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeapi/workloads/deployments"
+	"github.com/rancher/tests/actions/kubeapi/workloads/pods"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+)
+
+const (
+	machineDeploymentSteveType = "cluster.x-k8s.io.machinedeployment"
+	minSizeAnnotation          = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	maxSizeAnnotation          = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+	workloadSelectorLabel      = "workload.user.cattle.io/workloadselector"
+	hostnameLabel              = "kubernetes.io/hostname"
+	workerRoleLabel            = "node-role.kubernetes.io/worker"
+
+	notTriggerScaleUpReason = "NotTriggerScaleUp"
+	maxSizeReachedMessage   = "max node group size reached"
+
+	// deletionCandidateTaint is the soft taint the cluster autoscaler puts on the nodes it considers unneeded, and
+	// toBeDeletedTaint the one it puts on the nodes it is draining before removal
+	deletionCandidateTaint = "DeletionCandidateOfClusterAutoscaler"
+	toBeDeletedTaint       = "ToBeDeletedByClusterAutoscaler"
+
+	// pendingPodCPUPercent is the share of the allocatable CPU of a worker requested by every pending pod, so that
+	// two pending pods never fit on the same node
+	pendingPodCPUPercent = 60
+)
+
+// PoolBounds are the autoscaling bounds of a machine pool, as annotated on its machine deployment
+type PoolBounds struct {
+	Name     string
+	Min      int32
+	Max      int32
+	Quantity int32
+}
+
+// ScaleResult is the outcome of a scale up or scale down of a machine pool
+type ScaleResult struct {
+	From     int32
+	To       int32
+	Duration time.Duration
+}
+
+// PendingWorkload is a deployment whose pods can only be scheduled on nodes added after its creation
+type PendingWorkload struct {
+	ClusterID string
+	Namespace string
+	Name      string
+	Replicas  int32
+}
+
+// GetAutoscalerPoolBounds returns the bounds of the first autoscaled worker pool of the cluster. It verifies the
+// min and max sizes of the pool are annotated on its machine deployment, as read by the cluster autoscaler.
+func GetAutoscalerPoolBounds(client *rancher.Client, cluster *v1.SteveAPIObject) (*PoolBounds, error) {
+	cluster, err := client.Steve.SteveType(stevetypes.Provisioning).ByID(cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscalerMachinePools, err := getAutoscalerMachinePools(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(autoscalerMachinePools) == 0 {
+		return nil, fmt.Errorf("cluster %s has no autoscaled worker pool", cluster.Name)
+	}
+
+	pool := autoscalerMachinePools[0]
+	machineDeployment, err := client.Steve.SteveType(machineDeploymentSteveType).ByID(namespaces.FleetDefault + "/" + cluster.Name + "-" + pool.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := &PoolBounds{
+		Name:     pool.Name,
+		Quantity: *pool.Quantity,
+	}
+
+	bounds.Min, err = parseSizeAnnotation(machineDeployment, minSizeAnnotation)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds.Max, err = parseSizeAnnotation(machineDeployment, maxSizeAnnotation)
+	if err != nil {
+		return nil, err
+	}
+
+	if bounds.Min != *pool.AutoscalingMinSize || bounds.Max != *pool.AutoscalingMaxSize {
+		return nil, fmt.Errorf("machine deployment of pool %s is annotated with bounds %d-%d, expected %d-%d", pool.Name, bounds.Min, bounds.Max, *pool.AutoscalingMinSize, *pool.AutoscalingMaxSize)
+	}
+
+	return bounds, nil
+}
+
+// CreatePendingWorkload creates a deployment of the given number of replicas that can't be scheduled on any current
+// node of the cluster. Every replica requests a large share of the CPU of a worker and is kept apart from the others,
+// so the cluster autoscaler has to add one node per replica.
+func CreatePendingWorkload(client *rancher.Client, clusterID, namespace string, replicas int32) (*PendingWorkload, error) {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := clusterContext.Core.Node().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var currentNodes []string
+	var workerCPU *resource.Quantity
+	for _, node := range nodeList.Items {
+		currentNodes = append(currentNodes, node.Labels[hostnameLabel])
+
+		if _, ok := node.Labels[workerRoleLabel]; ok && workerCPU == nil {
+			allocatable := node.Status.Allocatable[corev1.ResourceCPU]
+			workerCPU = &allocatable
+		}
+	}
+
+	if workerCPU == nil {
+		return nil, errors.New("no worker node found to size the pending pods")
+	}
+
+	cpuRequest := resource.NewMilliQuantity(workerCPU.MilliValue()*pendingPodCPUPercent/100, resource.DecimalSI)
+	workloadName := namegen.AppendRandomString("pending")
+
+	container := extensionsworkloads.NewContainer(workloadName, pods.DefaultImageName, corev1.PullIfNotPresent, nil, nil, nil, nil, nil)
+	container.Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: *cpuRequest}
+
+	podTemplate := extensionsworkloads.NewPodTemplate([]corev1.Container{container}, nil, nil, nil, nil)
+	podTemplate.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{
+								Key:      hostnameLabel,
+								Operator: corev1.NodeSelectorOpNotIn,
+								Values:   currentNodes,
+							},
+						},
+					},
+				},
+			},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							workloadSelectorLabel: fmt.Sprintf("apps.deployment-%v-%v", namespace, workloadName),
+						},
+					},
+					TopologyKey: hostnameLabel,
+				},
+			},
+		},
+	}
+
+	logrus.Infof("Creating %d pending pods requesting %s CPU each in %s/%s", replicas, cpuRequest.String(), namespace, workloadName)
+	_, err = deployments.CreateDeployment(client, clusterID, workloadName, namespace, podTemplate, replicas)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PendingWorkload{
+		ClusterID: clusterID,
+		Namespace: namespace,
+		Name:      workloadName,
+		Replicas:  replicas,
+	}, nil
+}
+
+// DeletePendingWorkload deletes the deployment of the pending workload
+func DeletePendingWorkload(client *rancher.Client, workload *PendingWorkload) error {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, workload.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Deleting pending workload %s/%s", workload.Namespace, workload.Name)
+
+	return clusterContext.Apps.Deployment().Delete(workload.Namespace, workload.Name, &metav1.DeleteOptions{})
+}
+
+// ScaleUpBy creates pending pods forcing the autoscaled pool of the cluster to grow by the given number of nodes,
+// capped to the max size of the pool, and measures the time it takes the pool to reach that size.
+func ScaleUpBy(client *rancher.Client, cluster *v1.SteveAPIObject, clusterID, namespace string, nodes int32, timeout time.Duration) (*ScaleResult, *PendingWorkload, error) {
+	bounds, err := GetAutoscalerPoolBounds(client, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expectedQuantity := min(bounds.Quantity+nodes, bounds.Max)
+
+	start := time.Now()
+	workload, err := CreatePendingWorkload(client, clusterID, namespace, nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = waitForPoolQuantity(client, cluster, bounds.Name, expectedQuantity, timeout)
+	if err != nil {
+		return nil, workload, err
+	}
+
+	result := &ScaleResult{
+		From:     bounds.Quantity,
+		To:       expectedQuantity,
+		Duration: time.Since(start),
+	}
+
+	logrus.Infof("Pool %s scaled up from %d to %d in %s", bounds.Name, result.From, result.To, result.Duration)
+
+	return result, workload, nil
+}
+
+// ScaleDownTo measures the time it takes the autoscaled pool of the cluster to scale down to the given quantity,
+// raised to the min size of the pool. The workload keeping the pool up has to be deleted beforehand.
+func ScaleDownTo(client *rancher.Client, cluster *v1.SteveAPIObject, quantity int32, timeout time.Duration) (*ScaleResult, error) {
+	bounds, err := GetAutoscalerPoolBounds(client, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedQuantity := max(quantity, bounds.Min)
+
+	start := time.Now()
+	err = waitForPoolQuantity(client, cluster, bounds.Name, expectedQuantity, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScaleResult{
+		From:     bounds.Quantity,
+		To:       expectedQuantity,
+		Duration: time.Since(start),
+	}
+
+	logrus.Infof("Pool %s scaled down from %d to %d in %s", bounds.Name, result.From, result.To, result.Duration)
+
+	return result, nil
+}
+
+// GetWorkloadNodes returns the names of the nodes the pods of the pending workload are scheduled on
+func GetWorkloadNodes(client *rancher.Client, workload *PendingWorkload) ([]string, error) {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, workload.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := clusterContext.Core.Pod().List(workload.Namespace, metav1.ListOptions{
+		LabelSelector: workloadSelectorLabel + "=" + fmt.Sprintf("apps.deployment-%v-%v", workload.Namespace, workload.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeNames []string
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" {
+			return nil, fmt.Errorf("pod %s of pending workload %s is not scheduled", pod.Name, workload.Name)
+		}
+
+		nodeNames = append(nodeNames, pod.Spec.NodeName)
+	}
+
+	if len(nodeNames) == 0 {
+		return nil, fmt.Errorf("pending workload %s has no pods", workload.Name)
+	}
+
+	return nodeNames, nil
+}
+
+// VerifyScaleDownCandidates verifies the cluster autoscaler taints the given nodes, and only those, as unneeded. The
+// nodes are expected to be empty, e.g. the nodes a deleted pending workload ran on. Nodes already removed from the
+// cluster count as selected.
+func VerifyScaleDownCandidates(client *rancher.Client, clusterID string, candidates []string, timeout time.Duration) error {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Waiting for the autoscaler to select nodes %v as scale down candidates", candidates)
+
+	var lastErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), 10*time.Second, timeout, true, func(context.Context) (done bool, err error) {
+		nodeList, err := clusterContext.Core.Node().List(metav1.ListOptions{})
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		tainted := map[string]bool{}
+		for _, node := range nodeList.Items {
+			for _, taint := range node.Spec.Taints {
+				if taint.Key == deletionCandidateTaint || taint.Key == toBeDeletedTaint {
+					tainted[node.Name] = true
+				}
+			}
+
+			if tainted[node.Name] && !slices.Contains(candidates, node.Name) {
+				return false, fmt.Errorf("autoscaler selected node %s for scale down, expected only %v", node.Name, candidates)
+			}
+		}
+
+		for _, candidate := range candidates {
+			if tainted[candidate] {
+				continue
+			}
+
+			if slices.ContainsFunc(nodeList.Items, func(node corev1.Node) bool { return node.Name == candidate }) {
+				lastErr = fmt.Errorf("node %s is not tainted as a scale down candidate", candidate)
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return errors.Join(err, lastErr)
+	}
+
+	return nil
+}
+
+// VerifyScaleDownAnnotation verifies the cluster autoscaler annotates the machine of at least one of the candidate
+// nodes, and no other machine of the cluster, for deletion. A candidate machine already deleted counts as annotated.
+func VerifyScaleDownAnnotation(client *rancher.Client, cluster *v1.SteveAPIObject, candidates []string, timeout time.Duration) error {
+	logrus.Infof("Waiting for the autoscaler to annotate the machines of nodes %v for deletion", candidates)
+
+	var lastErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), 10*time.Second, timeout, true, func(context.Context) (done bool, err error) {
+		machineList, err := client.Steve.SteveType(stevetypes.Machine).List(nil)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		remaining := 0
+		annotated := false
+		for _, machineObject := range machineList.Data {
+			if machineObject.Labels[capi.ClusterNameLabel] != cluster.Name {
+				continue
+			}
+
+			machine := &capi.Machine{}
+			err = v1.ConvertToK8sType(machineObject.JSONResp, machine)
+			if err != nil {
+				lastErr = err
+				return false, nil
+			}
+
+			isCandidate := slices.Contains(candidates, machine.Status.NodeRef.Name)
+			if isCandidate {
+				remaining++
+			}
+
+			if _, ok := machine.Annotations[capi.DeleteMachineAnnotation]; !ok {
+				continue
+			}
+
+			if !isCandidate {
+				return false, fmt.Errorf("machine %s of node %s is annotated for deletion, expected only the machines of %v", machine.Name, machine.Status.NodeRef.Name, candidates)
+			}
+
+			logrus.Infof("Machine %s of node %s is annotated for deletion", machine.Name, machine.Status.NodeRef.Name)
+			annotated = true
+		}
+
+		if annotated || remaining < len(candidates) {
+			return true, nil
+		}
+
+		lastErr = fmt.Errorf("no machine of nodes %v is annotated with %s", candidates, capi.DeleteMachineAnnotation)
+		return false, nil
+	})
+	if err != nil {
+		return errors.Join(err, lastErr)
+	}
+
+	return nil
+}
+
+// VerifyNoAutoscaling verifies the quantity of the autoscaled pool of the cluster does not change for the duration
+func VerifyNoAutoscaling(client *rancher.Client, cluster *v1.SteveAPIObject, duration time.Duration) error {
+	bounds, err := GetAutoscalerPoolBounds(client, cluster)
+	if err != nil {
+		return err
+	}
+
+	err = kwait.PollUntilContextTimeout(context.TODO(), 30*time.Second, duration, true, func(context.Context) (done bool, err error) {
+		current, err := GetAutoscalerPoolBounds(client, cluster)
+		if err != nil {
+			return false, nil
+		}
+
+		if current.Quantity != bounds.Quantity {
+			return false, fmt.Errorf("pool %s scaled from %d to %d while autoscaling was expected to be inactive", bounds.Name, bounds.Quantity, current.Quantity)
+		}
+
+		return false, nil
+	})
+	if kwait.Interrupted(err) {
+		return nil
+	}
+
+	return err
+}
+
+// VerifyMaxSizeEvents verifies the cluster autoscaler reported, on the pods of the pending workload, that it could not
+// scale up because the pool reached its max size
+func VerifyMaxSizeEvents(client *rancher.Client, workload *PendingWorkload, timeout time.Duration) error {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, workload.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	return kwait.PollUntilContextTimeout(context.TODO(), 10*time.Second, timeout, true, func(context.Context) (done bool, err error) {
+		eventList, err := clusterContext.Core.Event().List(workload.Namespace, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+
+		for _, event := range eventList.Items {
+			if event.InvolvedObject.Kind != "Pod" || !strings.HasPrefix(event.InvolvedObject.Name, workload.Name+"-") {
+				continue
+			}
+
+			if event.Reason == notTriggerScaleUpReason && strings.Contains(event.Message, maxSizeReachedMessage) {
+				logrus.Infof("Found autoscaler event on pod %s: %s", event.InvolvedObject.Name, event.Message)
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// waitForPoolQuantity waits for the machine pool to reach the expected quantity
+func waitForPoolQuantity(client *rancher.Client, cluster *v1.SteveAPIObject, poolName string, expectedQuantity int32, timeout time.Duration) error {
+	logrus.Infof("Waiting for pool %s to reach %d nodes", poolName, expectedQuantity)
+
+	return kwait.PollUntilContextTimeout(context.TODO(), 30*time.Second, timeout, true, func(context.Context) (done bool, err error) {
+		cluster, err := client.Steve.SteveType(stevetypes.Provisioning).ByID(cluster.ID)
+		if err != nil {
+			return false, nil
+		}
+
+		clusterSpec := &apisV1.ClusterSpec{}
+		err = v1.ConvertToK8sType(cluster.Spec, clusterSpec)
+		if err != nil {
+			return false, nil
+		}
+
+		for _, pool := range clusterSpec.RKEConfig.MachinePools {
+			if pool.Name == poolName && pool.Quantity != nil && *pool.Quantity == expectedQuantity {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+func parseSizeAnnotation(machineDeployment *v1.SteveAPIObject, annotation string) (int32, error) {
+	value, ok := machineDeployment.Annotations[annotation]
+	if !ok {
+		return 0, fmt.Errorf("machine deployment %s is missing annotation %s", machineDeployment.Name, annotation)
+	}
+
+	size, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(size), nil
+}
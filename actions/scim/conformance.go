@@ -0,0 +1,297 @@
+package scim
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	scimclient "github.com/rancher/shepherd/clients/rancher/auth/scim"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SCIMConformanceInput = "scimConformanceInput"
+
+	SCIMSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMSchemaBulkRequest           = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	SCIMSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// RequirementLevel is the RFC 2119 level of a conformance requirement
+type RequirementLevel string
+
+const (
+	LevelMust   RequirementLevel = "MUST"
+	LevelShould RequirementLevel = "SHOULD"
+)
+
+// ConformanceStatus is the outcome of checking a conformance requirement
+type ConformanceStatus string
+
+const (
+	StatusPass ConformanceStatus = "PASS"
+	StatusFail ConformanceStatus = "FAIL"
+	StatusSkip ConformanceStatus = "SKIP"
+)
+
+// errRequirementSkipped is returned by the checks of requirements that don't apply to the service provider, such as
+// the requirements of optional features it doesn't advertise
+var errRequirementSkipped = errors.New("requirement skipped")
+
+// SCIMConformanceConfig is the configuration of the SCIM conformance suite, read from the scimConformanceInput key of
+// the config file
+type SCIMConformanceConfig struct {
+	// Provider is the auth provider the SCIM endpoint is tested for, OpenLDAP when empty
+	Provider string `json:"provider" yaml:"provider"`
+	// ReportFile is the path the JSON conformance report is written to, if set
+	ReportFile string `json:"reportFile" yaml:"reportFile"`
+}
+
+// Requirement is a requirement of RFC 7643 or RFC 7644 checked against the SCIM endpoint of Rancher
+type Requirement struct {
+	ID          string
+	Reference   string
+	Level       RequirementLevel
+	Description string
+	check       func(target *ConformanceTarget) error
+}
+
+// ConformanceResult is the outcome of checking a requirement
+type ConformanceResult struct {
+	Requirement string            `json:"requirement"`
+	Reference   string            `json:"reference"`
+	Level       RequirementLevel  `json:"level"`
+	Description string            `json:"description"`
+	Status      ConformanceStatus `json:"status"`
+	Detail      string            `json:"detail,omitempty"`
+}
+
+// ConformanceReport holds the per-requirement results of a conformance run
+type ConformanceReport struct {
+	Provider string              `json:"provider"`
+	Results  []ConformanceResult `json:"results"`
+}
+
+// ConformanceTarget is the SCIM endpoint of an auth provider under test. It tracks the resources created by the
+// checks, so they can be removed with Cleanup.
+type ConformanceTarget struct {
+	Provider string
+	Client   *scimclient.Client
+
+	baseURL        string
+	token          string
+	httpClient     *http.Client
+	providerConfig map[string]any
+	userIDs        []string
+	groupIDs       []string
+}
+
+// NewConformanceTarget enables SCIM for the auth provider and returns the conformance target of its SCIM endpoint
+func NewConformanceTarget(client *rancher.Client, providerName string) (*ConformanceTarget, error) {
+	scimClient, err := SetupSCIMClient(client, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := FetchSCIMBearerToken(client, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	insecure := client.RancherConfig.Insecure != nil && *client.RancherConfig.Insecure
+
+	return &ConformanceTarget{
+		Provider: providerName,
+		Client:   scimClient,
+		baseURL:  fmt.Sprintf("https://%s/v1-scim/%s", client.RancherConfig.Host, providerName),
+		token:    token,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			},
+		},
+	}, nil
+}
+
+// Check checks the requirement against the target and returns its result
+func (t *ConformanceTarget) Check(requirement Requirement) ConformanceResult {
+	result := ConformanceResult{
+		Requirement: requirement.ID,
+		Reference:   requirement.Reference,
+		Level:       requirement.Level,
+		Description: requirement.Description,
+		Status:      StatusPass,
+	}
+
+	err := requirement.check(t)
+	switch {
+	case errors.Is(err, errRequirementSkipped):
+		result.Status = StatusSkip
+		result.Detail = err.Error()
+	case err != nil:
+		result.Status = StatusFail
+		result.Detail = err.Error()
+	}
+
+	return result
+}
+
+// Cleanup deletes the groups and users created by the checks
+func (t *ConformanceTarget) Cleanup() {
+	for _, id := range t.groupIDs {
+		resp, err := t.Client.Groups().Delete(id)
+		if err != nil || (resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound) {
+			logrus.Warnf("Failed to delete SCIM group %s", id)
+		}
+	}
+
+	for _, id := range t.userIDs {
+		resp, err := t.Client.Users().Delete(id)
+		if err != nil || (resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound) {
+			logrus.Warnf("Failed to delete SCIM user %s", id)
+		}
+	}
+
+	t.groupIDs = nil
+	t.userIDs = nil
+}
+
+// Add adds the result of a requirement to the report
+func (r *ConformanceReport) Add(result ConformanceResult) {
+	r.Results = append(r.Results, result)
+}
+
+// Failed returns the failed results of the requirements of the level
+func (r *ConformanceReport) Failed(level RequirementLevel) []ConformanceResult {
+	var failed []ConformanceResult
+	for _, result := range r.Results {
+		if result.Status == StatusFail && result.Level == level {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// String renders the report as a table with a line per requirement and a summary
+func (r *ConformanceReport) String() string {
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+
+	counts := map[ConformanceStatus]int{}
+	fmt.Fprintf(writer, "REQUIREMENT\tREFERENCE\tLEVEL\tSTATUS\tDESCRIPTION\n")
+	for _, result := range r.Results {
+		counts[result.Status]++
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", result.Requirement, result.Reference, result.Level, result.Status, result.Description)
+		if result.Detail != "" {
+			fmt.Fprintf(writer, "\t\t\t\t  %s\n", result.Detail)
+		}
+	}
+	writer.Flush()
+
+	fmt.Fprintf(&buffer, "SCIM conformance of provider %s: %d passed, %d failed, %d skipped\n",
+		r.Provider, counts[StatusPass], counts[StatusFail], counts[StatusSkip])
+
+	return buffer.String()
+}
+
+// WriteJSON writes the report as JSON to the file
+func (r *ConformanceReport) WriteJSON(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
+
+// do sends a request to the SCIM endpoint for the requests the SCIM client doesn't support, like custom headers,
+// malformed bodies and the endpoints Rancher doesn't serve
+func (t *ConformanceTarget) do(method, path string, header http.Header, body string) (*scimclient.Response, error) {
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, t.baseURL+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("Content-Type", "application/scim+json")
+	req.Header.Set("Accept", "application/scim+json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scimclient.Response{StatusCode: resp.StatusCode, Body: respBody, Header: resp.Header}, nil
+}
+
+// featureSupported returns whether the service provider advertises the optional feature, like etag or bulk, in its
+// ServiceProviderConfig
+func (t *ConformanceTarget) featureSupported(feature string) (bool, error) {
+	if t.providerConfig == nil {
+		resp, err := t.Client.Discovery().ServiceProviderConfig()
+		if err != nil {
+			return false, err
+		}
+
+		err = CheckStatus(resp, http.StatusOK, "GET /ServiceProviderConfig")
+		if err != nil {
+			return false, err
+		}
+
+		err = resp.DecodeJSON(&t.providerConfig)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	featureConfig, _ := t.providerConfig[feature].(map[string]any)
+	supported, _ := featureConfig["supported"].(bool)
+
+	return supported, nil
+}
+
+func (t *ConformanceTarget) createUser(externalID string) (string, string, error) {
+	userName, userID, err := CreateSCIMUser(t.Client, externalID, true)
+	if err != nil {
+		return "", "", err
+	}
+	t.userIDs = append(t.userIDs, userID)
+
+	return userName, userID, nil
+}
+
+func (t *ConformanceTarget) createGroup() (string, string, error) {
+	groupName, groupID, err := CreateSCIMGroup(t.Client, "")
+	if err != nil {
+		return "", "", err
+	}
+	t.groupIDs = append(t.groupIDs, groupID)
+
+	return groupName, groupID, nil
+}
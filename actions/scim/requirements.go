@@ -0,0 +1,1013 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	scimclient "github.com/rancher/shepherd/clients/rancher/auth/scim"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+)
+
+// ConformanceRequirements returns the RFC 7643 and RFC 7644 requirements checked by the conformance suite, in the
+// order they are checked
+func ConformanceRequirements() []Requirement {
+	return []Requirement{
+		{"DISC-01", "RFC7644 4", LevelMust, "ServiceProviderConfig describes every feature", checkServiceProviderConfig},
+		{"DISC-02", "RFC7644 4", LevelMust, "ResourceTypes exposes the User and Group resources", checkResourceTypes},
+		{"DISC-03", "RFC7644 4", LevelMust, "Schemas exposes the core User and Group schemas by ID", checkSchemas},
+		{"AUTH-01", "RFC7644 2", LevelMust, "Requests with an invalid bearer token are rejected with 401", checkInvalidToken},
+		{"RES-01", "RFC7644 3.3", LevelMust, "Created resources are returned with 201, id, meta and Location", checkCreateUser},
+		{"RES-02", "RFC7644 3.3", LevelMust, "Creating a duplicate userName is rejected with 409", checkDuplicateUser},
+		{"RES-03", "RFC7644 3.12", LevelShould, "Uniqueness conflicts carry the uniqueness scimType", checkDuplicateUserScimType},
+		{"RES-04", "RFC7644 3.4.1", LevelMust, "Retrieving an unknown resource returns 404", checkUnknownUser},
+		{"RES-05", "RFC7644 3.6", LevelMust, "Deleted resources return 204 and are no longer retrievable", checkDeleteUser},
+		{"ERR-01", "RFC7644 3.12", LevelMust, "Errors use the Error schema with the HTTP status as a string", checkErrorSchema},
+		{"FLT-01", "RFC7644 3.4.2.2", LevelMust, "The eq filter on userName returns the matching user only", checkFilterUserName},
+		{"FLT-02", "RFC7643 4.1.1", LevelMust, "The userName filter is case insensitive", checkFilterUserNameCaseInsensitive},
+		{"FLT-03", "RFC7644 3.4.2", LevelMust, "A filter matching no resource returns an empty list", checkFilterNoMatch},
+		{"FLT-04", "RFC7644 3.4.2.2", LevelMust, "The eq filter on displayName returns the matching group only", checkFilterDisplayName},
+		{"FLT-05", "RFC7644 3.12", LevelMust, "An invalid filter is rejected with 400", checkInvalidFilter},
+		{"FLT-06", "RFC7644 3.12", LevelShould, "An invalid filter carries the invalidFilter scimType", checkInvalidFilterScimType},
+		{"PAG-01", "RFC7644 3.4.2", LevelMust, "List responses use the ListResponse schema with consistent counts", checkListResponse},
+		{"PAG-02", "RFC7644 3.4.2.4", LevelMust, "Pages of a filtered list are disjoint and cover every result", checkPagination},
+		{"PAG-03", "RFC7644 3.4.2.4", LevelMust, "A count of 0 returns totalResults without resources", checkZeroCount},
+		{"PAG-04", "RFC7644 3.4.2.4", LevelMust, "A startIndex past the results returns an empty page", checkStartIndexPastResults},
+		{"PAG-05", "RFC7644 3.4.2.4", LevelMust, "A startIndex less than 1 is interpreted as 1", checkStartIndexBelowOne},
+		{"PAG-06", "RFC7644 3.4.2.4", LevelMust, "A negative count is interpreted as 0", checkNegativeCount},
+		{"PAT-01", "RFC7644 3.5.2.1", LevelMust, "PATCH add appends members to a group", checkPatchAddMember},
+		{"PAT-02", "RFC7644 3.5.2.2", LevelMust, "PATCH remove with a value filter removes a group member", checkPatchRemoveMember},
+		{"PAT-03", "RFC7644 3.5.2.3", LevelMust, "PATCH replace on members sets the members of a group", checkPatchReplaceMembers},
+		{"PAT-04", "RFC7644 3.5.2.1", LevelMust, "PATCH add sets a user attribute", checkPatchAddAttribute},
+		{"PAT-05", "RFC7644 3.5.2.3", LevelMust, "PATCH replace changes a user attribute", checkPatchReplaceAttribute},
+		{"PAT-06", "RFC7644 3.5.2.2", LevelMust, "PATCH remove clears a user attribute", checkPatchRemoveAttribute},
+		{"PAT-07", "RFC7644 3.5.2", LevelMust, "PATCH with an unknown operation is rejected with 400", checkPatchInvalidOp},
+		{"PAT-08", "RFC7644 3.5.2", LevelMust, "PATCH operations are applied atomically", checkPatchAtomic},
+		{"ETAG-01", "RFC7644 3.14", LevelMust, "Resources carry an ETag header matching meta.version when etag is supported", checkETagHeader},
+		{"ETAG-02", "RFC7644 3.14", LevelMust, "If-None-Match with the current version returns 304 when etag is supported", checkETagNotModified},
+		{"BULK-01", "RFC7644 3.7", LevelMust, "Bulk requests are processed only when bulk is supported", checkBulk},
+	}
+}
+
+func checkServiceProviderConfig(t *ConformanceTarget) error {
+	resp, err := t.Client.Discovery().ServiceProviderConfig()
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusOK, "GET /ServiceProviderConfig")
+	if err != nil {
+		return err
+	}
+
+	err = expectSchema(body, SCIMSchemaServiceProviderConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, feature := range []string{"patch", "bulk", "filter", "changePassword", "sort", "etag"} {
+		featureConfig, ok := body[feature].(map[string]any)
+		if !ok {
+			return fmt.Errorf("feature %s is not described", feature)
+		}
+
+		if _, ok := featureConfig["supported"].(bool); !ok {
+			return fmt.Errorf("feature %s has no supported flag", feature)
+		}
+	}
+
+	schemes, _ := body["authenticationSchemes"].([]any)
+	if len(schemes) == 0 {
+		return fmt.Errorf("no authentication scheme is described")
+	}
+
+	return nil
+}
+
+func checkResourceTypes(t *ConformanceTarget) error {
+	resp, err := t.Client.Discovery().ResourceTypes()
+	if err != nil {
+		return err
+	}
+
+	resources, err := decodeResources(resp, "GET /ResourceTypes")
+	if err != nil {
+		return err
+	}
+
+	expected := map[string][2]string{
+		"User":  {"/Users", scimclient.SCIMSchemaUser},
+		"Group": {"/Groups", scimclient.SCIMSchemaGroup},
+	}
+	for name, want := range expected {
+		index := slices.IndexFunc(resources, func(resource map[string]any) bool { return resource["name"] == name })
+		if index < 0 {
+			return fmt.Errorf("resource type %s is not exposed", name)
+		}
+
+		if resources[index]["endpoint"] != want[0] || resources[index]["schema"] != want[1] {
+			return fmt.Errorf("resource type %s has endpoint %v and schema %v, expected %s and %s",
+				name, resources[index]["endpoint"], resources[index]["schema"], want[0], want[1])
+		}
+	}
+
+	return nil
+}
+
+func checkSchemas(t *ConformanceTarget) error {
+	resp, err := t.Client.Discovery().Schemas()
+	if err != nil {
+		return err
+	}
+
+	resources, err := decodeResources(resp, "GET /Schemas")
+	if err != nil {
+		return err
+	}
+
+	for _, schema := range []string{scimclient.SCIMSchemaUser, scimclient.SCIMSchemaGroup} {
+		if !slices.ContainsFunc(resources, func(resource map[string]any) bool { return resource["id"] == schema }) {
+			return fmt.Errorf("schema %s is not listed", schema)
+		}
+
+		resp, err := t.Client.Discovery().SchemaByID(schema)
+		if err != nil {
+			return err
+		}
+
+		body, err := decodeObject(resp, http.StatusOK, "GET /Schemas/"+schema)
+		if err != nil {
+			return err
+		}
+
+		if body["id"] != schema {
+			return fmt.Errorf("GET /Schemas/%s returned schema %v", schema, body["id"])
+		}
+	}
+
+	return nil
+}
+
+func checkInvalidToken(t *ConformanceTarget) error {
+	invalidTarget := &ConformanceTarget{baseURL: t.baseURL, token: "invalid", httpClient: t.httpClient}
+	resp, err := invalidTarget.do(http.MethodGet, "/Users", nil, "")
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusUnauthorized, "GET /Users with an invalid token")
+}
+
+func checkCreateUser(t *ConformanceTarget) error {
+	userName := namegen.AppendRandomString("scim-user")
+	resp, err := t.Client.Users().Create(scimclient.User{
+		Schemas:  []string{scimclient.SCIMSchemaUser},
+		UserName: userName,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusCreated, "POST /Users")
+	if err != nil {
+		return err
+	}
+
+	id, _ := body["id"].(string)
+	if id == "" {
+		return fmt.Errorf("created user has no id")
+	}
+	t.userIDs = append(t.userIDs, id)
+
+	if body["userName"] != userName {
+		return fmt.Errorf("created user has userName %v, expected %s", body["userName"], userName)
+	}
+
+	meta, _ := body["meta"].(map[string]any)
+	if meta["resourceType"] != "User" {
+		return fmt.Errorf("created user has meta.resourceType %v, expected User", meta["resourceType"])
+	}
+
+	location, _ := meta["location"].(string)
+	if location == "" {
+		return fmt.Errorf("created user has no meta.location")
+	}
+
+	if header := resp.Header.Get("Location"); header != location {
+		return fmt.Errorf("location header %q does not match meta.location %q", header, location)
+	}
+
+	return nil
+}
+
+func checkDuplicateUser(t *ConformanceTarget) error {
+	resp, err := createDuplicateUser(t)
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusConflict, "POST /Users with an existing userName")
+}
+
+func checkDuplicateUserScimType(t *ConformanceTarget) error {
+	resp, err := createDuplicateUser(t)
+	if err != nil {
+		return err
+	}
+
+	return expectScimType(resp, http.StatusConflict, "uniqueness")
+}
+
+func checkUnknownUser(t *ConformanceTarget) error {
+	resp, err := t.Client.Users().ByID(namegen.RandStringLower(16))
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusNotFound, "GET /Users with an unknown id")
+}
+
+func checkDeleteUser(t *ConformanceTarget) error {
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.Client.Users().Delete(userID)
+	if err != nil {
+		return err
+	}
+
+	err = CheckStatus(resp, http.StatusNoContent, "DELETE /Users/"+userID)
+	if err != nil {
+		return err
+	}
+
+	return WaitForSCIMResourceDeletion(func() (int, error) {
+		resp, err := t.Client.Users().ByID(userID)
+		if err != nil {
+			return 0, err
+		}
+
+		return resp.StatusCode, nil
+	})
+}
+
+func checkErrorSchema(t *ConformanceTarget) error {
+	resp, err := t.Client.Users().ByID(namegen.RandStringLower(16))
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusNotFound, "GET /Users with an unknown id")
+	if err != nil {
+		return err
+	}
+
+	err = expectSchema(body, SCIMSchemaError)
+	if err != nil {
+		return err
+	}
+
+	if body["status"] != strconv.Itoa(http.StatusNotFound) {
+		return fmt.Errorf("error status is %#v, expected the string %q", body["status"], strconv.Itoa(http.StatusNotFound))
+	}
+
+	return nil
+}
+
+func checkFilterUserName(t *ConformanceTarget) error {
+	userName, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	return expectFilteredIDs(t.Client.Users(), fmt.Sprintf("userName eq %q", userName), []string{userID})
+}
+
+func checkFilterUserNameCaseInsensitive(t *ConformanceTarget) error {
+	userName, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	return expectFilteredIDs(t.Client.Users(), fmt.Sprintf("userName eq %q", strings.ToUpper(userName)), []string{userID})
+}
+
+func checkFilterNoMatch(t *ConformanceTarget) error {
+	return expectFilteredIDs(t.Client.Users(), fmt.Sprintf("userName eq %q", namegen.AppendRandomString("scim-missing")), nil)
+}
+
+func checkFilterDisplayName(t *ConformanceTarget) error {
+	groupName, groupID, err := t.createGroup()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = t.createGroup()
+	if err != nil {
+		return err
+	}
+
+	return expectFilteredIDs(t.Client.Groups(), fmt.Sprintf("displayName eq %q", groupName), []string{groupID})
+}
+
+func checkInvalidFilter(t *ConformanceTarget) error {
+	resp, err := t.Client.Users().List(url.Values{"filter": {`userName eq "unterminated`}})
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusBadRequest, "GET /Users with an invalid filter")
+}
+
+func checkInvalidFilterScimType(t *ConformanceTarget) error {
+	resp, err := t.Client.Users().List(url.Values{"filter": {`userName eq "unterminated`}})
+	if err != nil {
+		return err
+	}
+
+	return expectScimType(resp, http.StatusBadRequest, "invalidFilter")
+}
+
+func checkListResponse(t *ConformanceTarget) error {
+	_, _, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	body, err := ListSCIMUsersPage(t.Client, 1, 10)
+	if err != nil {
+		return err
+	}
+
+	err = expectSchema(body, SCIMSchemaListResponse)
+	if err != nil {
+		return err
+	}
+
+	totalResults, itemsPerPage, startIndex, resources, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if totalResults < 1 {
+		return fmt.Errorf("totalResults is %d after creating a user", totalResults)
+	}
+
+	if itemsPerPage != len(resources) {
+		return fmt.Errorf("itemsPerPage is %d for %d resources", itemsPerPage, len(resources))
+	}
+
+	if startIndex != 1 {
+		return fmt.Errorf("startIndex is %d, expected 1", startIndex)
+	}
+
+	return nil
+}
+
+func checkPagination(t *ConformanceTarget) error {
+	externalID := namegen.AppendRandomString("scim-page")
+	var expectedIDs []string
+	for range 3 {
+		_, userID, err := t.createUser(externalID)
+		if err != nil {
+			return err
+		}
+		expectedIDs = append(expectedIDs, userID)
+	}
+
+	var pagedIDs []string
+	for startIndex := 1; startIndex <= len(expectedIDs); startIndex += 2 {
+		body, err := listPage(t.Client.Users(), url.Values{
+			"filter":     {fmt.Sprintf("externalId eq %q", externalID)},
+			"startIndex": {strconv.Itoa(startIndex)},
+			"count":      {"2"},
+		})
+		if err != nil {
+			return err
+		}
+
+		totalResults, _, pageStartIndex, resources, err := listCounts(body)
+		if err != nil {
+			return err
+		}
+
+		if totalResults != len(expectedIDs) {
+			return fmt.Errorf("totalResults is %d, expected %d", totalResults, len(expectedIDs))
+		}
+
+		if pageStartIndex != startIndex {
+			return fmt.Errorf("startIndex is %d, expected %d", pageStartIndex, startIndex)
+		}
+
+		for _, resource := range resources {
+			id, _ := resource["id"].(string)
+			if slices.Contains(pagedIDs, id) {
+				return fmt.Errorf("user %s is returned in more than one page", id)
+			}
+			pagedIDs = append(pagedIDs, id)
+		}
+	}
+
+	return expectSameIDs(pagedIDs, expectedIDs)
+}
+
+func checkZeroCount(t *ConformanceTarget) error {
+	_, _, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	body, err := listPage(t.Client.Users(), url.Values{"count": {"0"}})
+	if err != nil {
+		return err
+	}
+
+	totalResults, _, _, resources, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if len(resources) != 0 {
+		return fmt.Errorf("%d resources returned with a count of 0", len(resources))
+	}
+
+	if totalResults < 1 {
+		return fmt.Errorf("totalResults is %d after creating a user", totalResults)
+	}
+
+	return nil
+}
+
+func checkStartIndexPastResults(t *ConformanceTarget) error {
+	body, err := ListSCIMUsersPage(t.Client, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	totalResults, _, _, _, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	body, err = ListSCIMUsersPage(t.Client, totalResults+10, 10)
+	if err != nil {
+		return err
+	}
+
+	_, _, _, resources, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if len(resources) != 0 {
+		return fmt.Errorf("%d resources returned past the last result", len(resources))
+	}
+
+	return nil
+}
+
+func checkStartIndexBelowOne(t *ConformanceTarget) error {
+	body, err := listPage(t.Client.Users(), url.Values{"startIndex": {"0"}, "count": {"1"}})
+	if err != nil {
+		return err
+	}
+
+	_, _, startIndex, _, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if startIndex != 1 {
+		return fmt.Errorf("startIndex is %d, expected 1", startIndex)
+	}
+
+	return nil
+}
+
+func checkNegativeCount(t *ConformanceTarget) error {
+	body, err := listPage(t.Client.Users(), url.Values{"count": {"-1"}})
+	if err != nil {
+		return err
+	}
+
+	_, _, _, resources, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if len(resources) != 0 {
+		return fmt.Errorf("%d resources returned with a negative count", len(resources))
+	}
+
+	return nil
+}
+
+func checkPatchAddMember(t *ConformanceTarget) error {
+	_, groupID, err := t.createGroup()
+	if err != nil {
+		return err
+	}
+
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	err = patchGroup(t, groupID, scimclient.Operation{Op: "add", Path: "members", Value: []scimclient.Member{{Value: userID}}})
+	if err != nil {
+		return err
+	}
+
+	return expectGroupMembers(t, groupID, []string{userID})
+}
+
+func checkPatchRemoveMember(t *ConformanceTarget) error {
+	_, groupID, err := t.createGroup()
+	if err != nil {
+		return err
+	}
+
+	var userIDs []string
+	for range 2 {
+		_, userID, err := t.createUser("")
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	err = patchGroup(t, groupID, scimclient.Operation{Op: "add", Path: "members", Value: []scimclient.Member{{Value: userIDs[0]}, {Value: userIDs[1]}}})
+	if err != nil {
+		return err
+	}
+
+	err = WaitForGroupMemberCount(t.Client, groupID, len(userIDs))
+	if err != nil {
+		return fmt.Errorf("group members were not added: %w", err)
+	}
+
+	err = patchGroup(t, groupID, scimclient.Operation{Op: "remove", Path: fmt.Sprintf("members[value eq %q]", userIDs[0])})
+	if err != nil {
+		return err
+	}
+
+	return expectGroupMembers(t, groupID, userIDs[1:])
+}
+
+func checkPatchReplaceMembers(t *ConformanceTarget) error {
+	_, groupID, err := t.createGroup()
+	if err != nil {
+		return err
+	}
+
+	var userIDs []string
+	for range 2 {
+		_, userID, err := t.createUser("")
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	err = patchGroup(t, groupID, scimclient.Operation{Op: "add", Path: "members", Value: []scimclient.Member{{Value: userIDs[0]}}})
+	if err != nil {
+		return err
+	}
+
+	err = patchGroup(t, groupID, scimclient.Operation{Op: "replace", Path: "members", Value: []scimclient.Member{{Value: userIDs[1]}}})
+	if err != nil {
+		return err
+	}
+
+	return expectGroupMembers(t, groupID, userIDs[1:])
+}
+
+func checkPatchAddAttribute(t *ConformanceTarget) error {
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	externalID := namegen.AppendRandomString("scim-ext")
+	err = patchUser(t, userID, scimclient.Operation{Op: "add", Path: "externalId", Value: externalID})
+	if err != nil {
+		return err
+	}
+
+	return expectUserAttribute(t, userID, "externalId", externalID)
+}
+
+func checkPatchReplaceAttribute(t *ConformanceTarget) error {
+	_, userID, err := t.createUser(namegen.AppendRandomString("scim-ext"))
+	if err != nil {
+		return err
+	}
+
+	externalID := namegen.AppendRandomString("scim-ext")
+	err = patchUser(t, userID, scimclient.Operation{Op: "replace", Path: "externalId", Value: externalID})
+	if err != nil {
+		return err
+	}
+
+	return expectUserAttribute(t, userID, "externalId", externalID)
+}
+
+func checkPatchRemoveAttribute(t *ConformanceTarget) error {
+	_, userID, err := t.createUser(namegen.AppendRandomString("scim-ext"))
+	if err != nil {
+		return err
+	}
+
+	err = patchUser(t, userID, scimclient.Operation{Op: "remove", Path: "externalId"})
+	if err != nil {
+		return err
+	}
+
+	return expectUserAttribute(t, userID, "externalId", nil)
+}
+
+func checkPatchInvalidOp(t *ConformanceTarget) error {
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.Client.Users().Patch(userID, newPatchOp(scimclient.Operation{Op: "move", Path: "externalId", Value: "value"}))
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusBadRequest, "PATCH with an unknown operation")
+}
+
+func checkPatchAtomic(t *ConformanceTarget) error {
+	externalID := namegen.AppendRandomString("scim-ext")
+	_, userID, err := t.createUser(externalID)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.Client.Users().Patch(userID, newPatchOp(
+		scimclient.Operation{Op: "replace", Path: "externalId", Value: namegen.AppendRandomString("scim-ext")},
+		scimclient.Operation{Op: "replace", Path: "unknownAttribute", Value: "value"},
+	))
+	if err != nil {
+		return err
+	}
+
+	err = CheckStatus(resp, http.StatusBadRequest, "PATCH with an invalid operation")
+	if err != nil {
+		return err
+	}
+
+	return expectUserAttribute(t, userID, "externalId", externalID)
+}
+
+func checkETagHeader(t *ConformanceTarget) error {
+	supported, err := t.featureSupported("etag")
+	if err != nil {
+		return err
+	}
+
+	if !supported {
+		return fmt.Errorf("%w: etag is not supported by the service provider", errRequirementSkipped)
+	}
+
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.Client.Users().ByID(userID)
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusOK, "GET /Users/"+userID)
+	if err != nil {
+		return err
+	}
+
+	meta, _ := body["meta"].(map[string]any)
+	version, _ := meta["version"].(string)
+	if version == "" || resp.Header.Get("ETag") != version {
+		return fmt.Errorf("ETag header %q does not match meta.version %q", resp.Header.Get("ETag"), version)
+	}
+
+	return nil
+}
+
+func checkETagNotModified(t *ConformanceTarget) error {
+	supported, err := t.featureSupported("etag")
+	if err != nil {
+		return err
+	}
+
+	if !supported {
+		return fmt.Errorf("%w: etag is not supported by the service provider", errRequirementSkipped)
+	}
+
+	_, userID, err := t.createUser("")
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.Client.Users().ByID(userID)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp, err = t.do(http.MethodGet, "/Users/"+userID, header, "")
+	if err != nil {
+		return err
+	}
+
+	return CheckStatus(resp, http.StatusNotModified, "GET with If-None-Match of the current version")
+}
+
+func checkBulk(t *ConformanceTarget) error {
+	supported, err := t.featureSupported("bulk")
+	if err != nil {
+		return err
+	}
+
+	userName := namegen.AppendRandomString("scim-bulk")
+	resp, err := t.do(http.MethodPost, "/Bulk", nil, fmt.Sprintf(
+		`{"schemas":[%q],"Operations":[{"method":"POST","path":"/Users","bulkId":"user","data":{"schemas":[%q],"userName":%q}}]}`,
+		SCIMSchemaBulkRequest, scimclient.SCIMSchemaUser, userName))
+	if err != nil {
+		return err
+	}
+
+	// track the users created by the request, so they are removed with the target even if the check fails
+	filter := fmt.Sprintf("userName eq %q", userName)
+	createdIDs, err := filteredIDs(t.Client.Users(), filter)
+	if err != nil {
+		return err
+	}
+	t.userIDs = append(t.userIDs, createdIDs...)
+
+	if supported {
+		if err := CheckStatus(resp, http.StatusOK, "POST /Bulk"); err != nil {
+			return err
+		}
+
+		if len(createdIDs) != 1 {
+			return fmt.Errorf("POST /Bulk created %d users with userName %s, expected 1", len(createdIDs), userName)
+		}
+
+		return nil
+	}
+
+	if resp.StatusCode < http.StatusBadRequest {
+		return fmt.Errorf("POST /Bulk returned %d although bulk is not supported", resp.StatusCode)
+	}
+
+	// the user must not have been created by the rejected request
+	return expectSameIDs(createdIDs, nil)
+}
+
+// lister is implemented by the user and group clients of the SCIM client
+type lister interface {
+	List(query url.Values) (*scimclient.Response, error)
+}
+
+func createDuplicateUser(t *ConformanceTarget) (*scimclient.Response, error) {
+	userName, _, err := t.createUser("")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Client.Users().Create(scimclient.User{
+		Schemas:  []string{scimclient.SCIMSchemaUser},
+		UserName: userName,
+	})
+}
+
+func newPatchOp(operations ...scimclient.Operation) scimclient.PatchOp {
+	return scimclient.PatchOp{
+		Schemas:    []string{scimclient.SCIMSchemaPatchOp},
+		Operations: operations,
+	}
+}
+
+func patchUser(t *ConformanceTarget, userID string, operations ...scimclient.Operation) error {
+	resp, err := t.Client.Users().Patch(userID, newPatchOp(operations...))
+	if err != nil {
+		return err
+	}
+
+	return expectSuccessfulPatch(resp)
+}
+
+func patchGroup(t *ConformanceTarget, groupID string, operations ...scimclient.Operation) error {
+	resp, err := t.Client.Groups().Patch(groupID, newPatchOp(operations...))
+	if err != nil {
+		return err
+	}
+
+	return expectSuccessfulPatch(resp)
+}
+
+// expectSuccessfulPatch accepts both successful PATCH responses of RFC 7644 3.5.2: 200 with the resource or 204
+func expectSuccessfulPatch(resp *scimclient.Response) error {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("PATCH: expected 200 or 204, got %d: %s", resp.StatusCode, string(resp.Body))
+	}
+
+	return nil
+}
+
+func expectGroupMembers(t *ConformanceTarget, groupID string, expectedIDs []string) error {
+	err := WaitForGroupMemberCount(t.Client, groupID, len(expectedIDs))
+	if err != nil {
+		return fmt.Errorf("group %s does not have %d members: %w", groupID, len(expectedIDs), err)
+	}
+
+	resp, err := t.Client.Groups().ByID(groupID)
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusOK, "GET /Groups/"+groupID)
+	if err != nil {
+		return err
+	}
+
+	members, _ := body["members"].([]any)
+	var memberIDs []string
+	for _, member := range members {
+		memberMap, _ := member.(map[string]any)
+		value, _ := memberMap["value"].(string)
+		memberIDs = append(memberIDs, value)
+	}
+
+	return expectSameIDs(memberIDs, expectedIDs)
+}
+
+// expectUserAttribute checks the attribute of the user, or its absence when expected is nil
+func expectUserAttribute(t *ConformanceTarget, userID, attribute string, expected any) error {
+	resp, err := t.Client.Users().ByID(userID)
+	if err != nil {
+		return err
+	}
+
+	body, err := decodeObject(resp, http.StatusOK, "GET /Users/"+userID)
+	if err != nil {
+		return err
+	}
+
+	value, found := body[attribute]
+	if expected == nil {
+		if found && value != "" {
+			return fmt.Errorf("attribute %s is %v, expected no value", attribute, value)
+		}
+
+		return nil
+	}
+
+	if value != expected {
+		return fmt.Errorf("attribute %s is %v, expected %v", attribute, value, expected)
+	}
+
+	return nil
+}
+
+func expectFilteredIDs(client lister, filter string, expectedIDs []string) error {
+	body, err := listPage(client, url.Values{"filter": {filter}})
+	if err != nil {
+		return err
+	}
+
+	totalResults, _, _, resources, err := listCounts(body)
+	if err != nil {
+		return err
+	}
+
+	if totalResults != len(expectedIDs) {
+		return fmt.Errorf("filter %s: totalResults is %d, expected %d", filter, totalResults, len(expectedIDs))
+	}
+
+	return expectSameIDs(resourceIDs(resources), expectedIDs)
+}
+
+func filteredIDs(client lister, filter string) ([]string, error) {
+	body, err := listPage(client, url.Values{"filter": {filter}})
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, _, resources, err := listCounts(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return resourceIDs(resources), nil
+}
+
+func resourceIDs(resources []map[string]any) []string {
+	var ids []string
+	for _, resource := range resources {
+		id, _ := resource["id"].(string)
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func expectSameIDs(ids, expectedIDs []string) error {
+	ids = slices.Sorted(slices.Values(ids))
+	expectedIDs = slices.Sorted(slices.Values(expectedIDs))
+
+	if !slices.Equal(ids, expectedIDs) {
+		return fmt.Errorf("got resources %v, expected %v", ids, expectedIDs)
+	}
+
+	return nil
+}
+
+func expectSchema(body map[string]any, schema string) error {
+	schemas, _ := body["schemas"].([]any)
+	if !slices.Contains(schemas, any(schema)) {
+		return fmt.Errorf("schemas %v do not contain %s", schemas, schema)
+	}
+
+	return nil
+}
+
+func expectScimType(resp *scimclient.Response, status int, scimType string) error {
+	body, err := decodeObject(resp, status, "error response")
+	if err != nil {
+		return err
+	}
+
+	if body["scimType"] != scimType {
+		return fmt.Errorf("scimType is %v, expected %s", body["scimType"], scimType)
+	}
+
+	return nil
+}
+
+func listPage(client lister, query url.Values) (map[string]any, error) {
+	resp, err := client.List(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeObject(resp, http.StatusOK, "list with "+query.Encode())
+}
+
+func listCounts(body map[string]any) (totalResults, itemsPerPage, startIndex int, resources []map[string]any, err error) {
+	total, ok := body["totalResults"].(float64)
+	if !ok {
+		return 0, 0, 0, nil, fmt.Errorf("list response has no totalResults")
+	}
+
+	perPage, _ := body["itemsPerPage"].(float64)
+	start, _ := body["startIndex"].(float64)
+
+	rawResources, _ := body["Resources"].([]any)
+	for _, raw := range rawResources {
+		resource, _ := raw.(map[string]any)
+		resources = append(resources, resource)
+	}
+
+	return int(total), int(perPage), int(start), resources, nil
+}
+
+func decodeObject(resp *scimclient.Response, status int, msg string) (map[string]any, error) {
+	err := CheckStatus(resp, status, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var body map[string]any
+	err = resp.DecodeJSON(&body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decode response: %w", msg, err)
+	}
+
+	return body, nil
+}
+
+func decodeResources(resp *scimclient.Response, msg string) ([]map[string]any, error) {
+	body, err := decodeObject(resp, http.StatusOK, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, _, resources, err := listCounts(body)
+	return resources, err
+}
@@ -0,0 +1,226 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/tests/actions/kubeconfigs"
+	"github.com/rancher/tests/actions/tokens/exttokens"
+	"github.com/rancher/tests/actions/users"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// errUncertain stops a poll when the expected state became uncertain, because an expiration time is too close
+var errUncertain = errors.New("expected state is uncertain")
+
+// VerifyInvariants checks that the state of the user, its tokens and its kubeconfigs in Rancher matches the model.
+// Rancher applies some changes asynchronously, so each invariant is polled until it holds or the timeout is reached.
+func (l *Lifecycle) VerifyInvariants() error {
+	var errs []error
+
+	errs = append(errs, l.verifyUser())
+	for _, token := range l.Model.Tokens {
+		errs = append(errs, l.verifyToken(token))
+	}
+	for _, kubeconfig := range l.Model.Kubeconfigs {
+		errs = append(errs, l.verifyKubeconfig(kubeconfig))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *Lifecycle) verifyUser() error {
+	if l.Model.UserDeleted {
+		err := users.WaitForUserDeletion(l.client, l.user.ID)
+		if err != nil {
+			return fmt.Errorf("user %s was not deleted: %w", l.user.ID, err)
+		}
+
+		return nil
+	}
+
+	user, err := users.GetUserByName(l.client, l.user.ID)
+	if err != nil {
+		return err
+	}
+
+	enabled := user.Enabled == nil || *user.Enabled
+	if enabled != l.Model.UserEnabled {
+		return fmt.Errorf("user %s: expected enabled %t, got %t", l.user.ID, l.Model.UserEnabled, enabled)
+	}
+
+	if !l.Model.UserEnabled {
+		return nil
+	}
+
+	_, err = l.client.AsUser(&management.User{Username: l.user.Username, Password: l.Model.Password})
+	if err != nil {
+		return fmt.Errorf("user %s can't login with the current password: %w", l.user.ID, err)
+	}
+
+	if l.Model.PreviousPassword != "" {
+		_, err = l.client.AsUser(&management.User{Username: l.user.Username, Password: l.Model.PreviousPassword})
+		if err == nil {
+			return fmt.Errorf("user %s can still login with the previous password", l.user.ID)
+		}
+	}
+
+	return nil
+}
+
+func (l *Lifecycle) verifyToken(token *TokenState) error {
+	if l.Model.Gone(token) {
+		err := l.waitForTokenDeletion(token)
+		if err != nil {
+			return fmt.Errorf("%s token %s was not deleted: %w", token.Kind, token.Name, err)
+		}
+
+		return nil
+	}
+
+	if token.Kind != LegacyTokenKind {
+		err := l.verifyExtTokenStatus(token)
+		if err != nil && !errors.Is(err, errUncertain) {
+			return fmt.Errorf("%s token %s: %w", token.Kind, token.Name, err)
+		}
+	}
+
+	err := l.verifyAuthentication(token)
+	if err != nil && !errors.Is(err, errUncertain) {
+		return fmt.Errorf("%s token %s: %w", token.Kind, token.Name, err)
+	}
+
+	return nil
+}
+
+func (l *Lifecycle) waitForTokenDeletion(token *TokenState) error {
+	if token.Kind != LegacyTokenKind {
+		return exttokens.WaitForExtTokenDeletion(l.client, token.Name)
+	}
+
+	return l.waitForLegacyTokenDeletion(token.Name)
+}
+
+func (l *Lifecycle) waitForLegacyTokenDeletion(name string) error {
+	return kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := l.client.WranglerContext.Mgmt.Token().Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+}
+
+// verifyExtTokenStatus checks that status.expired of the ext token and whether it is enabled match the model
+func (l *Lifecycle) verifyExtTokenStatus(token *TokenState) error {
+	var lastErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		expired, certain := l.Model.Expired(token, time.Now())
+		if !certain {
+			return false, errUncertain
+		}
+
+		extToken, err := exttokens.GetExtToken(l.client, token.Name)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case extToken.Status.Expired != expired:
+			lastErr = fmt.Errorf("expected status.expired %t, got %t", expired, extToken.Status.Expired)
+		case extToken.GetIsEnabled() != token.Enabled:
+			lastErr = fmt.Errorf("expected enabled %t, got %t", token.Enabled, extToken.GetIsEnabled())
+		default:
+			return true, nil
+		}
+
+		return false, nil
+	})
+	if err != nil && lastErr != nil && !errors.Is(err, errUncertain) {
+		return lastErr
+	}
+
+	return err
+}
+
+// verifyAuthentication checks that a request authenticated with the token succeeds exactly when the model expects it
+func (l *Lifecycle) verifyAuthentication(token *TokenState) error {
+	var lastErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		expected, certain := l.Model.Authenticates(token, time.Now())
+		if !certain {
+			return false, errUncertain
+		}
+
+		authenticated, err := l.authenticates(token.BearerToken)
+		if err != nil {
+			return false, err
+		}
+
+		if authenticated != expected {
+			lastErr = fmt.Errorf("expected authentication to succeed %t, got %t", expected, authenticated)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil && lastErr != nil && !errors.Is(err, errUncertain) {
+		return lastErr
+	}
+
+	return err
+}
+
+// authenticates returns whether Rancher accepts the bearer token for a request of the user on itself
+func (l *Lifecycle) authenticates(bearerToken string) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/v3/users?me=true", l.client.RancherConfig.Host), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+// verifyKubeconfig checks that the backing tokens of a kubeconfig exist while it does, and are removed with it or
+// with the user
+func (l *Lifecycle) verifyKubeconfig(kubeconfig *KubeconfigState) error {
+	if kubeconfig.Deleted || l.Model.UserDeleted {
+		err := kubeconfigs.WaitForBackingTokenDeletion(l.client, kubeconfig.Name)
+		if err != nil {
+			return fmt.Errorf("backing tokens of kubeconfig %s were not deleted: %w", kubeconfig.Name, err)
+		}
+
+		return nil
+	}
+
+	for _, name := range kubeconfig.BackingTokens {
+		_, err := l.client.WranglerContext.Mgmt.Token().Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("backing token %s of kubeconfig %s: %w", name, kubeconfig.Name, err)
+		}
+	}
+
+	return nil
+}
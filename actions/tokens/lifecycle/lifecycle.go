@@ -0,0 +1,161 @@
+package lifecycle
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/tests/actions/rbac"
+)
+
+const (
+	TokenLifecycleInput = "tokenLifecycleInput"
+
+	defaultSequences          = 3
+	defaultSteps              = 15
+	defaultShortTTLSeconds    = 90
+	defaultWaitSeconds        = 30
+	defaultIdleTimeoutMinutes = 2
+)
+
+// LifecycleConfig is the configuration of the token lifecycle tests, read from the tokenLifecycleInput key of the
+// config file
+type LifecycleConfig struct {
+	// Seed is the seed of the first sequence, the following sequences use the next seeds. A random seed is used when
+	// zero, and logged so a failing sequence can be replayed.
+	Seed      uint64 `json:"seed" yaml:"seed"`
+	Sequences int    `json:"sequences" yaml:"sequences"`
+	Steps     int    `json:"steps" yaml:"steps"`
+	// ShortTTLSeconds is the TTL of the tokens created to expire during a sequence
+	ShortTTLSeconds int64 `json:"shortTTLSeconds" yaml:"shortTTLSeconds"`
+	// WaitSeconds is how long the wait operation lets the time pass
+	WaitSeconds int `json:"waitSeconds" yaml:"waitSeconds"`
+	// IdleTimeoutMinutes is the value the auth-user-session-idle-ttl-minutes setting is set to
+	IdleTimeoutMinutes int `json:"idleTimeoutMinutes" yaml:"idleTimeoutMinutes"`
+}
+
+// SetDefaults sets the fields of the configuration that are not set to their default values
+func (c *LifecycleConfig) SetDefaults() {
+	if c.Sequences == 0 {
+		c.Sequences = defaultSequences
+	}
+	if c.Steps == 0 {
+		c.Steps = defaultSteps
+	}
+	if c.ShortTTLSeconds == 0 {
+		c.ShortTTLSeconds = defaultShortTTLSeconds
+	}
+	if c.WaitSeconds == 0 {
+		c.WaitSeconds = defaultWaitSeconds
+	}
+	if c.IdleTimeoutMinutes == 0 {
+		c.IdleTimeoutMinutes = defaultIdleTimeoutMinutes
+	}
+}
+
+// Lifecycle runs a random sequence of operations on the tokens of a user and checks the state of Rancher against the
+// model of the expected state after each of them
+type Lifecycle struct {
+	Model   Model
+	Seed    uint64
+	History []string
+
+	client     *rancher.Client
+	user       *management.User
+	clusterID  string
+	config     *LifecycleConfig
+	rng        *rand.Rand
+	httpClient *http.Client
+}
+
+// NewLifecycle creates a standard user owning the cluster and returns the lifecycle of its tokens for the seed
+func NewLifecycle(client *rancher.Client, cluster *management.Cluster, config *LifecycleConfig, seed uint64) (*Lifecycle, error) {
+	user, _, err := rbac.AddUserWithRoleToCluster(client, rbac.StandardUser.String(), rbac.ClusterOwner.String(), cluster, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	insecure := client.RancherConfig.Insecure != nil && *client.RancherConfig.Insecure
+
+	return &Lifecycle{
+		Model: Model{
+			UserEnabled: true,
+			Password:    user.Password,
+		},
+		Seed:      seed,
+		client:    client,
+		user:      user,
+		clusterID: cluster.ID,
+		config:    config,
+		rng:       rand.New(rand.NewPCG(seed, seed)),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			},
+		},
+	}, nil
+}
+
+// Done returns whether the sequence can't continue, because the user was deleted
+func (l *Lifecycle) Done() bool {
+	return l.Model.UserDeleted
+}
+
+// Step applies a random operation among the ones whose preconditions hold, and updates the model
+func (l *Lifecycle) Step() error {
+	now := time.Now()
+
+	var ready []operation
+	totalWeight := 0
+	for _, op := range operations {
+		if op.ready(l, now) {
+			ready = append(ready, op)
+			totalWeight += op.weight
+		}
+	}
+
+	if totalWeight == 0 {
+		return fmt.Errorf("no operation can be applied")
+	}
+
+	pick := l.rng.IntN(totalWeight)
+	for _, op := range ready {
+		if pick >= op.weight {
+			pick -= op.weight
+			continue
+		}
+
+		description, err := op.apply(l)
+		l.History = append(l.History, fmt.Sprintf("%d. %s %s", len(l.History)+1, op.name, description))
+		if err != nil {
+			return fmt.Errorf("operation %s failed: %w", op.name, err)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// HistoryString returns the operations applied so far, one per line, to replay a failing sequence
+func (l *Lifecycle) HistoryString() string {
+	return fmt.Sprintf("seed %d:\n%s", l.Seed, strings.Join(l.History, "\n"))
+}
+
+// userClient logs in as the user. A new client is used for each operation, so the login token of the user doesn't
+// expire during the sequence.
+func (l *Lifecycle) userClient() (*rancher.Client, error) {
+	l.user.Password = l.Model.Password
+
+	userClient, err := l.client.AsUser(l.user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login as user %s: %w", l.user.Username, err)
+	}
+
+	return userClient, nil
+}
@@ -0,0 +1,142 @@
+package lifecycle
+
+import (
+	"time"
+)
+
+// TokenKind is the kind of a token in the model
+type TokenKind string
+
+const (
+	ExtTokenKind     TokenKind = "ext"
+	SessionTokenKind TokenKind = "session"
+	LegacyTokenKind  TokenKind = "legacy"
+)
+
+// clockMargin is how close to an expiration time the model considers the state of a token uncertain, to absorb the
+// clock skew between the test runner and Rancher and the latency of the requests
+const clockMargin = 15 * time.Second
+
+// TokenState is the expected state of a token of the user
+type TokenState struct {
+	Name string
+	Kind TokenKind
+	// BearerToken is the value used to authenticate with the token
+	BearerToken string
+	Enabled     bool
+	// ExpiresAt is the time the TTL of the token expires, zero when the token never expires
+	ExpiresAt time.Time
+	// IdleExpiresAt is the time the session token expires if no activity is seen, zero when unknown
+	IdleExpiresAt time.Time
+	Deleted       bool
+}
+
+// KubeconfigState is the expected state of a kubeconfig of the user and of its backing tokens
+type KubeconfigState struct {
+	Name          string
+	BackingTokens []string
+	Deleted       bool
+}
+
+// Model is the expected state of a user, its tokens and its kubeconfigs
+type Model struct {
+	UserEnabled bool
+	UserDeleted bool
+	Password    string
+	// PreviousPassword is set after a password change until the passwords are verified
+	PreviousPassword string
+	Tokens           []*TokenState
+	Kubeconfigs      []*KubeconfigState
+}
+
+// Expired returns whether the TTL of the token expired at the time, and whether that is certain
+func (m *Model) Expired(token *TokenState, now time.Time) (expired, certain bool) {
+	return pastTime(token.ExpiresAt, now)
+}
+
+// IdleExpired returns whether the session token expired for lack of activity at the time, and whether that is
+// certain
+func (m *Model) IdleExpired(token *TokenState, now time.Time) (expired, certain bool) {
+	return pastTime(token.IdleExpiresAt, now)
+}
+
+// Gone returns whether the token is expected to be deleted
+func (m *Model) Gone(token *TokenState) bool {
+	return token.Deleted || m.UserDeleted
+}
+
+// Authenticates returns whether a request authenticated with the token is expected to succeed at the time, and
+// whether that is certain
+func (m *Model) Authenticates(token *TokenState, now time.Time) (authenticates, certain bool) {
+	if m.Gone(token) || !token.Enabled || !m.UserEnabled {
+		return false, true
+	}
+
+	expired, certain := m.Expired(token, now)
+	if token.Kind == SessionTokenKind {
+		idleExpired, idleCertain := m.IdleExpired(token, now)
+		switch {
+		case expired && certain, idleExpired && idleCertain:
+			return false, true
+		case !certain || !idleCertain:
+			return false, false
+		}
+	}
+
+	return !expired, certain
+}
+
+// usableTokens returns the tokens that are not deleted, enabled, and certainly not expired at the time
+func (m *Model) usableTokens(kind TokenKind, now time.Time) []*TokenState {
+	var tokens []*TokenState
+	for _, token := range m.Tokens {
+		if token.Kind != kind || m.Gone(token) || !token.Enabled {
+			continue
+		}
+
+		if expired, certain := m.Expired(token, now); expired || !certain {
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func (m *Model) tokensWithState(enabled bool) []*TokenState {
+	var tokens []*TokenState
+	for _, token := range m.Tokens {
+		if !m.Gone(token) && token.Enabled == enabled {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func (m *Model) liveKubeconfigs() []*KubeconfigState {
+	var kubeconfigs []*KubeconfigState
+	for _, kubeconfig := range m.Kubeconfigs {
+		if !kubeconfig.Deleted && !m.UserDeleted {
+			kubeconfigs = append(kubeconfigs, kubeconfig)
+		}
+	}
+
+	return kubeconfigs
+}
+
+// pastTime returns whether the deadline passed at the time, and whether that is certain. A zero deadline never
+// passes.
+func pastTime(deadline, now time.Time) (past, certain bool) {
+	switch {
+	case deadline.IsZero():
+		return false, true
+	case now.After(deadline.Add(clockMargin)):
+		return true, true
+	case now.Before(deadline.Add(-clockMargin)):
+		return false, true
+	default:
+		return false, false
+	}
+}
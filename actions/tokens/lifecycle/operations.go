@@ -0,0 +1,386 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeconfigs"
+	"github.com/rancher/tests/actions/tokens/exttokens"
+	"github.com/rancher/tests/actions/useractivity"
+	"github.com/rancher/tests/actions/users"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	longTTL        = time.Hour
+	passwordLength = 16
+)
+
+// operation is a step of a sequence. It can only be picked when ready returns true, with a probability proportional
+// to its weight, and apply performs it against Rancher, updates the model and describes what it did.
+type operation struct {
+	name   string
+	weight int
+	ready  func(l *Lifecycle, now time.Time) bool
+	apply  func(l *Lifecycle) (string, error)
+}
+
+var operations = []operation{
+	{name: "create-ext-token", weight: 4, ready: userActive, apply: createExtToken},
+	{name: "create-session-token", weight: 3, ready: userActive, apply: createSessionToken},
+	{name: "create-legacy-token", weight: 3, ready: userActive, apply: createLegacyToken},
+	{name: "disable-token", weight: 3, ready: hasTokens(true), apply: setTokenEnabled(false)},
+	{name: "enable-token", weight: 3, ready: hasTokens(false), apply: setTokenEnabled(true)},
+	{name: "refresh-activity", weight: 3, ready: hasUsableSessionTokens, apply: refreshActivity},
+	{name: "disable-user", weight: 2, ready: userEnabled(true), apply: setUserEnabled(false)},
+	{name: "enable-user", weight: 4, ready: userEnabled(false), apply: setUserEnabled(true)},
+	{name: "change-password", weight: 2, ready: userExists, apply: changePassword},
+	{name: "create-kubeconfig", weight: 2, ready: userActive, apply: createKubeconfig},
+	{name: "delete-kubeconfig", weight: 2, ready: hasKubeconfigs, apply: deleteKubeconfig},
+	{name: "wait", weight: 3, ready: userExists, apply: wait},
+	{name: "delete-user", weight: 1, ready: userExists, apply: deleteUser},
+}
+
+func userExists(l *Lifecycle, _ time.Time) bool {
+	return !l.Model.UserDeleted
+}
+
+func userActive(l *Lifecycle, _ time.Time) bool {
+	return !l.Model.UserDeleted && l.Model.UserEnabled
+}
+
+func userEnabled(enabled bool) func(l *Lifecycle, now time.Time) bool {
+	return func(l *Lifecycle, _ time.Time) bool {
+		return !l.Model.UserDeleted && l.Model.UserEnabled == enabled
+	}
+}
+
+func hasTokens(enabled bool) func(l *Lifecycle, now time.Time) bool {
+	return func(l *Lifecycle, _ time.Time) bool {
+		return len(l.Model.tokensWithState(enabled)) > 0
+	}
+}
+
+func hasKubeconfigs(l *Lifecycle, now time.Time) bool {
+	return userActive(l, now) && len(l.Model.liveKubeconfigs()) > 0
+}
+
+// hasUsableSessionTokens returns whether the user can refresh the activity of one of its session tokens, which isn't
+// possible once the session idle timeout expired
+func hasUsableSessionTokens(l *Lifecycle, now time.Time) bool {
+	return userActive(l, now) && len(l.refreshableSessionTokens(now)) > 0
+}
+
+func (l *Lifecycle) refreshableSessionTokens(now time.Time) []*TokenState {
+	var tokens []*TokenState
+	for _, token := range l.Model.usableTokens(SessionTokenKind, now) {
+		if expired, certain := l.Model.IdleExpired(token, now); !expired && certain {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func pick[T any](l *Lifecycle, items []T) T {
+	return items[l.rng.IntN(len(items))]
+}
+
+func createExtToken(l *Lifecycle) (string, error) {
+	ttl := longTTL
+	if l.rng.IntN(2) == 0 {
+		ttl = time.Duration(l.config.ShortTTLSeconds) * time.Second
+	}
+
+	userClient, err := l.userClient()
+	if err != nil {
+		return "", err
+	}
+
+	createdAt := time.Now()
+	extToken, err := exttokens.CreateExtToken(userClient, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Sprintf("with TTL %s", ttl), err
+	}
+
+	bearerToken := extToken.Status.BearerToken
+	if bearerToken == "" {
+		bearerToken = fmt.Sprintf("ext/%s:%s", extToken.Name, extToken.Status.Value)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, extToken.Status.ExpiresAt)
+	if err != nil {
+		expiresAt = createdAt.Add(ttl)
+	}
+
+	l.Model.Tokens = append(l.Model.Tokens, &TokenState{
+		Name:        extToken.Name,
+		Kind:        ExtTokenKind,
+		BearerToken: bearerToken,
+		Enabled:     true,
+		ExpiresAt:   expiresAt,
+	})
+
+	return fmt.Sprintf("%s with TTL %s", extToken.Name, ttl), nil
+}
+
+func createSessionToken(l *Lifecycle) (string, error) {
+	userClient, err := l.userClient()
+	if err != nil {
+		return "", err
+	}
+
+	extToken, err := exttokens.CreateExtSessionToken(userClient)
+	if err != nil {
+		return "", err
+	}
+
+	bearerToken := extToken.Status.BearerToken
+	if bearerToken == "" {
+		bearerToken = fmt.Sprintf("ext/%s:%s", extToken.Name, extToken.Status.Value)
+	}
+
+	token := &TokenState{
+		Name:        extToken.Name,
+		Kind:        SessionTokenKind,
+		BearerToken: bearerToken,
+		Enabled:     true,
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, extToken.Status.ExpiresAt)
+	if err == nil {
+		token.ExpiresAt = expiresAt
+	}
+
+	// the idle timeout only applies once an activity is recorded for the session
+	userActivity, err := useractivity.GetUserActivity(userClient, extToken.Name)
+	if err == nil && userActivity.Status.ExpiresAt != "" {
+		idleExpiresAt, err := time.Parse(time.RFC3339, userActivity.Status.ExpiresAt)
+		if err == nil {
+			token.IdleExpiresAt = idleExpiresAt
+		}
+	}
+
+	l.Model.Tokens = append(l.Model.Tokens, token)
+
+	return extToken.Name, nil
+}
+
+func createLegacyToken(l *Lifecycle) (string, error) {
+	ttl := longTTL
+	if l.rng.IntN(2) == 0 {
+		ttl = time.Duration(l.config.ShortTTLSeconds) * time.Second
+	}
+
+	userClient, err := l.userClient()
+	if err != nil {
+		return "", err
+	}
+
+	createdAt := time.Now()
+	legacyToken, err := userClient.Management.Token.Create(&management.Token{
+		Description: "token lifecycle test",
+		TTLMillis:   ttl.Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Sprintf("with TTL %s", ttl), fmt.Errorf("failed to create legacy token: %w", err)
+	}
+
+	l.Model.Tokens = append(l.Model.Tokens, &TokenState{
+		Name:        legacyToken.ID,
+		Kind:        LegacyTokenKind,
+		BearerToken: legacyToken.Token,
+		Enabled:     true,
+		ExpiresAt:   createdAt.Add(ttl),
+	})
+
+	return fmt.Sprintf("%s with TTL %s", legacyToken.ID, ttl), nil
+}
+
+// setTokenEnabled enables or disables a token as admin, so that it can be done while the user is disabled
+func setTokenEnabled(enabled bool) func(l *Lifecycle) (string, error) {
+	return func(l *Lifecycle) (string, error) {
+		token := pick(l, l.Model.tokensWithState(!enabled))
+
+		var err error
+		if token.Kind == LegacyTokenKind {
+			err = updateLegacyTokenEnabled(l.client, token.Name, enabled)
+		} else {
+			err = updateExtTokenEnabled(l.client, token.Name, enabled)
+		}
+		if err != nil {
+			return token.Name, err
+		}
+
+		token.Enabled = enabled
+
+		return token.Name, nil
+	}
+}
+
+func updateExtTokenEnabled(client *rancher.Client, name string, enabled bool) error {
+	extToken, err := exttokens.GetExtToken(client, name)
+	if err != nil {
+		return err
+	}
+
+	extToken.Spec.Enabled = &enabled
+	_, err = exttokens.UpdateExtToken(client, extToken)
+
+	return err
+}
+
+func updateLegacyTokenEnabled(client *rancher.Client, name string, enabled bool) error {
+	legacyToken, err := client.WranglerContext.Mgmt.Token().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get token %s: %w", name, err)
+	}
+
+	legacyToken.Enabled = &enabled
+	_, err = client.WranglerContext.Mgmt.Token().Update(legacyToken)
+	if err != nil {
+		return fmt.Errorf("failed to update token %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func refreshActivity(l *Lifecycle) (string, error) {
+	token := pick(l, l.refreshableSessionTokens(time.Now()))
+
+	userClient, err := l.userClient()
+	if err != nil {
+		return token.Name, err
+	}
+
+	userActivity, err := useractivity.GetUserActivity(userClient, token.Name)
+	if err != nil {
+		return token.Name, err
+	}
+
+	if userActivity.Labels == nil {
+		userActivity.Labels = map[string]string{}
+	}
+	userActivity.Labels["seen"] = namegen.RandStringLower(5)
+
+	updatedUserActivity, err := useractivity.UpdateUserActivity(userClient, userActivity)
+	if err != nil {
+		return token.Name, err
+	}
+
+	idleExpiresAt, err := time.Parse(time.RFC3339, updatedUserActivity.Status.ExpiresAt)
+	if err != nil {
+		idleExpiresAt = time.Now().Add(time.Duration(l.config.IdleTimeoutMinutes) * time.Minute)
+	}
+	token.IdleExpiresAt = idleExpiresAt
+
+	return fmt.Sprintf("%s, idle until %s", token.Name, idleExpiresAt.Format(time.RFC3339)), nil
+}
+
+func setUserEnabled(enabled bool) func(l *Lifecycle) (string, error) {
+	return func(l *Lifecycle) (string, error) {
+		user, err := users.GetUserByName(l.client, l.user.ID)
+		if err != nil {
+			return l.user.ID, err
+		}
+
+		user.Enabled = &enabled
+		_, err = users.UpdateUser(l.client, user)
+		if err != nil {
+			return l.user.ID, err
+		}
+
+		l.Model.UserEnabled = enabled
+
+		return l.user.ID, nil
+	}
+}
+
+func changePassword(l *Lifecycle) (string, error) {
+	password := namegen.RandStringLower(passwordLength)
+
+	err := users.UpdateUserPassword(l.client, l.user, password)
+	if err != nil {
+		return l.user.ID, err
+	}
+
+	l.Model.PreviousPassword = l.Model.Password
+	l.Model.Password = password
+
+	return l.user.ID, nil
+}
+
+func createKubeconfig(l *Lifecycle) (string, error) {
+	userClient, err := l.userClient()
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfig, err := kubeconfigs.CreateKubeconfig(userClient, []string{l.clusterID}, "", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var backingTokens []management.Token
+	err = kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		backingTokens, err = kubeconfigs.GetBackingTokensForKubeconfigName(l.client, kubeconfig.Name)
+		if err != nil {
+			return false, err
+		}
+
+		return len(backingTokens) > 0, nil
+	})
+	if err != nil {
+		return kubeconfig.Name, fmt.Errorf("no backing token found for kubeconfig %s: %w", kubeconfig.Name, err)
+	}
+
+	state := &KubeconfigState{Name: kubeconfig.Name}
+	for _, backingToken := range backingTokens {
+		state.BackingTokens = append(state.BackingTokens, backingToken.ID)
+	}
+	l.Model.Kubeconfigs = append(l.Model.Kubeconfigs, state)
+
+	return fmt.Sprintf("%s backed by %v", kubeconfig.Name, state.BackingTokens), nil
+}
+
+func deleteKubeconfig(l *Lifecycle) (string, error) {
+	kubeconfig := pick(l, l.Model.liveKubeconfigs())
+
+	userClient, err := l.userClient()
+	if err != nil {
+		return kubeconfig.Name, err
+	}
+
+	err = kubeconfigs.DeleteKubeconfig(userClient, kubeconfig.Name)
+	if err != nil {
+		return kubeconfig.Name, err
+	}
+
+	kubeconfig.Deleted = true
+
+	return kubeconfig.Name, nil
+}
+
+func wait(l *Lifecycle) (string, error) {
+	duration := time.Duration(l.config.WaitSeconds) * time.Second
+	time.Sleep(duration)
+
+	return duration.String(), nil
+}
+
+func deleteUser(l *Lifecycle) (string, error) {
+	err := users.DeleteUser(l.client, l.user.ID)
+	if err != nil {
+		return l.user.ID, err
+	}
+
+	l.Model.UserDeleted = true
+
+	return l.user.ID, nil
+}
@@ -0,0 +1,175 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/tests/actions/namespaces"
+	"github.com/sirupsen/logrus"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	activeState        = "active"
+	readyConditionType = "Ready"
+)
+
+// RancherState is a snapshot of the Rancher objects that must survive an upgrade or a rollback
+type RancherState struct {
+	Users    []string `json:"users" yaml:"users"`
+	Tokens   []string `json:"tokens" yaml:"tokens"`
+	Projects []string `json:"projects" yaml:"projects"`
+	Clusters []string `json:"clusters" yaml:"clusters"`
+}
+
+// CaptureRancherState lists the users, non expired tokens, projects and clusters that currently exist in Rancher
+func CaptureRancherState(client *rancher.Client) (*RancherState, error) {
+	state := &RancherState{}
+
+	userList, err := client.Management.User.ListAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range userList.Data {
+		state.Users = append(state.Users, user.ID)
+	}
+
+	tokenList, err := client.Management.Token.ListAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokenList.Data {
+		if !token.Expired {
+			state.Tokens = append(state.Tokens, token.ID)
+		}
+	}
+
+	projectList, err := client.Management.Project.ListAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, project := range projectList.Data {
+		state.Projects = append(state.Projects, project.ID)
+	}
+
+	clusterList, err := client.Management.Cluster.ListAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cluster := range clusterList.Data {
+		state.Clusters = append(state.Clusters, cluster.ID)
+	}
+
+	logrus.Infof("Captured Rancher state: %d users, %d tokens, %d projects, %d clusters", len(state.Users), len(state.Tokens), len(state.Projects), len(state.Clusters))
+
+	return state, nil
+}
+
+// VerifyRancherState verifies that every user, token, project and cluster of the expected state still exists,
+// and that every cluster is active, ready and reachable through its agent
+func VerifyRancherState(client *rancher.Client, expected *RancherState) error {
+	actual, err := CaptureRancherState(client)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	errs = append(errs, missingItems("user", expected.Users, actual.Users)...)
+	errs = append(errs, missingItems("token", expected.Tokens, actual.Tokens)...)
+	errs = append(errs, missingItems("project", expected.Projects, actual.Projects)...)
+	errs = append(errs, missingItems("cluster", expected.Clusters, actual.Clusters)...)
+
+	for _, clusterID := range expected.Clusters {
+		if !slices.Contains(actual.Clusters, clusterID) {
+			continue
+		}
+
+		logrus.Infof("Verifying cluster %s is registered and its agent is connected", clusterID)
+		err = waitForClusterAgent(client, clusterID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitForRancherReady waits until the Rancher API responds again, and returns a new client logged in to it
+func WaitForRancherReady(client *rancher.Client) (*rancher.Client, error) {
+	var readyClient *rancher.Client
+
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+		readyClient, err = client.ReLogin()
+		if err != nil {
+			return false, nil
+		}
+
+		isConnected, err := readyClient.IsConnected()
+		if err != nil {
+			return false, nil
+		}
+
+		return isConnected, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readyClient, nil
+}
+
+// waitForClusterAgent waits until the cluster is active with a Ready condition and its API can be reached through the agent
+func waitForClusterAgent(client *rancher.Client, clusterID string) error {
+	return kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.TenMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+		cluster, err := client.Management.Cluster.ByID(clusterID)
+		if err != nil {
+			return false, nil
+		}
+
+		if cluster.State != activeState {
+			return false, nil
+		}
+
+		isReady := false
+		for _, condition := range cluster.Conditions {
+			if condition.Type == readyConditionType && condition.Status == "True" {
+				isReady = true
+			}
+		}
+
+		if !isReady {
+			return false, nil
+		}
+
+		steveClient, err := client.Steve.ProxyDownstream(clusterID)
+		if err != nil {
+			return false, nil
+		}
+
+		_, err = steveClient.SteveType(namespaces.NamespaceSteveType).List(nil)
+		if err != nil {
+			return false, nil
+		}
+
+		return true, nil
+	})
+}
+
+func missingItems(kind string, expected, actual []string) []error {
+	var errs []error
+	for _, item := range expected {
+		if !slices.Contains(actual, item) {
+			errs = append(errs, fmt.Errorf("%s %s not found", kind, item))
+		}
+	}
+
+	return errs
+}
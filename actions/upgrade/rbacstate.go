@@ -0,0 +1,362 @@
+package upgrade
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
+	"github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	crtbKind               = "ClusterRoleTemplateBinding"
+	prtbKind               = "ProjectRoleTemplateBinding"
+	roleBindingKind        = "RoleBinding"
+	clusterRoleBindingKind = "ClusterRoleBinding"
+)
+
+// RBACState is a normalized snapshot of the Rancher RBAC graph: the global role bindings, the cluster and project
+// role template bindings, and the role bindings and cluster role bindings generated for them in the local and
+// downstream clusters. Objects are identified by their content, so generated names and UIDs don't show in a diff.
+type RBACState struct {
+	ClusterIDs           []string                   `json:"clusterIDs" yaml:"clusterIDs"`
+	CapturedAt           time.Time                  `json:"capturedAt" yaml:"capturedAt"`
+	GlobalRoleBindings   []GlobalRoleBindingState   `json:"globalRoleBindings" yaml:"globalRoleBindings"`
+	RoleTemplateBindings []RoleTemplateBindingState `json:"roleTemplateBindings" yaml:"roleTemplateBindings"`
+	Bindings             []BindingState             `json:"bindings" yaml:"bindings"`
+}
+
+// GlobalRoleBindingState is a global role granted to a subject
+type GlobalRoleBindingState struct {
+	GlobalRole string `json:"globalRole" yaml:"globalRole"`
+	Subject    string `json:"subject" yaml:"subject"`
+}
+
+// RoleTemplateBindingState is a role template granted to a subject in a cluster, or in a project as cluster:project
+type RoleTemplateBindingState struct {
+	Kind         string `json:"kind" yaml:"kind"`
+	Scope        string `json:"scope" yaml:"scope"`
+	RoleTemplate string `json:"roleTemplate" yaml:"roleTemplate"`
+	Subject      string `json:"subject" yaml:"subject"`
+}
+
+// BindingState is a role binding or cluster role binding generated for a role template binding
+type BindingState struct {
+	ClusterID string   `json:"clusterID" yaml:"clusterID"`
+	Kind      string   `json:"kind" yaml:"kind"`
+	Namespace string   `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	RoleRef   string   `json:"roleRef" yaml:"roleRef"`
+	Subjects  []string `json:"subjects" yaml:"subjects"`
+}
+
+// RBACStateDiff holds the entries of an RBAC state that were removed or added, as returned by String on each entry
+type RBACStateDiff struct {
+	Missing []string
+	Added   []string
+}
+
+// String returns the global role binding as a single line
+func (g GlobalRoleBindingState) String() string {
+	return fmt.Sprintf("GlobalRoleBinding %s -> %s", g.Subject, g.GlobalRole)
+}
+
+// String returns the role template binding as a single line
+func (r RoleTemplateBindingState) String() string {
+	return fmt.Sprintf("%s %s: %s -> %s", r.Kind, r.Scope, r.Subject, r.RoleTemplate)
+}
+
+// String returns the binding as a single line
+func (b BindingState) String() string {
+	location := b.ClusterID
+	if b.Namespace != "" {
+		location += "/" + b.Namespace
+	}
+
+	return fmt.Sprintf("%s %s: %s -> %s", b.Kind, location, strings.Join(b.Subjects, ","), b.RoleRef)
+}
+
+// CaptureRBACState snapshots the global role bindings, and the role template bindings of the given clusters with the
+// role bindings and cluster role bindings generated for them in the local cluster and in the clusters themselves
+func CaptureRBACState(client *rancher.Client, clusterIDs []string) (*RBACState, error) {
+	state := &RBACState{
+		ClusterIDs: clusterIDs,
+		CapturedAt: time.Now().UTC(),
+	}
+
+	grbList, err := rbacapi.ListGlobalRoleBindings(client, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, grb := range grbList.Items {
+		state.GlobalRoleBindings = append(state.GlobalRoleBindings, GlobalRoleBindingState{
+			GlobalRole: grb.GlobalRoleName,
+			Subject:    bindingSubject(grb.UserName, grb.UserPrincipalName, grb.GroupPrincipalName),
+		})
+	}
+
+	crtbList, err := rbacapi.ListClusterRoleTemplateBindings(client, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	crtbs := &v3.ClusterRoleTemplateBindingList{}
+	for _, crtb := range crtbList.Items {
+		if !slices.Contains(clusterIDs, crtb.ClusterName) {
+			continue
+		}
+
+		crtbs.Items = append(crtbs.Items, crtb)
+		state.RoleTemplateBindings = append(state.RoleTemplateBindings, RoleTemplateBindingState{
+			Kind:         crtbKind,
+			Scope:        crtb.ClusterName,
+			RoleTemplate: crtb.RoleTemplateName,
+			Subject:      bindingSubject(crtb.UserName, crtb.UserPrincipalName, crtb.GroupPrincipalName),
+		})
+	}
+
+	prtbList, err := rbacapi.ListProjectRoleTemplateBindings(client, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var prtbs []v3.ProjectRoleTemplateBinding
+	for _, prtb := range prtbList.Items {
+		if !slices.Contains(clusterIDs, prtb.ObjClusterName()) {
+			continue
+		}
+
+		prtbs = append(prtbs, prtb)
+		state.RoleTemplateBindings = append(state.RoleTemplateBindings, RoleTemplateBindingState{
+			Kind:         prtbKind,
+			Scope:        prtb.ProjectName,
+			RoleTemplate: prtb.RoleTemplateName,
+			Subject:      bindingSubject(prtb.UserName, prtb.UserPrincipalName, prtb.GroupPrincipalName),
+		})
+	}
+
+	localRoleBindings, err := rbacapi.GetRoleBindingsForCRTBs(client, crtbs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, roleBinding := range localRoleBindings.Items {
+		state.Bindings = append(state.Bindings, newBindingState(extclusterapi.LocalCluster, roleBindingKind, roleBinding.Namespace, roleBinding.RoleRef, roleBinding.Subjects))
+	}
+
+	localClusterRoleBindings, err := rbacapi.GetClusterRoleBindingsForCRTBs(client, crtbs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, clusterRoleBinding := range localClusterRoleBindings.Items {
+		state.Bindings = append(state.Bindings, newBindingState(extclusterapi.LocalCluster, clusterRoleBindingKind, "", clusterRoleBinding.RoleRef, clusterRoleBinding.Subjects))
+	}
+
+	for _, clusterID := range clusterIDs {
+		logrus.Infof("Capturing the RBAC bindings of cluster %s", clusterID)
+		bindings, err := captureClusterBindings(client, clusterID, crtbs.Items, prtbs)
+		if err != nil {
+			return nil, err
+		}
+
+		state.Bindings = append(state.Bindings, bindings...)
+	}
+
+	state.normalize()
+
+	logrus.Infof("Captured RBAC state: %d global role bindings, %d role template bindings, %d generated bindings", len(state.GlobalRoleBindings), len(state.RoleTemplateBindings), len(state.Bindings))
+
+	return state, nil
+}
+
+// SaveRBACState writes the RBAC state as JSON to the given path, so it can be compared after the upgrade
+func SaveRBACState(state *RBACState, path string) error {
+	return saveState(state, path)
+}
+
+// LoadRBACState reads an RBAC state previously written by SaveRBACState
+func LoadRBACState(path string) (*RBACState, error) {
+	return loadState[RBACState](path)
+}
+
+// DiffRBACState compares two RBAC states of the same clusters. Entries are compared as multisets, so a binding
+// duplicated before the upgrade must still be duplicated after it.
+func DiffRBACState(pre, post *RBACState) *RBACStateDiff {
+	preEntries := pre.entries()
+	postEntries := post.entries()
+
+	return &RBACStateDiff{
+		Missing: subtractEntries(preEntries, postEntries),
+		Added:   subtractEntries(postEntries, preEntries),
+	}
+}
+
+// VerifyRBACState captures the current RBAC state of the clusters found in the pre upgrade state and returns an
+// error if any entry is missing. Added entries are logged, as an upgrade may add new built-in bindings.
+func VerifyRBACState(client *rancher.Client, pre *RBACState) (*RBACStateDiff, error) {
+	post, err := CaptureRBACState(client, pre.ClusterIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffRBACState(pre, post)
+	for _, entry := range diff.Added {
+		logrus.Infof("RBAC entry added after upgrade: %s", entry)
+	}
+
+	return diff, diff.Err()
+}
+
+// Err returns an error listing every missing entry, or nil if there are none
+func (r *RBACStateDiff) Err() error {
+	if len(r.Missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("RBAC state changed after upgrade:\nmissing: %s", strings.Join(r.Missing, "\nmissing: "))
+}
+
+// captureClusterBindings returns the role bindings and cluster role bindings of the cluster granting the role
+// template of a binding to its subject. Their role refs start with the role template name, as for the aggregated
+// cluster roles, or are the role template name itself.
+func captureClusterBindings(client *rancher.Client, clusterID string, crtbs []v3.ClusterRoleTemplateBinding, prtbs []v3.ProjectRoleTemplateBinding) ([]BindingState, error) {
+	type grant struct {
+		subject      string
+		roleTemplate string
+	}
+
+	var grants []grant
+	for _, crtb := range crtbs {
+		if crtb.ClusterName == clusterID {
+			grants = append(grants, grant{bindingSubject(crtb.UserName, crtb.UserPrincipalName, crtb.GroupPrincipalName), crtb.RoleTemplateName})
+		}
+	}
+
+	for _, prtb := range prtbs {
+		if prtb.ObjClusterName() == clusterID {
+			grants = append(grants, grant{bindingSubject(prtb.UserName, prtb.UserPrincipalName, prtb.GroupPrincipalName), prtb.RoleTemplateName})
+		}
+	}
+
+	isGranted := func(roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) bool {
+		return slices.ContainsFunc(grants, func(g grant) bool {
+			return strings.HasPrefix(roleRef.Name, g.roleTemplate) && slices.Contains(normalizeSubjects(subjects), g.subject)
+		})
+	}
+
+	roleBindings, err := rbacapi.ListRoleBindings(client, clusterID, "", metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []BindingState
+	for _, roleBinding := range roleBindings.Items {
+		if isGranted(roleBinding.RoleRef, roleBinding.Subjects) {
+			bindings = append(bindings, newBindingState(clusterID, roleBindingKind, roleBinding.Namespace, roleBinding.RoleRef, roleBinding.Subjects))
+		}
+	}
+
+	clusterRoleBindings, err := rbacapi.ListClusterRoleBindings(client, clusterID, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, clusterRoleBinding := range clusterRoleBindings.Items {
+		if isGranted(clusterRoleBinding.RoleRef, clusterRoleBinding.Subjects) {
+			bindings = append(bindings, newBindingState(clusterID, clusterRoleBindingKind, "", clusterRoleBinding.RoleRef, clusterRoleBinding.Subjects))
+		}
+	}
+
+	return bindings, nil
+}
+
+func newBindingState(clusterID, kind, namespace string, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) BindingState {
+	return BindingState{
+		ClusterID: clusterID,
+		Kind:      kind,
+		Namespace: namespace,
+		RoleRef:   roleRef.Kind + "/" + roleRef.Name,
+		Subjects:  normalizeSubjects(subjects),
+	}
+}
+
+// bindingSubject returns the subject of a Rancher binding, which is a user, a user principal or a group principal
+func bindingSubject(userName, userPrincipalName, groupPrincipalName string) string {
+	switch {
+	case userName != "":
+		return userName
+	case userPrincipalName != "":
+		return userPrincipalName
+	}
+
+	return groupPrincipalName
+}
+
+// normalizeSubjects returns the sorted names of the subjects, which for users and groups are the Rancher user names
+// and principal names
+func normalizeSubjects(subjects []rbacv1.Subject) []string {
+	var names []string
+	for _, subject := range subjects {
+		name := subject.Name
+		if subject.Kind == rbacv1.ServiceAccountKind {
+			name = subject.Namespace + "/" + subject.Name
+		}
+
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func (r *RBACState) normalize() {
+	slices.SortFunc(r.GlobalRoleBindings, func(a, b GlobalRoleBindingState) int { return strings.Compare(a.String(), b.String()) })
+	slices.SortFunc(r.RoleTemplateBindings, func(a, b RoleTemplateBindingState) int { return strings.Compare(a.String(), b.String()) })
+	slices.SortFunc(r.Bindings, func(a, b BindingState) int { return strings.Compare(a.String(), b.String()) })
+}
+
+func (r *RBACState) entries() []string {
+	var entries []string
+	for _, grb := range r.GlobalRoleBindings {
+		entries = append(entries, grb.String())
+	}
+
+	for _, rtb := range r.RoleTemplateBindings {
+		entries = append(entries, rtb.String())
+	}
+
+	for _, binding := range r.Bindings {
+		entries = append(entries, binding.String())
+	}
+
+	return entries
+}
+
+// subtractEntries returns the entries of a that are not in b, counting duplicates
+func subtractEntries(a, b []string) []string {
+	counts := map[string]int{}
+	for _, entry := range b {
+		counts[entry]++
+	}
+
+	var remaining []string
+	for _, entry := range a {
+		if counts[entry] > 0 {
+			counts[entry]--
+			continue
+		}
+
+		remaining = append(remaining, entry)
+	}
+
+	return remaining
+}
@@ -0,0 +1,47 @@
+package upgrade
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// StateDirEnvVar is the environment variable of the directory the pre-upgrade states are stored in. The pre and post
+// upgrade runs must use the same directory, the temporary directory of the system when it is not set.
+const StateDirEnvVar = "UPGRADE_STATE_DIR"
+
+// StateFilePath returns the absolute path of the state file with the given name in the state directory
+func StateFilePath(name string) (string, error) {
+	dir := os.Getenv(StateDirEnvVar)
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	return filepath.Abs(filepath.Join(dir, name))
+}
+
+// saveState writes a state captured before an upgrade as JSON to the given path, so it can be compared after the upgrade
+func saveState(state any, path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadState reads a state previously written by saveState
+func loadState[T any](path string) (*T, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := new(T)
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
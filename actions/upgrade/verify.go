@@ -0,0 +1,229 @@
+package upgrade
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/sirupsen/logrus"
+)
+
+// WorkloadStateChange describes a single difference found between a pre and post upgrade workload state
+type WorkloadStateChange struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Detail    string
+}
+
+// String returns a human readable representation of the change
+func (w WorkloadStateChange) String() string {
+	return fmt.Sprintf("%s %s/%s: %s", w.Kind, w.Namespace, w.Name, w.Detail)
+}
+
+// WorkloadStateDiff groups the differences found between a pre and post upgrade workload state
+type WorkloadStateDiff struct {
+	Missing              []WorkloadStateChange
+	Drift                []WorkloadStateChange
+	Restarts             []WorkloadStateChange
+	UnavailableEndpoints []WorkloadStateChange
+	NotReady             []WorkloadStateChange
+}
+
+// IsEmpty returns true if no missing objects, drift, unavailable endpoints or pods that lost readiness were found.
+// Restarts are informational, as pods are expected to be rescheduled while nodes are drained.
+func (w *WorkloadStateDiff) IsEmpty() bool {
+	return len(w.Missing) == 0 && len(w.Drift) == 0 && len(w.UnavailableEndpoints) == 0 && len(w.NotReady) == 0
+}
+
+// Err returns an error listing every missing object, drift, unavailable endpoint and pod that lost readiness, or nil
+// if there are none
+func (w *WorkloadStateDiff) Err() error {
+	if w.IsEmpty() {
+		return nil
+	}
+
+	var lines []string
+	for _, change := range w.Missing {
+		lines = append(lines, "missing: "+change.String())
+	}
+
+	for _, change := range w.Drift {
+		lines = append(lines, "drift: "+change.String())
+	}
+
+	for _, change := range w.UnavailableEndpoints {
+		lines = append(lines, "unavailable: "+change.String())
+	}
+
+	for _, change := range w.NotReady {
+		lines = append(lines, "not ready: "+change.String())
+	}
+
+	return fmt.Errorf("workload state changed after upgrade:\n%s", strings.Join(lines, "\n"))
+}
+
+// DiffWorkloadState compares two workload states of the same namespaces and reports missing objects,
+// spec drift, pod restarts, and pods and endpoints that were ready or available before but not after
+func DiffWorkloadState(pre, post *WorkloadState) *WorkloadStateDiff {
+	diff := &WorkloadStateDiff{}
+
+	for _, preNamespace := range pre.Namespaces {
+		postNamespace := findNamespaceState(post, preNamespace.Name)
+		if postNamespace == nil {
+			diff.Missing = append(diff.Missing, WorkloadStateChange{Namespace: preNamespace.Name, Kind: "Namespace", Name: preNamespace.Name, Detail: "namespace not found"})
+			continue
+		}
+
+		diffWorkloads(diff, &preNamespace, postNamespace)
+		diffPods(diff, &preNamespace, postNamespace)
+		diffServices(diff, &preNamespace, postNamespace)
+		diffIngresses(diff, &preNamespace, postNamespace)
+	}
+
+	return diff
+}
+
+// VerifyWorkloadState captures the current state of the namespaces found in the pre upgrade state and
+// returns an error if any object is missing, drifted or no longer available
+func VerifyWorkloadState(client *rancher.Client, pre *WorkloadState, checkIngresses bool) (*WorkloadStateDiff, error) {
+	post, err := CaptureWorkloadState(client, pre.ClusterID, pre.NamespaceNames(), checkIngresses)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffWorkloadState(pre, post)
+	for _, change := range diff.Restarts {
+		logrus.Infof("Restart detected after upgrade: %s", change)
+	}
+
+	return diff, diff.Err()
+}
+
+func diffWorkloads(diff *WorkloadStateDiff, pre, post *NamespaceState) {
+	for _, preWorkload := range pre.Workloads {
+		index := slices.IndexFunc(post.Workloads, func(w WorkloadSpec) bool {
+			return w.Kind == preWorkload.Kind && w.Name == preWorkload.Name
+		})
+		if index < 0 {
+			diff.Missing = append(diff.Missing, WorkloadStateChange{Namespace: pre.Name, Kind: preWorkload.Kind, Name: preWorkload.Name, Detail: "workload not found"})
+			continue
+		}
+
+		postWorkload := post.Workloads[index]
+		change := WorkloadStateChange{Namespace: pre.Name, Kind: preWorkload.Kind, Name: preWorkload.Name}
+
+		if preWorkload.Generation != postWorkload.Generation {
+			change.Detail = fmt.Sprintf("generation changed from %d to %d", preWorkload.Generation, postWorkload.Generation)
+			diff.Drift = append(diff.Drift, change)
+		}
+
+		if !slices.Equal(preWorkload.Images, postWorkload.Images) {
+			change.Detail = fmt.Sprintf("images changed from %v to %v", preWorkload.Images, postWorkload.Images)
+			diff.Drift = append(diff.Drift, change)
+		}
+
+		if preWorkload.ReadyReplicas >= preWorkload.DesiredReplicas && postWorkload.ReadyReplicas < postWorkload.DesiredReplicas {
+			change.Detail = fmt.Sprintf("%d/%d replicas ready", postWorkload.ReadyReplicas, postWorkload.DesiredReplicas)
+			diff.UnavailableEndpoints = append(diff.UnavailableEndpoints, change)
+		}
+	}
+}
+
+func diffPods(diff *WorkloadStateDiff, pre, post *NamespaceState) {
+	for _, prePod := range pre.Pods {
+		index := slices.IndexFunc(post.Pods, func(p PodState) bool {
+			return p.Name == prePod.Name
+		})
+
+		change := WorkloadStateChange{Namespace: pre.Name, Kind: "Pod", Name: prePod.Name}
+		if index < 0 {
+			change.Detail = "pod was replaced"
+			if prePod.Owner != "" {
+				change.Detail += " (owner " + prePod.Owner + ")"
+			}
+
+			diff.Restarts = append(diff.Restarts, change)
+
+			// a ready pod must have a ready replacement, owned by the same workload
+			if prePod.Ready && prePod.Owner != "" && !slices.ContainsFunc(post.Pods, func(p PodState) bool {
+				return p.Owner == prePod.Owner && p.Ready
+			}) {
+				diff.NotReady = append(diff.NotReady, WorkloadStateChange{Namespace: pre.Name, Kind: "Pod", Name: prePod.Name, Detail: "no ready replacement pod for owner " + prePod.Owner})
+			}
+
+			continue
+		}
+
+		postPod := post.Pods[index]
+		if postPod.RestartCount > prePod.RestartCount {
+			change.Detail = fmt.Sprintf("restart count increased from %d to %d", prePod.RestartCount, postPod.RestartCount)
+			diff.Restarts = append(diff.Restarts, change)
+		}
+
+		if prePod.Ready && !postPod.Ready {
+			diff.NotReady = append(diff.NotReady, WorkloadStateChange{Namespace: pre.Name, Kind: "Pod", Name: prePod.Name, Detail: "pod was ready before upgrade"})
+		}
+	}
+}
+
+func diffServices(diff *WorkloadStateDiff, pre, post *NamespaceState) {
+	for _, preService := range pre.Services {
+		index := slices.IndexFunc(post.Services, func(s ServiceState) bool {
+			return s.Name == preService.Name
+		})
+		if index < 0 {
+			diff.Missing = append(diff.Missing, WorkloadStateChange{Namespace: pre.Name, Kind: "Service", Name: preService.Name, Detail: "service not found"})
+			continue
+		}
+
+		postService := post.Services[index]
+		change := WorkloadStateChange{Namespace: pre.Name, Kind: "Service", Name: preService.Name}
+
+		if preService.Type != postService.Type || preService.SpecHash != postService.SpecHash {
+			change.Detail = "ports, selector or type changed"
+			diff.Drift = append(diff.Drift, change)
+		}
+
+		if preService.ReadyEndpoints > 0 && postService.ReadyEndpoints == 0 {
+			change.Detail = fmt.Sprintf("no ready endpoints, had %d before upgrade", preService.ReadyEndpoints)
+			diff.UnavailableEndpoints = append(diff.UnavailableEndpoints, change)
+		}
+	}
+}
+
+func diffIngresses(diff *WorkloadStateDiff, pre, post *NamespaceState) {
+	for _, preIngress := range pre.Ingresses {
+		index := slices.IndexFunc(post.Ingresses, func(i IngressState) bool {
+			return i.Name == preIngress.Name
+		})
+		if index < 0 {
+			diff.Missing = append(diff.Missing, WorkloadStateChange{Namespace: pre.Name, Kind: "Ingress", Name: preIngress.Name, Detail: "ingress not found"})
+			continue
+		}
+
+		postIngress := post.Ingresses[index]
+		change := WorkloadStateChange{Namespace: pre.Name, Kind: "Ingress", Name: preIngress.Name}
+
+		if preIngress.SpecHash != postIngress.SpecHash {
+			change.Detail = fmt.Sprintf("rules changed, hosts %v to %v", preIngress.Hosts, postIngress.Hosts)
+			diff.Drift = append(diff.Drift, change)
+		}
+
+		if preIngress.Accessible && !postIngress.Accessible {
+			change.Detail = "ingress is no longer accessible"
+			diff.UnavailableEndpoints = append(diff.UnavailableEndpoints, change)
+		}
+	}
+}
+
+func findNamespaceState(state *WorkloadState, name string) *NamespaceState {
+	for i := range state.Namespaces {
+		if state.Namespaces[i].Name == name {
+			return &state.Namespaces[i]
+		}
+	}
+
+	return nil
+}
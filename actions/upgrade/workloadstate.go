@@ -0,0 +1,289 @@
+package upgrade
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/ingresses"
+	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
+	"github.com/rancher/shepherd/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	DeploymentKind  = "Deployment"
+	DaemonSetKind   = "DaemonSet"
+	StatefulSetKind = "StatefulSet"
+)
+
+// WorkloadState is a point-in-time snapshot of the workloads, pods, services and ingresses of a set of namespaces in a cluster
+type WorkloadState struct {
+	ClusterID  string           `json:"clusterID" yaml:"clusterID"`
+	CapturedAt time.Time        `json:"capturedAt" yaml:"capturedAt"`
+	Namespaces []NamespaceState `json:"namespaces" yaml:"namespaces"`
+}
+
+// NamespaceState holds the captured objects of a single namespace
+type NamespaceState struct {
+	Name      string         `json:"name" yaml:"name"`
+	Workloads []WorkloadSpec `json:"workloads" yaml:"workloads"`
+	Pods      []PodState     `json:"pods" yaml:"pods"`
+	Services  []ServiceState `json:"services" yaml:"services"`
+	Ingresses []IngressState `json:"ingresses" yaml:"ingresses"`
+}
+
+// WorkloadSpec holds the relevant spec and readiness of a deployment, daemonset or statefulset
+type WorkloadSpec struct {
+	Kind            string   `json:"kind" yaml:"kind"`
+	Name            string   `json:"name" yaml:"name"`
+	Generation      int64    `json:"generation" yaml:"generation"`
+	Images          []string `json:"images" yaml:"images"`
+	DesiredReplicas int32    `json:"desiredReplicas" yaml:"desiredReplicas"`
+	ReadyReplicas   int32    `json:"readyReplicas" yaml:"readyReplicas"`
+}
+
+// PodState holds the readiness and restart count of a single pod
+type PodState struct {
+	Name         string `json:"name" yaml:"name"`
+	Owner        string `json:"owner" yaml:"owner"`
+	Ready        bool   `json:"ready" yaml:"ready"`
+	RestartCount int32  `json:"restartCount" yaml:"restartCount"`
+}
+
+// ServiceState holds the spec hash and the number of ready endpoint addresses of a service
+type ServiceState struct {
+	Name           string `json:"name" yaml:"name"`
+	Type           string `json:"type" yaml:"type"`
+	SpecHash       string `json:"specHash" yaml:"specHash"`
+	ReadyEndpoints int    `json:"readyEndpoints" yaml:"readyEndpoints"`
+}
+
+// IngressState holds the spec hash, hosts and external reachability of an ingress
+type IngressState struct {
+	Name       string   `json:"name" yaml:"name"`
+	Hosts      []string `json:"hosts" yaml:"hosts"`
+	SpecHash   string   `json:"specHash" yaml:"specHash"`
+	Accessible bool     `json:"accessible" yaml:"accessible"`
+}
+
+// CaptureWorkloadState snapshots the workloads, pods, services and ingresses of the given namespaces in a cluster.
+// When checkIngresses is true, every ingress host is probed to record its external reachability.
+func CaptureWorkloadState(client *rancher.Client, clusterID string, namespaces []string, checkIngresses bool) (*WorkloadState, error) {
+	clusterContext, err := extclusterapi.GetClusterWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &WorkloadState{
+		ClusterID:  clusterID,
+		CapturedAt: time.Now().UTC(),
+	}
+
+	for _, namespace := range namespaces {
+		logrus.Infof("Capturing workload state of namespace %s in cluster %s", namespace, clusterID)
+		namespaceState, err := captureNamespaceState(client, clusterContext, namespace, checkIngresses)
+		if err != nil {
+			return nil, err
+		}
+
+		state.Namespaces = append(state.Namespaces, *namespaceState)
+	}
+
+	return state, nil
+}
+
+// SaveWorkloadState writes the workload state as JSON to the given path, so it can be compared after the upgrade
+func SaveWorkloadState(state *WorkloadState, path string) error {
+	return saveState(state, path)
+}
+
+// LoadWorkloadState reads a workload state previously written by SaveWorkloadState
+func LoadWorkloadState(path string) (*WorkloadState, error) {
+	return loadState[WorkloadState](path)
+}
+
+// NamespaceNames returns the names of the namespaces contained in the workload state
+func (w *WorkloadState) NamespaceNames() []string {
+	var names []string
+	for _, namespace := range w.Namespaces {
+		names = append(names, namespace.Name)
+	}
+
+	return names
+}
+
+func captureNamespaceState(client *rancher.Client, clusterContext *wrangler.Context, namespace string, checkIngresses bool) (*NamespaceState, error) {
+	state := &NamespaceState{Name: namespace}
+
+	deployments, err := clusterContext.Apps.Deployment().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range deployments.Items {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+
+		state.Workloads = append(state.Workloads, newWorkloadSpec(DeploymentKind, deployment.ObjectMeta, deployment.Spec.Template, replicas, deployment.Status.ReadyReplicas))
+	}
+
+	daemonSets, err := clusterContext.Apps.DaemonSet().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, daemonSet := range daemonSets.Items {
+		state.Workloads = append(state.Workloads, newWorkloadSpec(DaemonSetKind, daemonSet.ObjectMeta, daemonSet.Spec.Template, daemonSet.Status.DesiredNumberScheduled, daemonSet.Status.NumberReady))
+	}
+
+	statefulSets, err := clusterContext.Apps.StatefulSet().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statefulSet := range statefulSets.Items {
+		replicas := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+
+		state.Workloads = append(state.Workloads, newWorkloadSpec(StatefulSetKind, statefulSet.ObjectMeta, statefulSet.Spec.Template, replicas, statefulSet.Status.ReadyReplicas))
+	}
+
+	pods, err := clusterContext.Core.Pod().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		state.Pods = append(state.Pods, newPodState(&pod))
+	}
+
+	services, err := clusterContext.Core.Service().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services.Items {
+		serviceState := ServiceState{
+			Name: service.Name,
+			Type: string(service.Spec.Type),
+			SpecHash: hashObject(struct {
+				Ports    []corev1.ServicePort
+				Selector map[string]string
+			}{service.Spec.Ports, service.Spec.Selector}),
+		}
+
+		endpoints, err := clusterContext.Core.Endpoints().Get(namespace, service.Name, metav1.GetOptions{})
+		if err == nil {
+			for _, subset := range endpoints.Subsets {
+				serviceState.ReadyEndpoints += len(subset.Addresses)
+			}
+		}
+
+		state.Services = append(state.Services, serviceState)
+	}
+
+	ingressList, err := clusterContext.Networking.Ingress().List(namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ingress := range ingressList.Items {
+		ingressState := IngressState{
+			Name:     ingress.Name,
+			SpecHash: hashObject(ingress.Spec.Rules),
+		}
+
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != "" {
+				ingressState.Hosts = append(ingressState.Hosts, rule.Host)
+			}
+		}
+
+		if checkIngresses && len(ingressState.Hosts) > 0 {
+			ingressState.Accessible = isIngressAccessible(client, ingressState.Hosts[0])
+		}
+
+		state.Ingresses = append(state.Ingresses, ingressState)
+	}
+
+	return state, nil
+}
+
+func newWorkloadSpec(kind string, objectMeta metav1.ObjectMeta, template corev1.PodTemplateSpec, desired, ready int32) WorkloadSpec {
+	var images []string
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+
+	sort.Strings(images)
+
+	return WorkloadSpec{
+		Kind:            kind,
+		Name:            objectMeta.Name,
+		Generation:      objectMeta.Generation,
+		Images:          images,
+		DesiredReplicas: desired,
+		ReadyReplicas:   ready,
+	}
+}
+
+func newPodState(pod *corev1.Pod) PodState {
+	podState := PodState{Name: pod.Name}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			podState.Owner = owner.Kind + "/" + owner.Name
+		}
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			podState.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		podState.RestartCount += status.RestartCount
+	}
+
+	return podState
+}
+
+// isIngressAccessible polls the ingress host for a short period and reports whether it answered
+func isIngressAccessible(client *rancher.Client, hostname string) bool {
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (done bool, err error) {
+		accessible, err := ingresses.IsIngressExternallyAccessible(client, hostname, "", false)
+		if err != nil {
+			return false, nil
+		}
+
+		return accessible, nil
+	})
+
+	return err == nil
+}
+
+// hashObject returns a stable hash of the JSON representation of the object
+func hashObject(object any) string {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
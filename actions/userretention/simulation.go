@@ -0,0 +1,247 @@
+package userretention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/users"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Outcome is the expected state of a user after a retention run
+type Outcome string
+
+const (
+	OutcomeEnabled  Outcome = "enabled"
+	OutcomeDisabled Outcome = "disabled"
+	OutcomeDeleted  Outcome = "deleted"
+)
+
+// Scenario describes a user whose activity is simulated with a synthetic last login, and the outcome expected from
+// the retention process
+type Scenario struct {
+	Name       string
+	GlobalRole string
+	// LastLoginAgo is how long before the simulation started the user last logged in
+	LastLoginAgo time.Duration
+	// NeverLoggedIn users have no recorded login, the user-last-login-default setting applies to them
+	NeverLoggedIn bool
+	// WithoutAttributes users never logged in and have no user attributes at all, the retention process skips them
+	WithoutAttributes bool
+	// DisabledAgo users were disabled by the retention process that long before the simulation started, which is
+	// recorded in the DisabledAtLabelKey label. Their last login is the disable time minus the disable period, so the
+	// delete period runs from the disable time for the difference between the delete and disable periods.
+	DisabledAgo time.Duration
+	// DisableAfter and DeleteAfter are the user specific overrides of the settings, if set
+	DisableAfter *time.Duration
+	DeleteAfter  *time.Duration
+	Expected     Outcome
+}
+
+// SimulatedUser is a user created for a scenario
+type SimulatedUser struct {
+	Scenario
+	User       *management.User
+	LastLogin  time.Time
+	DisabledAt time.Time
+}
+
+// CreateSimulatedUser creates the user of the scenario and records its synthetic last login relative to now. The
+// retention settings are needed for the scenarios of disabled users, whose last login is derived from the disable
+// period.
+func CreateSimulatedUser(client *rancher.Client, scenario Scenario, retention RetentionSettings, now time.Time) (*SimulatedUser, error) {
+	user, err := users.CreateUserWithRole(client, users.UserConfig(), scenario.GlobalRole)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: failed to create user: %w", scenario.Name, err)
+	}
+
+	simulated := &SimulatedUser{Scenario: scenario, User: user}
+	if scenario.WithoutAttributes {
+		return simulated, nil
+	}
+
+	if !scenario.NeverLoggedIn {
+		simulated.LastLogin = now.Add(-scenario.LastLoginAgo).Truncate(time.Second)
+	}
+
+	if scenario.DisabledAgo > 0 {
+		disablePeriod, err := retentionPeriod(retention.DisableAfter, scenario.DisableAfter)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", scenario.Name, err)
+		}
+		if disablePeriod <= 0 {
+			return nil, fmt.Errorf("scenario %s: a disabled user needs a disable period", scenario.Name)
+		}
+
+		simulated.DisabledAt = now.Add(-scenario.DisabledAgo).Truncate(time.Second)
+		simulated.LastLogin = simulated.DisabledAt.Add(-disablePeriod)
+	}
+
+	err = SetLastLogin(client, user.ID, simulated.LastLogin)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", scenario.Name, err)
+	}
+
+	if scenario.DisableAfter != nil || scenario.DeleteAfter != nil {
+		err = SetRetentionOverrides(client, user.ID, scenario.DisableAfter, scenario.DeleteAfter)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", scenario.Name, err)
+		}
+	}
+
+	if !simulated.DisabledAt.IsZero() {
+		err = SetDisabledAt(client, user.ID, simulated.DisabledAt)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", scenario.Name, err)
+		}
+	}
+
+	return simulated, nil
+}
+
+// RecordLogin simulates a login of the user at the time
+func (u *SimulatedUser) RecordLogin(client *rancher.Client, loginTime time.Time) error {
+	u.LastLogin = loginTime.Truncate(time.Second)
+	u.NeverLoggedIn = false
+
+	return SetLastLogin(client, u.User.ID, u.LastLogin)
+}
+
+// Reenable enables the user again, as an admin would after the retention process disabled it
+func (u *SimulatedUser) Reenable(client *rancher.Client) error {
+	user, err := client.WranglerContext.Mgmt.User().Get(u.User.ID, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", u.User.ID, err)
+	}
+
+	enabled := true
+	user.Enabled = &enabled
+	_, err = client.WranglerContext.Mgmt.User().Update(user)
+	if err != nil {
+		return fmt.Errorf("failed to enable user %s: %w", u.User.ID, err)
+	}
+
+	return nil
+}
+
+// ExpectedLabels returns the retention labels expected on the user for the settings. Zero times are expected to be
+// absent.
+func (u *SimulatedUser) ExpectedLabels(retention RetentionSettings) (lastLogin, disableAfter, deleteAfter time.Time, err error) {
+	if u.WithoutAttributes {
+		return
+	}
+
+	lastLogin = u.LastLogin
+	if lastLogin.IsZero() {
+		lastLogin = retention.LastLoginDefault.Truncate(time.Second)
+	}
+	if lastLogin.IsZero() {
+		return
+	}
+
+	disableAfter, err = retentionTime(lastLogin, retention.DisableAfter, u.DisableAfter)
+	if err != nil {
+		return
+	}
+
+	deleteAfter, err = retentionTime(lastLogin, retention.DeleteAfter, u.DeleteAfter)
+
+	return
+}
+
+// retentionTime returns the time the retention period ends, zero when the period is unset or the user is exempted by
+// its override
+func retentionTime(lastLogin time.Time, setting string, override *time.Duration) (time.Time, error) {
+	period, err := retentionPeriod(setting, override)
+	if err != nil || period <= 0 {
+		return time.Time{}, err
+	}
+
+	return lastLogin.Add(period), nil
+}
+
+// retentionPeriod returns the retention period of the user, zero when the setting is unset
+func retentionPeriod(setting string, override *time.Duration) (time.Duration, error) {
+	if setting == "" {
+		return 0, nil
+	}
+
+	period, err := time.ParseDuration(setting)
+	if err != nil {
+		return 0, err
+	}
+
+	if override != nil {
+		period = *override
+	}
+
+	return period, nil
+}
+
+// Verify checks that the user reached the expected outcome and carries the retention labels expected for the
+// settings
+func (u *SimulatedUser) Verify(client *rancher.Client, retention RetentionSettings) error {
+	var state Outcome
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		user, err := client.WranglerContext.Mgmt.User().Get(u.User.ID, metav1.GetOptions{})
+		switch {
+		case k8serrors.IsNotFound(err):
+			state = OutcomeDeleted
+		case err != nil:
+			return false, err
+		case user.GetEnabled():
+			state = OutcomeEnabled
+		default:
+			state = OutcomeDisabled
+		}
+
+		return state == u.Expected, nil
+	})
+	if err != nil {
+		if state != "" && state != u.Expected {
+			return fmt.Errorf("scenario %s: expected user %s to be %s, got %s", u.Name, u.User.ID, u.Expected, state)
+		}
+
+		return fmt.Errorf("scenario %s: %w", u.Name, err)
+	}
+
+	if u.Expected == OutcomeDeleted {
+		return nil
+	}
+
+	lastLogin, disableAfter, deleteAfter, err := u.ExpectedLabels(retention)
+	if err != nil {
+		return fmt.Errorf("scenario %s: %w", u.Name, err)
+	}
+
+	err = WaitForRetentionLabels(client, u.User.ID, lastLogin, disableAfter, deleteAfter)
+	if err != nil {
+		return fmt.Errorf("scenario %s: %w", u.Name, err)
+	}
+
+	if u.DisabledAt.IsZero() {
+		return nil
+	}
+
+	user, err := client.WranglerContext.Mgmt.User().Get(u.User.ID, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("scenario %s: failed to get user %s: %w", u.Name, u.User.ID, err)
+	}
+
+	disabledAt, err := RetentionLabel(user, DisabledAtLabelKey)
+	if err != nil {
+		return fmt.Errorf("scenario %s: %w", u.Name, err)
+	}
+
+	if !disabledAt.Equal(u.DisabledAt) {
+		return fmt.Errorf("scenario %s: expected label %s to be %s, got %s", u.Name, DisabledAtLabelKey, formatLabelTime(u.DisabledAt), formatLabelTime(disabledAt))
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package userretention
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/tests/actions/settings"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	DisableInactiveUserAfter = "disable-inactive-user-after"
+	DeleteInactiveUserAfter  = "delete-inactive-user-after"
+	UserLastLoginDefault     = "user-last-login-default"
+	UserRetentionCron        = "user-retention-cron"
+	UserRetentionDryRun      = "user-retention-dry-run"
+	// AuthUserSessionTTLMinutes is validated against the retention periods, which can't be shorter than a session
+	AuthUserSessionTTLMinutes = "auth-user-session-ttl-minutes"
+
+	LastLoginLabelKey    = "cattle.io/last-login"
+	DisableAfterLabelKey = "cattle.io/disable-after"
+	DeleteAfterLabelKey  = "cattle.io/delete-after"
+	// DisabledAtLabelKey records when a simulated user was disabled. Rancher doesn't record it, the label is only
+	// synthesized by the simulation and left untouched by the retention process.
+	DisabledAtLabelKey = "cattle.io/disabled-at"
+
+	// EveryMinuteCron is the most frequent schedule the user-retention-cron setting accepts
+	EveryMinuteCron = "* * * * *"
+
+	// runGracePeriod is how long a retention run is given to complete after it is scheduled
+	runGracePeriod = 20 * time.Second
+)
+
+// RetentionSettings are the values of the user retention settings. Empty values disable the corresponding behavior.
+type RetentionSettings struct {
+	DisableAfter string
+	DeleteAfter  string
+	// LastLoginDefault is the last login time of the users with attributes but without a recorded login
+	LastLoginDefault time.Time
+	Cron             string
+	DryRun           bool
+}
+
+// ApplyRetentionSettings updates the user retention settings to the values
+func ApplyRetentionSettings(client *rancher.Client, retention RetentionSettings) error {
+	lastLoginDefault := ""
+	if !retention.LastLoginDefault.IsZero() {
+		lastLoginDefault = retention.LastLoginDefault.UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+
+	values := []struct {
+		name  string
+		value string
+	}{
+		{DisableInactiveUserAfter, retention.DisableAfter},
+		{DeleteInactiveUserAfter, retention.DeleteAfter},
+		{UserLastLoginDefault, lastLoginDefault},
+		{UserRetentionDryRun, strconv.FormatBool(retention.DryRun)},
+		{UserRetentionCron, retention.Cron},
+	}
+
+	for _, setting := range values {
+		err := UpdateSetting(client, setting.name, setting.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResetRetentionSettings resets the user retention settings and the session TTL they are validated against to their
+// default values
+func ResetRetentionSettings(client *rancher.Client) error {
+	for _, name := range []string{UserRetentionCron, DisableInactiveUserAfter, DeleteInactiveUserAfter, UserLastLoginDefault, UserRetentionDryRun, AuthUserSessionTTLMinutes} {
+		logrus.Infof("Resetting %s to its default value", name)
+		err := settings.ResetGlobalSettingToDefaultValue(client, name)
+		if err != nil {
+			return fmt.Errorf("failed to reset setting %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateSetting updates the value of a global setting
+func UpdateSetting(client *rancher.Client, name, value string) error {
+	logrus.Infof("Updating setting %s to %q", name, value)
+	setting, err := client.WranglerContext.Mgmt.Setting().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get setting %s: %w", name, err)
+	}
+
+	setting.Value = value
+	_, err = client.WranglerContext.Mgmt.Setting().Update(setting)
+	if err != nil {
+		return fmt.Errorf("failed to update setting %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// SetLastLogin records a synthetic last login time for the user, creating its user attributes if the user never
+// logged in. The time is truncated to the second, like the logins Rancher records. A zero time clears the last
+// login, so the user-last-login-default setting applies.
+func SetLastLogin(client *rancher.Client, userID string, lastLogin time.Time) error {
+	return updateUserAttribute(client, userID, func(attribs *v3.UserAttribute) {
+		if lastLogin.IsZero() {
+			attribs.LastLogin = nil
+			return
+		}
+
+		loginTime := metav1.NewTime(lastLogin.Truncate(time.Second))
+		attribs.LastLogin = &loginTime
+	})
+}
+
+// SetRetentionOverrides sets the user specific disable and delete periods, which take precedence over the settings.
+// A zero duration exempts the user, a nil one removes the override.
+func SetRetentionOverrides(client *rancher.Client, userID string, disableAfter, deleteAfter *time.Duration) error {
+	return updateUserAttribute(client, userID, func(attribs *v3.UserAttribute) {
+		attribs.DisableAfter = nil
+		if disableAfter != nil {
+			attribs.DisableAfter = &metav1.Duration{Duration: *disableAfter}
+		}
+
+		attribs.DeleteAfter = nil
+		if deleteAfter != nil {
+			attribs.DeleteAfter = &metav1.Duration{Duration: *deleteAfter}
+		}
+	})
+}
+
+func updateUserAttribute(client *rancher.Client, userID string, update func(attribs *v3.UserAttribute)) error {
+	attribs, err := client.WranglerContext.Mgmt.UserAttribute().Get(userID, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		attribs = &v3.UserAttribute{ObjectMeta: metav1.ObjectMeta{Name: userID}}
+		update(attribs)
+
+		_, err = client.WranglerContext.Mgmt.UserAttribute().Create(attribs)
+		if err != nil {
+			return fmt.Errorf("failed to create user attributes of %s: %w", userID, err)
+		}
+
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user attributes of %s: %w", userID, err)
+	}
+
+	update(attribs)
+	_, err = client.WranglerContext.Mgmt.UserAttribute().Update(attribs)
+	if err != nil {
+		return fmt.Errorf("failed to update user attributes of %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// SetDisabledAt disables the user as the retention process would have at the time, and records the time in the
+// DisabledAtLabelKey label
+func SetDisabledAt(client *rancher.Client, userID string, disabledAt time.Time) error {
+	user, err := client.WranglerContext.Mgmt.User().Get(userID, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get user %s: %w", userID, err)
+	}
+
+	if user.Labels == nil {
+		user.Labels = map[string]string{}
+	}
+	user.Labels[DisabledAtLabelKey] = strconv.FormatInt(disabledAt.Unix(), 10)
+
+	enabled := false
+	user.Enabled = &enabled
+	_, err = client.WranglerContext.Mgmt.User().Update(user)
+	if err != nil {
+		return fmt.Errorf("failed to disable user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// RetentionLabel returns the time stored in a retention label of the user, zero when the label is not set
+func RetentionLabel(user *v3.User, labelKey string) (time.Time, error) {
+	value, ok := user.Labels[labelKey]
+	if !ok || value == "" {
+		return time.Time{}, nil
+	}
+
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s label %q on user %s: %w", labelKey, value, user.Name, err)
+	}
+
+	return time.Unix(epoch, 0), nil
+}
+
+// WaitForRetentionRuns waits until the retention process scheduled with EveryMinuteCron ran the number of times since
+// the call. The runs are inferred from the schedule, as Rancher doesn't record them.
+func WaitForRetentionRuns(runs int) {
+	next := time.Now().Truncate(time.Minute).Add(time.Duration(runs) * time.Minute)
+	wait := time.Until(next.Add(runGracePeriod))
+
+	logrus.Infof("Waiting %s for %d user retention run(s)", wait.Round(time.Second), runs)
+	time.Sleep(wait)
+}
+
+// WaitForRetentionLabels polls until the retention labels of the user match the expected times. A zero time
+// expects the label to be absent.
+func WaitForRetentionLabels(client *rancher.Client, userID string, lastLogin, disableAfter, deleteAfter time.Time) error {
+	expected := map[string]time.Time{
+		LastLoginLabelKey:    lastLogin,
+		DisableAfterLabelKey: disableAfter,
+		DeleteAfterLabelKey:  deleteAfter,
+	}
+
+	var mismatch error
+	err := kwait.PollUntilContextTimeout(context.TODO(), defaults.FiveSecondTimeout, defaults.OneMinuteTimeout, true, func(ctx context.Context) (bool, error) {
+		user, err := client.WranglerContext.Mgmt.User().Get(userID, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for labelKey, expectedTime := range expected {
+			actual, err := RetentionLabel(user, labelKey)
+			if err != nil {
+				return false, err
+			}
+
+			if !actual.Equal(expectedTime.Truncate(time.Second)) {
+				mismatch = fmt.Errorf("user %s: expected label %s to be %s, got %s", userID, labelKey, formatLabelTime(expectedTime), formatLabelTime(actual))
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil && mismatch != nil {
+		return mismatch
+	}
+
+	return err
+}
+
+func formatLabelTime(t time.Time) string {
+	if t.IsZero() {
+		return "unset"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -17,4 +17,6 @@ type BackupRestoreConfig struct {
 	AccessKey                 string `json:"accessKey" yaml:"accessKey"`
 	SecretKey                 string `json:"secretKey" yaml:"secretKey"`
 	ClusterNamespace          string `json:"clusterNamespace" yaml:"clusterNamespace"`
+	RancherUpgradeChart       string `json:"rancherUpgradeChart" yaml:"rancherUpgradeChart"`
+	RancherUpgradeVersion     string `json:"rancherUpgradeVersion" yaml:"rancherUpgradeVersion"`
 }
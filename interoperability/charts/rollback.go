@@ -0,0 +1,142 @@
+package charts
+
+import (
+	"fmt"
+
+	bv1 "github.com/rancher/backup-restore-operator/pkg/apis/resources.cattle.io/v1"
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/tests/actions/kubeapi/backups"
+	"github.com/rancher/tests/actions/kubeapi/helm"
+	"github.com/rancher/tests/actions/upgrade"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BackupSteveType = "resources.cattle.io.backup"
+)
+
+// RancherRollbackOpts is a struct of the options used to upgrade Rancher and roll it back with the rancher-backup chart
+type RancherRollbackOpts struct {
+	ResourceSetName string
+	Chart           string
+	UpgradeVersion  string
+	HelmArgs        []string
+}
+
+// CreateRancherBackup creates a one-time backup of the local cluster with the given resource set, waits for it to
+// complete and returns the name of the backup file. The rancher-backup chart must be installed with a default storage location.
+func CreateRancherBackup(client *rancher.Client, resourceSetName string) (string, error) {
+	backupName := namegen.AppendRandomString("rollback-backup")
+	backup := bv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: backupName,
+		},
+		Spec: bv1.BackupSpec{
+			ResourceSetName: resourceSetName,
+		},
+	}
+
+	logrus.Infof("Creating backup %s", backupName)
+	createdBackup, err := client.Steve.SteveType(BackupSteveType).Create(bv1.NewBackup("", backupName, backup))
+	if err != nil {
+		return "", err
+	}
+
+	ready, err := VerifyBackupCompleted(client, BackupSteveType, createdBackup)
+	if err != nil {
+		return "", err
+	}
+
+	if !ready {
+		return "", fmt.Errorf("backup %s did not complete", backupName)
+	}
+
+	backupObj, err := client.Steve.SteveType(BackupSteveType).ByID(createdBackup.ID)
+	if err != nil {
+		return "", err
+	}
+
+	backupStatus := &bv1.BackupStatus{}
+	err = v1.ConvertToK8sType(backupObj.Status, backupStatus)
+	if err != nil {
+		return "", err
+	}
+
+	return backupStatus.Filename, nil
+}
+
+// UpgradeAndRollbackRancher takes a backup of Rancher, upgrades it with helm, and rolls it back by scaling Rancher down,
+// restoring the backup, rolling the helm release back to its previous revision and scaling Rancher back up. It then verifies the users, tokens, projects and
+// clusters that existed before the upgrade are still present, and that every downstream agent is connected.
+// It returns a client logged in to the rolled back Rancher.
+func UpgradeAndRollbackRancher(client *rancher.Client, opts *RancherRollbackOpts) (*rancher.Client, error) {
+	preUpgradeState, err := upgrade.CaptureRancherState(client)
+	if err != nil {
+		return nil, err
+	}
+
+	backupFilename, err := CreateRancherBackup(client, opts.ResourceSetName)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := helm.GetRancherRevision()
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Upgrading Rancher from revision %d to chart %s version %s", revision, opts.Chart, opts.UpgradeVersion)
+	err = helm.UpgradeRancher(opts.Chart, opts.UpgradeVersion, opts.HelmArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err = upgrade.WaitForRancherReady(client)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := helm.RancherRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Info("Scaling Rancher down to 0 before the restore")
+	replicas, err := helm.ScaleRancher(restConfig, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = backups.CreateRestore(restConfig, backupFilename, false)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Rolling Rancher back to revision %d", revision)
+	err = helm.RollbackRancher(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Scaling Rancher back up to %d replicas", replicas)
+	_, err = helm.ScaleRancher(restConfig, replicas)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err = upgrade.WaitForRancherReady(client)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Info("Verifying the Rancher state after the rollback")
+	err = upgrade.VerifyRancherState(client, preUpgradeState)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -0,0 +1,15 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress
+
+package freeipa
+
+import (
+	"testing"
+
+	authactions "github.com/rancher/tests/actions/auth"
+	"github.com/rancher/tests/validation/auth/provider/ldap"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestFreeIPAAuthProviderSuite(t *testing.T) {
+	suite.Run(t, &ldap.LDAPProviderSuite{ProviderName: authactions.FreeIpa})
+}
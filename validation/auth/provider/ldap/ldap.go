@@ -0,0 +1,460 @@
+package ldap
+
+import (
+	"fmt"
+	"slices"
+
+	managementv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	v3 "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/users"
+	"github.com/rancher/shepherd/pkg/session"
+	authactions "github.com/rancher/tests/actions/auth"
+	projectapi "github.com/rancher/tests/actions/kubeapi/projects"
+	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
+	"github.com/rancher/tests/actions/rbac"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LDAPProviderSuite runs the scenarios shared by the LDAP-based auth providers: enabling and disabling the provider,
+// access modes, group membership, nested groups and login verification. The provider packages run it with the name
+// of their provider, and the provider specifics come from its authactions.LDAPProvider descriptor.
+type LDAPProviderSuite struct {
+	suite.Suite
+	ProviderName string
+	session      *session.Session
+	client       *rancher.Client
+	cluster      *v3.Cluster
+	adminUser    *v3.User
+	authConfig   *authactions.AuthConfig
+	provider     *authactions.LDAPProvider
+}
+
+func (a *LDAPProviderSuite) SetupSuite() {
+	a.session = session.NewSession()
+
+	client, err := rancher.NewClient("", a.session)
+	require.NoError(a.T(), err, "Failed to create Rancher client")
+	a.client = client
+
+	logrus.Infof("Loading %s auth configuration", a.ProviderName)
+	a.authConfig, err = authactions.LoadLDAPAuthInput(client, a.ProviderName)
+	require.NoError(a.T(), err, "Failed to load auth configuration")
+	require.NotNil(a.T(), a.authConfig, "Auth configuration is not provided")
+
+	a.provider, err = authactions.NewLDAPProvider(client, a.ProviderName)
+	require.NoError(a.T(), err, "Failed to describe auth provider %s", a.ProviderName)
+
+	logrus.Info("Getting cluster name from the config file")
+	clusterName := client.RancherConfig.ClusterName
+	require.NotEmpty(a.T(), clusterName, "Cluster name should be set")
+
+	clusterID, err := clusters.GetClusterIDByName(a.client, clusterName)
+	require.NoError(a.T(), err, "Error getting cluster ID for cluster: %s", clusterName)
+
+	a.cluster, err = a.client.Management.Cluster.ByID(clusterID)
+	require.NoError(a.T(), err, "Failed to retrieve cluster by ID: %s", clusterID)
+
+	logrus.Infof("Setting up admin user credentials for %s authentication", a.provider.DisplayName)
+	a.adminUser = &v3.User{
+		Username: a.provider.AdminUser.Username,
+		Password: a.provider.AdminUser.Password,
+	}
+}
+
+func (a *LDAPProviderSuite) TearDownSuite() {
+	if a.client != nil && a.provider != nil {
+		providerConfig, err := a.client.Management.AuthConfig.ByID(a.provider.Name)
+		if err == nil && providerConfig.Enabled {
+			logrus.Infof("Disabling %s authentication after test suite", a.provider.DisplayName)
+			err := a.provider.NewClient(a.client).Disable()
+			require.NoError(a.T(), err, "Failed to disable %s in teardown", a.provider.DisplayName)
+		}
+	}
+
+	a.session.Cleanup()
+}
+
+func (a *LDAPProviderSuite) TestEnableProvider() {
+	subSession := a.session.NewSession()
+	defer subSession.Cleanup()
+
+	err := authactions.EnsureAuthProviderEnabled(a.client, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to enable %s", a.provider.DisplayName)
+
+	providerConfig, err := a.client.Management.AuthConfig.ByID(a.provider.Name)
+	require.NoError(a.T(), err, "Failed to retrieve %s config", a.provider.DisplayName)
+
+	require.True(a.T(), providerConfig.Enabled, "%s should be enabled", a.provider.DisplayName)
+	require.Equal(a.T(), authactions.AuthProvCleanupAnnotationValUnlocked, providerConfig.Annotations[authactions.AuthProvCleanupAnnotationKey], "Annotation should be unlocked")
+
+	secret, err := a.client.WranglerContext.Core.Secret().Get(
+		rbac.GlobalDataNS,
+		a.provider.PasswordSecretID,
+		metav1.GetOptions{},
+	)
+	require.NoError(a.T(), err, "Failed to retrieve password secret")
+
+	require.Equal(a.T(), a.provider.ServiceAccountPassword, string(secret.Data["serviceaccountpassword"]), "Password mismatch")
+}
+
+func (a *LDAPProviderSuite) TestDisableAndReenableProvider() {
+	subSession := a.session.NewSession()
+	defer subSession.Cleanup()
+
+	err := authactions.EnsureAuthProviderEnabled(a.client, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to enable %s", a.provider.DisplayName)
+
+	err = a.provider.NewClient(a.client).Disable()
+	require.NoError(a.T(), err, "Failed to disable %s", a.provider.DisplayName)
+
+	providerConfig, err := authactions.WaitForAuthProviderAnnotationUpdate(a.client, a.provider.Name, authactions.AuthProvCleanupAnnotationValLocked)
+	require.NoError(a.T(), err, "Failed waiting for annotation update")
+
+	require.False(a.T(), providerConfig.Enabled, "%s should be disabled", a.provider.DisplayName)
+	require.Equal(a.T(), authactions.AuthProvCleanupAnnotationValLocked, providerConfig.Annotations[authactions.AuthProvCleanupAnnotationKey], "Annotation should be locked")
+
+	_, err = a.client.WranglerContext.Core.Secret().Get(
+		rbac.GlobalDataNS,
+		a.provider.PasswordSecretID,
+		metav1.GetOptions{},
+	)
+	require.Error(a.T(), err, "Password secret should not exist")
+	require.Contains(a.T(), err.Error(), "not found", "Should return not found error")
+	err = authactions.EnsureAuthProviderEnabled(a.client, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to re-enable %s", a.provider.DisplayName)
+}
+
+func (a *LDAPProviderSuite) TestUnrestrictedAccessMode() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	allUsers := slices.Concat(a.authConfig.Users, a.authConfig.NestedUsers, a.authConfig.DoubleNestedUsers)
+	err = authactions.VerifyUserLogins(authAdmin, a.provider.Name, allUsers, authactions.AccessModeUnrestricted+" access mode", true)
+	require.NoError(a.T(), err, "All users should be able to login")
+}
+
+func (a *LDAPProviderSuite) TestGroupMembershipRefresh() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	adminGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.Group)
+	adminGlobalRole := &managementv3.GlobalRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "grb-",
+		},
+		GlobalRoleName:     rbac.Admin.String(),
+		GroupPrincipalName: adminGroupPrincipalID,
+	}
+
+	adminGRB, err := authAdmin.WranglerContext.Mgmt.GlobalRoleBinding().Create(adminGlobalRole)
+	require.NoError(a.T(), err, "Failed to create admin global role binding")
+
+	err = users.RefreshGroupMembership(authAdmin)
+	require.NoError(a.T(), err, "Failed to refresh group membership")
+
+	standardGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.NestedGroup)
+	standardGlobalRole := &managementv3.GlobalRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "grb-",
+		},
+		GlobalRoleName:     rbac.StandardUser.String(),
+		GroupPrincipalName: standardGroupPrincipalID,
+	}
+
+	standardGRB, err := authAdmin.WranglerContext.Mgmt.GlobalRoleBinding().Create(standardGlobalRole)
+	require.NoError(a.T(), err, "Failed to create standard global role binding")
+
+	err = users.RefreshGroupMembership(authAdmin)
+	require.NoError(a.T(), err, "Failed to refresh group membership")
+
+	err = authAdmin.WranglerContext.Mgmt.GlobalRoleBinding().Delete(adminGRB.Name, &metav1.DeleteOptions{})
+	require.NoError(a.T(), err, "Failed to delete admin GRB: %v", adminGRB.Name)
+
+	err = authAdmin.WranglerContext.Mgmt.GlobalRoleBinding().Delete(standardGRB.Name, &metav1.DeleteOptions{})
+	require.NoError(a.T(), err, "Failed to delete standard GRB: %v", standardGRB.Name)
+}
+
+func (a *LDAPProviderSuite) TestNestedGroupClusterAccess() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	doubleNestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.DoubleNestedGroup)
+	crtb, err := rbacapi.CreateGroupClusterRoleTemplateBinding(authAdmin, a.cluster.ID, doubleNestedGroupPrincipalID, rbac.ClusterOwner.String())
+	require.NoError(a.T(), err, "Failed to create cluster role template binding")
+
+	for _, userInfo := range a.authConfig.DoubleNestedUsers {
+		user := &v3.User{
+			Username: userInfo.Username,
+			Password: userInfo.Password,
+		}
+		userClient, err := authactions.LoginAsAuthUser(authAdmin, user, a.provider.Name)
+		require.NoError(a.T(), err, "Failed to login user [%v]", userInfo.Username)
+
+		rbac.VerifyUserCanListCluster(a.T(), a.client, userClient, a.cluster.ID, rbac.ClusterOwner)
+	}
+
+	foundCRTB, err := rbacapi.GetClusterRoleTemplateBindingsForGroup(a.client, doubleNestedGroupPrincipalID, a.cluster.ID)
+	require.NoError(a.T(), err, "Failed to get group CRTB")
+	require.NotNil(a.T(), foundCRTB, "Cluster role binding should exist for group")
+
+	err = authAdmin.WranglerContext.Mgmt.ClusterRoleTemplateBinding().Delete(crtb.Namespace, crtb.Name, &metav1.DeleteOptions{})
+	require.NoError(a.T(), err, "Failed to delete CRTB: %s/%s", crtb.Namespace, crtb.Name)
+}
+
+func (a *LDAPProviderSuite) TestNonMemberClusterAccessDenied() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	doubleNestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.DoubleNestedGroup)
+	_, err = rbacapi.CreateGroupClusterRoleTemplateBinding(authAdmin, a.cluster.ID, doubleNestedGroupPrincipalID, rbac.ClusterOwner.String())
+	require.NoError(a.T(), err, "Failed to create group cluster role template binding")
+
+	for _, userInfo := range a.authConfig.Users {
+		user := &v3.User{
+			Username: userInfo.Username,
+			Password: userInfo.Password,
+		}
+		userClient, err := authactions.LoginAsAuthUser(authAdmin, user, a.provider.Name)
+		require.NoError(a.T(), err, "Failed to login user [%v]", userInfo.Username)
+
+		_, err = userClient.Steve.SteveType(clusters.ProvisioningSteveResourceType).List(nil)
+		require.NotNil(a.T(), err, "User [%v] should NOT list clusters", userInfo.Username)
+		require.Contains(a.T(), err.Error(), "Resource type [provisioning.cattle.io.cluster] has no method GET", "Should indicate insufficient permissions")
+	}
+}
+
+func (a *LDAPProviderSuite) TestNestedGroupProjectAccess() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	projectResp, _, err := projectapi.CreateProjectAndNamespace(authAdmin, a.cluster.ID)
+	require.NoError(a.T(), err, "Failed to create project and namespace")
+
+	nestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.NestedGroup)
+
+	prtbNamespace := projectResp.Name
+	if projectResp.Status.BackingNamespace != "" {
+		prtbNamespace = projectResp.Status.BackingNamespace
+	}
+
+	projectName := fmt.Sprintf("%s:%s", projectResp.Namespace, projectResp.Name)
+
+	groupPRTBResp, err := rbacapi.CreateGroupProjectRoleTemplateBinding(authAdmin, projectName, prtbNamespace, nestedGroupPrincipalID, rbac.ProjectOwner.String())
+	require.NoError(a.T(), err, "Failed to create PRTB")
+	require.NotNil(a.T(), groupPRTBResp, "PRTB should be created")
+
+	for _, userInfo := range a.authConfig.NestedUsers {
+		user := &v3.User{
+			Username: userInfo.Username,
+			Password: userInfo.Password,
+		}
+		userClient, err := authactions.LoginAsAuthUser(authAdmin, user, a.provider.Name)
+		require.NoError(a.T(), err, "Failed to login user [%v]", userInfo.Username)
+
+		_, err = userClient.WranglerContext.Mgmt.Project().Get(projectResp.Namespace, projectResp.Name, metav1.GetOptions{})
+		require.NoError(a.T(), err, "User [%v] should be able to get project %s", userInfo.Username, projectResp.Name)
+	}
+
+	err = authAdmin.WranglerContext.Mgmt.ProjectRoleTemplateBinding().Delete(groupPRTBResp.Namespace, groupPRTBResp.Name, &metav1.DeleteOptions{})
+	require.NoError(a.T(), err, "Failed to delete PRTB: %s/%s", groupPRTBResp.Namespace, groupPRTBResp.Name)
+}
+
+func (a *LDAPProviderSuite) TestRestrictedModeBindings() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	groupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.Group)
+	_, err = rbacapi.CreateGroupClusterRoleTemplateBinding(authAdmin, a.cluster.ID, groupPrincipalID, rbac.ClusterMember.String())
+	require.NoError(a.T(), err, "Failed to create cluster role template binding")
+
+	projectResp, _, err := projectapi.CreateProjectAndNamespace(authAdmin, a.cluster.ID)
+	require.NoError(a.T(), err, "Failed to create project")
+
+	prtbNamespace := projectResp.Name
+	if projectResp.Status.BackingNamespace != "" {
+		prtbNamespace = projectResp.Status.BackingNamespace
+	}
+
+	err = authactions.WaitForNamespaceReady(authAdmin, prtbNamespace)
+	require.NoError(a.T(), err, "Namespace should be ready")
+
+	projectName := fmt.Sprintf("%s:%s", projectResp.Namespace, projectResp.Name)
+
+	for _, userInfo := range a.authConfig.NestedUsers {
+		nestedUserPrincipalID := a.provider.UserPrincipalID(userInfo.Username)
+		userPRTB := &managementv3.ProjectRoleTemplateBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:    prtbNamespace,
+				GenerateName: "prtb-",
+			},
+			ProjectName:       projectName,
+			UserPrincipalName: nestedUserPrincipalID,
+			RoleTemplateName:  rbac.ProjectOwner.String(),
+		}
+
+		userPRTBResp, err := authAdmin.WranglerContext.Mgmt.ProjectRoleTemplateBinding().Create(userPRTB)
+		require.NoError(a.T(), err, "Failed to create PRTB for user [%v]", userInfo.Username)
+		require.NotNil(a.T(), userPRTBResp, "PRTB should be created for user [%v]", userInfo.Username)
+	}
+}
+
+func (a *LDAPProviderSuite) TestAllowClusterAndProjectMembersAccessMode() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	doubleNestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.DoubleNestedGroup)
+	_, err = rbacapi.CreateGroupClusterRoleTemplateBinding(authAdmin, a.cluster.ID, doubleNestedGroupPrincipalID, rbac.ClusterMember.String())
+	require.NoError(a.T(), err, "Failed to create group cluster role template binding")
+
+	projectResp, _, err := projectapi.CreateProjectAndNamespace(authAdmin, a.cluster.ID)
+	require.NoError(a.T(), err, "Failed to create project")
+
+	prtbNamespace := projectResp.Name
+	if projectResp.Status.BackingNamespace != "" {
+		prtbNamespace = projectResp.Status.BackingNamespace
+	}
+	projectName := fmt.Sprintf("%s:%s", projectResp.Namespace, projectResp.Name)
+
+	nestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.NestedGroup)
+
+	groupPRTBResp, err := rbacapi.CreateGroupProjectRoleTemplateBinding(authAdmin, projectName, prtbNamespace, nestedGroupPrincipalID, rbac.ProjectOwner.String())
+	require.NoError(a.T(), err, "Failed to create PRTB")
+	require.NotNil(a.T(), groupPRTBResp, "PRTB should be created")
+
+	var allowedPrincipalIDs []string
+	allowedPrincipalIDs = append(allowedPrincipalIDs, nestedGroupPrincipalID)
+	doubleNestedGroupPrincipalID = a.provider.GroupPrincipalID(a.authConfig.DoubleNestedGroup)
+	allowedPrincipalIDs = append(allowedPrincipalIDs, doubleNestedGroupPrincipalID)
+
+	newAuthConfig, err := authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeRestricted, allowedPrincipalIDs)
+	require.NoError(a.T(), err, "Failed to update access mode")
+	require.Equal(a.T(), authactions.AccessModeRestricted, newAuthConfig.AccessMode, "Access mode should be restricted")
+
+	allowedUsers := slices.Concat(a.authConfig.DoubleNestedUsers, a.authConfig.NestedUsers)
+	err = authactions.VerifyUserLogins(authAdmin, a.provider.Name, allowedUsers, "restricted access mode", true)
+	require.NoError(a.T(), err, "Cluster/project members should be able to login")
+
+	err = authactions.VerifyUserLogins(authAdmin, a.provider.Name, a.authConfig.Users, "restricted access mode", false)
+	require.NoError(a.T(), err, "Non-members should NOT be able to login")
+
+	_, err = authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeUnrestricted, nil)
+	require.NoError(a.T(), err, "Failed to rollback access mode")
+}
+
+func (a *LDAPProviderSuite) TestRestrictedAccessModeAuthorizedUsersCanLogin() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	principalIDs, err := authactions.SetupRequiredAccessModePrincipals(
+		authAdmin,
+		a.cluster.ID,
+		a.authConfig,
+		a.provider.Name,
+		a.provider.UserSearchBase,
+		a.provider.GroupSearchBase,
+	)
+	require.NoError(a.T(), err, "Failed to setup required access mode test")
+
+	newAuthConfig, err := authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeRequired, principalIDs)
+	require.NoError(a.T(), err, "Failed to update access mode")
+	require.Equal(a.T(), authactions.AccessModeRequired, newAuthConfig.AccessMode, "Access mode should be required")
+
+	err = authactions.VerifyUserLogins(authAdmin, a.provider.Name, a.authConfig.Users, "required access mode", true)
+	require.NoError(a.T(), err, "Authorized users should be able to login")
+
+	_, err = authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeUnrestricted, nil)
+	require.NoError(a.T(), err, "Failed to rollback access mode")
+}
+
+func (a *LDAPProviderSuite) TestRequiredModeNestedGroupAccess() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	nestedGroupPrincipalID := a.provider.GroupPrincipalID(a.authConfig.NestedGroup)
+
+	crtb, err := rbacapi.CreateGroupClusterRoleTemplateBinding(
+		authAdmin,
+		a.cluster.ID,
+		nestedGroupPrincipalID,
+		rbac.ClusterMember.String(),
+	)
+	require.NoError(a.T(), err, "Failed to create cluster role template binding")
+
+	principalIDs := []string{nestedGroupPrincipalID}
+
+	nestedUsers := slices.Concat(a.authConfig.NestedUsers, a.authConfig.DoubleNestedUsers)
+	for _, user := range nestedUsers {
+		userPrincipalID := a.provider.UserPrincipalID(user.Username)
+		principalIDs = append(principalIDs, userPrincipalID)
+	}
+
+	newAuthConfig, err := authactions.UpdateAccessMode(
+		a.client,
+		a.provider.Name,
+		authactions.AccessModeRequired,
+		principalIDs,
+	)
+	require.NoError(a.T(), err, "Failed to update access mode")
+	require.Equal(a.T(), authactions.AccessModeRequired, newAuthConfig.AccessMode, "Access mode should be required")
+
+	err = authactions.VerifyUserLogins(
+		authAdmin,
+		a.provider.Name,
+		nestedUsers,
+		"required access mode with nested groups",
+		true,
+	)
+	require.NoError(a.T(), err, "Nested group members should be able to login")
+
+	_, err = authactions.UpdateAccessMode(
+		a.client,
+		a.provider.Name,
+		authactions.AccessModeUnrestricted,
+		nil,
+	)
+	require.NoError(a.T(), err, "Failed to rollback access mode")
+
+	err = a.client.WranglerContext.Mgmt.ClusterRoleTemplateBinding().Delete(crtb.Namespace, crtb.Name, &metav1.DeleteOptions{})
+	require.NoError(a.T(), err, "Failed to delete CRTB: %s/%s", crtb.Namespace, crtb.Name)
+}
+
+func (a *LDAPProviderSuite) TestRequiredModeUnauthorizedLoginDenied() {
+	subSession, authAdmin, err := authactions.SetupAuthenticatedSession(a.client, a.session, a.adminUser, a.provider.Name)
+	require.NoError(a.T(), err, "Failed to setup authenticated test")
+	defer subSession.Cleanup()
+
+	principalIDs, err := authactions.SetupRequiredAccessModePrincipals(
+		authAdmin,
+		a.cluster.ID,
+		a.authConfig,
+		a.provider.Name,
+		a.provider.UserSearchBase,
+		a.provider.GroupSearchBase,
+	)
+	require.NoError(a.T(), err, "Failed to setup required access mode test")
+
+	newAuthConfig, err := authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeRequired, principalIDs)
+	require.NoError(a.T(), err, "Failed to update access mode")
+	require.Equal(a.T(), authactions.AccessModeRequired, newAuthConfig.AccessMode, "Access mode should be required")
+
+	unauthorizedUsers := a.authConfig.TripleNestedUsers
+	err = authactions.VerifyUserLogins(authAdmin, a.provider.Name, unauthorizedUsers, "required access mode", false)
+	require.NoError(a.T(), err, "Unauthorized users should NOT be able to login")
+
+	_, err = authactions.UpdateAccessMode(a.client, a.provider.Name, authactions.AccessModeUnrestricted, nil)
+	require.NoError(a.T(), err, "Failed to rollback access mode")
+}
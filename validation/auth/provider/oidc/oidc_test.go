@@ -0,0 +1,184 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress
+
+package oidc
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/shepherd/pkg/session"
+	authactions "github.com/rancher/tests/actions/auth"
+	"github.com/rancher/tests/actions/rbac"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type OIDCAuthProviderSuite struct {
+	suite.Suite
+	session    *session.Session
+	client     *rancher.Client
+	fixture    *authactions.OIDCFixture
+	authConfig *authactions.AuthConfig
+}
+
+func (o *OIDCAuthProviderSuite) SetupSuite() {
+	o.session = session.NewSession()
+
+	client, err := rancher.NewClient("", o.session)
+	require.NoError(o.T(), err, "Failed to create Rancher client")
+	o.client = client
+
+	fixtureConfig := new(authactions.OIDCFixtureConfig)
+	config.LoadConfig(authactions.OIDCFixtureInput, fixtureConfig)
+	if fixtureConfig.SeedFile == "" {
+		o.T().Skip("OIDC fixture seed file is not configured")
+	}
+
+	seed, err := authactions.LoadOpenLDAPSeed(fixtureConfig.SeedFile)
+	require.NoError(o.T(), err, "Failed to load OIDC fixture seed")
+	o.authConfig = seed.AuthConfig()
+
+	logrus.Info("Deploying the OIDC identity provider fixture")
+	o.fixture, err = authactions.DeployOIDCFixture(client, seed, fixtureConfig.Image)
+	require.NoError(o.T(), err, "Failed to deploy OIDC fixture")
+
+	logrus.Info("Enabling generic OIDC against the fixture")
+	err = authactions.EnableGenericOIDC(client, o.fixture)
+	require.NoError(o.T(), err, "Failed to enable generic OIDC")
+}
+
+func (o *OIDCAuthProviderSuite) TearDownSuite() {
+	o.session.Cleanup()
+}
+
+func (o *OIDCAuthProviderSuite) TestEnableGenericOIDC() {
+	providerConfig, err := o.client.Management.AuthConfig.ByID(authactions.GenericOIDC)
+	require.NoError(o.T(), err, "Failed to retrieve generic OIDC config")
+
+	require.True(o.T(), providerConfig.Enabled, "Generic OIDC should be enabled")
+	require.Equal(o.T(), authactions.AuthProvCleanupAnnotationValUnlocked, providerConfig.Annotations[authactions.AuthProvCleanupAnnotationKey], "Annotation should be unlocked")
+
+	secret, err := o.client.WranglerContext.Core.Secret().Get(rbac.GlobalDataNS, authactions.GenericOIDCClientSecretID, metav1.GetOptions{})
+	require.NoError(o.T(), err, "Failed to retrieve client secret")
+	require.Equal(o.T(), o.fixture.ClientSecret, string(secret.Data["clientsecret"]), "Client secret mismatch")
+}
+
+func (o *OIDCAuthProviderSuite) TestGroupClaimsMapping() {
+	allUsers := slices.Concat(o.authConfig.Users, o.authConfig.NestedUsers, o.authConfig.DoubleNestedUsers, o.authConfig.TripleNestedUsers)
+
+	for _, user := range allUsers {
+		_, token, err := authactions.LoginAsOIDCUser(o.client, o.fixture, user)
+		require.NoError(o.T(), err, "User [%v] should be able to login", user.Username)
+
+		expectedGroups := authactions.SeedGroupsOfUser(o.fixture.Seed, user.Username)
+		logrus.Infof("Verifying group principals %v of user [%v]", expectedGroups, user.Username)
+		err = authactions.VerifyUserGroupPrincipals(o.client, token.UserID, authactions.GenericOIDC, expectedGroups)
+		require.NoError(o.T(), err, "Group principals of user [%v] do not match the groups claim", user.Username)
+	}
+}
+
+func (o *OIDCAuthProviderSuite) TestPrincipalSearch() {
+	groupPrincipals, err := authactions.SearchPrincipals(o.client, o.authConfig.Group, "group")
+	require.NoError(o.T(), err, "Failed to search group principals")
+	require.Len(o.T(), groupPrincipals, 1, "Group search should return a single principal")
+	require.Equal(o.T(), authactions.GetOIDCGroupPrincipalID(authactions.GenericOIDC, o.authConfig.Group), groupPrincipals[0].ID, "Group principal ID mismatch")
+
+	user := o.authConfig.Users[0]
+	userClient, token, err := authactions.LoginAsOIDCUser(o.client, o.fixture, user)
+	require.NoError(o.T(), err, "User [%v] should be able to login", user.Username)
+
+	userPrincipal, err := authactions.GetPrincipal(userClient, token.UserPrincipal)
+	require.NoError(o.T(), err, "Failed to get the principal of user [%v]", user.Username)
+	require.Equal(o.T(), token.UserPrincipal, userPrincipal.ID, "Principal ID mismatch")
+	require.Equal(o.T(), "user", userPrincipal.PrincipalType, "Principal type mismatch")
+	require.True(o.T(), userPrincipal.Me, "Principal of the token should be the logged in user")
+
+	// the users of the seed group are its direct members, so the group is in the groups claim of their login
+	groupPrincipalID := authactions.GetOIDCGroupPrincipalID(authactions.GenericOIDC, o.authConfig.Group)
+	principals, err := authactions.ListPrincipals(userClient)
+	require.NoError(o.T(), err, "Failed to list the principals of user [%v]", user.Username)
+
+	principalIndex := slices.IndexFunc(principals, func(principal management.Principal) bool {
+		return principal.ID == groupPrincipalID
+	})
+	require.NotEqual(o.T(), -1, principalIndex, "User [%v] should be a member of group %s", user.Username, o.authConfig.Group)
+	require.True(o.T(), principals[principalIndex].MemberOf, "User [%v] should be a member of group %s", user.Username, o.authConfig.Group)
+
+	// generic OIDC has no user lookup, so the search echoes any name; the identity provider must reject the name instead
+	unseededUser := authactions.User{Username: namegen.AppendRandomString("unseeded"), Password: user.Password}
+	_, _, err = authactions.LoginAsOIDCUser(o.client, o.fixture, unseededUser)
+	require.Error(o.T(), err, "User [%v] that is not in the seed should NOT be able to login", unseededUser.Username)
+}
+
+func (o *OIDCAuthProviderSuite) TestRestrictedAccessModeByGroup() {
+	adminPrincipalID := o.adminPrincipalID()
+	defer o.restoreUnrestrictedAccessMode()
+
+	allowedPrincipalIDs := []string{adminPrincipalID, authactions.GetOIDCGroupPrincipalID(authactions.GenericOIDC, o.authConfig.Group)}
+	_, err := authactions.UpdateOIDCAccessMode(o.client, authactions.GenericOIDC, authactions.AccessModeRestricted, allowedPrincipalIDs)
+	require.NoError(o.T(), err, "Failed to update access mode")
+
+	err = authactions.VerifyOIDCUserLogins(o.client, o.fixture, o.authConfig.Users, "member of the allowed group", true)
+	require.NoError(o.T(), err)
+
+	// the groups claim only has the direct groups of the user, so members of nested groups are not allowed
+	err = authactions.VerifyOIDCUserLogins(o.client, o.fixture, o.authConfig.NestedUsers, "member of a nested group only", false)
+	require.NoError(o.T(), err)
+
+	err = authactions.VerifyOIDCUserLogins(o.client, o.fixture, o.authConfig.TripleNestedUsers, "not a member of the allowed group", false)
+	require.NoError(o.T(), err)
+}
+
+func (o *OIDCAuthProviderSuite) TestRequiredAccessModeByGroup() {
+	adminPrincipalID := o.adminPrincipalID()
+	defer o.restoreUnrestrictedAccessMode()
+
+	allowedPrincipalIDs := []string{adminPrincipalID, authactions.GetOIDCGroupPrincipalID(authactions.GenericOIDC, o.authConfig.TripleNestedGroup)}
+	_, err := authactions.UpdateOIDCAccessMode(o.client, authactions.GenericOIDC, authactions.AccessModeRequired, allowedPrincipalIDs)
+	require.NoError(o.T(), err, "Failed to update access mode")
+
+	err = authactions.VerifyOIDCUserLogins(o.client, o.fixture, o.authConfig.TripleNestedUsers, "member of the required group", true)
+	require.NoError(o.T(), err)
+
+	unauthorizedUsers := slices.Concat(o.authConfig.Users, o.authConfig.NestedUsers, o.authConfig.DoubleNestedUsers)
+	err = authactions.VerifyOIDCUserLogins(o.client, o.fixture, unauthorizedUsers, "not a member of the required group", false)
+	require.NoError(o.T(), err)
+}
+
+func (o *OIDCAuthProviderSuite) TestLogoutRevokesToken() {
+	user := o.authConfig.Users[0]
+	userClient, _, err := authactions.LoginAsOIDCUser(o.client, o.fixture, user)
+	require.NoError(o.T(), err, "User [%v] should be able to login", user.Username)
+
+	_, err = userClient.Management.Principal.List(nil)
+	require.NoError(o.T(), err, "User [%v] should be able to list principals before logout", user.Username)
+
+	err = authactions.LogoutAuthUser(userClient)
+	require.NoError(o.T(), err, "Failed to logout user [%v]", user.Username)
+
+	_, err = userClient.Management.Principal.List(nil)
+	require.Error(o.T(), err, "The token of user [%v] should be revoked after logout", user.Username)
+	require.Contains(o.T(), err.Error(), "401", "Should return unauthorized error")
+}
+
+func (o *OIDCAuthProviderSuite) adminPrincipalID() string {
+	_, token, err := authactions.LoginAsOIDCUser(o.client, o.fixture, o.fixture.Seed.Admin())
+	require.NoError(o.T(), err, "Admin should be able to login")
+
+	return token.UserPrincipal
+}
+
+func (o *OIDCAuthProviderSuite) restoreUnrestrictedAccessMode() {
+	_, err := authactions.UpdateOIDCAccessMode(o.client, authactions.GenericOIDC, authactions.AccessModeUnrestricted, nil)
+	require.NoError(o.T(), err, "Failed to restore unrestricted access mode")
+}
+
+func TestOIDCAuthProviderSuite(t *testing.T) {
+	suite.Run(t, new(OIDCAuthProviderSuite))
+}
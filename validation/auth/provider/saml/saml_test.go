@@ -0,0 +1,178 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress
+
+package saml
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/pkg/config"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/shepherd/pkg/session"
+	authactions "github.com/rancher/tests/actions/auth"
+	"github.com/rancher/tests/actions/rbac"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SAMLAuthProviderSuite struct {
+	suite.Suite
+	session    *session.Session
+	client     *rancher.Client
+	fixture    *authactions.SAMLFixture
+	authConfig *authactions.AuthConfig
+}
+
+func (s *SAMLAuthProviderSuite) SetupSuite() {
+	s.session = session.NewSession()
+
+	client, err := rancher.NewClient("", s.session)
+	require.NoError(s.T(), err, "Failed to create Rancher client")
+	s.client = client
+
+	fixtureConfig := new(authactions.SAMLFixtureConfig)
+	config.LoadConfig(authactions.SAMLFixtureInput, fixtureConfig)
+	if fixtureConfig.SeedFile == "" {
+		s.T().Skip("SAML fixture seed file is not configured")
+	}
+
+	seed, err := authactions.LoadOpenLDAPSeed(fixtureConfig.SeedFile)
+	require.NoError(s.T(), err, "Failed to load SAML fixture seed")
+	s.authConfig = seed.AuthConfig()
+
+	logrus.Info("Deploying the SAML identity provider fixture")
+	s.fixture, err = authactions.DeploySAMLFixture(client, seed, fixtureConfig.Image)
+	require.NoError(s.T(), err, "Failed to deploy SAML fixture")
+
+	logrus.Info("Enabling Keycloak SAML against the fixture")
+	err = authactions.EnableKeyCloakSAML(client, s.fixture)
+	require.NoError(s.T(), err, "Failed to enable Keycloak SAML")
+}
+
+func (s *SAMLAuthProviderSuite) TearDownSuite() {
+	s.session.Cleanup()
+}
+
+func (s *SAMLAuthProviderSuite) TestEnableKeyCloakSAML() {
+	providerConfig, err := s.client.Management.AuthConfig.ByID(authactions.KeyCloakSAML)
+	require.NoError(s.T(), err, "Failed to retrieve Keycloak SAML config")
+
+	require.True(s.T(), providerConfig.Enabled, "Keycloak SAML should be enabled")
+	require.Equal(s.T(), authactions.AuthProvCleanupAnnotationValUnlocked, providerConfig.Annotations[authactions.AuthProvCleanupAnnotationKey], "Annotation should be unlocked")
+
+	secret, err := s.client.WranglerContext.Core.Secret().Get(rbac.GlobalDataNS, authactions.KeyCloakSAMLSPKeySecretID, metav1.GetOptions{})
+	require.NoError(s.T(), err, "Failed to retrieve service provider key secret")
+	require.Equal(s.T(), s.fixture.SPKey, string(secret.Data["spkey"]), "Service provider key mismatch")
+}
+
+func (s *SAMLAuthProviderSuite) TestGroupAttributeMapping() {
+	allUsers := slices.Concat(s.authConfig.Users, s.authConfig.NestedUsers, s.authConfig.DoubleNestedUsers, s.authConfig.TripleNestedUsers)
+
+	for _, user := range allUsers {
+		_, token, err := authactions.LoginAsSAMLUser(s.client, s.fixture, user)
+		require.NoError(s.T(), err, "User [%v] should be able to login", user.Username)
+
+		expectedGroups := authactions.SeedGroupsOfUser(s.fixture.Seed, user.Username)
+		logrus.Infof("Verifying group principals %v of user [%v]", expectedGroups, user.Username)
+		err = authactions.VerifyUserGroupPrincipals(s.client, token.UserID, authactions.KeyCloakSAML, expectedGroups)
+		require.NoError(s.T(), err, "Group principals of user [%v] do not match the groups of the assertion", user.Username)
+	}
+}
+
+func (s *SAMLAuthProviderSuite) TestPrincipalLookup() {
+	user := s.authConfig.Users[0]
+	userClient, token, err := authactions.LoginAsSAMLUser(s.client, s.fixture, user)
+	require.NoError(s.T(), err, "User [%v] should be able to login", user.Username)
+
+	userPrincipal, err := authactions.GetPrincipal(userClient, token.UserPrincipal)
+	require.NoError(s.T(), err, "Failed to get the principal of user [%v]", user.Username)
+	require.Equal(s.T(), token.UserPrincipal, userPrincipal.ID, "Principal ID mismatch")
+	require.Equal(s.T(), "user", userPrincipal.PrincipalType, "Principal type mismatch")
+	require.True(s.T(), userPrincipal.Me, "Principal of the token should be the logged in user")
+
+	// the users of the seed group are its direct members, so the group is in the member attribute of their assertion
+	groupPrincipalID := authactions.GetSAMLGroupPrincipalID(authactions.KeyCloakSAML, s.authConfig.Group)
+	principals, err := authactions.ListPrincipals(userClient)
+	require.NoError(s.T(), err, "Failed to list the principals of user [%v]", user.Username)
+
+	principalIndex := slices.IndexFunc(principals, func(principal management.Principal) bool {
+		return principal.ID == groupPrincipalID
+	})
+	require.NotEqual(s.T(), -1, principalIndex, "User [%v] should be a member of group %s", user.Username, s.authConfig.Group)
+	require.True(s.T(), principals[principalIndex].MemberOf, "User [%v] should be a member of group %s", user.Username, s.authConfig.Group)
+
+	unseededUser := authactions.User{Username: namegen.AppendRandomString("unseeded"), Password: user.Password}
+	_, _, err = authactions.LoginAsSAMLUser(s.client, s.fixture, unseededUser)
+	require.Error(s.T(), err, "User [%v] that is not in the seed should NOT be able to login", unseededUser.Username)
+}
+
+func (s *SAMLAuthProviderSuite) TestRestrictedAccessModeByGroup() {
+	adminPrincipalID := s.adminPrincipalID()
+	defer s.restoreUnrestrictedAccessMode()
+
+	allowedPrincipalIDs := []string{adminPrincipalID, authactions.GetSAMLGroupPrincipalID(authactions.KeyCloakSAML, s.authConfig.Group)}
+	_, err := authactions.UpdateSAMLAccessMode(s.client, authactions.KeyCloakSAML, authactions.AccessModeRestricted, allowedPrincipalIDs)
+	require.NoError(s.T(), err, "Failed to update access mode")
+
+	err = authactions.VerifySAMLUserLogins(s.client, s.fixture, s.authConfig.Users, "member of the allowed group", true)
+	require.NoError(s.T(), err)
+
+	// the member attribute only has the direct groups of the user, so members of nested groups are not allowed
+	err = authactions.VerifySAMLUserLogins(s.client, s.fixture, s.authConfig.NestedUsers, "member of a nested group only", false)
+	require.NoError(s.T(), err)
+
+	err = authactions.VerifySAMLUserLogins(s.client, s.fixture, s.authConfig.TripleNestedUsers, "not a member of the allowed group", false)
+	require.NoError(s.T(), err)
+}
+
+func (s *SAMLAuthProviderSuite) TestRequiredAccessModeByGroup() {
+	adminPrincipalID := s.adminPrincipalID()
+	defer s.restoreUnrestrictedAccessMode()
+
+	allowedPrincipalIDs := []string{adminPrincipalID, authactions.GetSAMLGroupPrincipalID(authactions.KeyCloakSAML, s.authConfig.TripleNestedGroup)}
+	_, err := authactions.UpdateSAMLAccessMode(s.client, authactions.KeyCloakSAML, authactions.AccessModeRequired, allowedPrincipalIDs)
+	require.NoError(s.T(), err, "Failed to update access mode")
+
+	err = authactions.VerifySAMLUserLogins(s.client, s.fixture, s.authConfig.TripleNestedUsers, "member of the required group", true)
+	require.NoError(s.T(), err)
+
+	unauthorizedUsers := slices.Concat(s.authConfig.Users, s.authConfig.NestedUsers, s.authConfig.DoubleNestedUsers)
+	err = authactions.VerifySAMLUserLogins(s.client, s.fixture, unauthorizedUsers, "not a member of the required group", false)
+	require.NoError(s.T(), err)
+}
+
+func (s *SAMLAuthProviderSuite) TestLogoutRevokesToken() {
+	user := s.authConfig.Users[0]
+	userClient, _, err := authactions.LoginAsSAMLUser(s.client, s.fixture, user)
+	require.NoError(s.T(), err, "User [%v] should be able to login", user.Username)
+
+	_, err = userClient.Management.Principal.List(nil)
+	require.NoError(s.T(), err, "User [%v] should be able to list principals before logout", user.Username)
+
+	err = authactions.LogoutAuthUser(userClient)
+	require.NoError(s.T(), err, "Failed to logout user [%v]", user.Username)
+
+	_, err = userClient.Management.Principal.List(nil)
+	require.Error(s.T(), err, "The token of user [%v] should be revoked after logout", user.Username)
+	require.Contains(s.T(), err.Error(), "401", "Should return unauthorized error")
+}
+
+func (s *SAMLAuthProviderSuite) adminPrincipalID() string {
+	_, token, err := authactions.LoginAsSAMLUser(s.client, s.fixture, s.fixture.Seed.Admin())
+	require.NoError(s.T(), err, "Admin should be able to login")
+
+	return token.UserPrincipal
+}
+
+func (s *SAMLAuthProviderSuite) restoreUnrestrictedAccessMode() {
+	_, err := authactions.UpdateSAMLAccessMode(s.client, authactions.KeyCloakSAML, authactions.AccessModeUnrestricted, nil)
+	require.NoError(s.T(), err, "Failed to restore unrestricted access mode")
+}
+
+func TestSAMLAuthProviderSuite(t *testing.T) {
+	suite.Run(t, new(SAMLAuthProviderSuite))
+}
@@ -0,0 +1,107 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress && !2.8 && !2.9 && !2.10 && !2.11 && !2.12 && !2.13
+
+package scim
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	authactions "github.com/rancher/tests/actions/auth"
+	scimactions "github.com/rancher/tests/actions/scim"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type SCIMConformanceTestSuite struct {
+	suite.Suite
+	session         *session.Session
+	client          *rancher.Client
+	config          *scimactions.SCIMConformanceConfig
+	provider        *authactions.LDAPProvider
+	providerEnabled bool
+	target          *scimactions.ConformanceTarget
+	report          *scimactions.ConformanceReport
+}
+
+func (s *SCIMConformanceTestSuite) SetupSuite() {
+	s.session = session.NewSession()
+
+	client, err := rancher.NewClient("", s.session)
+	require.NoError(s.T(), err, "Failed to create Rancher client")
+	s.client = client
+
+	s.config = new(scimactions.SCIMConformanceConfig)
+	config.LoadConfig(scimactions.SCIMConformanceInput, s.config)
+	if s.config.Provider == "" {
+		s.config.Provider = authactions.OpenLdap
+	}
+
+	logrus.Infof("Loading %s auth configuration", s.config.Provider)
+	_, err = authactions.LoadLDAPAuthInput(client, s.config.Provider)
+	require.NoError(s.T(), err, "Failed to load auth configuration")
+
+	s.provider, err = authactions.NewLDAPProvider(client, s.config.Provider)
+	require.NoError(s.T(), err, "Failed to describe auth provider %s", s.config.Provider)
+
+	providerConfig, err := client.Management.AuthConfig.ByID(s.provider.Name)
+	require.NoError(s.T(), err, "Failed to retrieve %s config", s.provider.DisplayName)
+	s.providerEnabled = providerConfig.Enabled
+
+	logrus.Infof("Setting up the SCIM endpoint of %s", s.provider.DisplayName)
+	s.target, err = scimactions.NewConformanceTarget(client, s.provider.Name)
+	require.NoError(s.T(), err, "Failed to setup SCIM conformance target")
+
+	s.report = &scimactions.ConformanceReport{Provider: s.provider.Name}
+}
+
+func (s *SCIMConformanceTestSuite) TearDownSuite() {
+	if s.target != nil {
+		s.target.Cleanup()
+	}
+
+	if s.report != nil {
+		logrus.Infof("SCIM conformance report:\n%s", s.report)
+
+		if s.config.ReportFile != "" {
+			err := s.report.WriteJSON(s.config.ReportFile)
+			if err != nil {
+				logrus.WithError(err).Warnf("Failed to write SCIM conformance report to %s", s.config.ReportFile)
+			}
+		}
+	}
+
+	if s.provider != nil && !s.providerEnabled {
+		logrus.Infof("Disabling %s authentication after test suite", s.provider.DisplayName)
+		err := s.provider.NewClient(s.client).Disable()
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to disable %s in teardown", s.provider.DisplayName)
+		}
+	}
+
+	s.session.Cleanup()
+}
+
+func (s *SCIMConformanceTestSuite) TestSCIMConformance() {
+	for _, requirement := range scimactions.ConformanceRequirements() {
+		s.Run(requirement.ID, func() {
+			result := s.target.Check(requirement)
+			s.report.Add(result)
+
+			switch {
+			case result.Status == scimactions.StatusSkip:
+				s.T().Skip(result.Detail)
+			case result.Status == scimactions.StatusFail && requirement.Level == scimactions.LevelMust:
+				s.Failf(requirement.Description, "%s (%s): %s", requirement.ID, requirement.Reference, result.Detail)
+			case result.Status == scimactions.StatusFail:
+				logrus.Warnf("%s requirement %s (%s) is not met: %s", requirement.Level, requirement.ID, requirement.Reference, result.Detail)
+			}
+		})
+	}
+}
+
+func TestSCIMConformanceSuite(t *testing.T) {
+	suite.Run(t, new(SCIMConformanceTestSuite))
+}
@@ -13,7 +13,6 @@ import (
 	v3 "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
 	"github.com/rancher/shepherd/extensions/clusters"
 	"github.com/rancher/shepherd/extensions/defaults"
-	"github.com/rancher/shepherd/pkg/config"
 	namegen "github.com/rancher/shepherd/pkg/namegenerator"
 	"github.com/rancher/shepherd/pkg/session"
 
@@ -53,10 +52,9 @@ func (s *SCIMOpenLDAPTestSuite) SetupSuite() {
 	require.NoError(s.T(), err, "Failed to create Rancher client")
 	s.client = client
 
-	logrus.Info("Loading OpenLDAP auth configuration from config file")
-	s.authConfig = new(authactions.AuthConfig)
-	config.LoadConfig(authactions.OpenLdapAuthInput, s.authConfig)
-	require.NotNil(s.T(), s.authConfig, "Auth configuration is not provided")
+	logrus.Info("Loading OpenLDAP auth configuration, deploying the OpenLDAP fixture if a seed is configured")
+	s.authConfig, err = authactions.LoadLDAPAuthInput(s.client, authactions.OpenLdap)
+	require.NoError(s.T(), err, "Failed to load OpenLDAP auth configuration")
 
 	logrus.Info("Getting cluster name from the config file")
 	clusterName := client.RancherConfig.ClusterName
@@ -0,0 +1,97 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress && !2.8 && !2.9 && !2.10 && !2.11 && !2.12
+
+package tokens
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	extensionscluster "github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/settings"
+	"github.com/rancher/tests/actions/tokens/lifecycle"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TokenLifecycleTestSuite struct {
+	suite.Suite
+	client  *rancher.Client
+	session *session.Session
+	cluster *management.Cluster
+	config  *lifecycle.LifecycleConfig
+}
+
+func (tl *TokenLifecycleTestSuite) TearDownSuite() {
+	log.Infof("Resetting %s to its default value", settings.AuthUserSessionIdleTTlMinutesSetting)
+	err := settings.ResetGlobalSettingToDefaultValue(tl.client, settings.AuthUserSessionIdleTTlMinutesSetting)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to reset %s", settings.AuthUserSessionIdleTTlMinutesSetting)
+	}
+
+	tl.session.Cleanup()
+}
+
+func (tl *TokenLifecycleTestSuite) SetupSuite() {
+	tl.session = session.NewSession()
+
+	client, err := rancher.NewClient("", tl.session)
+	require.NoError(tl.T(), err)
+	tl.client = client
+
+	log.Info("Getting cluster name from the config file and append cluster details in the struct.")
+	clusterName := client.RancherConfig.ClusterName
+	require.NotEmptyf(tl.T(), clusterName, "Cluster name to install should be set")
+	clusterID, err := extensionscluster.GetClusterIDByName(tl.client, clusterName)
+	require.NoError(tl.T(), err, "Error getting cluster ID")
+	tl.cluster, err = tl.client.Management.Cluster.ByID(clusterID)
+	require.NoError(tl.T(), err)
+
+	tl.config = new(lifecycle.LifecycleConfig)
+	config.LoadConfig(lifecycle.TokenLifecycleInput, tl.config)
+	tl.config.SetDefaults()
+	if tl.config.Seed == 0 {
+		tl.config.Seed = rand.Uint64()
+	}
+
+	log.Infof("Set %s to %d minutes", settings.AuthUserSessionIdleTTlMinutesSetting, tl.config.IdleTimeoutMinutes)
+	err = settings.SetGlobalSetting(tl.client, settings.AuthUserSessionIdleTTlMinutesSetting, strconv.Itoa(tl.config.IdleTimeoutMinutes))
+	require.NoError(tl.T(), err)
+}
+
+func (tl *TokenLifecycleTestSuite) TestTokenLifecycleSequences() {
+	for i := range tl.config.Sequences {
+		seed := tl.config.Seed + uint64(i)
+
+		tl.Run(fmt.Sprintf("seed %d", seed), func() {
+			subSession := tl.session.NewSession()
+			defer subSession.Cleanup()
+
+			client, err := tl.client.WithSession(subSession)
+			require.NoError(tl.T(), err)
+
+			log.Infof("Running token lifecycle sequence with seed %d", seed)
+			sequence, err := lifecycle.NewLifecycle(client, tl.cluster, tl.config, seed)
+			require.NoError(tl.T(), err)
+
+			for step := 0; step < tl.config.Steps && !sequence.Done(); step++ {
+				err = sequence.Step()
+				require.NoError(tl.T(), err, sequence.HistoryString())
+				log.Info(sequence.History[len(sequence.History)-1])
+
+				err = sequence.VerifyInvariants()
+				require.NoError(tl.T(), err, sequence.HistoryString())
+			}
+		})
+	}
+}
+
+func TestTokenLifecycleTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenLifecycleTestSuite))
+}
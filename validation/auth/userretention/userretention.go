@@ -9,102 +9,20 @@ import (
 	"github.com/rancher/shepherd/extensions/defaults"
 	"github.com/rancher/shepherd/extensions/users"
 	"github.com/rancher/tests/actions/auth"
-	"github.com/rancher/tests/actions/settings"
 	"github.com/sirupsen/logrus"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
-	disableInactiveUserAfter  = "disable-inactive-user-after"
-	deleteInactiveUserAfter   = "delete-inactive-user-after"
-	userRetentionCron         = "user-retention-cron"
-	authUserSessionTTLMinutes = "auth-user-session-ttl-minutes"
-	testTTLValue              = "1"
-	userRetentionDryRun       = "user-retention-dry-run"
-	lastloginLabel            = "cattle.io/last-login"
-	defaultWaitDuration       = 70 * time.Second
-	isActive                  = true
-	isInActive                = false
-	webhookErrorMessage       = "admission webhook \"rancher.cattle.io.settings.management.cattle.io\" denied the request: value: Invalid value:"
-	forbiddenError            = "403 Forbidden"
-	unauthorizedError         = "401 Unauthorized"
+	testTTLValue        = "1"
+	defaultWaitDuration = 70 * time.Second
+	isActive            = true
+	isInActive          = false
+	webhookErrorMessage = "admission webhook \"rancher.cattle.io.settings.management.cattle.io\" denied the request: value: Invalid value:"
+	forbiddenError      = "403 Forbidden"
+	unauthorizedError   = "401 Unauthorized"
 )
 
-func setupUserRetentionSettings(client *rancher.Client, disableAfterValue string, deleteAfterValue string, userRetentionCronValue string, dryRunValue string) error {
-	logrus.Info("Setting up user retention settings TO : ")
-	logrus.Infof("DisableAfterValue as %s, DeleteAfterValue as %s, UserRetentionCronValue as %s, DryRunValue as %s", disableAfterValue, deleteAfterValue, userRetentionCronValue, dryRunValue)
-	err := updateUserRetentionSettings(client, disableInactiveUserAfter, disableAfterValue)
-	if err != nil {
-		return fmt.Errorf("failed to update disable-inactive-user-after setting: %w", err)
-	}
-	err = updateUserRetentionSettings(client, deleteInactiveUserAfter, deleteAfterValue)
-	if err != nil {
-		return fmt.Errorf("failed to update delete-inactive-user-after setting: %w", err)
-	}
-
-	err = updateUserRetentionSettings(client, userRetentionCron, userRetentionCronValue)
-	if err != nil {
-		return fmt.Errorf("failed to update user-retention-cron setting: %w", err)
-	}
-	err = updateUserRetentionSettings(client, userRetentionDryRun, dryRunValue)
-	if err != nil {
-		return fmt.Errorf("failed to update user-retention-dry-run setting: %w", err)
-	}
-	logrus.Info("User retention settings setup completed")
-	return nil
-}
-
-func resetUserRetentionSettings(client *rancher.Client) error {
-	logrus.Infof("Resetting %s to default value", disableInactiveUserAfter)
-	err := settings.ResetGlobalSettingToDefaultValue(client, disableInactiveUserAfter)
-	if err != nil {
-		return fmt.Errorf("failed to update disable-inactive-user-after setting: %w", err)
-	}
-
-	logrus.Infof("Resetting %s to default value", deleteInactiveUserAfter)
-	err = settings.ResetGlobalSettingToDefaultValue(client, deleteInactiveUserAfter)
-	if err != nil {
-		return fmt.Errorf("failed to update delete-inactive-user-after setting: %w", err)
-	}
-
-	logrus.Infof("Resetting %s to default value", userRetentionCron)
-	err = settings.ResetGlobalSettingToDefaultValue(client, userRetentionCron)
-	if err != nil {
-		return fmt.Errorf("failed to update user-retention-cron setting: %w", err)
-	}
-
-	logrus.Infof("Resetting %s to default value", userRetentionDryRun)
-	err = settings.ResetGlobalSettingToDefaultValue(client, userRetentionDryRun)
-	if err != nil {
-		return fmt.Errorf("failed to update user-retention-dry-run setting: %w", err)
-	}
-
-	logrus.Infof("Resetting %s to default value", authUserSessionTTLMinutes)
-	err = settings.ResetGlobalSettingToDefaultValue(client, authUserSessionTTLMinutes)
-	if err != nil {
-		return fmt.Errorf("failed to update auth-user-session-ttl-minutes setting: %w", err)
-	}
-
-	logrus.Info("User retention settings reset to default values successfully")
-	return nil
-}
-
-func updateUserRetentionSettings(client *rancher.Client, settingName string, settingValue string) error {
-	logrus.Infof("Updating setting: %s, value: %s", settingName, settingValue)
-	setting, err := client.WranglerContext.Mgmt.Setting().Get(settingName, v1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get setting %s: %w", settingName, err)
-	}
-
-	setting.Value = settingValue
-	_, err = client.WranglerContext.Mgmt.Setting().Update(setting)
-	if err != nil {
-		return fmt.Errorf("failed to update setting %s: %w", settingName, err)
-	}
-	return nil
-}
-
 func pollUserStatus(rancherClient *rancher.Client, userID string, expectedStatus bool) error {
 	logrus.Infof("Polling user status for user %s, expected status: %v", userID, expectedStatus)
 	ctx, cancel := context.WithTimeout(context.Background(), defaultWaitDuration)
@@ -12,6 +12,7 @@ import (
 	"github.com/rancher/shepherd/pkg/session"
 	"github.com/rancher/tests/actions/auth"
 	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
+	"github.com/rancher/tests/actions/userretention"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -33,12 +34,12 @@ func (ur *URDeleteTestSuite) SetupSuite() {
 	require.NoError(ur.T(), err)
 	ur.client = client
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "0")
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "0")
 	require.NoError(ur.T(), err)
 }
 
 func (ur *URDeleteTestSuite) TearDownSuite() {
-	err := resetUserRetentionSettings(ur.client)
+	err := userretention.ResetRetentionSettings(ur.client)
 	require.NoError(ur.T(), err)
 
 	ur.session.Cleanup()
@@ -48,7 +49,8 @@ func (ur *URDeleteTestSuite) TestDefaultAdminUserIsNotDeleted() {
 	logrus.Info("Setting up user retention settings")
 	subSession := ur.session.NewSession()
 	defer subSession.Cleanup()
-	setupUserRetentionSettings(ur.client, "", "400h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DeleteAfter: "400h", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Getting admin user details")
 	adminID, err := users.GetUserIDByName(ur.client, "admin")
@@ -80,7 +82,8 @@ func (ur *URDeleteTestSuite) TestAdminUserGetDeleted() {
 	logrus.Info("Setting up user retention settings")
 	subSession := ur.session.NewSession()
 	defer subSession.Cleanup()
-	setupUserRetentionSettings(ur.client, "", "400h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DeleteAfter: "400h", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new admin user")
 	newAdminUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "admin")
@@ -126,7 +129,8 @@ func (ur *URDeleteTestSuite) TestStandardUserGetDeleted() {
 	logrus.Info("Setting up user retention settings")
 	subSession := ur.session.NewSession()
 	defer subSession.Cleanup()
-	setupUserRetentionSettings(ur.client, "", "400h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DeleteAfter: "400h", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newStdUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -174,7 +178,8 @@ func (ur *URDeleteTestSuite) TestUserIsNotDeletedWithBlankSettings() {
 	logrus.Info("Setting up user retention settings with blank values")
 	subSession := ur.session.NewSession()
 	defer subSession.Cleanup()
-	setupUserRetentionSettings(ur.client, "", "400h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DeleteAfter: "400h", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -209,7 +214,8 @@ func (ur *URDeleteTestSuite) TestUserIsNotGetDeletedWithDryRun() {
 	logrus.Info("Setting up user retention settings with dry run")
 	subSession := ur.session.NewSession()
 	defer subSession.Cleanup()
-	setupUserRetentionSettings(ur.client, "", "400h", "*/1 * * * *", "true")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DeleteAfter: "400h", Cron: userretention.EveryMinuteCron, DryRun: true})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -251,7 +257,7 @@ func (ur *URDeleteTestSuite) TestUserIsNotGetDeletedWithDryRun() {
 	require.NoError(ur.T(), err)
 
 	logrus.Info("Resetting user retention dry run setting")
-	err = updateUserRetentionSettings(ur.client, userRetentionDryRun, "false")
+	err = userretention.UpdateSetting(ur.client, userretention.UserRetentionDryRun, "false")
 	require.NoError(ur.T(), err)
 }
 
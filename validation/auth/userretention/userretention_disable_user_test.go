@@ -12,6 +12,7 @@ import (
 	"github.com/rancher/shepherd/pkg/session"
 	"github.com/rancher/tests/actions/auth"
 	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
+	"github.com/rancher/tests/actions/userretention"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -34,7 +35,7 @@ func (ur *URDisableTestSuite) SetupSuite() {
 	require.NoError(ur.T(), err)
 	ur.client = client
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "0")
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "0")
 	require.NoError(ur.T(), err)
 }
 
@@ -48,7 +49,7 @@ func (ur *URDisableTestSuite) TearDownSuite() {
 		}
 	}
 
-	err := resetUserRetentionSettings(ur.client)
+	err := userretention.ResetRetentionSettings(ur.client)
 	require.NoError(ur.T(), err)
 
 	ur.session.Cleanup()
@@ -66,7 +67,8 @@ func (ur *URDisableTestSuite) TestDefaultAdminUserIsNotDisabled() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 	logrus.Info("Retrieving admin user details")
 	adminID, err := users.GetUserIDByName(ur.client, "admin")
 	require.NoError(ur.T(), err)
@@ -91,7 +93,8 @@ func (ur *URDisableTestSuite) TestAdminUserGetDisabled() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new admin user")
 	newAdminUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "admin")
@@ -125,7 +128,8 @@ func (ur *URDisableTestSuite) TestStandardUserGetDisabled() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newStdUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -161,7 +165,8 @@ func (ur *URDisableTestSuite) TestDisabledUserGetEnabled() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newStdUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -209,7 +214,8 @@ func (ur *URDisableTestSuite) TestStandardUserDidNotGetDisabledWithBlankSettings
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings with blank values")
-	setupUserRetentionSettings(ur.client, "", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -232,7 +238,8 @@ func (ur *URDisableTestSuite) TestUserDisableByUpdatingUserattributes() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newStdUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -279,7 +286,8 @@ func (ur *URDisableTestSuite) TestUserIsNotDisabledWithDryRun() {
 	defer subSession.Cleanup()
 
 	logrus.Info("Setting up user retention settings with dry run")
-	setupUserRetentionSettings(ur.client, "10s", "", "*/1 * * * *", "true")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "10s", Cron: userretention.EveryMinuteCron, DryRun: true})
+	require.NoError(ur.T(), err)
 
 	logrus.Info("Creating new standard user")
 	newStdUser, err := users.CreateUserWithRole(ur.client, users.UserConfig(), "user")
@@ -317,7 +325,7 @@ func (ur *URDisableTestSuite) TestUserIsNotDisabledWithDryRun() {
 	ur.assertBindingsEqual(bindingsBefore, bindingsAfter)
 
 	logrus.Info("User retention settings:dry run settings back to default value")
-	err = updateUserRetentionSettings(ur.client, userRetentionDryRun, "false")
+	err = userretention.UpdateSetting(ur.client, userretention.UserRetentionDryRun, "false")
 	require.NoError(ur.T(), err)
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/rancher/shepherd/clients/rancher"
 	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/userretention"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,12 +29,12 @@ func (ur *UserRetentionSettingsTestSuite) SetupSuite() {
 	require.NoError(ur.T(), err)
 	ur.client = client
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "0")
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "0")
 	require.NoError(ur.T(), err)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TearDownSuite() {
-	err := resetUserRetentionSettings(ur.client)
+	err := userretention.ResetRetentionSettings(ur.client)
 	require.NoError(ur.T(), err)
 
 	ur.session.Cleanup()
@@ -47,7 +48,7 @@ func (ur *UserRetentionSettingsTestSuite) testPositiveInputValues(settingName st
 	logrus.Infof("Updating %s settings with positive values:", settingName)
 	for _, inputValue := range tests {
 		ur.T().Run(inputValue.name, func(*testing.T) {
-			err := updateUserRetentionSettings(ur.client, settingName, inputValue.value)
+			err := userretention.UpdateSetting(ur.client, settingName, inputValue.value)
 			assert.NoError(ur.T(), err, "Unexpected error for input '%s'", inputValue.value)
 
 			if err == nil {
@@ -70,7 +71,7 @@ func (ur *UserRetentionSettingsTestSuite) testNegativeInputValues(settingName st
 	logrus.Infof("Updating %s settings with negative values:", settingName)
 	for _, inputValue := range tests {
 		ur.T().Run(inputValue.name, func(*testing.T) {
-			err := updateUserRetentionSettings(ur.client, settingName, inputValue.value)
+			err := userretention.UpdateSetting(ur.client, settingName, inputValue.value)
 			assert.Error(ur.T(), err, "Expected an error for input '%s', but got nil", inputValue.value)
 
 			if err != nil {
@@ -118,7 +119,7 @@ func (ur *UserRetentionSettingsTestSuite) validateSettingsNotUpdated(settingName
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDisableInactiveUserAfterWithPositiveInputValues() {
 
-	err := updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "1")
+	err := userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "1")
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -137,11 +138,11 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDisableInactiveUs
 		{"DisableAfterUpdatedTenThousandMinutes", "10000m", "Users will be deactivated after 10000m"},
 		{"DisableAfterUpdatedTenThousandHours", "10000h", "Users will be deactivated after 10000h"},
 	}
-	ur.testPositiveInputValues(disableInactiveUserAfter, tests)
+	ur.testPositiveInputValues(userretention.DisableInactiveUserAfter, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDisableInactiveUserAfterWithNegativeInputValues() {
-	err := updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "1")
+	err := userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "1")
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -160,7 +161,7 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDisableInactiveUs
 		{"DisableAfterUpdateErrorNegativeDuration", "-20m", "Invalid value: \"-20m\": negative value"},
 		{"DisableAfterUpdateErrorInvalidDuration", "tens", "Invalid value: \"tens\": time: invalid duration \"tens\""},
 	}
-	ur.testNegativeInputValues(disableInactiveUserAfter, tests)
+	ur.testNegativeInputValues(userretention.DisableInactiveUserAfter, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDeleteInactiveUserAfterWithPositiveInputValues() {
@@ -174,7 +175,7 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDeleteInactiveUse
 		{"DeleteAfterTwoHundredThousandMinutes", "200000m", "Users will delete after 200000m"},
 		{"DeleteAfterTenThousandHours", "10000h", "Users will delete after 10000h"},
 	}
-	ur.testPositiveInputValues(deleteInactiveUserAfter, tests)
+	ur.testPositiveInputValues(userretention.DeleteInactiveUserAfter, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDeleteInactiveUserAfterWithNegativeInputValues() {
@@ -196,7 +197,7 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForDeleteInactiveUse
 		{"DeleteErrorInvalidUnitDay", "1d", "time: unknown unit"},
 		{"DeleteErrorNegativeDuration", "-20m", "negative value"},
 	}
-	ur.testNegativeInputValues(deleteInactiveUserAfter, tests)
+	ur.testNegativeInputValues(userretention.DeleteInactiveUserAfter, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForUserRetentionCronWithPositiveInputValues() {
@@ -215,7 +216,7 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForUserRetentionCron
 		{"CronRunsFirstSecondDayMidnight", "0 0 1,2 * *", "at midnight of 1st, 2nd day of each month"},
 		{"CronRunsFirstSecondDayWednesdayMidnight", "0 0 1,2 * 3", "at midnight of 1st, 2nd day of each month, and each Wednesday"},
 	}
-	ur.testPositiveInputValues(userRetentionCron, tests)
+	ur.testPositiveInputValues(userretention.UserRetentionCron, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForUserRetentionCronWithNegativeInputValues() {
@@ -234,12 +235,12 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForUserRetentionCron
 		{"CronUpdateErrorLessFields2", "1d", "Invalid value: \"1d\": Expected exactly 5 fields, found 1: 1d"},
 		{"CronUpdateErrorInvalidNegative", "-20m", "Invalid value: \"-20m\": Expected exactly 5 fields, found 1: -20m"},
 	}
-	ur.testNegativeInputValues(userRetentionCron, tests)
+	ur.testNegativeInputValues(userretention.UserRetentionCron, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForAuthUserSessionTTLWithPositiveInputValues() {
 
-	err := setupUserRetentionSettings(ur.client, "1600h", "1600h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "1600h", DeleteAfter: "1600h", Cron: userretention.EveryMinuteCron})
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -252,7 +253,7 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForAuthUserSessionTT
 		{"TTLUpdatedDay", "1440", "24 hour session"},
 		{"TTLUpdatedWeek", "10080", "One week session"},
 	}
-	ur.testPositiveInputValues(authUserSessionTTLMinutes, tests)
+	ur.testPositiveInputValues(userretention.AuthUserSessionTTLMinutes, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForAuthUserSessionTTLWithNegativeInputValues() {
@@ -266,14 +267,14 @@ func (ur *UserRetentionSettingsTestSuite) TestUpdateSettingsForAuthUserSessionTT
 		{"TTLErrorDecimal", "10.5", "strconv.ParseInt: parsing \"10.5\": invalid syntax"},
 		{"TTLErrorSpecialChars", "10@20", "strconv.ParseInt: parsing \"10@20\": invalid syntax"},
 	}
-	ur.testNegativeInputValues(authUserSessionTTLMinutes, tests)
+	ur.testNegativeInputValues(userretention.AuthUserSessionTTLMinutes, tests)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestDisableInactiveUserLessThanTTL() {
-	err := setupUserRetentionSettings(ur.client, "1600m", "1600h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "1600m", DeleteAfter: "1600h", Cron: userretention.EveryMinuteCron})
 	require.NoError(ur.T(), err)
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "1600")
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "1600")
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -285,17 +286,17 @@ func (ur *UserRetentionSettingsTestSuite) TestDisableInactiveUserLessThanTTL() {
 		{"DisableLessThanTTL599m", "599m", "Forbidden: can't be less than auth-user-session-ttl-minutes"},
 		{"DisableLessThanTTL1h", "1h", "Forbidden: can't be less than auth-user-session-ttl-minutes"},
 	}
-	ur.testNegativeInputValues(disableInactiveUserAfter, tests)
+	ur.testNegativeInputValues(userretention.DisableInactiveUserAfter, tests)
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, testTTLValue)
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, testTTLValue)
 	require.NoError(ur.T(), err)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestDeleteInactiveUserLessThanTTL() {
-	err := setupUserRetentionSettings(ur.client, "21600m", "1600h", "*/1 * * * *", "false")
+	err := userretention.ApplyRetentionSettings(ur.client, userretention.RetentionSettings{DisableAfter: "21600m", DeleteAfter: "1600h", Cron: userretention.EveryMinuteCron})
 	require.NoError(ur.T(), err)
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "21600")
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "21600")
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -307,14 +308,14 @@ func (ur *UserRetentionSettingsTestSuite) TestDeleteInactiveUserLessThanTTL() {
 		{"DeleteLessThanTTL359m", "359h", "Forbidden: can't be less than auth-user-session-ttl-minutes"},
 		{"DeleteLessThanTTL5h", "5h", "Forbidden: must be at least 336h0m0s"},
 	}
-	ur.testNegativeInputValues(deleteInactiveUserAfter, tests)
+	ur.testNegativeInputValues(userretention.DeleteInactiveUserAfter, tests)
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, testTTLValue)
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, testTTLValue)
 	require.NoError(ur.T(), err)
 }
 
 func (ur *UserRetentionSettingsTestSuite) TestInactiveUserSettingsGreaterThanTTL() {
-	err := updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, "60")
+	err := userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, "60")
 	require.NoError(ur.T(), err)
 
 	tests := []struct {
@@ -326,7 +327,7 @@ func (ur *UserRetentionSettingsTestSuite) TestInactiveUserSettingsGreaterThanTTL
 		{"ValidDisableAfter61m", "61m", "Valid value greater than TTL"},
 		{"ValidDisableAfter3600s", "3600s", "Valid value greater than TTL"},
 	}
-	ur.testPositiveInputValues(disableInactiveUserAfter, tests)
+	ur.testPositiveInputValues(userretention.DisableInactiveUserAfter, tests)
 
 	deleteTests := []struct {
 		name        string
@@ -336,9 +337,9 @@ func (ur *UserRetentionSettingsTestSuite) TestInactiveUserSettingsGreaterThanTTL
 		{"ValidDeleteAfter337h", "337h", "Valid value greater than minimum and TTL"},
 		{"ValidDeleteAfter20160m", "20160m", "Valid value greater than minimum and TTL"},
 	}
-	ur.testPositiveInputValues(deleteInactiveUserAfter, deleteTests)
+	ur.testPositiveInputValues(userretention.DeleteInactiveUserAfter, deleteTests)
 
-	err = updateUserRetentionSettings(ur.client, authUserSessionTTLMinutes, testTTLValue)
+	err = userretention.UpdateSetting(ur.client, userretention.AuthUserSessionTTLMinutes, testTTLValue)
 	require.NoError(ur.T(), err)
 }
 
@@ -0,0 +1,146 @@
+//go:build (validation || infra.any || cluster.any || sanity) && !stress && !extended
+
+package userretention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/users"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/userretention"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	day = 24 * time.Hour
+)
+
+type URSimulationTestSuite struct {
+	suite.Suite
+	client    *rancher.Client
+	session   *session.Session
+	retention userretention.RetentionSettings
+	users     map[string]*userretention.SimulatedUser
+}
+
+func (ur *URSimulationTestSuite) SetupSuite() {
+	logrus.Info("Setting up URSimulationTestSuite")
+	ur.session = session.NewSession()
+
+	client, err := rancher.NewClient("", ur.session)
+	require.NoError(ur.T(), err)
+	ur.client = client
+}
+
+func (ur *URSimulationTestSuite) TearDownSuite() {
+	logrus.Info("Tearing down URSimulationTestSuite")
+	err := userretention.ResetRetentionSettings(ur.client)
+	require.NoError(ur.T(), err)
+
+	ur.session.Cleanup()
+}
+
+func (ur *URSimulationTestSuite) verifyUsers() {
+	for _, name := range []string{
+		"RecentUser", "InactiveUser", "InactiveAdmin", "AbandonedUser", "NeverLoggedInWithoutAttributes",
+		"NeverLoggedInWithDefault", "ExemptUser", "ShortOverrideUser", "MidPeriodUser", "RecentlyDisabledUser",
+		"LongDisabledUser",
+	} {
+		simulated := ur.users[name]
+		ur.Run(name, func() {
+			err := simulated.Verify(ur.client, ur.retention)
+			require.NoError(ur.T(), err)
+		})
+	}
+}
+
+func (ur *URSimulationTestSuite) TestRetentionSimulation() {
+	subSession := ur.session.NewSession()
+	defer subSession.Cleanup()
+
+	now := time.Now()
+	zero := time.Duration(0)
+	week := 7 * day
+
+	ur.retention = userretention.RetentionSettings{
+		DisableAfter:     "720h",
+		DeleteAfter:      "1440h",
+		LastLoginDefault: now.Add(-45 * day),
+		Cron:             userretention.EveryMinuteCron,
+	}
+	err := userretention.ApplyRetentionSettings(ur.client, ur.retention)
+	require.NoError(ur.T(), err)
+
+	scenarios := []userretention.Scenario{
+		{Name: "RecentUser", GlobalRole: "user", LastLoginAgo: day, Expected: userretention.OutcomeEnabled},
+		{Name: "InactiveUser", GlobalRole: "user", LastLoginAgo: 40 * day, Expected: userretention.OutcomeDisabled},
+		{Name: "InactiveAdmin", GlobalRole: "admin", LastLoginAgo: 40 * day, Expected: userretention.OutcomeDisabled},
+		{Name: "AbandonedUser", GlobalRole: "user", LastLoginAgo: 90 * day, Expected: userretention.OutcomeDeleted},
+		{Name: "NeverLoggedInWithoutAttributes", GlobalRole: "user", NeverLoggedIn: true, WithoutAttributes: true, Expected: userretention.OutcomeEnabled},
+		{Name: "NeverLoggedInWithDefault", GlobalRole: "user", NeverLoggedIn: true, Expected: userretention.OutcomeDisabled},
+		{Name: "ExemptUser", GlobalRole: "user", LastLoginAgo: 90 * day, DisableAfter: &zero, DeleteAfter: &zero, Expected: userretention.OutcomeEnabled},
+		{Name: "ShortOverrideUser", GlobalRole: "user", LastLoginAgo: 10 * day, DisableAfter: &week, Expected: userretention.OutcomeDisabled},
+		{Name: "MidPeriodUser", GlobalRole: "user", LastLoginAgo: 10 * day, Expected: userretention.OutcomeEnabled},
+		// the delete period runs for 30 days from the disable time
+		{Name: "RecentlyDisabledUser", GlobalRole: "user", DisabledAgo: 10 * day, Expected: userretention.OutcomeDisabled},
+		{Name: "LongDisabledUser", GlobalRole: "user", DisabledAgo: 40 * day, Expected: userretention.OutcomeDeleted},
+	}
+
+	logrus.Info("Creating users with synthetic last logins and disable times")
+	ur.users = map[string]*userretention.SimulatedUser{}
+	for _, scenario := range scenarios {
+		simulated, err := userretention.CreateSimulatedUser(ur.client, scenario, ur.retention, now)
+		require.NoError(ur.T(), err)
+		ur.users[scenario.Name] = simulated
+	}
+
+	userretention.WaitForRetentionRuns(1)
+
+	logrus.Info("Verifying the users after the first retention run")
+	ur.verifyUsers()
+
+	logrus.Info("Verifying the default admin is not subject to retention")
+	adminID, err := users.GetUserIDByName(ur.client, "admin")
+	require.NoError(ur.T(), err)
+	adminUser, err := ur.client.WranglerContext.Mgmt.User().Get(adminID, v1.GetOptions{})
+	require.NoError(ur.T(), err)
+	require.True(ur.T(), adminUser.GetEnabled())
+	for _, labelKey := range []string{userretention.DisableAfterLabelKey, userretention.DeleteAfterLabelKey} {
+		_, ok := adminUser.Labels[labelKey]
+		require.Falsef(ur.T(), ok, "Expected no %s label on the default admin", labelKey)
+	}
+
+	logrus.Info("Re-enabling the inactive users, the admin after a simulated login")
+	err = ur.users["InactiveUser"].Reenable(ur.client)
+	require.NoError(ur.T(), err)
+	err = ur.users["InactiveAdmin"].RecordLogin(ur.client, time.Now())
+	require.NoError(ur.T(), err)
+	err = ur.users["InactiveAdmin"].Reenable(ur.client)
+	require.NoError(ur.T(), err)
+	ur.users["InactiveAdmin"].Expected = userretention.OutcomeEnabled
+
+	userretention.WaitForRetentionRuns(1)
+
+	logrus.Info("Verifying a re-enabled user without a new login is disabled again")
+	ur.verifyUsers()
+
+	logrus.Info("Shortening disable-inactive-user-after in the middle of the inactivity period")
+	ur.retention.DisableAfter = "168h"
+	err = userretention.ApplyRetentionSettings(ur.client, ur.retention)
+	require.NoError(ur.T(), err)
+	ur.users["MidPeriodUser"].Expected = userretention.OutcomeDisabled
+
+	userretention.WaitForRetentionRuns(1)
+
+	logrus.Info("Verifying the users after the settings change")
+	ur.verifyUsers()
+}
+
+func TestURSimulationSuite(t *testing.T) {
+	suite.Run(t, new(URSimulationTestSuite))
+}
@@ -0,0 +1,169 @@
+//go:build (validation || recurring || infra.rke2k3s || cluster.any || stress) && !infra.any && !infra.aks && !infra.eks && !infra.gke && !sanity && !extended
+
+package rke2
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	extClusters "github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/clusters/kubernetesversions"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/config/operations"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/availability"
+	"github.com/rancher/tests/actions/certificates"
+	"github.com/rancher/tests/actions/clusters"
+	"github.com/rancher/tests/actions/config/defaults"
+	"github.com/rancher/tests/actions/logging"
+	"github.com/rancher/tests/actions/machinepools"
+	"github.com/rancher/tests/actions/provisioning"
+	"github.com/rancher/tests/actions/upgrade"
+	resources "github.com/rancher/tests/validation/provisioning/resources/provisioncluster"
+	standard "github.com/rancher/tests/validation/provisioning/resources/standarduser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type AvailabilityTestSuite struct {
+	suite.Suite
+	session            *session.Session
+	client             *rancher.Client
+	cattleConfig       map[string]any
+	availabilityConfig *availability.Config
+	cluster            *v1.SteveAPIObject
+}
+
+func (a *AvailabilityTestSuite) TearDownSuite() {
+	a.session.Cleanup()
+}
+
+func (a *AvailabilityTestSuite) SetupSuite() {
+	testSession := session.NewSession()
+	a.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	require.NoError(a.T(), err)
+
+	a.client = client
+
+	a.cattleConfig = config.LoadConfigFromFile(os.Getenv(config.ConfigEnvironmentKey))
+
+	a.cattleConfig, err = defaults.LoadPackageDefaults(a.cattleConfig, "")
+	require.NoError(a.T(), err)
+
+	loggingConfig := new(logging.Logging)
+	operations.LoadObjectFromMap(logging.LoggingKey, a.cattleConfig, loggingConfig)
+
+	err = logging.SetLogger(loggingConfig)
+	require.NoError(a.T(), err)
+
+	a.availabilityConfig = new(availability.Config)
+	operations.LoadObjectFromMap(availability.ConfigurationFileKey, a.cattleConfig, a.availabilityConfig)
+
+	clusterConfig := new(clusters.ClusterConfig)
+	operations.LoadObjectFromMap(defaults.ClusterConfigKey, a.cattleConfig, clusterConfig)
+
+	rancherConfig := new(rancher.Config)
+	operations.LoadObjectFromMap(defaults.RancherConfigKey, a.cattleConfig, rancherConfig)
+
+	if rancherConfig.ClusterName == "" {
+		standardUserClient, _, _, err := standard.CreateStandardUser(a.client)
+		require.NoError(a.T(), err)
+
+		provider := provisioning.CreateProvider(clusterConfig.Provider)
+		machineConfigSpec := provider.LoadMachineConfigFunc(a.cattleConfig)
+
+		logrus.Info("Provisioning RKE2 cluster")
+		a.cluster, err = resources.ProvisionRKE2K3SCluster(a.T(), standardUserClient, defaults.RKE2, provider, *clusterConfig, machineConfigSpec, nil, false, false)
+		require.NoError(a.T(), err)
+	} else {
+		logrus.Infof("Using existing cluster %s", rancherConfig.ClusterName)
+		a.cluster, err = a.client.Steve.SteveType(stevetypes.Provisioning).ByID("fleet-default/" + rancherConfig.ClusterName)
+		require.NoError(a.T(), err)
+	}
+}
+
+// probeOperation runs the operation while probing a test service on its node port and the kube-apiserver, and verifies the downtime budgets
+func (a *AvailabilityTestSuite) probeOperation(operation func() error) {
+	clusterID, err := extClusters.GetClusterIDByName(a.client, a.cluster.Name)
+	require.NoError(a.T(), err)
+
+	serviceTarget, err := availability.DeployProbeWorkload(a.client, clusterID)
+	require.NoError(a.T(), err)
+
+	apiServerTarget, err := availability.NewAPIServerTarget(a.client, clusterID)
+	require.NoError(a.T(), err)
+
+	workerBudget, controlPlaneBudget, err := availability.ClusterDowntimeBudgets(a.availabilityConfig, a.cluster)
+	require.NoError(a.T(), err)
+
+	prober, err := availability.NewProber(a.availabilityConfig, serviceTarget, apiServerTarget)
+	require.NoError(a.T(), err)
+
+	report, err := prober.Run(operation)
+	require.NoError(a.T(), err)
+
+	logrus.Infof("Verifying downtime of %s (budget %s) and %s (budget %s)", serviceTarget.Name, workerBudget, apiServerTarget.Name, controlPlaneBudget)
+	err = availability.VerifyDowntime(report, map[string]time.Duration{
+		serviceTarget.Name:   workerBudget,
+		apiServerTarget.Name: controlPlaneBudget,
+	})
+	require.NoError(a.T(), err)
+
+	logrus.Infof("Verifying the cluster is ready (%s)", a.cluster.Name)
+	err = provisioning.VerifyClusterReady(a.client, a.cluster)
+	require.NoError(a.T(), err)
+}
+
+func (a *AvailabilityTestSuite) TestUpgradeAvailability() {
+	latestVersion, err := kubernetesversions.Default(a.client, defaults.RKE2, nil)
+	require.NoError(a.T(), err)
+
+	a.probeOperation(func() error {
+		logrus.Infof("Upgrading cluster (%s) to %s", a.cluster.Name, latestVersion[0])
+		cluster, err := upgrade.UpgradeCluster(a.T(), a.client, a.cluster, latestVersion[0])
+		if err != nil {
+			return err
+		}
+
+		return provisioning.VerifyClusterReady(a.client, cluster)
+	})
+}
+
+func (a *AvailabilityTestSuite) TestScaleAvailability() {
+	nodeRoles := machinepools.NodeRoles{
+		Worker:   true,
+		Quantity: 1,
+	}
+
+	a.probeOperation(func() error {
+		logrus.Infof("Scaling up the worker pool (%s)", a.cluster.Name)
+		cluster, err := machinepools.ScaleMachinePool(a.client, a.cluster, nodeRoles)
+		if err != nil {
+			return err
+		}
+
+		nodeRoles.Quantity = -1
+		logrus.Infof("Scaling down the worker pool (%s)", a.cluster.Name)
+		_, err = machinepools.ScaleMachinePool(a.client, cluster, nodeRoles)
+
+		return err
+	})
+}
+
+func (a *AvailabilityTestSuite) TestCertRotationAvailability() {
+	a.probeOperation(func() error {
+		logrus.Infof("Rotating certificates on cluster (%s)", a.cluster.Name)
+		return certificates.RotateCerts(a.client, a.cluster.Name)
+	})
+}
+
+func TestAvailabilityTestSuite(t *testing.T) {
+	suite.Run(t, new(AvailabilityTestSuite))
+}
@@ -5,6 +5,7 @@ package k3s
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/rancher/shepherd/clients/rancher"
 	v1 "github.com/rancher/shepherd/clients/rancher/v1"
@@ -27,6 +28,12 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
+const (
+	// shortLivedCertificateDays is well within the renewal window of the certificates, 90 days before they expire
+	shortLivedCertificateDays = 30
+	nearExpiryThreshold       = 60 * 24 * time.Hour
+)
+
 type CertRotationTestSuite struct {
 	suite.Suite
 	session      *session.Session
@@ -94,9 +101,11 @@ func (c *CertRotationTestSuite) TestCertRotation() {
 	for _, tt := range tests {
 		var err error
 		c.Run(tt.name, func() {
-			oldCertificates, err := certificates.GetClusterCertificates(c.client, tt.cluster.Name)
+			oldCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
 			require.NoError(c.T(), err)
 
+			oldCertificates := certificates.GetCertificateEndDates(oldCertificateInfo)
+
 			logrus.Infof("Rotating certificates on cluster (%s)", tt.cluster.Name)
 			require.NoError(c.T(), certificates.RotateCerts(c.client, tt.cluster.Name))
 
@@ -112,12 +121,157 @@ func (c *CertRotationTestSuite) TestCertRotation() {
 			err = pods.VerifyClusterPods(c.client, tt.cluster)
 			require.NoError(c.T(), err)
 
-			newCertificates, err := certificates.GetClusterCertificates(c.client, tt.cluster.Name)
+			newCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
 			require.NoError(c.T(), err)
 
+			newCertificates := certificates.GetCertificateEndDates(newCertificateInfo)
+
 			logrus.Infof("Verifying certificates were rotated (%s)", tt.cluster.Name)
 			isRotated := certificates.VerifyCertificateRotation(oldCertificates, newCertificates)
 			require.True(c.T(), isRotated)
+
+			logrus.Infof("Verifying rotated certificates were extended and kept their SANs and CA (%s)", tt.cluster.Name)
+			err = certificates.VerifyRotatedCertificates(oldCertificateInfo, newCertificateInfo, nil)
+			require.NoError(c.T(), err)
+		})
+
+		params := provisioning.GetProvisioningSchemaParams(c.client, c.cattleConfig)
+		err = qase.UpdateSchemaParameters(tt.name, params)
+		if err != nil {
+			logrus.Warningf("Failed to upload schema parameters %s", err)
+		}
+	}
+}
+
+func (c *CertRotationTestSuite) TestServiceCertRotation() {
+	tests := []struct {
+		name     string
+		cluster  *v1.SteveAPIObject
+		services []string
+	}{
+		{"K3S_API_Server_Certificate_Rotation", c.cluster, []string{certificates.APIServerService}},
+		{"K3S_Etcd_Certificate_Rotation", c.cluster, []string{certificates.EtcdService}},
+		{"K3S_Kubelet_Scheduler_Certificate_Rotation", c.cluster, []string{certificates.KubeletService, certificates.SchedulerService}},
+	}
+
+	for _, tt := range tests {
+		var err error
+		c.Run(tt.name, func() {
+			oldCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Rotating %v certificates on cluster (%s)", tt.services, tt.cluster.Name)
+			require.NoError(c.T(), certificates.RotateServiceCerts(c.client, tt.cluster.Name, tt.services))
+
+			logrus.Infof("Verifying the cluster is ready (%s)", tt.cluster.Name)
+			err = provisioning.VerifyClusterReady(c.client, tt.cluster)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying cluster pods (%s)", tt.cluster.Name)
+			err = pods.VerifyClusterPods(c.client, tt.cluster)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying a generated kubeconfig can reach the cluster (%s)", tt.cluster.Name)
+			err = certificates.VerifyClusterKubeconfig(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			newCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying only the %v certificates were rotated (%s)", tt.services, tt.cluster.Name)
+			err = certificates.VerifyRotatedCertificates(oldCertificateInfo, newCertificateInfo, tt.services)
+			require.NoError(c.T(), err)
+		})
+
+		params := provisioning.GetProvisioningSchemaParams(c.client, c.cattleConfig)
+		err = qase.UpdateSchemaParameters(tt.name, params)
+		if err != nil {
+			logrus.Warningf("Failed to upload schema parameters %s", err)
+		}
+	}
+}
+
+func (c *CertRotationTestSuite) TestCARotation() {
+	tests := []struct {
+		name    string
+		cluster *v1.SteveAPIObject
+	}{
+		{"K3S_CA_Certificate_Rotation", c.cluster},
+	}
+
+	for _, tt := range tests {
+		var err error
+		c.Run(tt.name, func() {
+			oldCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Rotating the CA certificates on cluster (%s)", tt.cluster.Name)
+			require.NoError(c.T(), certificates.RotateCA(c.client, tt.cluster.Name))
+
+			logrus.Infof("Verifying the cluster is ready (%s)", tt.cluster.Name)
+			err = provisioning.VerifyClusterReady(c.client, tt.cluster)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying cluster pods (%s)", tt.cluster.Name)
+			err = pods.VerifyClusterPods(c.client, tt.cluster)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying a generated kubeconfig can reach the cluster (%s)", tt.cluster.Name)
+			err = certificates.VerifyClusterKubeconfig(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			newCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying the CA and every component certificate were re-issued from the new CA (%s)", tt.cluster.Name)
+			err = certificates.VerifyRotatedCA(oldCertificateInfo, newCertificateInfo)
+			require.NoError(c.T(), err)
+		})
+
+		params := provisioning.GetProvisioningSchemaParams(c.client, c.cattleConfig)
+		err = qase.UpdateSchemaParameters(tt.name, params)
+		if err != nil {
+			logrus.Warningf("Failed to upload schema parameters %s", err)
+		}
+	}
+}
+
+func (c *CertRotationTestSuite) TestNearExpiryCertRotation() {
+	tests := []struct {
+		name    string
+		cluster *v1.SteveAPIObject
+	}{
+		{"K3S_Near_Expiry_Certificate_Rotation", c.cluster},
+	}
+
+	for _, tt := range tests {
+		var err error
+		c.Run(tt.name, func() {
+			logrus.Infof("Issuing certificates valid for %d days on cluster (%s)", shortLivedCertificateDays, tt.cluster.Name)
+			err = certificates.IssueShortLivedCertificates(c.client, tt.cluster.Name, shortLivedCertificateDays)
+			require.NoError(c.T(), err)
+
+			oldCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			threshold := time.Now().Add(nearExpiryThreshold)
+			expiring := certificates.GetExpiringCertificates(oldCertificateInfo, threshold)
+			require.NotEmpty(c.T(), expiring, "no certificate expires before %s", threshold)
+
+			logrus.Infof("Restarting the services without rotating the certificates on cluster (%s)", tt.cluster.Name)
+			err = certificates.RestartClusterServices(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying the cluster is ready (%s)", tt.cluster.Name)
+			err = provisioning.VerifyClusterReady(c.client, tt.cluster)
+			require.NoError(c.T(), err)
+
+			newCertificateInfo, err := certificates.GetClusterCertificateInfo(c.client, tt.cluster.Name)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying the near expiry certificates were renewed on restart (%s)", tt.cluster.Name)
+			err = certificates.VerifyRenewedCertificates(oldCertificateInfo, newCertificateInfo, expiring, threshold)
+			require.NoError(c.T(), err)
 		})
 
 		params := provisioning.GetProvisioningSchemaParams(c.client, c.cattleConfig)
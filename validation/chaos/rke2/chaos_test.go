@@ -0,0 +1,156 @@
+//go:build (validation || recurring || infra.rke2k3s || cluster.any || stress) && !infra.any && !infra.aks && !infra.eks && !infra.gke && !sanity && !extended
+
+package rke2
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	v1 "github.com/rancher/shepherd/clients/rancher/v1"
+	extClusters "github.com/rancher/shepherd/extensions/clusters"
+	extDefaults "github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/config/operations"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/certificates"
+	"github.com/rancher/tests/actions/chaos"
+	"github.com/rancher/tests/actions/clusters"
+	"github.com/rancher/tests/actions/config/defaults"
+	"github.com/rancher/tests/actions/logging"
+	"github.com/rancher/tests/actions/provisioning"
+	"github.com/rancher/tests/actions/workloads/pods"
+	resources "github.com/rancher/tests/validation/provisioning/resources/provisioncluster"
+	standard "github.com/rancher/tests/validation/provisioning/resources/standarduser"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	faultDuration = 2 * time.Minute
+	diskFillSize  = "5G"
+	rke2Server    = "rke2-server"
+	rke2Agent     = "rke2-agent"
+)
+
+type ChaosTestSuite struct {
+	suite.Suite
+	session      *session.Session
+	client       *rancher.Client
+	cattleConfig map[string]any
+	cluster      *v1.SteveAPIObject
+	clusterID    string
+}
+
+func (c *ChaosTestSuite) TearDownSuite() {
+	c.session.Cleanup()
+}
+
+func (c *ChaosTestSuite) SetupSuite() {
+	testSession := session.NewSession()
+	c.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	require.NoError(c.T(), err)
+
+	c.client = client
+
+	c.cattleConfig = config.LoadConfigFromFile(os.Getenv(config.ConfigEnvironmentKey))
+
+	c.cattleConfig, err = defaults.LoadPackageDefaults(c.cattleConfig, "")
+	require.NoError(c.T(), err)
+
+	loggingConfig := new(logging.Logging)
+	operations.LoadObjectFromMap(logging.LoggingKey, c.cattleConfig, loggingConfig)
+
+	err = logging.SetLogger(loggingConfig)
+	require.NoError(c.T(), err)
+
+	clusterConfig := new(clusters.ClusterConfig)
+	operations.LoadObjectFromMap(defaults.ClusterConfigKey, c.cattleConfig, clusterConfig)
+
+	rancherConfig := new(rancher.Config)
+	operations.LoadObjectFromMap(defaults.RancherConfigKey, c.cattleConfig, rancherConfig)
+
+	if rancherConfig.ClusterName == "" {
+		standardUserClient, _, _, err := standard.CreateStandardUser(c.client)
+		require.NoError(c.T(), err)
+
+		provider := provisioning.CreateProvider(clusterConfig.Provider)
+		machineConfigSpec := provider.LoadMachineConfigFunc(c.cattleConfig)
+
+		logrus.Info("Provisioning RKE2 cluster")
+		c.cluster, err = resources.ProvisionRKE2K3SCluster(c.T(), standardUserClient, defaults.RKE2, provider, *clusterConfig, machineConfigSpec, nil, false, false)
+		require.NoError(c.T(), err)
+	} else {
+		logrus.Infof("Using existing cluster %s", rancherConfig.ClusterName)
+		c.cluster, err = c.client.Steve.SteveType(stevetypes.Provisioning).ByID("fleet-default/" + rancherConfig.ClusterName)
+		require.NoError(c.T(), err)
+	}
+
+	c.clusterID, err = extClusters.GetClusterIDByName(c.client, c.cluster.Name)
+	require.NoError(c.T(), err)
+}
+
+// firstTarget returns the first node of the cluster with the given role
+func (c *ChaosTestSuite) firstTarget(role string) *chaos.Target {
+	targets, err := chaos.GetTargetsWithRole(c.client, c.clusterID, role)
+	require.NoError(c.T(), err)
+
+	return targets[0]
+}
+
+func (c *ChaosTestSuite) TestNodeFaultRecovery() {
+	tests := []struct {
+		name  string
+		fault func() chaos.Fault
+	}{
+		{"RKE2_Worker_Reboot", func() chaos.Fault { return chaos.NewReboot(c.firstTarget(chaos.WorkerRole)) }},
+		{"RKE2_Worker_Agent_Stop", func() chaos.Fault { return chaos.NewServiceStop(c.firstTarget(chaos.WorkerRole), rke2Agent) }},
+		{"RKE2_Control_Plane_Server_Stop", func() chaos.Fault {
+			return chaos.NewServiceStop(c.firstTarget(chaos.ControlPlaneRole), rke2Server)
+		}},
+		{"RKE2_Worker_Network_Partition", func() chaos.Fault { return chaos.NewNetworkPartition(c.firstTarget(chaos.WorkerRole)) }},
+		{"RKE2_Etcd_Member_Kill", func() chaos.Fault { return chaos.NewEtcdMemberKill(c.firstTarget(chaos.EtcdRole), defaults.RKE2) }},
+		{"RKE2_Worker_Disk_Fill", func() chaos.Fault {
+			return chaos.NewDiskFill(c.firstTarget(chaos.WorkerRole), defaults.RKE2, diskFillSize)
+		}},
+	}
+
+	for _, tt := range tests {
+		c.Run(tt.name, func() {
+			fault := tt.fault()
+
+			err := chaos.InjectAndRecover(c.client, c.clusterID, fault, faultDuration)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying the cluster is ready (%s)", c.cluster.Name)
+			err = provisioning.VerifyClusterReady(c.client, c.cluster)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying cluster pods (%s)", c.cluster.Name)
+			err = pods.VerifyClusterPods(c.client, c.cluster)
+			require.NoError(c.T(), err)
+		})
+	}
+}
+
+func (c *ChaosTestSuite) TestFaultDuringCertRotation() {
+	fault := chaos.NewServiceStop(c.firstTarget(chaos.WorkerRole), rke2Agent)
+
+	err := chaos.RunDuring(fault, 30*time.Second, faultDuration, func() error {
+		logrus.Infof("Rotating certificates on cluster (%s)", c.cluster.Name)
+		return certificates.RotateCerts(c.client, c.cluster.Name)
+	})
+	require.NoError(c.T(), err)
+
+	err = chaos.VerifyRecovery(c.client, c.clusterID, extDefaults.ThirtyMinuteTimeout)
+	require.NoError(c.T(), err)
+}
+
+func TestChaosTestSuite(t *testing.T) {
+	suite.Run(t, new(ChaosTestSuite))
+}
@@ -0,0 +1,71 @@
+//go:build (validation || recurring || infra.rke2k3s || cluster.any || stress) && !infra.any && !infra.aks && !infra.eks && !infra.gke && !sanity && !extended
+
+package rke2
+
+import (
+	"time"
+
+	extDefaults "github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
+	"github.com/rancher/tests/actions/chaos"
+	"github.com/rancher/tests/actions/machinepools"
+	"github.com/rancher/tests/actions/machines"
+	"github.com/rancher/tests/actions/provisioning"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	unhealthyNodeTimeout = 5 * time.Minute
+	// replacementGracePeriod covers the node monitor grace period before the node is marked not ready, and the
+	// machine health check reconciling the unhealthy machine
+	replacementGracePeriod = 2 * time.Minute
+)
+
+func (c *ChaosTestSuite) TestMachineHealthCheckReplacement() {
+	tests := []struct {
+		name string
+		role string
+	}{
+		{"RKE2_Worker_Health_Check_Replacement", chaos.WorkerRole},
+	}
+
+	for _, tt := range tests {
+		c.Run(tt.name, func() {
+			subSession := c.session.NewSession()
+			defer subSession.Cleanup()
+
+			client, err := c.client.WithSession(subSession)
+			require.NoError(c.T(), err)
+
+			target := c.firstTarget(tt.role)
+
+			unhealthyMachine, err := client.Steve.SteveType(stevetypes.Machine).ByID(target.MachineID)
+			require.NoError(c.T(), err)
+
+			c.cluster, err = machinepools.UpdateMachinePoolHealthCheck(client, c.cluster, unhealthyMachine, unhealthyNodeTimeout, "")
+			require.NoError(c.T(), err)
+
+			err = provisioning.VerifyClusterReady(client, c.cluster)
+			require.NoError(c.T(), err)
+
+			fault := chaos.NewPowerOff(client, target)
+			err = fault.Inject(0)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Waiting for unhealthy machine %s to be replaced", unhealthyMachine.Name)
+			_, err = machines.VerifyUnhealthyMachineReplaced(client, unhealthyMachine, unhealthyNodeTimeout+replacementGracePeriod)
+			if err != nil {
+				require.NoError(c.T(), fault.Revert())
+			}
+			require.NoError(c.T(), err)
+
+			err = chaos.VerifyRecovery(client, c.clusterID, extDefaults.ThirtyMinuteTimeout)
+			require.NoError(c.T(), err)
+
+			logrus.Infof("Verifying the cluster is ready (%s)", c.cluster.Name)
+			err = provisioning.VerifyClusterReady(client, c.cluster)
+			require.NoError(c.T(), err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress
+
+package backup_restore
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	shepCharts "github.com/rancher/shepherd/extensions/charts"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/projects"
+	"github.com/rancher/tests/interoperability/charts"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RollbackTestSuite struct {
+	suite.Suite
+	client    *rancher.Client
+	session   *session.Session
+	broConfig *charts.BackupRestoreConfig
+}
+
+func (r *RollbackTestSuite) TearDownSuite() {
+	r.session.Cleanup()
+}
+
+func (r *RollbackTestSuite) SetupSuite() {
+	r.session = session.NewSession()
+
+	client, err := rancher.NewClient("", r.session)
+	require.NoError(r.T(), err)
+
+	r.client = client
+
+	config.LoadConfig(charts.BackupRestoreConfigurationFileKey, backupRestoreConfig)
+	r.broConfig = backupRestoreConfig
+
+	if r.broConfig.RancherUpgradeChart == "" || r.broConfig.RancherUpgradeVersion == "" {
+		r.T().Skip("Rancher rollback test requires rancherUpgradeChart and rancherUpgradeVersion")
+	}
+}
+
+func (r *RollbackTestSuite) TestRancherUpgradeRollback() {
+	project, err := projects.GetProjectByName(r.client, cluster, "System")
+	require.NoError(r.T(), err)
+
+	logrus.Info("Checking if the backup chart is already installed...")
+	initialBackupChart, err := shepCharts.GetChartStatus(r.client, project.ClusterID, "cattle-resources-system", "rancher-backup")
+	require.NoError(r.T(), err)
+
+	if !initialBackupChart.IsAlreadyInstalled {
+		err = installBroChart(r.client)
+		require.NoError(r.T(), err)
+	}
+
+	r.client, err = r.client.ReLogin()
+	require.NoError(r.T(), err)
+
+	logrus.Info("Creating two users, projects, and role templates...")
+	userList, projList, roleList, err := createRancherResources(r.client, project.ClusterID, "cluster")
+	require.NoError(r.T(), err)
+
+	rollbackOpts := &charts.RancherRollbackOpts{
+		ResourceSetName: r.broConfig.ResourceSetName,
+		Chart:           r.broConfig.RancherUpgradeChart,
+		UpgradeVersion:  r.broConfig.RancherUpgradeVersion,
+	}
+
+	logrus.Infof("Upgrading Rancher to %s and rolling it back", r.broConfig.RancherUpgradeVersion)
+	r.client, err = charts.UpgradeAndRollbackRancher(r.client, rollbackOpts)
+	require.NoError(r.T(), err)
+
+	logrus.Info("Validating Rancher resources...")
+	err = verifyRancherResources(r.client, userList, projList, roleList)
+	require.NoError(r.T(), err)
+}
+
+func TestRollbackTestSuite(t *testing.T) {
+	suite.Run(t, new(RollbackTestSuite))
+}
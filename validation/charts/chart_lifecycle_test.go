@@ -0,0 +1,146 @@
+//go:build (validation || cluster.any || stress) && !infra.any && !infra.aks && !infra.eks && !infra.gke && !infra.rke2k3s && !sanity && !extended
+
+package charts
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/catalog"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	extencharts "github.com/rancher/shepherd/extensions/charts"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/charts"
+	"github.com/rancher/tests/actions/projects"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ChartLifecycleTestSuite struct {
+	suite.Suite
+	client  *rancher.Client
+	session *session.Session
+	cluster *clusters.ClusterMeta
+	project *management.Project
+}
+
+func (c *ChartLifecycleTestSuite) TearDownSuite() {
+	c.session.Cleanup()
+}
+
+func (c *ChartLifecycleTestSuite) SetupSuite() {
+	testSession := session.NewSession()
+	c.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	require.NoError(c.T(), err)
+
+	c.client = client
+
+	// Get clusterName from config yaml
+	clusterName := client.RancherConfig.ClusterName
+	require.NotEmptyf(c.T(), clusterName, "Cluster name to install is not set")
+
+	// Get cluster meta
+	c.cluster, err = clusters.NewClusterMeta(client, clusterName)
+	require.NoError(c.T(), err)
+
+	// Get project system projectId
+	c.project, err = projects.GetProjectByName(client, c.cluster.ID, projectName)
+	require.NoError(c.T(), err)
+	require.NotEmpty(c.T(), c.project)
+}
+
+func (c *ChartLifecycleTestSuite) waitForWorkloads(client *rancher.Client, namespace string) {
+	err := extencharts.WatchAndWaitDeployments(client, c.cluster.ID, namespace, metav1.ListOptions{})
+	require.NoError(c.T(), err)
+
+	err = extencharts.WatchAndWaitDaemonSets(client, c.cluster.ID, namespace, metav1.ListOptions{})
+	require.NoError(c.T(), err)
+}
+
+func (c *ChartLifecycleTestSuite) TestChartLifecycle() {
+	tests := []struct {
+		name       string
+		descriptor charts.ChartDescriptor
+	}{
+		{charts.RancherGatekeeperName, charts.RancherGatekeeperChart()},
+		{charts.RancherLoggingName, charts.RancherLoggingChart(&charts.RancherLoggingOpts{AdditionalLoggingSources: true})},
+		{charts.RancherMonitoringName, charts.RancherMonitoringChart(&charts.RancherMonitoringOpts{})},
+		{charts.ComplianceName, charts.RancherComplianceChart()},
+	}
+
+	for _, tt := range tests {
+		c.Run(tt.name, func() {
+			subSession := c.session.NewSession()
+			defer subSession.Cleanup()
+
+			client, err := c.client.WithSession(subSession)
+			require.NoError(c.T(), err)
+
+			versions, err := client.Catalog.GetListChartVersions(tt.descriptor.Name, catalog.RancherChartRepo)
+			require.NoError(c.T(), err)
+			if len(versions) < 2 {
+				c.T().Skipf("Chart %s has a single version, it can't be upgraded", tt.descriptor.Name)
+			}
+			latestVersion, previousVersion := versions[0], versions[1]
+
+			c.T().Log("Taking a snapshot of the cluster before the install")
+			snapshotBefore, err := charts.TakeClusterSnapshot(client, c.cluster.ID)
+			require.NoError(c.T(), err)
+
+			lifecycle, err := charts.NewChartLifecycle(client, tt.descriptor, &charts.InstallOptions{
+				Cluster:   c.cluster,
+				Version:   previousVersion,
+				ProjectID: c.project.ID,
+			})
+			require.NoError(c.T(), err)
+
+			c.T().Logf("Installing %s chart version %s", tt.descriptor.Name, previousVersion)
+			err = lifecycle.Install()
+			require.NoError(c.T(), err)
+			c.waitForWorkloads(client, tt.descriptor.Namespace)
+			require.NoError(c.T(), lifecycle.Verify())
+
+			c.T().Logf("Upgrading %s chart to version %s", tt.descriptor.Name, latestVersion)
+			err = lifecycle.Upgrade(latestVersion)
+			require.NoError(c.T(), err)
+			c.waitForWorkloads(client, tt.descriptor.Namespace)
+			require.NoError(c.T(), lifecycle.Verify())
+
+			c.T().Logf("Rolling back %s chart", tt.descriptor.Name)
+			err = lifecycle.Rollback()
+			require.NoError(c.T(), err)
+			c.waitForWorkloads(client, tt.descriptor.Namespace)
+			require.NoError(c.T(), lifecycle.Verify())
+			require.Equal(c.T(), previousVersion, lifecycle.Version())
+
+			c.T().Logf("Upgrading %s chart to version %s again", tt.descriptor.Name, latestVersion)
+			err = lifecycle.Upgrade(latestVersion)
+			require.NoError(c.T(), err)
+			c.waitForWorkloads(client, tt.descriptor.Namespace)
+			require.NoError(c.T(), lifecycle.Verify())
+
+			c.T().Logf("Uninstalling %s chart", tt.descriptor.Name)
+			err = lifecycle.Uninstall()
+			require.NoError(c.T(), err)
+
+			chartStatus, err := extencharts.GetChartStatus(client, c.cluster.ID, tt.descriptor.Namespace, tt.descriptor.Name)
+			require.NoError(c.T(), err)
+			require.False(c.T(), chartStatus.IsAlreadyInstalled)
+
+			c.T().Logf("Auditing the objects left behind by %s chart", tt.descriptor.Name)
+			snapshotAfter, err := charts.TakeClusterSnapshot(client, c.cluster.ID)
+			require.NoError(c.T(), err)
+
+			leftovers := charts.ChartLeftovers(tt.descriptor, snapshotBefore, snapshotAfter)
+			require.NoError(c.T(), charts.LeftoversError(leftovers))
+		})
+	}
+}
+
+func TestChartLifecycleTestSuite(t *testing.T) {
+	suite.Run(t, new(ChartLifecycleTestSuite))
+}
@@ -0,0 +1,134 @@
+//go:build (validation || cluster.any || stress) && !infra.any && !infra.aks && !infra.eks && !infra.gke && !infra.rke2k3s && !sanity && !extended
+
+package charts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/clients/rancher/catalog"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/config"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/charts"
+	"github.com/rancher/tests/actions/projects"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	// defaultMaxValuesCases bounds the number of installs per chart when chartValuesMatrix.maxCases is not set
+	defaultMaxValuesCases = 8
+)
+
+type ChartValuesMatrixTestSuite struct {
+	suite.Suite
+	client   *rancher.Client
+	session  *session.Session
+	cluster  *clusters.ClusterMeta
+	project  *management.Project
+	maxCases int
+	seed     int64
+}
+
+func (c *ChartValuesMatrixTestSuite) TearDownSuite() {
+	c.session.Cleanup()
+}
+
+func (c *ChartValuesMatrixTestSuite) SetupSuite() {
+	testSession := session.NewSession()
+	c.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	require.NoError(c.T(), err)
+
+	c.client = client
+
+	matrixConfig := new(charts.ValuesMatrixConfig)
+	config.LoadConfig(charts.ValuesMatrixConfigurationFileKey, matrixConfig)
+	c.maxCases = matrixConfig.MaxCases
+	if c.maxCases <= 0 {
+		c.maxCases = defaultMaxValuesCases
+	}
+
+	c.seed = time.Now().UnixNano()
+	if matrixConfig.Seed != nil {
+		c.seed = *matrixConfig.Seed
+	}
+	c.T().Logf("Rotating the chart values matrix with seed %d, set %s.seed to repeat the run", c.seed, charts.ValuesMatrixConfigurationFileKey)
+
+	// Get clusterName from config yaml
+	clusterName := client.RancherConfig.ClusterName
+	require.NotEmptyf(c.T(), clusterName, "Cluster name to install is not set")
+
+	// Get cluster meta
+	c.cluster, err = clusters.NewClusterMeta(client, clusterName)
+	require.NoError(c.T(), err)
+
+	// Get project system projectId
+	c.project, err = projects.GetProjectByName(client, c.cluster.ID, projectName)
+	require.NoError(c.T(), err)
+	require.NotEmpty(c.T(), c.project)
+}
+
+func (c *ChartValuesMatrixTestSuite) TestChartValuesMatrix() {
+	descriptors := []charts.ChartDescriptor{
+		charts.RancherGatekeeperChart(),
+		charts.RancherLoggingChart(&charts.RancherLoggingOpts{}),
+		charts.RancherMonitoringChart(&charts.RancherMonitoringOpts{}),
+	}
+
+	catalogClient, err := c.client.GetClusterCatalogClient(c.cluster.ID)
+	require.NoError(c.T(), err)
+
+	for _, descriptor := range descriptors {
+		latestVersion, err := catalogClient.GetLatestChartVersion(descriptor.Name, catalog.RancherChartRepo)
+		require.NoError(c.T(), err)
+
+		options, err := charts.GetChartValueOptions(catalogClient, catalog.RancherChartRepo, descriptor.Name, latestVersion)
+		require.NoError(c.T(), err)
+		options = charts.ProviderValueOptions(options, c.cluster.Provider)
+
+		matrix := charts.ValuesMatrix(options, c.maxCases, c.seed)
+		c.T().Logf("Testing %s chart version %s with %d value options in %d cases", descriptor.Name, latestVersion, len(options), len(matrix))
+
+		for _, valuesCase := range matrix {
+			c.Run(descriptor.Name+"/"+valuesCase.Name, func() {
+				subSession := c.session.NewSession()
+				defer subSession.Cleanup()
+
+				client, err := c.client.WithSession(subSession)
+				require.NoError(c.T(), err)
+
+				c.T().Logf("Installing %s chart with values %v", descriptor.Name, valuesCase.Values)
+				lifecycle, err := charts.NewChartLifecycle(client, charts.WithValues(descriptor, valuesCase.Values), &charts.InstallOptions{
+					Cluster:   c.cluster,
+					Version:   latestVersion,
+					ProjectID: c.project.ID,
+				})
+				require.NoError(c.T(), err)
+
+				err = lifecycle.Install()
+				require.NoError(c.T(), err)
+
+				c.T().Log("Verifying the chart was deployed with the values")
+				err = lifecycle.Verify()
+				require.NoError(c.T(), err)
+
+				c.T().Log("Waiting for the chart workloads to be ready")
+				err = lifecycle.WaitForWorkloads()
+				require.NoError(c.T(), err)
+
+				c.T().Logf("Uninstalling %s chart", descriptor.Name)
+				err = lifecycle.Uninstall()
+				require.NoError(c.T(), err)
+			})
+		}
+	}
+}
+
+func TestChartValuesMatrixTestSuite(t *testing.T) {
+	suite.Run(t, new(ChartValuesMatrixTestSuite))
+}
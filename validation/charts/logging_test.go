@@ -3,6 +3,7 @@
 package charts
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/rancher/shepherd/clients/rancher"
@@ -10,11 +11,14 @@ import (
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
 	shepherdCharts "github.com/rancher/shepherd/extensions/charts"
 	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/namegenerator"
 	"github.com/rancher/shepherd/pkg/session"
 	"github.com/rancher/tests/actions/charts"
+	"github.com/rancher/tests/actions/namespaces"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type LoggingTestSuite struct {
@@ -57,31 +61,9 @@ func (l *LoggingTestSuite) SetupSuite() {
 	l.project = createdProject
 }
 
-func (l *LoggingTestSuite) TestLoggingInstallation() {
-	l.T().Logf("Resolving latest chart version for [%s] from repository [%s]", charts.LonghornNamespace, charts.LonghornChartName)
-	longhornChart, err := shepherdCharts.GetChartStatus(l.client, l.cluster.ID, charts.LonghornNamespace, charts.LonghornChartName)
-	require.NoError(l.T(), err)
-
-	if !longhornChart.IsAlreadyInstalled {
-		// Get latest versions of longhorn
-		latestLonghornVersion, err := l.client.Catalog.GetLatestChartVersion(charts.LonghornChartName, catalog.RancherChartRepo)
-		require.NoError(l.T(), err)
-
-		payloadOpts := charts.PayloadOpts{
-			Namespace: charts.LonghornNamespace,
-			Host:      l.client.RancherConfig.Host,
-			InstallOptions: charts.InstallOptions{
-				Cluster:   l.cluster,
-				Version:   latestLonghornVersion,
-				ProjectID: l.project.ID,
-			},
-		}
-
-		l.T().Logf("Installing Longhorn chart in cluster [%v] with latest version [%v] in project [%v] and namespace [%v]", l.cluster.Name, payloadOpts.Version, l.project.Name, payloadOpts.Namespace)
-		err = charts.InstallLonghornChart(l.client, payloadOpts, nil)
-		require.NoError(l.T(), err)
-	}
-
+// installLoggingChart installs the logging chart with additional logging sources, unless it is already installed, and
+// waits for its workloads
+func (l *LoggingTestSuite) installLoggingChart() {
 	loggingChart, err := shepherdCharts.GetChartStatus(l.client, l.cluster.ID, charts.RancherLoggingNamespace, charts.RancherLoggingName)
 	require.NoError(l.T(), err)
 
@@ -117,12 +99,130 @@ func (l *LoggingTestSuite) TestLoggingInstallation() {
 	l.T().Logf("Waiting for logging statefulsets to become ready in namespace [%s]", charts.RancherLoggingNamespace)
 	err = shepherdCharts.WatchAndWaitStatefulSets(l.client, l.cluster.ID, charts.RancherLoggingNamespace, metav1.ListOptions{})
 	require.NoError(l.T(), err, "Fluentd StatefulSets not ready")
+}
+
+func (l *LoggingTestSuite) TestLoggingInstallation() {
+	l.T().Logf("Resolving latest chart version for [%s] from repository [%s]", charts.LonghornNamespace, charts.LonghornChartName)
+	longhornChart, err := shepherdCharts.GetChartStatus(l.client, l.cluster.ID, charts.LonghornNamespace, charts.LonghornChartName)
+	require.NoError(l.T(), err)
+
+	if !longhornChart.IsAlreadyInstalled {
+		// Get latest versions of longhorn
+		latestLonghornVersion, err := l.client.Catalog.GetLatestChartVersion(charts.LonghornChartName, catalog.RancherChartRepo)
+		require.NoError(l.T(), err)
+
+		payloadOpts := charts.PayloadOpts{
+			Namespace: charts.LonghornNamespace,
+			Host:      l.client.RancherConfig.Host,
+			InstallOptions: charts.InstallOptions{
+				Cluster:   l.cluster,
+				Version:   latestLonghornVersion,
+				ProjectID: l.project.ID,
+			},
+		}
+
+		l.T().Logf("Installing Longhorn chart in cluster [%v] with latest version [%v] in project [%v] and namespace [%v]", l.cluster.Name, payloadOpts.Version, l.project.Name, payloadOpts.Namespace)
+		err = charts.InstallLonghornChart(l.client, payloadOpts, nil)
+		require.NoError(l.T(), err)
+	}
+
+	l.installLoggingChart()
 
 	logs, err := verifyLoggingReceiver(l.client, l.cluster.ID)
 	require.NoError(l.T(), err, "Verify Logging Receiver error")
 	require.NotEmpty(l.T(), logs, "Logs are empty")
 }
 
+func (l *LoggingTestSuite) TestLoggingPipeline() {
+	subSession := l.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := l.client.WithSession(subSession)
+	require.NoError(l.T(), err)
+
+	l.installLoggingChart()
+
+	id := namegenerator.RandStringLower(defaultRandStringLength)
+
+	l.T().Log("Creating the namespace of the flow and the logging sink")
+	flowNamespace, err := namespaces.CreateNamespace(client, "logging-flow-"+id, "{}", map[string]string{}, map[string]string{}, l.project)
+	require.NoError(l.T(), err)
+
+	l.T().Logf("Creating logging sink in namespace [%s]", flowNamespace.Name)
+	sink, err := charts.CreateLoggingSink(client, l.cluster.ID, flowNamespace.Name, "logging-sink-"+id)
+	require.NoError(l.T(), err)
+
+	// Lines are JSON, the parser filter turns their fields into record fields, so they reach the sink unescaped
+	flowInfoID, flowDebugID, clusterFlowID := id+"-flow-info", id+"-flow-debug", id+"-clusterflow"
+
+	l.T().Log("Creating a flow that parses the lines of an emitter and drops its debug lines")
+	flowEmitterLabels, err := charts.CreateLogEmitter(client, l.cluster.ID, flowNamespace.Name, "emitter-"+id, []string{
+		fmt.Sprintf(`{"id":"%s","level":"info"}`, flowInfoID),
+		fmt.Sprintf(`{"id":"%s","level":"debug"}`, flowDebugID),
+	})
+	require.NoError(l.T(), err)
+
+	err = charts.CreateLoggingOutput(client, l.cluster.ID, flowNamespace.Name, "output-"+id, sink)
+	require.NoError(l.T(), err)
+
+	flowFilters := []map[string]any{
+		charts.ParserFilter("json"),
+		charts.GrepExcludeFilter("level", "/^debug$/"),
+	}
+	err = charts.CreateLoggingFlow(client, l.cluster.ID, flowNamespace.Name, "flow-"+id, flowEmitterLabels, flowFilters, "output-"+id)
+	require.NoError(l.T(), err)
+
+	l.T().Log("Creating a cluster flow that parses the lines of an emitter in another namespace and adds a field to them")
+	clusterFlowNamespace, err := namespaces.CreateNamespace(client, "logging-clusterflow-"+id, "{}", map[string]string{}, map[string]string{}, l.project)
+	require.NoError(l.T(), err)
+
+	_, err = charts.CreateLogEmitter(client, l.cluster.ID, clusterFlowNamespace.Name, "emitter-"+id, []string{
+		fmt.Sprintf(`{"id":"%s","level":"info"}`, clusterFlowID),
+	})
+	require.NoError(l.T(), err)
+
+	err = charts.CreateLoggingClusterOutput(client, l.cluster.ID, "clusteroutput-"+id, sink)
+	require.NoError(l.T(), err)
+
+	clusterFlowFilters := []map[string]any{
+		charts.ParserFilter("json"),
+		charts.RecordModifierFilter(map[string]string{"pipeline": id}),
+	}
+	err = charts.CreateLoggingClusterFlow(client, l.cluster.ID, "clusterflow-"+id, []string{clusterFlowNamespace.Name}, clusterFlowFilters, "clusteroutput-"+id)
+	require.NoError(l.T(), err)
+
+	expectedRecords := []string{
+		fmt.Sprintf(`"id":"%s"`, flowInfoID),
+		fmt.Sprintf(`"id":"%s"`, clusterFlowID),
+		fmt.Sprintf(`"pipeline":"%s"`, id),
+	}
+
+	loggingChart, err := shepherdCharts.GetChartStatus(client, l.cluster.ID, charts.RancherLoggingNamespace, charts.RancherLoggingName)
+	require.NoError(l.T(), err)
+
+	provider := string(l.cluster.Provider)
+	nodeLogsEnabled, _, _ := unstructured.NestedBool(loggingChart.ChartDetails.Spec.Values, "additionalLoggingSources", provider, "enabled")
+	isNodeLogsProvider := l.cluster.Provider == clusters.KubernetesProviderRKE2 || l.cluster.Provider == clusters.KubernetesProviderK3S
+	if isNodeLogsProvider && nodeLogsEnabled {
+		l.T().Logf("Creating a cluster flow for the %s node logs of the additional logging sources", provider)
+		nodeLogsFilters := []map[string]any{
+			charts.GrepRegexpFilter("$.kubernetes.pod_name", fmt.Sprintf("/%s-%s/", provider, charts.JournaldAggregatorName)),
+			charts.RecordModifierFilter(map[string]string{"source": id + "-nodes"}),
+		}
+		err = charts.CreateLoggingClusterFlow(client, l.cluster.ID, "nodes-"+id, []string{charts.RancherLoggingNamespace}, nodeLogsFilters, "clusteroutput-"+id)
+		require.NoError(l.T(), err)
+
+		expectedRecords = append(expectedRecords, fmt.Sprintf(`"source":"%s-nodes"`, id))
+	} else {
+		l.T().Logf("Skipping node logs validation, additional logging sources are not enabled for %s clusters", provider)
+	}
+
+	l.T().Logf("Waiting for the logging sink to receive records with %v", expectedRecords)
+	records, err := charts.WaitForLoggingSinkRecords(client, l.cluster.ID, sink, expectedRecords)
+	require.NoError(l.T(), err)
+	require.NotContains(l.T(), records, flowDebugID, "Debug lines excluded by the flow reached the logging sink")
+}
+
 func TestLoggingTestSuite(t *testing.T) {
 	suite.Run(t, new(LoggingTestSuite))
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/rancher/tests/validation/charts/resources"
@@ -17,6 +18,7 @@ import (
 	"github.com/rancher/shepherd/clients/rancher"
 	v1 "github.com/rancher/shepherd/clients/rancher/v1"
 	"github.com/rancher/shepherd/extensions/clusterrolebindings"
+	"github.com/rancher/shepherd/extensions/clusters"
 	"github.com/rancher/shepherd/extensions/configmaps"
 	"github.com/rancher/shepherd/extensions/defaults/stevetypes"
 	"github.com/rancher/shepherd/extensions/ingresses"
@@ -85,6 +87,10 @@ var (
 	prometheusTargetsPath = prometheusPath + "/targets"
 	// Rancher monitoring chart prometheus targets API path
 	prometheusTargetsPathAPI = prometheusPath + "/api/v1/targets"
+	// Rancher monitoring chart prometheus alerts API path
+	prometheusAlertsPathAPI = prometheusPath + "/api/v1/alerts"
+	// Rancher monitoring chart alert manager alerts API path
+	alertManagerAlertsPathAPI = "api/v1/namespaces/cattle-monitoring-system/services/http:rancher-monitoring-alertmanager:9093/proxy/api/v2/alerts"
 	// Webhook receiver kubernetes object names
 	webhookReceiverNamespaceName  = "webhook-namespace-" + namegenerator.RandStringLower(defaultRandStringLength)
 	webhookReceiverDeploymentName = "webhook-" + namegenerator.RandStringLower(defaultRandStringLength)
@@ -263,19 +269,19 @@ func editAlertRoute(alertConfigByte []byte) ([]byte, error) {
 }
 
 // createPrometheusRule is a private helper function
-// that creates a prometheus rule to be used by the webhook receiver.
-func createPrometheusRule(client *rancher.Client, clusterID string) error {
+// that creates a prometheus rule to be used by the webhook receiver, and returns the name of its alert.
+func createPrometheusRule(client *rancher.Client, clusterID string) (string, error) {
 	ruleName := "webhook-rule-" + namegenerator.RandStringLower(defaultRandStringLength)
 	alertName := "alert-" + namegenerator.RandStringLower(defaultRandStringLength)
 
 	_, err := client.ReLogin()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	steveclient, err := client.Steve.ProxyDownstream(clusterID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	zeroDuration := monitoringv1.Duration("0s")
@@ -303,10 +309,10 @@ func createPrometheusRule(client *rancher.Client, clusterID string) error {
 	}
 	_, err = steveclient.SteveType(prometheusRulesSteveType).Create(prometheusRule)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return alertName, nil
 }
 
 // createWebhookReceiverDeployment is a private helper function that creates a service account, cluster role binding, and deployment for webhook receiver.
@@ -466,3 +472,253 @@ func createAlertWebhookReceiverDeployment(client *rancher.Client, clusterID, nam
 
 	return deployment, nil
 }
+
+// prometheusTargetsResponse is the response of the prometheus targets API
+type prometheusTargetsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ActiveTargets []prometheusTarget `json:"activeTargets"`
+	} `json:"data"`
+}
+
+// prometheusTarget is an active target of the prometheus targets API
+type prometheusTarget struct {
+	ScrapePool string            `json:"scrapePool"`
+	Labels     map[string]string `json:"labels"`
+	Health     string            `json:"health"`
+	LastError  string            `json:"lastError"`
+}
+
+// prometheusAlertsResponse is the response of the prometheus alerts API
+type prometheusAlertsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Alerts []struct {
+			Labels map[string]string `json:"labels"`
+			State  string            `json:"state"`
+		} `json:"alerts"`
+	} `json:"data"`
+}
+
+// alertManagerAlert is an alert of the alert manager v2 alerts API
+type alertManagerAlert struct {
+	Labels    map[string]string `json:"labels"`
+	Receivers []struct {
+		Name string `json:"name"`
+	} `json:"receivers"`
+	Status struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// expectedMonitoringTargets is a private helper function
+// that returns the control plane components whose prometheus targets are enabled by the options on the provider. RKE2
+// has an exporter per component, K3s has a single target for its server, and hosted providers don't expose them.
+func expectedMonitoringTargets(opts *charts.RancherMonitoringOpts, provider clusters.KubernetesProvider) []string {
+	var components []string
+	switch provider {
+	case clusters.KubernetesProviderRKE2:
+		if opts.Etcd {
+			components = append(components, "etcd")
+		}
+		if opts.ControllerManager {
+			components = append(components, "controller-manager")
+		}
+		if opts.Scheduler {
+			components = append(components, "scheduler")
+		}
+		if opts.Proxy {
+			components = append(components, "proxy")
+		}
+		if opts.IngressNginx {
+			components = append(components, "ingress-nginx")
+		}
+	case clusters.KubernetesProviderK3S:
+		if opts.Server {
+			components = append(components, "k3s-server")
+		}
+	}
+
+	return components
+}
+
+// isComponentTarget is a private helper function
+// that checks if a prometheus target scrapes the component, by the service monitor of its scrape pool or its job label,
+// like serviceMonitor/cattle-monitoring-system/rancher-monitoring-kube-etcd/0 for etcd.
+func isComponentTarget(target prometheusTarget, component string) bool {
+	names := []string{target.Labels["job"]}
+	if pool := strings.Split(target.ScrapePool, "/"); len(pool) > 2 {
+		names = append(names, pool[2])
+	}
+
+	for _, name := range names {
+		if name == component || strings.HasSuffix(name, "-"+component) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitForPrometheusTargetsUp is a private helper function
+// that waits until every component has at least one active prometheus target, and all of them are up, by using prometheus API.
+func waitForPrometheusTargetsUp(client *rancher.Client, components []string) error {
+	var unhealthy []string
+	err := kubewait.PollUntilContextTimeout(context.TODO(), 10*time.Second, 10*time.Minute, true, func(context.Context) (bool, error) {
+		bodyString, err := ingresses.GetExternalIngressResponse(client, client.RancherConfig.Host, prometheusTargetsPathAPI, true)
+		if err != nil {
+			return false, err
+		}
+
+		var response prometheusTargetsResponse
+		if err = json.Unmarshal([]byte(bodyString), &response); err != nil || response.Status != "success" {
+			logrus.Infof("Prometheus targets API is not ready, retrying: %v", err)
+			return false, nil
+		}
+
+		unhealthy = nil
+		for _, component := range components {
+			found := false
+			for _, target := range response.Data.ActiveTargets {
+				if !isComponentTarget(target, component) {
+					continue
+				}
+
+				found = true
+				if target.Health != "up" {
+					unhealthy = append(unhealthy, fmt.Sprintf("%s target %s is %s: %s", component, target.Labels["instance"], target.Health, target.LastError))
+				}
+			}
+
+			if !found {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s has no active target", component))
+			}
+		}
+
+		if len(unhealthy) > 0 {
+			logrus.Infof("Prometheus targets are not up yet, retrying: %v", unhealthy)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil && len(unhealthy) > 0 {
+		return errors.Wrapf(err, "prometheus targets are not up: %v", unhealthy)
+	}
+
+	return err
+}
+
+// waitForPrometheusAlertFiring is a private helper function
+// that waits until the alert is firing, by using prometheus API.
+func waitForPrometheusAlertFiring(client *rancher.Client, alertName string) error {
+	return kubewait.PollUntilContextTimeout(context.TODO(), 10*time.Second, 5*time.Minute, true, func(context.Context) (bool, error) {
+		bodyString, err := ingresses.GetExternalIngressResponse(client, client.RancherConfig.Host, prometheusAlertsPathAPI, true)
+		if err != nil {
+			return false, err
+		}
+
+		var response prometheusAlertsResponse
+		if err = json.Unmarshal([]byte(bodyString), &response); err != nil || response.Status != "success" {
+			logrus.Infof("Prometheus alerts API is not ready, retrying: %v", err)
+			return false, nil
+		}
+
+		for _, alert := range response.Data.Alerts {
+			if alert.Labels["alertname"] == alertName {
+				logrus.Infof("Prometheus alert %s is %s", alertName, alert.State)
+				return alert.State == "firing", nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// waitForAlertManagerAlert is a private helper function
+// that waits until alert manager has the alert active and routed to the receiver, by using alert manager API.
+func waitForAlertManagerAlert(client *rancher.Client, alertName, receiverName string) error {
+	return kubewait.PollUntilContextTimeout(context.TODO(), 10*time.Second, 5*time.Minute, true, func(context.Context) (bool, error) {
+		bodyString, err := ingresses.GetExternalIngressResponse(client, client.RancherConfig.Host, alertManagerAlertsPathAPI, true)
+		if err != nil {
+			return false, err
+		}
+
+		var alerts []alertManagerAlert
+		if err = json.Unmarshal([]byte(bodyString), &alerts); err != nil {
+			logrus.Infof("Alert manager alerts API is not ready, retrying: %v", err)
+			return false, nil
+		}
+
+		for _, alert := range alerts {
+			if alert.Labels["alertname"] != alertName || alert.Status.State != "active" {
+				continue
+			}
+
+			for _, receiver := range alert.Receivers {
+				if receiver.Name == receiverName {
+					return true, nil
+				}
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// addWebhookReceiverRoute is a private helper function
+// that adds a webhook receiver to the alert config, and a route to it for the alert ahead of the existing routes.
+// The route continues matching, so the existing routes still receive the alert.
+func addWebhookReceiverRoute(alertConfigByte []byte, receiverName, receiverURL, alertName string) ([]byte, error) {
+	alertConfig := &resources.AlertmanagerConfig{}
+	err := yaml.Unmarshal(alertConfigByte, alertConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal alert config")
+	}
+
+	if alertConfig.Route == nil {
+		return nil, errors.New("alert config has no route")
+	}
+
+	vsendresolved := false
+
+	alertConfig.Receivers = append(alertConfig.Receivers, &resources.Receiver{
+		Name: receiverName,
+		WebhookConfigs: []*resources.WebhookConfig{
+			{
+				VSendResolved: &vsendresolved,
+				URL:           receiverURL,
+			},
+		},
+	})
+
+	route := &resources.Route{
+		Receiver:       receiverName,
+		Match:          map[string]string{"alertname": alertName},
+		Continue:       true,
+		GroupWait:      "10s",
+		GroupInterval:  alertConfig.Route.GroupInterval,
+		RepeatInterval: alertConfig.Route.RepeatInterval,
+	}
+	alertConfig.Route.Routes = append([]*resources.Route{route}, alertConfig.Route.Routes...)
+
+	return yaml.Marshal(alertConfig)
+}
+
+// waitForWebhookReceiverRequest is a private helper function
+// that waits until the webhook receiver deployment is annotated by its kubectl container, after it received a request from alert manager.
+func waitForWebhookReceiverRequest(client *rancher.Client, clusterID, namespace, deploymentName string) error {
+	steveclient, err := client.Steve.ProxyDownstream(clusterID)
+	if err != nil {
+		return err
+	}
+
+	return kubewait.PollUntilContextTimeout(context.TODO(), 10*time.Second, 10*time.Minute, true, func(context.Context) (bool, error) {
+		deployment, err := steveclient.SteveType(stevetypes.Deployment).ByID(namespace + "/" + deploymentName)
+		if err != nil {
+			return false, err
+		}
+
+		return deployment.Annotations[webhookReceiverAnnotationKey] == webhookReceiverAnnotationValue, nil
+	})
+}
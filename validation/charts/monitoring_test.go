@@ -18,6 +18,7 @@ import (
 	"github.com/rancher/shepherd/extensions/clusters"
 	"github.com/rancher/shepherd/extensions/ingresses"
 	kubeapinodes "github.com/rancher/shepherd/extensions/kubeapi/nodes"
+	"github.com/rancher/shepherd/pkg/namegenerator"
 	"github.com/rancher/shepherd/pkg/session"
 	"github.com/rancher/tests/actions/charts"
 	actionsClusters "github.com/rancher/tests/actions/clusters"
@@ -74,6 +75,8 @@ func (m *MonitoringTestSuite) SetupSuite() {
 		alertManagerPath = fmt.Sprintf("k8s/clusters/%s/%s", cluster.ID, alertManagerPath)
 		grafanaPath = fmt.Sprintf("k8s/clusters/%s/%s", cluster.ID, grafanaPath)
 		prometheusTargetsPathAPI = fmt.Sprintf("k8s/clusters/%s/%s", cluster.ID, prometheusTargetsPathAPI)
+		prometheusAlertsPathAPI = fmt.Sprintf("k8s/clusters/%s/%s", cluster.ID, prometheusAlertsPathAPI)
+		alertManagerAlertsPathAPI = fmt.Sprintf("k8s/clusters/%s/%s", cluster.ID, alertManagerAlertsPathAPI)
 	}
 
 	// Change prometheus paths to use the clusterID
@@ -103,19 +106,12 @@ func (m *MonitoringTestSuite) SetupSuite() {
 		Etcd:              true,
 		Proxy:             true,
 		Scheduler:         true,
+		Server:            true,
 	}
 }
 
-func (m *MonitoringTestSuite) TestMonitoringChart() {
-	subSession := m.session.NewSession()
-	defer subSession.Cleanup()
-
-	client, err := m.client.WithSession(subSession)
-	require.NoError(m.T(), err)
-
-	steveclient, err := client.Steve.ProxyDownstream(m.project.ClusterID)
-	require.NoError(m.T(), err)
-
+// installMonitoringChart installs the monitoring chart with the suite options, unless it is already installed
+func (m *MonitoringTestSuite) installMonitoringChart(client *rancher.Client) {
 	m.T().Log("Checking if the monitoring chart is already installed")
 	initialMonitoringChart, err := extencharts.GetChartStatus(client, m.project.ClusterID, charts.RancherMonitoringNamespace, charts.RancherMonitoringName)
 	require.NoError(m.T(), err)
@@ -137,6 +133,19 @@ func (m *MonitoringTestSuite) TestMonitoringChart() {
 		err = extencharts.WatchAndWaitStatefulSets(client, m.project.ClusterID, charts.RancherMonitoringNamespace, metav1.ListOptions{})
 		require.NoError(m.T(), err)
 	}
+}
+
+func (m *MonitoringTestSuite) TestMonitoringChart() {
+	subSession := m.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := m.client.WithSession(subSession)
+	require.NoError(m.T(), err)
+
+	steveclient, err := client.Steve.ProxyDownstream(m.project.ClusterID)
+	require.NoError(m.T(), err)
+
+	m.installMonitoringChart(client)
 
 	paths := []string{alertManagerPath, grafanaPath, prometheusGraphPath, prometheusRulesPath, prometheusTargetsPath}
 	for _, path := range paths {
@@ -245,7 +254,7 @@ func (m *MonitoringTestSuite) TestMonitoringChart() {
 	assert.Equal(m.T(), editedReceiverSecretResp.Name, charts.RancherMonitoringAlertSecret)
 
 	m.T().Logf("Creating prometheus rule")
-	err = createPrometheusRule(client, m.project.ClusterID)
+	_, err = createPrometheusRule(client, m.project.ClusterID)
 	require.NoError(m.T(), err)
 
 	m.T().Logf("Getting alert manager secret to edit routes")
@@ -276,6 +285,120 @@ func (m *MonitoringTestSuite) TestMonitoringChart() {
 	require.NoError(m.T(), err)
 }
 
+func (m *MonitoringTestSuite) TestMonitoringDataPath() {
+	subSession := m.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := m.client.WithSession(subSession)
+	require.NoError(m.T(), err)
+
+	steveclient, err := client.Steve.ProxyDownstream(m.project.ClusterID)
+	require.NoError(m.T(), err)
+
+	m.installMonitoringChart(client)
+
+	// hosted providers don't expose the control plane components
+	components := expectedMonitoringTargets(m.chartFeatureOptions, m.cluster.Provider)
+	if len(components) > 0 {
+		m.T().Logf("Validating Prometheus targets of %v are up", components)
+		err = waitForPrometheusTargetsUp(client, components)
+		require.NoError(m.T(), err)
+	} else {
+		m.T().Logf("Skipping control plane targets validation, %s clusters don't expose the control plane components", m.cluster.Provider)
+	}
+
+	m.T().Log("Creating webhook receiver's namespace")
+	namespaceName := "webhook-namespace-" + namegenerator.RandStringLower(defaultRandStringLength)
+	webhookReceiverNamespace, err := namespaces.CreateNamespace(client, namespaceName, "{}", map[string]string{}, map[string]string{}, m.project)
+	require.NoError(m.T(), err)
+
+	m.T().Log("Creating alert webhook receiver deployment and its resources")
+	deploymentName := "webhook-" + namegenerator.RandStringLower(defaultRandStringLength)
+	webhookReceiverDeploymentResp, err := createAlertWebhookReceiverDeployment(client, m.project.ClusterID, webhookReceiverNamespace.Name, deploymentName)
+	require.NoError(m.T(), err)
+
+	m.T().Log("Waiting webhook receiver deployment to have expected number of available replicas")
+	err = extencharts.WatchAndWaitDeployments(client, m.project.ClusterID, webhookReceiverNamespace.Name, metav1.ListOptions{})
+	require.NoError(m.T(), err)
+
+	webhookReceiverDeploymentSpec := &appv1.DeploymentSpec{}
+	err = v1.ConvertToK8sType(webhookReceiverDeploymentResp.Spec, webhookReceiverDeploymentSpec)
+	require.NoError(m.T(), err)
+
+	m.T().Log("Creating cluster IP service for webhook receiver deployment")
+	webhookServiceTemplate := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-service-" + namegenerator.RandStringLower(defaultRandStringLength),
+			Namespace: webhookReceiverNamespace.Name,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Name: "web",
+					Port: 80,
+				},
+			},
+			Selector: webhookReceiverDeploymentSpec.Template.Labels,
+		},
+	}
+	_, err = steveclient.SteveType(services.ServiceSteveType).Create(webhookServiceTemplate)
+	require.NoError(m.T(), err)
+
+	receiverURL := fmt.Sprintf("http://%s.%s.svc:%d", webhookServiceTemplate.Name, webhookServiceTemplate.Namespace, webhookServiceTemplate.Spec.Ports[0].Port)
+
+	m.T().Log("Creating prometheus rule")
+	alertName, err := createPrometheusRule(client, m.project.ClusterID)
+	require.NoError(m.T(), err)
+
+	m.T().Logf("Validating Prometheus alert %s is firing", alertName)
+	err = waitForPrometheusAlertFiring(client, alertName)
+	require.NoError(m.T(), err)
+
+	m.T().Log("Getting alert manager secret to add the webhook receiver")
+	alertManagerSecretResp, err := steveclient.SteveType(secrets.SecretSteveType).ByID(alertManagerSecretID)
+	require.NoError(m.T(), err)
+
+	alertManagerSecret := &corev1.Secret{}
+	err = v1.ConvertToK8sType(alertManagerSecretResp.JSONResp, alertManagerSecret)
+	require.NoError(m.T(), err)
+
+	originalAlertConfig := alertManagerSecret.Data[secretPath]
+	subSession.RegisterCleanupFunc(func() error {
+		secretResp, err := steveclient.SteveType(secrets.SecretSteveType).ByID(alertManagerSecretID)
+		if err != nil {
+			return err
+		}
+
+		secret := &corev1.Secret{}
+		err = v1.ConvertToK8sType(secretResp.JSONResp, secret)
+		if err != nil {
+			return err
+		}
+
+		secret.Data[secretPath] = originalAlertConfig
+		_, err = steveclient.SteveType(secrets.SecretSteveType).Update(secretResp, secret)
+
+		return err
+	})
+
+	m.T().Logf("Routing alert %s to webhook receiver %s", alertName, receiverURL)
+	alertConfig, err := addWebhookReceiverRoute(originalAlertConfig, deploymentName, receiverURL, alertName)
+	require.NoError(m.T(), err)
+
+	alertManagerSecret.Data[secretPath] = alertConfig
+	_, err = steveclient.SteveType(secrets.SecretSteveType).Update(alertManagerSecretResp, alertManagerSecret)
+	require.NoError(m.T(), err)
+
+	m.T().Logf("Validating alert manager routes alert %s to the webhook receiver", alertName)
+	err = waitForAlertManagerAlert(client, alertName, deploymentName)
+	require.NoError(m.T(), err)
+
+	m.T().Log("Validating the webhook receiver received the alert from alert manager")
+	err = waitForWebhookReceiverRequest(client, m.project.ClusterID, webhookReceiverNamespace.Name, deploymentName)
+	require.NoError(m.T(), err)
+}
+
 func (m *MonitoringTestSuite) TestUpgradeMonitoringChart() {
 	subSession := m.session.NewSession()
 	defer subSession.Cleanup()
@@ -0,0 +1,170 @@
+//go:build (validation || infra.aks || infra.eks || infra.gke || extended) && !infra.any && !infra.rke2k3s && !cluster.any && !cluster.custom && !cluster.nodedriver && !sanity && !stress
+
+package hosted
+
+import (
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/clusters/aks"
+	"github.com/rancher/shepherd/extensions/clusters/eks"
+	"github.com/rancher/shepherd/extensions/clusters/gke"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/pkg/config"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/hosted"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type HostedLifecycleTestSuite struct {
+	suite.Suite
+	client          *rancher.Client
+	session         *session.Session
+	hostedConfig    *hosted.Config
+	clusterConfig   any
+	provider        hosted.HostedProvider
+	clusterID       string
+	importClusterID string
+}
+
+func (h *HostedLifecycleTestSuite) TearDownSuite() {
+	h.session.Cleanup()
+}
+
+func (h *HostedLifecycleTestSuite) SetupSuite() {
+	testSession := session.NewSession()
+	h.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	require.NoError(h.T(), err)
+
+	h.client = client
+
+	h.hostedConfig = new(hosted.Config)
+	config.LoadConfig(hosted.ConfigurationFileKey, h.hostedConfig)
+
+	switch h.hostedConfig.Provider {
+	case hosted.AKS:
+		aksClusterConfig := aks.ClusterConfig{}
+		config.LoadConfig(aks.AKSClusterConfigConfigurationFileKey, &aksClusterConfig)
+		h.clusterConfig = aksClusterConfig
+	case hosted.EKS:
+		eksClusterConfig := eks.ClusterConfig{}
+		config.LoadConfig(eks.EKSClusterConfigConfigurationFileKey, &eksClusterConfig)
+		h.clusterConfig = eksClusterConfig
+	case hosted.GKE:
+		gkeClusterConfig := gke.ClusterConfig{}
+		config.LoadConfig(gke.GKEClusterConfigConfigurationFileKey, &gkeClusterConfig)
+		h.clusterConfig = gkeClusterConfig
+	}
+
+	h.provider, err = hosted.NewHostedProvider(h.client, h.hostedConfig.Provider, h.clusterConfig)
+	require.NoError(h.T(), err)
+
+	if h.client.RancherConfig.ClusterName == "" {
+		logrus.Infof("Provisioning %s cluster", h.provider.Type())
+		h.clusterID, err = h.provider.Provision()
+		require.NoError(h.T(), err)
+	} else {
+		logrus.Infof("Using existing cluster %s", h.client.RancherConfig.ClusterName)
+		h.clusterID, err = clusters.GetClusterIDByName(h.client, h.client.RancherConfig.ClusterName)
+		require.NoError(h.T(), err)
+	}
+
+	if h.hostedConfig.ImportClusterName != "" {
+		logrus.Infof("Importing %s cluster %s", h.provider.Type(), h.hostedConfig.ImportClusterName)
+		h.importClusterID, err = h.provider.Import(h.hostedConfig.ImportClusterName)
+		require.NoError(h.T(), err)
+	}
+}
+
+func (h *HostedLifecycleTestSuite) TestScaleNodePool() {
+	nodePools, err := h.provider.GetNodePools(h.clusterID)
+	require.NoError(h.T(), err)
+
+	tests := []struct {
+		name  string
+		nodes int64
+	}{
+		{"Hosted_Scale_Node_Pool_Up_By_1", 1},
+		{"Hosted_Scale_Node_Pool_Down_By_1", -1},
+	}
+
+	for _, tt := range tests {
+		h.Run(tt.name, func() {
+			err := hosted.ScaleNodePoolBy(h.provider, h.clusterID, nodePools[0].Name, tt.nodes)
+			require.NoError(h.T(), err)
+		})
+	}
+}
+
+func (h *HostedLifecycleTestSuite) TestAddAndRemoveNodePool() {
+	nodePool := hosted.NodePool{
+		Name:  namegen.RandStringLower(8),
+		Count: 1,
+	}
+
+	err := hosted.AddAndRemoveNodePool(h.provider, h.clusterID, nodePool)
+	require.NoError(h.T(), err)
+}
+
+func (h *HostedLifecycleTestSuite) TestUpgradeCluster() {
+	if h.hostedConfig.UpgradeVersion == "" {
+		h.T().Skip("No upgrade version provided")
+	}
+
+	err := hosted.UpgradeCluster(h.provider, h.clusterID, h.hostedConfig.UpgradeVersion)
+	require.NoError(h.T(), err)
+}
+
+func (h *HostedLifecycleTestSuite) TestImportCluster() {
+	if h.hostedConfig.ImportClusterName == "" {
+		h.T().Skip("No cluster to import provided")
+	}
+
+	nodePools, err := h.provider.GetNodePools(h.importClusterID)
+	require.NoError(h.T(), err)
+	require.NotEmpty(h.T(), nodePools)
+}
+
+func (h *HostedLifecycleTestSuite) TestImportDriftReconciliation() {
+	if h.hostedConfig.ImportClusterName == "" {
+		h.T().Skip("No cluster to import provided")
+	}
+
+	cloud, err := hosted.NewCLICloudClient(h.hostedConfig.Provider, h.clusterConfig)
+	require.NoError(h.T(), err)
+
+	logrus.Infof("Verifying the upstream spec of %s matches the cloud provider", h.hostedConfig.ImportClusterName)
+	err = hosted.WaitForUpstreamSpec(h.provider, cloud, h.importClusterID, h.hostedConfig.ImportClusterName, defaults.FifteenMinuteTimeout)
+	require.NoError(h.T(), err)
+
+	state, err := cloud.GetClusterState(h.hostedConfig.ImportClusterName)
+	require.NoError(h.T(), err)
+	require.NotEmpty(h.T(), state.NodePools)
+
+	nodePool := state.NodePools[0]
+
+	tests := []struct {
+		name  string
+		count int64
+	}{
+		{"Hosted_Drift_Scale_Node_Pool_Up_By_1", nodePool.Count + 1},
+		{"Hosted_Drift_Scale_Node_Pool_Back", nodePool.Count},
+	}
+
+	for _, tt := range tests {
+		h.Run(tt.name, func() {
+			err := hosted.ScaleNodePoolOutOfBand(h.provider, cloud, h.importClusterID, h.hostedConfig.ImportClusterName, nodePool.Name, tt.count, defaults.ThirtyMinuteTimeout)
+			require.NoError(h.T(), err)
+		})
+	}
+}
+
+func TestHostedLifecycleTestSuite(t *testing.T) {
+	suite.Run(t, new(HostedLifecycleTestSuite))
+}
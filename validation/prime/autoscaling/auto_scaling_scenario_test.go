@@ -0,0 +1,219 @@
+//go:build validation || prime
+
+//nolint:forbidigo
+package autoscaling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	shepherdClusters "github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults/namespaces"
+	"github.com/rancher/shepherd/pkg/config/operations"
+	"github.com/rancher/tests/actions/clusters"
+	"github.com/rancher/tests/actions/config/defaults"
+	"github.com/rancher/tests/actions/projects"
+	"github.com/rancher/tests/actions/provisioning"
+	"github.com/rancher/tests/actions/provisioninginput"
+	"github.com/rancher/tests/actions/qase"
+	"github.com/rancher/tests/actions/scaling"
+	"github.com/rancher/tests/actions/workloads/deployment"
+	resources "github.com/rancher/tests/validation/provisioning/resources/provisioncluster"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoScalingScenarios(t *testing.T) {
+	s := autoScalingSetup(t)
+
+	nodeRolesStandard := []provisioninginput.MachinePools{
+		provisioninginput.EtcdMachinePool,
+		provisioninginput.ControlPlaneMachinePool,
+		provisioninginput.WorkerMachinePool,
+	}
+
+	nodeRolesStandard[0].MachinePoolConfig.Quantity = 3
+	nodeRolesStandard[1].MachinePoolConfig.Quantity = 2
+	nodeRolesStandard[2].MachinePoolConfig.Quantity = 1
+
+	tests := []struct {
+		name         string
+		client       *rancher.Client
+		clusterType  string
+		nodeRoles    []provisioninginput.MachinePools
+		minNodeCount int32
+		maxNodeCount int32
+	}{
+		{"RKE2_Auto_Scale_Pending_Pods", s.standardUserClient, defaults.RKE2, nodeRolesStandard, 1, 3},
+		{"K3S_Auto_Scale_Pending_Pods", s.standardUserClient, defaults.K3S, nodeRolesStandard, 1, 3},
+	}
+
+	for _, tt := range tests {
+		var err error
+		t.Cleanup(func() {
+			logrus.Infof("Running cleanup (%s)", tt.name)
+			s.session.Cleanup()
+		})
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clusterConfig := new(clusters.ClusterConfig)
+			operations.LoadObjectFromMap(defaults.ClusterConfigKey, s.cattleConfig, clusterConfig)
+
+			clusterConfig.MachinePools = tt.nodeRoles
+			clusterConfig.MachinePools[2].MachinePoolConfig.AutoscalingMinSize = &tt.minNodeCount
+			clusterConfig.MachinePools[2].MachinePoolConfig.AutoscalingMaxSize = &tt.maxNodeCount
+
+			provider := provisioning.CreateProvider(clusterConfig.Provider)
+			machineConfigSpec := provider.LoadMachineConfigFunc(s.cattleConfig)
+
+			logrus.Infof("Provisioning %s cluster", tt.clusterType)
+			cluster, err := resources.ProvisionRKE2K3SCluster(t, s.client, tt.clusterType, provider, *clusterConfig, machineConfigSpec, nil, true, false)
+			require.NoError(t, err)
+
+			logrus.Infof("Verifying cluster autoscaler (%s)", cluster.Name)
+			scaling.VerifyAutoscaler(t, s.client, cluster)
+
+			v3ClusterID, err := shepherdClusters.GetClusterIDByName(s.client, cluster.Name)
+			require.NoError(t, err)
+
+			_, namespace, err := projects.CreateProjectAndNamespace(s.client, v3ClusterID)
+			require.NoError(t, err)
+
+			logrus.Infof("Pausing cluster autoscaler (%s)", cluster.Name)
+			err = scaling.UpdateAutoscalerState(s.client, cluster, true)
+			require.NoError(t, err)
+
+			pausedWorkload, err := scaling.CreatePendingWorkload(s.client, v3ClusterID, namespace.Name, 1)
+			require.NoError(t, err)
+
+			logrus.Infof("Verifying the paused autoscaler does not scale (%s)", cluster.Name)
+			err = scaling.VerifyNoAutoscaling(s.client, cluster, time.Minute*5)
+			require.NoError(t, err)
+
+			err = scaling.DeletePendingWorkload(s.client, pausedWorkload)
+			require.NoError(t, err)
+
+			logrus.Infof("Unpausing cluster autoscaler (%s)", cluster.Name)
+			err = scaling.UpdateAutoscalerState(s.client, cluster, false)
+			require.NoError(t, err)
+
+			err = deployment.WaitForDeploymentUpdate(s.client, cluster.ID, namespaces.KubeSystem, scaling.AutoscalerDeploymentName)
+			require.NoError(t, err)
+
+			scaleUpResult, _, err := scaling.ScaleUpBy(s.client, cluster, v3ClusterID, namespace.Name, tt.maxNodeCount-tt.minNodeCount, time.Minute*20)
+			require.NoError(t, err)
+			require.Equal(t, tt.maxNodeCount, scaleUpResult.To)
+
+			logrus.Infof("Creating pending pods beyond the max size of the pool (%s)", cluster.Name)
+			overflowWorkload, err := scaling.CreatePendingWorkload(s.client, v3ClusterID, namespace.Name, 1)
+			require.NoError(t, err)
+
+			err = scaling.VerifyMaxSizeEvents(s.client, overflowWorkload, time.Minute*10)
+			require.NoError(t, err)
+
+			logrus.Infof("Verifying the cluster is ready (%s)", cluster.Name)
+			err = provisioning.VerifyClusterReady(s.client, cluster)
+			require.NoError(t, err)
+		})
+
+		params := provisioning.GetProvisioningSchemaParams(tt.client, s.cattleConfig)
+		err = qase.UpdateSchemaParameters(tt.name, params)
+		if err != nil {
+			logrus.Warningf("Failed to upload schema parameters %s", err)
+		}
+	}
+}
+
+func TestAutoScalingScenarioDown(t *testing.T) {
+	s := autoScalingSetup(t)
+
+	nodeRolesStandard := []provisioninginput.MachinePools{
+		provisioninginput.EtcdMachinePool,
+		provisioninginput.ControlPlaneMachinePool,
+		provisioninginput.WorkerMachinePool,
+	}
+
+	nodeRolesStandard[0].MachinePoolConfig.Quantity = 3
+	nodeRolesStandard[1].MachinePoolConfig.Quantity = 2
+	nodeRolesStandard[2].MachinePoolConfig.Quantity = 1
+
+	tests := []struct {
+		name         string
+		client       *rancher.Client
+		clusterType  string
+		nodeRoles    []provisioninginput.MachinePools
+		minNodeCount int32
+		maxNodeCount int32
+	}{
+		{"RKE2_Auto_Scale_Pending_Pods_Down", s.standardUserClient, defaults.RKE2, nodeRolesStandard, 1, 3},
+		{"K3S_Auto_Scale_Pending_Pods_Down", s.standardUserClient, defaults.K3S, nodeRolesStandard, 1, 3},
+	}
+
+	for _, tt := range tests {
+		var err error
+		t.Cleanup(func() {
+			logrus.Infof("Running cleanup (%s)", tt.name)
+			s.session.Cleanup()
+		})
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clusterConfig := new(clusters.ClusterConfig)
+			operations.LoadObjectFromMap(defaults.ClusterConfigKey, s.cattleConfig, clusterConfig)
+
+			clusterConfig.MachinePools = tt.nodeRoles
+			clusterConfig.MachinePools[2].MachinePoolConfig.AutoscalingMinSize = &tt.minNodeCount
+			clusterConfig.MachinePools[2].MachinePoolConfig.AutoscalingMaxSize = &tt.maxNodeCount
+
+			provider := provisioning.CreateProvider(clusterConfig.Provider)
+			machineConfigSpec := provider.LoadMachineConfigFunc(s.cattleConfig)
+
+			logrus.Infof("Provisioning %s cluster", tt.clusterType)
+			cluster, err := resources.ProvisionRKE2K3SCluster(t, s.client, tt.clusterType, provider, *clusterConfig, machineConfigSpec, nil, true, false)
+			require.NoError(t, err)
+
+			logrus.Infof("Verifying cluster autoscaler (%s)", cluster.Name)
+			scaling.VerifyAutoscaler(t, s.client, cluster)
+
+			v3ClusterID, err := shepherdClusters.GetClusterIDByName(s.client, cluster.Name)
+			require.NoError(t, err)
+
+			_, namespace, err := projects.CreateProjectAndNamespace(s.client, v3ClusterID)
+			require.NoError(t, err)
+
+			_, workload, err := scaling.ScaleUpBy(s.client, cluster, v3ClusterID, namespace.Name, 1, time.Minute*20)
+			require.NoError(t, err)
+
+			candidates, err := scaling.GetWorkloadNodes(s.client, workload)
+			require.NoError(t, err)
+
+			err = scaling.DeletePendingWorkload(s.client, workload)
+			require.NoError(t, err)
+
+			logrus.Infof("Verifying the autoscaler selects the emptied nodes for scale down (%s)", cluster.Name)
+			err = scaling.VerifyScaleDownCandidates(s.client, v3ClusterID, candidates, time.Minute*20)
+			require.NoError(t, err)
+
+			err = scaling.VerifyScaleDownAnnotation(s.client, cluster, candidates, time.Minute*20)
+			require.NoError(t, err)
+
+			t.Skip("Skipping the removal of the scaled down nodes due to https://github.com/rancher/rancher/issues/52665")
+
+			scaleDownResult, err := scaling.ScaleDownTo(s.client, cluster, tt.minNodeCount, time.Hour*2)
+			require.NoError(t, err)
+			require.Equal(t, tt.minNodeCount, scaleDownResult.To)
+
+			logrus.Infof("Verifying the cluster is ready (%s)", cluster.Name)
+			err = provisioning.VerifyClusterReady(s.client, cluster)
+			require.NoError(t, err)
+		})
+
+		params := provisioning.GetProvisioningSchemaParams(tt.client, s.cattleConfig)
+		err = qase.UpdateSchemaParameters(tt.name, params)
+		if err != nil {
+			logrus.Warningf("Failed to upload schema parameters %s", err)
+		}
+	}
+}
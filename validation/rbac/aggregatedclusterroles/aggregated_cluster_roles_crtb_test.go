@@ -3,16 +3,20 @@
 package aggregatedclusterroles
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/shepherd/clients/rancher"
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
 	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
 	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
 	"github.com/rancher/shepherd/extensions/users"
 	namegen "github.com/rancher/shepherd/pkg/namegenerator"
 	"github.com/rancher/shepherd/pkg/session"
+	"github.com/rancher/tests/actions/chaos"
 	namespaceapi "github.com/rancher/tests/actions/kubeapi/namespaces"
 	projectapi "github.com/rancher/tests/actions/kubeapi/projects"
 	rbacapi "github.com/rancher/tests/actions/kubeapi/rbac"
@@ -28,6 +32,16 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// bindingPropagationMaxLatency is the time a binding change may take to reach a connected downstream cluster
+	bindingPropagationMaxLatency = 30 * time.Second
+	// agentDisconnectDuration is the time the cluster agent is kept scaled down while a binding changes
+	agentDisconnectDuration = 3 * time.Minute
+	// agentReconnectMaxLatency is the time a binding change made while the cluster agent was scaled down may take to
+	// reach the downstream cluster, including the time for the agent to reconnect
+	agentReconnectMaxLatency = agentDisconnectDuration + 3*time.Minute
+)
+
 type AggregatedClusterRolesCrtbTestSuite struct {
 	suite.Suite
 	client  *rancher.Client
@@ -984,6 +998,155 @@ func (acrc *AggregatedClusterRolesCrtbTestSuite) TestCrtbVerifyCrossClusterAcces
 	require.NoError(acrc.T(), rbacapi.VerifyUserPermission(acrc.client, extclusterapi.LocalCluster, createdUser, "create", "projects", "", "", false, true))
 }
 
+func (acrc *AggregatedClusterRolesCrtbTestSuite) TestCrtbEffectivePermissionsOfInheritanceGraph() {
+	subSession := acrc.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := acrc.client.WithSession(subSession)
+	require.NoError(acrc.T(), err)
+
+	log.Info("Creating a graph of nested cluster role templates, with a role template inherited twice.")
+	sharedRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["readProjects"], nil, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create shared role template")
+
+	parentRT1, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["readPods"], []*v3.RoleTemplate{sharedRT}, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create parent role template 1")
+
+	parentRT2, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["readSecrets"], []*v3.RoleTemplate{sharedRT}, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create parent role template 2")
+
+	mainRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["createProjects"], []*v3.RoleTemplate{parentRT1, parentRT2}, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create main role template")
+
+	log.Info("Computing the effective permissions of the main role template from the role templates in Rancher.")
+	graph, err := rbacapi.GetRoleGraph(client)
+	require.NoError(acrc.T(), err)
+	permissions, err := graph.EffectiveRoleTemplatePermissions(mainRT.Name)
+	require.NoError(acrc.T(), err)
+	require.Equal(acrc.T(), 2, permissions.Depth)
+	require.ElementsMatch(acrc.T(), []string{mainRT.Name, parentRT1.Name, parentRT2.Name, sharedRT.Name}, permissions.RoleTemplates)
+
+	log.Info("Verifying the aggregated cluster roles in the local and downstream clusters match the effective permissions.")
+	err = rbacapi.VerifyEffectiveRoleTemplatePermissions(client, acrc.cluster.ID, graph, mainRT.Name)
+	require.NoError(acrc.T(), err)
+	err = rbacapi.VerifyEffectiveRoleTemplatePermissions(client, acrc.cluster.ID, graph, parentRT2.Name)
+	require.NoError(acrc.T(), err)
+
+	log.Info("Creating a global role inheriting the main role template and computing its effective permissions.")
+	createdGR, err := rbacapi.CreateGlobalRoleWithInheritedClusterRoles(client, []string{mainRT.Name})
+	require.NoError(acrc.T(), err, "Failed to create global role")
+
+	graph, err = rbacapi.GetRoleGraph(client)
+	require.NoError(acrc.T(), err)
+	globalPermissions, err := graph.EffectiveGlobalRolePermissions(createdGR.Name)
+	require.NoError(acrc.T(), err)
+	require.ElementsMatch(acrc.T(), permissions.Rules, globalPermissions.DownstreamRules)
+	require.ElementsMatch(acrc.T(), permissions.ClusterManagementRules, globalPermissions.ClusterManagementRules)
+
+	log.Info("Verifying the aggregated cluster roles in the downstream cluster grant the effective permissions of the global role.")
+	err = rbacapi.VerifyEffectiveGlobalRolePermissions(client, acrc.cluster.ID, graph, createdGR.Name)
+	require.NoError(acrc.T(), err)
+}
+
+func (acrc *AggregatedClusterRolesCrtbTestSuite) TestCrtbBindingPropagation() {
+	subSession := acrc.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := acrc.client.WithSession(subSession)
+	require.NoError(acrc.T(), err)
+
+	_, _, createdUser, _, _, _, err := acrc.acrCreateTestResourcesForCrtb(client, acrc.cluster)
+	require.NoError(acrc.T(), err)
+
+	createdRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["readPods"], nil, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create role template")
+
+	log.Infof("Adding user %s to the downstream cluster with role %s", createdUser.Username, createdRT.Name)
+	createdAt := time.Now()
+	createdCrtb, err := rbacapi.CreateClusterRoleTemplateBinding(client, acrc.cluster.ID, createdUser, createdRT.Name)
+	require.NoError(acrc.T(), err, "Failed to assign role to user")
+
+	change := rbacapi.BindingChange{
+		UserName:         createdUser.ID,
+		RoleTemplateName: createdRT.Name,
+		BindingName:      createdCrtb.Name,
+	}
+
+	log.Info("Measuring the propagation of the cluster role binding to the downstream cluster.")
+	results := rbacapi.MeasureBindingPropagation(client, change, []string{acrc.cluster.ID}, createdAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrc.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+
+	log.Infof("Deleting CRTB %s and verifying nothing is left in the downstream cluster.", createdCrtb.Name)
+	deletedAt := time.Now()
+	err = rbacapi.DeleteClusterRoleTemplateBinding(client, createdCrtb.Namespace, createdCrtb.Name)
+	require.NoError(acrc.T(), err)
+
+	results = rbacapi.MeasureBindingRemoval(client, change, []string{acrc.cluster.ID}, deletedAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrc.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+}
+
+func (acrc *AggregatedClusterRolesCrtbTestSuite) TestCrtbBindingPropagationWithAgentDisconnected() {
+	subSession := acrc.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := acrc.client.WithSession(subSession)
+	require.NoError(acrc.T(), err)
+
+	_, _, createdUser, _, _, _, err := acrc.acrCreateTestResourcesForCrtb(client, acrc.cluster)
+	require.NoError(acrc.T(), err)
+
+	createdRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ClusterContext, rbacapi.PolicyRules["readPods"], nil, false, false, nil)
+	require.NoError(acrc.T(), err, "Failed to create role template")
+
+	log.Infof("Adding user %s to the downstream cluster with role %s", createdUser.Username, createdRT.Name)
+	createdAt := time.Now()
+	createdCrtb, err := rbacapi.CreateClusterRoleTemplateBinding(client, acrc.cluster.ID, createdUser, createdRT.Name)
+	require.NoError(acrc.T(), err, "Failed to assign role to user")
+
+	change := rbacapi.BindingChange{
+		UserName:         createdUser.ID,
+		RoleTemplateName: createdRT.Name,
+		BindingName:      createdCrtb.Name,
+	}
+
+	results := rbacapi.MeasureBindingPropagation(client, change, []string{acrc.cluster.ID}, createdAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrc.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+
+	log.Infof("Disconnecting the cluster agent of %s for %s.", acrc.cluster.ID, agentDisconnectDuration)
+	disconnect, err := disconnectClusterAgent(client, acrc.cluster)
+	require.NoError(acrc.T(), err)
+	defer disconnect.Stop()
+
+	log.Infof("Deleting CRTB %s while the cluster agent is disconnected.", createdCrtb.Name)
+	deletedAt := time.Now()
+	err = rbacapi.DeleteClusterRoleTemplateBinding(client, createdCrtb.Namespace, createdCrtb.Name)
+	require.NoError(acrc.T(), err)
+
+	log.Info("Verifying the cluster role binding is removed once the cluster agent reconnects.")
+	results = rbacapi.MeasureBindingRemoval(client, change, []string{acrc.cluster.ID}, deletedAt, defaults.TenMinuteTimeout)
+	require.NoError(acrc.T(), disconnect.Wait())
+	require.NoError(acrc.T(), rbacapi.VerifyPropagationResults(results, agentReconnectMaxLatency))
+	require.True(acrc.T(), results[0].Unreachable, "cluster %s was never unreachable while the cluster agent was scaled down", acrc.cluster.ID)
+}
+
+// disconnectClusterAgent scales the cluster agent of the downstream cluster down for agentDisconnectDuration, and
+// waits for the cluster to be unreachable through Rancher. The agent is scaled back up once the duration has passed.
+func disconnectClusterAgent(client *rancher.Client, cluster *management.Cluster) (*chaos.ScheduledFault, error) {
+	targets, err := chaos.GetTargetsWithRole(client, cluster.ID, chaos.ControlPlaneRole)
+	if err != nil {
+		return nil, err
+	}
+
+	disconnect := chaos.Schedule(chaos.NewClusterAgentScaleDown(targets[0], cluster.Provider), 0, agentDisconnectDuration)
+
+	err = rbacapi.WaitForClusterUnreachable(client, cluster.ID, defaults.TwoMinuteTimeout)
+	if err != nil {
+		return nil, errors.Join(err, disconnect.Stop())
+	}
+
+	return disconnect, nil
+}
+
 func TestAggregatedClusterRolesCrtbTestSuite(t *testing.T) {
 	suite.Run(t, new(AggregatedClusterRolesCrtbTestSuite))
 }
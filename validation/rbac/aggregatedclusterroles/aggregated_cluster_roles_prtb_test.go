@@ -4,11 +4,13 @@ package aggregatedclusterroles
 
 import (
 	"testing"
+	"time"
 
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/shepherd/clients/rancher"
 	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
 	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/extensions/defaults"
 	extclusterapi "github.com/rancher/shepherd/extensions/kubeapi/cluster"
 	"github.com/rancher/shepherd/extensions/users"
 	namegen "github.com/rancher/shepherd/pkg/namegenerator"
@@ -1105,6 +1107,96 @@ func (acrp *AggregatedClusterRolesPrtbTestSuite) TestProjectRoleTemplateInheriti
 	require.NoError(acrp.T(), rbacapi.VerifyUserPermission(acrp.client, acrp.cluster.ID, createdUser, "delete", "projects", "", createdProject.Name, false, true))
 }
 
+func (acrp *AggregatedClusterRolesPrtbTestSuite) TestPrtbBindingPropagation() {
+	subSession := acrp.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := acrp.client.WithSession(subSession)
+	require.NoError(acrp.T(), err)
+
+	createdProject, createdNamespaces, createdUser, _, _, _, err := acrp.acrCreateTestResourcesForPrtb(client, acrp.cluster)
+	require.NoError(acrp.T(), err)
+
+	createdRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ProjectContext, rbacapi.PolicyRules["readPods"], nil, false, false, nil)
+	require.NoError(acrp.T(), err, "Failed to create role template")
+
+	log.Infof("Adding user %s to the project with role %s", createdUser.Username, createdRT.Name)
+	createdAt := time.Now()
+	createdPrtb, err := rbacapi.CreateProjectRoleTemplateBinding(client, createdUser, createdProject, createdRT.Name)
+	require.NoError(acrp.T(), err, "Failed to assign role to user")
+
+	change := rbacapi.BindingChange{
+		UserName:         createdUser.ID,
+		RoleTemplateName: createdRT.Name,
+		BindingName:      createdPrtb.Name,
+	}
+
+	for _, namespace := range createdNamespaces {
+		change.Namespaces = append(change.Namespaces, namespace.Name)
+	}
+
+	log.Info("Measuring the propagation of the role bindings to the namespaces of the project.")
+	results := rbacapi.MeasureBindingPropagation(client, change, []string{acrp.cluster.ID}, createdAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrp.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+
+	log.Infof("Deleting PRTB %s and verifying nothing is left in the downstream cluster.", createdPrtb.Name)
+	deletedAt := time.Now()
+	err = rbacapi.DeleteProjectRoleTemplateBinding(client, createdPrtb.Namespace, createdPrtb.Name)
+	require.NoError(acrp.T(), err)
+
+	results = rbacapi.MeasureBindingRemoval(client, change, []string{acrp.cluster.ID}, deletedAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrp.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+}
+
+func (acrp *AggregatedClusterRolesPrtbTestSuite) TestPrtbBindingPropagationWithAgentDisconnected() {
+	subSession := acrp.session.NewSession()
+	defer subSession.Cleanup()
+
+	client, err := acrp.client.WithSession(subSession)
+	require.NoError(acrp.T(), err)
+
+	createdProject, createdNamespaces, createdUser, _, _, _, err := acrp.acrCreateTestResourcesForPrtb(client, acrp.cluster)
+	require.NoError(acrp.T(), err)
+
+	createdRT, err := rbacapi.CreateRoleTemplate(client, rbacapi.ProjectContext, rbacapi.PolicyRules["readPods"], nil, false, false, nil)
+	require.NoError(acrp.T(), err, "Failed to create role template")
+
+	log.Infof("Adding user %s to the project with role %s", createdUser.Username, createdRT.Name)
+	createdAt := time.Now()
+	createdPrtb, err := rbacapi.CreateProjectRoleTemplateBinding(client, createdUser, createdProject, createdRT.Name)
+	require.NoError(acrp.T(), err, "Failed to assign role to user")
+
+	change := rbacapi.BindingChange{
+		UserName:         createdUser.ID,
+		RoleTemplateName: createdRT.Name,
+		BindingName:      createdPrtb.Name,
+	}
+
+	for _, namespace := range createdNamespaces {
+		change.Namespaces = append(change.Namespaces, namespace.Name)
+	}
+
+	log.Info("Measuring the propagation of the role bindings to the namespaces of the project.")
+	results := rbacapi.MeasureBindingPropagation(client, change, []string{acrp.cluster.ID}, createdAt, defaults.TwoMinuteTimeout)
+	require.NoError(acrp.T(), rbacapi.VerifyPropagationResults(results, bindingPropagationMaxLatency))
+
+	log.Infof("Disconnecting the cluster agent of %s for %s.", acrp.cluster.ID, agentDisconnectDuration)
+	disconnect, err := disconnectClusterAgent(client, acrp.cluster)
+	require.NoError(acrp.T(), err)
+	defer disconnect.Stop()
+
+	log.Infof("Deleting PRTB %s while the cluster agent is disconnected.", createdPrtb.Name)
+	deletedAt := time.Now()
+	err = rbacapi.DeleteProjectRoleTemplateBinding(client, createdPrtb.Namespace, createdPrtb.Name)
+	require.NoError(acrp.T(), err)
+
+	log.Info("Verifying the role bindings are removed once the cluster agent reconnects.")
+	results = rbacapi.MeasureBindingRemoval(client, change, []string{acrp.cluster.ID}, deletedAt, defaults.TenMinuteTimeout)
+	require.NoError(acrp.T(), disconnect.Wait())
+	require.NoError(acrp.T(), rbacapi.VerifyPropagationResults(results, agentReconnectMaxLatency))
+	require.True(acrp.T(), results[0].Unreachable, "cluster %s was never unreachable while the cluster agent was scaled down", acrp.cluster.ID)
+}
+
 func TestAggregatedClusterRolesPrtbTestSuite(t *testing.T) {
 	suite.Run(t, new(AggregatedClusterRolesPrtbTestSuite))
 }
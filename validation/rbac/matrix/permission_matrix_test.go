@@ -0,0 +1,92 @@
+//go:build (validation || infra.any || cluster.any || extended) && !sanity && !stress
+
+package matrix
+
+import (
+	"path/filepath"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	management "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+	"github.com/rancher/shepherd/extensions/clusters"
+	"github.com/rancher/shepherd/pkg/session"
+	projectapi "github.com/rancher/tests/actions/kubeapi/projects"
+	"github.com/rancher/tests/actions/rbac"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const matricesDir = "matrices"
+
+type PermissionMatrixTestSuite struct {
+	suite.Suite
+	client    *rancher.Client
+	session   *session.Session
+	cluster   *management.Cluster
+	project   *v3.Project
+	namespace *corev1.Namespace
+}
+
+func (pm *PermissionMatrixTestSuite) TearDownSuite() {
+	pm.session.Cleanup()
+}
+
+func (pm *PermissionMatrixTestSuite) SetupSuite() {
+	pm.session = session.NewSession()
+
+	client, err := rancher.NewClient("", pm.session)
+	require.NoError(pm.T(), err)
+
+	pm.client = client
+
+	log.Info("Getting cluster name from the config file and append cluster details in pm")
+	clusterName := client.RancherConfig.ClusterName
+	require.NotEmptyf(pm.T(), clusterName, "Cluster name to install should be set")
+	clusterID, err := clusters.GetClusterIDByName(pm.client, clusterName)
+	require.NoError(pm.T(), err, "Error getting cluster ID")
+	pm.cluster, err = pm.client.Management.Cluster.ByID(clusterID)
+	require.NoError(pm.T(), err)
+
+	log.Info("Creating a project and a namespace to evaluate the project and namespace scoped rules")
+	pm.project, pm.namespace, err = projectapi.CreateProjectAndNamespace(pm.client, pm.cluster.ID)
+	require.NoError(pm.T(), err)
+}
+
+func (pm *PermissionMatrixTestSuite) TestPermissionMatrix() {
+	tests := []struct {
+		name       string
+		matrixFile string
+	}{
+		{"Global roles", "global_roles.yaml"},
+		{"Cluster roles", "cluster_roles.yaml"},
+		{"Project roles", "project_roles.yaml"},
+	}
+
+	for _, tt := range tests {
+		pm.Run(tt.name, func() {
+			subSession := pm.session.NewSession()
+			defer subSession.Cleanup()
+
+			client, err := pm.client.WithSession(subSession)
+			require.NoError(pm.T(), err)
+
+			matrix, err := rbac.LoadPermissionMatrix(filepath.Join(matricesDir, tt.matrixFile))
+			require.NoError(pm.T(), err)
+
+			log.Infof("Evaluating %d rules of %s", len(matrix.Rules), tt.matrixFile)
+			err = rbac.VerifyPermissionMatrix(client, matrix, &rbac.MatrixTarget{
+				Cluster:   pm.cluster,
+				Project:   pm.project,
+				Namespace: pm.namespace.Name,
+			})
+			require.NoError(pm.T(), err)
+		})
+	}
+}
+
+func TestPermissionMatrixTestSuite(t *testing.T) {
+	suite.Run(t, new(PermissionMatrixTestSuite))
+}
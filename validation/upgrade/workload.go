@@ -23,6 +23,7 @@ import (
 	"github.com/rancher/tests/actions/projects"
 	"github.com/rancher/tests/actions/secrets"
 	"github.com/rancher/tests/actions/services"
+	"github.com/rancher/tests/actions/upgrade"
 	"github.com/rancher/tests/actions/upgradeinput"
 	"github.com/rancher/tests/actions/workloads"
 	"github.com/sirupsen/logrus"
@@ -238,6 +239,24 @@ func createPreUpgradeWorkloads(t *testing.T, client *rancher.Client, clusterName
 		assert.True(t, isIngressForDaemonsetAccessible)
 	}
 
+	logrus.Infof("Capturing the pre-upgrade workload state of namespace %v", namespace.Name)
+	workloadState, err := upgrade.CaptureWorkloadState(client, project.ClusterID, []string{namespace.Name}, *featuresToTest.Ingress)
+	require.NoError(t, err)
+
+	workloadStatePath := stateFilePath(t, "upgrade-workload-state-"+clusterName+".json")
+	logrus.Infof("Saving the pre-upgrade workload state to %s", workloadStatePath)
+	err = upgrade.SaveWorkloadState(workloadState, workloadStatePath)
+	require.NoError(t, err)
+
+	logrus.Infof("Capturing the pre-upgrade RBAC state of cluster %v", project.ClusterID)
+	rbacState, err := upgrade.CaptureRBACState(client, []string{project.ClusterID})
+	require.NoError(t, err)
+
+	rbacStatePath := stateFilePath(t, "upgrade-rbac-state-"+clusterName+".json")
+	logrus.Infof("Saving the pre-upgrade RBAC state to %s", rbacStatePath)
+	err = upgrade.SaveRBACState(rbacState, rbacStatePath)
+	require.NoError(t, err)
+
 	if *featuresToTest.Chart {
 		logrus.Infof("Checking if the logging chart is installed in cluster: %v", project.ClusterID)
 		loggingChart, err := extensionscharts.GetChartStatus(client, project.ClusterID, charts.RancherLoggingNamespace, charts.RancherLoggingName)
@@ -372,6 +391,30 @@ func createPostUpgradeWorkloads(t *testing.T, client *rancher.Client, clusterNam
 		require.NoError(t, err)
 		assert.True(t, loggingChart.IsAlreadyInstalled)
 	}
+
+	rbacStatePath := stateFilePath(t, "upgrade-rbac-state-"+clusterName+".json")
+	preUpgradeRBACState, err := upgrade.LoadRBACState(rbacStatePath)
+	require.NoErrorf(t, err, "Pre-upgrade RBAC state couldn't be loaded from %s, the pre-upgrade run must use the same %s", rbacStatePath, upgrade.StateDirEnvVar)
+
+	logrus.Infof("Comparing the RBAC state of cluster %s with the pre-upgrade state...", project.ClusterID)
+	_, err = upgrade.VerifyRBACState(client, preUpgradeRBACState)
+	assert.NoError(t, err)
+
+	workloadStatePath := stateFilePath(t, "upgrade-workload-state-"+clusterName+".json")
+	preUpgradeState, err := upgrade.LoadWorkloadState(workloadStatePath)
+	require.NoErrorf(t, err, "Pre-upgrade workload state couldn't be loaded from %s, the pre-upgrade run must use the same %s", workloadStatePath, upgrade.StateDirEnvVar)
+
+	logrus.Infof("Comparing the workload state of namespace %s with the pre-upgrade state...", namespace.Name)
+	_, err = upgrade.VerifyWorkloadState(client, preUpgradeState, *featuresToTest.Ingress)
+	assert.NoError(t, err)
+}
+
+// stateFilePath returns the path of a file used to store a pre-upgrade state, in the directory of upgrade.StateDirEnvVar
+func stateFilePath(t *testing.T, name string) string {
+	path, err := upgrade.StateFilePath(name)
+	require.NoError(t, err)
+
+	return path
 }
 
 func getSteveID(namespaceName, resourceName string) string {